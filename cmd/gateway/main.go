@@ -5,42 +5,81 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/dskow/gateway-core/internal/admin"
 	"github.com/dskow/gateway-core/internal/config"
 	"github.com/dskow/gateway-core/internal/gateway"
 	"github.com/dskow/gateway-core/internal/logging"
 )
 
+// Build metadata, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=...".
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
 func main() {
-	configPath := flag.String("config", "configs/gateway.yaml", "path to configuration file")
-	flag.Parse()
+	os.Exit(Run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run parses args, loads the config, and either validates it (-validate) or
+// runs the gateway until SIGINT/SIGTERM. It returns the process exit code:
+// 0 on success, 1 on a config or runtime error, 2 on a usage error.
+// Exported so tests can drive the CLI without spawning a process.
+func Run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "configs/gateway.yaml", "path to configuration file")
+	validateOnly := fs.Bool("validate", false, "validate the config, print warnings and the effective config, and exit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *validateOnly {
+		return validateConfig(*configPath, stdout, stderr)
+	}
 
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		slog.New(slog.NewJSONHandler(os.Stderr, nil)).Error("failed to load config", "error", err)
-		os.Exit(1)
+		slog.New(slog.NewJSONHandler(stderr, nil)).Error("failed to load config", "error", err)
+		return 1
 	}
 
-	logWriter, logCloser := buildLogWriter(cfg.Logging)
+	logWriter, logCloser := buildLogWriter(cfg.Logging, stdout, stderr)
 	if logCloser != nil {
 		defer func() {
 			if err := logCloser.Close(); err != nil {
-				slog.New(slog.NewJSONHandler(os.Stderr, nil)).Error("failed to close log writer", "error", err)
+				slog.New(slog.NewJSONHandler(stderr, nil)).Error("failed to close log writer", "error", err)
 			}
 		}()
 	}
 	logger := slog.New(slog.NewJSONHandler(logWriter, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	if cfg.Logging.ExternalRotation {
+		if rw, ok := logCloser.(*logging.RotatingWriter); ok {
+			defer reopenOnSIGHUP(rw, logger)()
+		} else {
+			// The log file could not be opened and logging fell back to
+			// stdout. Reload is on SIGUSR1 regardless; keep the rotator's
+			// SIGHUP from stopping the gateway.
+			signal.Ignore(syscall.SIGHUP)
+		}
+	}
 
 	for _, w := range cfg.Warnings {
 		logger.Warn("config warning", "message", w)
 	}
 	logger.Info("configuration loaded",
+		"version", version,
 		"port", cfg.Server.Port,
 		"routes", len(cfg.Routes),
 		"auth_enabled", cfg.Auth.Enabled,
@@ -57,33 +96,127 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	gw, err := gateway.NewGateway(ctx, cfg, logger, gateway.Options{})
+	gw, err := gateway.NewGateway(ctx, cfg, logger, gateway.Options{
+		Build: admin.BuildInfo{Version: version, Commit: commit, BuildDate: buildDate},
+	})
 	if err != nil {
 		logger.Error("failed to build gateway", "error", err)
-		os.Exit(1)
+		return 1
 	}
 	gw.SetReloadPath(*configPath)
 
 	if err := gw.Run(ctx); err != nil {
 		logger.Error("gateway exited with error", "error", err)
-		os.Exit(1)
+		return 1
+	}
+	return 0
+}
+
+// validateConfig loads and validates the config at path without binding the
+// port or starting any background work. Warnings go to stderr; the effective
+// config (defaults applied, secrets redacted as in the admin API) goes to
+// stdout as JSON.
+func validateConfig(path string, stdout, stderr io.Writer) int {
+	cfg, err := config.Load(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "config %s is invalid: %v\n", path, err)
+		return 1
+	}
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintf(stderr, "writing effective config: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "config %s is valid\n", path)
+	return 0
+}
+
+// reopenOnSIGHUP reopens rw on every SIGHUP so logging follows an external
+// rotator's rename to a fresh file. The returned func stops listening.
+func reopenOnSIGHUP(rw *logging.RotatingWriter, logger *slog.Logger) func() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigCh:
+				if err := rw.Reopen(); err != nil {
+					logger.Error("failed to reopen log file", "error", err)
+				} else {
+					logger.Info("SIGHUP received, log file reopened")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
 	}
 }
 
 // buildLogWriter returns the io.Writer for the slog handler and an optional
-// io.Closer for file-based writers. Returns (os.Stdout, nil) for the default.
-func buildLogWriter(cfg config.LoggingConfig) (io.Writer, io.Closer) {
+// io.Closer for file-based writers. Returns (stdout, nil) for the default.
+// With logging.also_stdout, file and syslog output is mirrored to stdout;
+// the closer still closes only the file or syslog connection.
+func buildLogWriter(cfg config.LoggingConfig, stdout, stderr io.Writer) (io.Writer, io.Closer) {
+	w, closer := buildLogOutput(cfg, stdout, stderr)
+	if cfg.AlsoStdout && closer != nil {
+		return teeWriter{stdout, w}, closer
+	}
+	return w, closer
+}
+
+// teeWriter writes to every one of its writers. Unlike io.MultiWriter it
+// carries on past a failing writer, so a full disk or a lost syslog
+// connection does not also silence stdout.
+type teeWriter []io.Writer
+
+// Write writes p to each writer in turn and returns the first error.
+func (t teeWriter) Write(p []byte) (int, error) {
+	var firstErr error
+	for _, w := range t {
+		if _, err := w.Write(p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(p), firstErr
+}
+
+// buildLogOutput opens the single sink named by logging.output.
+func buildLogOutput(cfg config.LoggingConfig, stdout, stderr io.Writer) (io.Writer, io.Closer) {
 	switch cfg.Output {
 	case "stdout", "":
-		return os.Stdout, nil
+		return stdout, nil
 	case "stderr":
-		return os.Stderr, nil
+		return stderr, nil
+	case "syslog":
+		sl := cfg.Syslog
+		sw, err := logging.NewSyslogWriter(sl.Network, sl.Address, sl.Facility, sl.Tag)
+		if err != nil {
+			slog.New(slog.NewJSONHandler(stderr, nil)).Warn("failed to connect to syslog, falling back to stdout",
+				"network", sl.Network, "address", sl.Address, "error", err)
+			return stdout, nil
+		}
+		return sw, sw
 	default:
 		rw, err := logging.NewRotatingWriter(cfg.Output, cfg.MaxSizeMB, cfg.MaxBackups, cfg.MaxAgeDays)
 		if err != nil {
-			slog.New(slog.NewJSONHandler(os.Stderr, nil)).Error("failed to open log file, falling back to stdout",
+			slog.New(slog.NewJSONHandler(stderr, nil)).Error("failed to open log file, falling back to stdout",
 				"path", cfg.Output, "error", err)
-			return os.Stdout, nil
+			return stdout, nil
+		}
+		if cfg.RotateDaily {
+			rw.EnableDailyRotation()
+		}
+		if cfg.Compress {
+			rw.EnableCompression()
 		}
 		return rw, rw
 	}
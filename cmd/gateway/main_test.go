@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestRun_ValidateValidConfig(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 8080
+auth:
+  enabled: true
+  jwt_secret: "${GATEWAY_TEST_UNSET_SECRET}"
+  issuer: gateway-test
+  audience: gateway-test
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"-config", path, "-validate"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit 0, got %d; stderr: %s", code, stderr.String())
+	}
+
+	var effective map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &effective); err != nil {
+		t.Fatalf("stdout is not the effective config JSON: %v\n%s", err, stdout.String())
+	}
+	auth := effective["auth"].(map[string]interface{})
+	if auth["jwt_secret"] != "***" {
+		t.Errorf("expected jwt_secret redacted, got %v", auth["jwt_secret"])
+	}
+	server := effective["server"].(map[string]interface{})
+	if server["max_body_bytes"] == float64(0) {
+		t.Error("expected defaults applied in effective config")
+	}
+	if !strings.Contains(stderr.String(), "warning: auth.jwt_secret contains unresolved environment variable") {
+		t.Errorf("expected config warning on stderr, got %q", stderr.String())
+	}
+}
+
+func TestRun_ValidateInvalidConfig(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 70000
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"-config", path, "-validate"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "server.port") {
+		t.Errorf("expected validation error on stderr, got %q", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no effective config on failure, got %q", stdout.String())
+	}
+}
+
+func TestRun_ValidateMissingFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml"), "-validate"}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+}
+
+func TestRun_UnknownFlag(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"-bogus"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+}
+
+func TestBuildLogWriter_AlsoStdoutWritesBothSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.log")
+	var stdout, stderr bytes.Buffer
+	w, closer := buildLogWriter(config.LoggingConfig{
+		Output:     path,
+		MaxSizeMB:  1,
+		MaxBackups: 1,
+		MaxAgeDays: 1,
+		AlsoStdout: true,
+	}, &stdout, &stderr)
+	if closer == nil {
+		t.Fatal("expected a closer for the file sink")
+	}
+
+	slog.New(slog.NewJSONHandler(w, nil)).Info("hello both")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(data), "hello both") {
+		t.Errorf("log line missing from file: %q", data)
+	}
+	if !strings.Contains(stdout.String(), "hello both") {
+		t.Errorf("log line missing from stdout: %q", stdout.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+// A failing sink does not stop the line from reaching the others.
+func TestTeeWriter_WritesPastAFailingWriter(t *testing.T) {
+	var before, after bytes.Buffer
+	w := teeWriter{&before, failingWriter{}, &after}
+
+	n, err := w.Write([]byte("line\n"))
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("err = %v, want the failing writer's error", err)
+	}
+	if n != len("line\n") {
+		t.Errorf("n = %d, want %d", n, len("line\n"))
+	}
+	if before.String() != "line\n" || after.String() != "line\n" {
+		t.Errorf("sinks got %q and %q, want the line in both", before.String(), after.String())
+	}
+}
+
+func TestBuildLogWriter_FileOnlyByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.log")
+	var stdout, stderr bytes.Buffer
+	w, closer := buildLogWriter(config.LoggingConfig{Output: path, MaxSizeMB: 1}, &stdout, &stderr)
+	defer func() { _ = closer.Close() }()
+
+	slog.New(slog.NewJSONHandler(w, nil)).Info("file only")
+	if stdout.Len() != 0 {
+		t.Errorf("stdout written without also_stdout: %q", stdout.String())
+	}
+}
@@ -1,27 +1,41 @@
-// Package admin provides read-only admin API endpoints for runtime inspection
-// of gateway state. All endpoints are protected by IP allowlist.
+// Package admin provides admin API endpoints for runtime inspection of
+// gateway state. All endpoints are protected by IP allowlist and are
+// read-only, except DELETE /admin/limiters for clearing rate-limit buckets.
 package admin
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dskow/gateway-core/internal/circuitbreaker"
 	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/proxy"
 	"github.com/dskow/gateway-core/internal/ratelimit"
+	"gopkg.in/yaml.v3"
 )
 
 // Handler provides admin API endpoints.
 type Handler struct {
 	reloader    ConfigProvider
 	limiter     *ratelimit.Limiter
-	breakers    map[string]*circuitbreaker.CompositeBreaker
-	routes      []config.RouteConfig
 	allowedNets []*net.IPNet
 	logger      *slog.Logger
+	status      StatusSource
+	transports  func() []proxy.TransportStats // nil until SetTransportStats
+	events      *circuitbreaker.EventBus      // nil until SetEvents
+
+	mu       sync.RWMutex // guards routes and breakers, replaced by UpdateRoutes
+	routes   []config.RouteConfig
+	breakers map[string]*circuitbreaker.CompositeBreaker
 }
 
 // ConfigProvider abstracts config access for testability.
@@ -29,6 +43,28 @@ type ConfigProvider interface {
 	Current() *config.Config
 }
 
+// ReloadStatusProvider is optionally implemented by the ConfigProvider
+// (*config.Reloader does) to expose reload history on /admin/status.
+type ReloadStatusProvider interface {
+	ReloadStatus() config.ReloadStatus
+}
+
+// BuildInfo identifies the running binary. main populates it from
+// variables set with -ldflags at build time.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// StatusSource supplies the process-level facts reported by /admin/status
+// that the admin package cannot observe itself.
+type StatusSource struct {
+	Build     BuildInfo
+	StartedAt time.Time
+	Draining  func() bool // nil reports false
+}
+
 // New creates a new admin Handler. The allowlist CIDRs must be pre-validated
 // (config validation ensures this).
 func New(
@@ -39,6 +75,63 @@ func New(
 	allowlist []string,
 	logger *slog.Logger,
 ) *Handler {
+	return &Handler{
+		reloader:    reloader,
+		limiter:     limiter,
+		breakers:    breakers,
+		routes:      routes,
+		allowedNets: parseAllowlist(allowlist),
+		logger:      logger,
+		status:      StatusSource{StartedAt: time.Now()},
+	}
+}
+
+// UpdateRoutes makes /admin/routes list routes, with the state of their
+// breakers, from now on. Safe to call while serving; a config reload uses
+// it.
+func (h *Handler) UpdateRoutes(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routes, h.breakers = routes, breakers
+}
+
+// SetStatusSource wires build info, start time, and drain state for
+// /admin/status. Must be called before the handler serves requests.
+func (h *Handler) SetStatusSource(src StatusSource) {
+	h.status = src
+}
+
+// SetTransportStats wires the per-backend connection counters served by
+// /admin/transport, normally (*proxy.Router).TransportStats. Must be called
+// before the handler serves requests.
+func (h *Handler) SetTransportStats(fn func() []proxy.TransportStats) {
+	h.transports = fn
+}
+
+// SetEvents wires the bus whose breaker state changes /admin/events
+// streams. Must be called before the handler serves requests.
+func (h *Handler) SetEvents(bus *circuitbreaker.EventBus) {
+	h.events = bus
+}
+
+// IPAllowlist returns middleware that admits only clients whose address is
+// in allowlist and answers everyone else with 403, exactly like the admin
+// endpoints. Used to protect /metrics when metrics.protected is set.
+func IPAllowlist(allowlist []string, logger *slog.Logger) func(http.Handler) http.Handler {
+	h := &Handler{allowedNets: parseAllowlist(allowlist), logger: logger}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !h.allowRemote(w, r) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseAllowlist converts CIDR strings to networks. The CIDRs must be
+// pre-validated (config validation ensures this).
+func parseAllowlist(allowlist []string) []*net.IPNet {
 	nets := make([]*net.IPNet, 0, len(allowlist))
 	for _, cidr := range allowlist {
 		_, ipNet, err := net.ParseCIDR(cidr)
@@ -47,45 +140,58 @@ func New(
 		}
 		nets = append(nets, ipNet)
 	}
-	return &Handler{
-		reloader:    reloader,
-		limiter:     limiter,
-		breakers:    breakers,
-		routes:      routes,
-		allowedNets: nets,
-		logger:      logger,
-	}
+	return nets
 }
 
 // RegisterRoutes adds admin routes to the given mux.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/admin/routes", h.guard(h.routesHandler))
+	mux.HandleFunc("/admin/routes/", h.guard(h.routeDetailHandler))
 	mux.HandleFunc("/admin/config", h.guard(h.configHandler))
-	mux.HandleFunc("/admin/limiters", h.guard(h.limitersHandler))
+	mux.HandleFunc("/admin/config.yaml", h.guard(h.configYAMLHandler))
+	mux.HandleFunc("/admin/limiters", h.guardMethods(h.limitersHandler, http.MethodGet, http.MethodDelete))
+	mux.HandleFunc("/admin/status", h.guard(h.statusHandler))
+	mux.HandleFunc("/admin/transport", h.guard(h.transportHandler))
+	mux.HandleFunc("/admin/events", h.guard(h.eventsHandler))
 }
 
-// guard wraps a handler with IP allowlist checking.
+// guard wraps a GET-only handler with IP allowlist checking.
 func (h *Handler) guard(next http.HandlerFunc) http.HandlerFunc {
+	return h.guardMethods(next, http.MethodGet)
+}
+
+// guardMethods wraps a handler with IP allowlist checking, answering any
+// method not in methods with 405.
+func (h *Handler) guardMethods(next http.HandlerFunc, methods ...string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if !slices.Contains(methods, r.Method) {
 			h.writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
 				"error": "Method Not Allowed",
 			})
 			return
 		}
 
-		ip := extractIP(r.RemoteAddr)
-		if !h.isAllowed(ip) {
-			h.logger.Warn("admin access denied", "client_ip", ip, "path", r.URL.Path)
-			h.writeJSON(w, http.StatusForbidden, map[string]string{
-				"error": "Forbidden",
-			})
+		if !h.allowRemote(w, r) {
 			return
 		}
 		next(w, r)
 	}
 }
 
+// allowRemote reports whether the client address is allowlisted, writing a
+// 403 when it is not.
+func (h *Handler) allowRemote(w http.ResponseWriter, r *http.Request) bool {
+	ip := extractIP(r.RemoteAddr)
+	if !h.isAllowed(ip) {
+		h.logger.Warn("admin access denied", "client_ip", ip, "path", r.URL.Path)
+		h.writeJSON(w, http.StatusForbidden, map[string]string{
+			"error": "Forbidden",
+		})
+		return false
+	}
+	return true
+}
+
 func (h *Handler) isAllowed(ipStr string) bool {
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
@@ -110,6 +216,7 @@ func extractIP(remoteAddr string) string {
 // routeStatus is the response type for /admin/routes.
 type routeStatus struct {
 	PathPrefix          string   `json:"path_prefix"`
+	Hosts               []string `json:"hosts,omitempty"`
 	Backend             string   `json:"backend"`
 	Methods             []string `json:"methods,omitempty"`
 	AuthRequired        bool     `json:"auth_required"`
@@ -118,44 +225,361 @@ type routeStatus struct {
 }
 
 func (h *Handler) routesHandler(w http.ResponseWriter, _ *http.Request) {
-	statuses := make([]routeStatus, len(h.routes))
-	for i, route := range h.routes {
-		cbState := "unknown"
-		if cb, ok := h.breakers[route.Backend]; ok && cb != nil {
-			switch cb.State() {
-			case circuitbreaker.StateClosed:
-				cbState = "closed"
-			case circuitbreaker.StateOpen:
-				cbState = "open"
-			case circuitbreaker.StateHalfOpen:
-				cbState = "half-open"
-			}
-		}
+	h.mu.RLock()
+	routes := h.routes
+	h.mu.RUnlock()
+	statuses := make([]routeStatus, len(routes))
+	for i, route := range routes {
 		statuses[i] = routeStatus{
 			PathPrefix:          route.PathPrefix,
+			Hosts:               route.Hosts,
 			Backend:             route.Backend,
 			Methods:             route.Methods,
 			AuthRequired:        route.AuthRequired,
 			TimeoutMs:           route.TimeoutMs,
-			CircuitBreakerState: cbState,
+			CircuitBreakerState: h.breakerState(route),
 		}
 	}
 	h.writeJSON(w, http.StatusOK, map[string]interface{}{"routes": statuses})
 }
 
-func (h *Handler) configHandler(w http.ResponseWriter, _ *http.Request) {
+// effectiveRoute is the response type for /admin/routes/{prefix}: one
+// route's settings with defaults and global fallbacks resolved.
+type effectiveRoute struct {
+	PathPrefix              string                  `json:"path_prefix"`
+	MatchType               string                  `json:"match_type"`
+	Hosts                   []string                `json:"hosts,omitempty"`
+	MatchConditions         []config.MatchCondition `json:"match_conditions,omitempty"`
+	Backend                 string                  `json:"backend"`
+	BackendAllowedHosts     []string                `json:"backend_allowed_hosts,omitempty"`
+	Methods                 []string                `json:"methods,omitempty"`
+	MethodRewrite           map[string]string       `json:"method_rewrite,omitempty"`
+	StripPrefix             bool                    `json:"strip_prefix"`
+	AuthRequired            bool                    `json:"auth_required"`
+	AuthEnforced            bool                    `json:"auth_enforced"` // auth_required and auth.enabled
+	Scopes                  []string                `json:"scopes,omitempty"`
+	TimeoutMs               int64                   `json:"timeout_ms"`
+	TimeoutJitter           float64                 `json:"timeout_jitter"`
+	RetryAttempts           int                     `json:"retry_attempts"`
+	RetryMaxBufferBytes     int                     `json:"retry_max_buffer_bytes"`
+	WrapUpstreamErrors      bool                    `json:"wrap_upstream_errors"`
+	RequestSchema           string                  `json:"request_schema,omitempty"`
+	StripResponseFields     []string                `json:"strip_response_fields,omitempty"`
+	StripResponseHeaders    []string                `json:"strip_response_headers,omitempty"`
+	ResponseHeaders         map[string]string       `json:"response_headers,omitempty"`
+	ResponseHeadersOverride bool                    `json:"response_headers_override"`
+	ClientBodyTimeoutMs     int                     `json:"client_body_timeout_ms"`
+	ResponseHeaderTimeoutMs int                     `json:"response_header_timeout_ms"`
+	MaxConcurrent           int                     `json:"max_concurrent"`
+	QueueTimeoutMs          int                     `json:"queue_timeout_ms"`
+	RateLimit               effectiveRateLimit      `json:"rate_limit"`
+	CircuitBreaker          effectiveBreaker        `json:"circuit_breaker"`
+	LogLevel                string                  `json:"log_level"`
+	LogSampleRate           float64                 `json:"log_sample_rate"`
+	LogFields               []string                `json:"log_fields,omitempty"`
+	MetricsLabel            string                  `json:"metrics_label"`
+	MetricsDisabled         bool                    `json:"metrics_disabled"`
+	ExcludeFromReadiness    bool                    `json:"exclude_from_readiness"`
+	Critical                bool                    `json:"critical"`
+	RedirectPolicy          string                  `json:"redirect_policy"`
+	Headers                 map[string]string       `json:"headers,omitempty"`
+	RequestScript           string                  `json:"request_script,omitempty"`
+	ScriptTimeoutMs         int64                   `json:"script_timeout_ms"`
+}
+
+type effectiveRateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	BurstSize         int     `json:"burst_size"`
+	Source            string  `json:"source"`  // "route" (rate_override) or "global"
+	Skipped           bool    `json:"skipped"` // skip_rate_limit: the route is never limited
+}
+
+type effectiveBreaker struct {
+	Scope string `json:"scope"`
+	Key   string `json:"key"`
+	State string `json:"state"`
+	config.CircuitBreakerConfig
+
+	// Source is "route" when the route's circuit_breaker applies, else
+	// "global".
+	Source string `json:"source"`
+
+	// FailOpen mirrors the route's fail_open.
+	FailOpen bool `json:"fail_open"`
+
+	// ServeStale mirrors the route's serve_stale_on_open.
+	ServeStale bool `json:"serve_stale_on_open"`
+}
+
+// routeDetailHandler serves /admin/routes/{prefix}. The prefix is the
+// route's path_prefix, URL-encoded (/admin/routes/%2Fapi%2Fusers) or not
+// (/admin/routes/api/users), and must match a route exactly. When routes
+// on different hosts share the prefix, ?host= picks the one serving that
+// host; without it a route without hosts is preferred. Among routes that
+// differ only in match conditions, the one without conditions is shown.
+func (h *Handler) routeDetailHandler(w http.ResponseWriter, r *http.Request) {
+	prefix, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/admin/routes/"))
+	if err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid route prefix encoding"})
+		return
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
 	cfg := h.reloader.Current()
+	host := r.URL.Query().Get("host")
+	var found config.RouteConfig
+	bestRank := 0
+	for _, route := range cfg.Routes {
+		if route.PathPrefix != prefix {
+			continue
+		}
+		rank := route.HostRank(host)
+		if host == "" && len(route.Hosts) > 0 {
+			rank = 1 // any host-specific route, below a route without hosts
+		} else if host == "" {
+			rank = 2
+		}
+		if rank > bestRank || (rank == bestRank && len(found.MatchConditions) > 0 && len(route.MatchConditions) == 0) {
+			found, bestRank = route, rank
+		}
+	}
+	if bestRank > 0 {
+		h.writeJSON(w, http.StatusOK, h.effectiveRoute(cfg, found))
+		return
+	}
+	h.writeJSON(w, http.StatusNotFound, map[string]string{"error": "no route with path_prefix " + prefix})
+}
+
+func (h *Handler) effectiveRoute(cfg *config.Config, route config.RouteConfig) effectiveRoute {
+	rl := effectiveRateLimit{
+		RequestsPerSecond: cfg.RateLimit.RequestsPerSecond,
+		BurstSize:         cfg.RateLimit.BurstSize,
+		Source:            "global",
+	}
+	if o := route.RateOverride; o != nil {
+		rl = effectiveRateLimit{RequestsPerSecond: o.RequestsPerSecond, BurstSize: o.BurstSize, Source: "route"}
+	}
+	rl.Skipped = route.SkipRateLimit
+
+	logLevel := route.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+	}
+	sampleRate := cfg.Logging.AccessLogSampleRate()
+	if route.LogSampleRate != nil {
+		sampleRate = *route.LogSampleRate
+	}
+	scope := route.BreakerScope
+	if scope == "" {
+		scope = "backend"
+	}
+	breakerSource := "global"
+	if route.CircuitBreaker != nil {
+		breakerSource = "route"
+	}
+	redirect := route.RedirectPolicy
+	if redirect == "" {
+		redirect = "passthrough"
+	}
+	matchType := route.MatchType
+	if matchType == "" {
+		matchType = "prefix"
+	}
 
-	// Deep copy and redact sensitive fields.
-	redacted := *cfg
-	if redacted.Auth.JWTSecret != "" {
-		redacted.Auth.JWTSecret = "***"
+	var scopes []string
+	if route.AuthRequired {
+		scopes = cfg.Auth.Scopes
 	}
 
-	h.writeJSON(w, http.StatusOK, redacted)
+	return effectiveRoute{
+		PathPrefix:              route.PathPrefix,
+		MatchType:               matchType,
+		Hosts:                   route.Hosts,
+		MatchConditions:         route.MatchConditions,
+		Backend:                 route.Backend,
+		BackendAllowedHosts:     route.BackendAllowedHosts,
+		Methods:                 route.Methods,
+		MethodRewrite:           route.MethodRewrite,
+		StripPrefix:             route.StripPrefix,
+		AuthRequired:            route.AuthRequired,
+		AuthEnforced:            route.AuthRequired && cfg.Auth.Enabled,
+		Scopes:                  scopes,
+		TimeoutMs:               route.Timeout().Milliseconds(),
+		TimeoutJitter:           route.TimeoutJitter,
+		RetryAttempts:           route.RetryAttempts,
+		RetryMaxBufferBytes:     route.RetryBufferLimit(),
+		WrapUpstreamErrors:      route.WrapUpstreamErrors,
+		RequestSchema:           route.RequestSchema,
+		StripResponseFields:     route.StripResponseFields,
+		StripResponseHeaders:    route.StripResponseHeaders,
+		ResponseHeaders:         route.ResponseHeaders,
+		ResponseHeadersOverride: route.ResponseHeadersOverride,
+		ClientBodyTimeoutMs:     route.ClientBodyTimeoutMs,
+		ResponseHeaderTimeoutMs: route.ResponseHeaderTimeoutMs,
+		MaxConcurrent:           route.MaxConcurrent,
+		QueueTimeoutMs:          route.QueueTimeoutMs,
+		RateLimit:               rl,
+		CircuitBreaker: effectiveBreaker{
+			Scope:                scope,
+			Key:                  route.BreakerKey(),
+			State:                h.breakerState(route),
+			Source:               breakerSource,
+			FailOpen:             route.FailOpen,
+			ServeStale:           route.ServeStaleOnOpen,
+			CircuitBreakerConfig: route.Breaker(cfg.CircuitBreaker),
+		},
+		LogLevel:             logLevel,
+		LogSampleRate:        sampleRate,
+		LogFields:            cfg.Logging.AccessLogFields(route),
+		MetricsLabel:         route.MetricsRoute(),
+		MetricsDisabled:      route.MetricsDisabled,
+		ExcludeFromReadiness: route.ExcludeFromReadiness,
+		Critical:             route.Critical,
+		RedirectPolicy:       redirect,
+		Headers:              route.Headers,
+		RequestScript:        route.RequestScript,
+		ScriptTimeoutMs:      route.ScriptTimeout().Milliseconds(),
+	}
+}
+
+// breakerState reports the circuit state of route's breaker, "unknown"
+// when it has none.
+func (h *Handler) breakerState(route config.RouteConfig) string {
+	h.mu.RLock()
+	cb := h.breakers[route.BreakerKey()]
+	h.mu.RUnlock()
+	if cb == nil {
+		return "unknown"
+	}
+	switch cb.State() {
+	case circuitbreaker.StateClosed:
+		return "closed"
+	case circuitbreaker.StateOpen:
+		return "open"
+	case circuitbreaker.StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+func (h *Handler) configHandler(w http.ResponseWriter, _ *http.Request) {
+	h.writeJSON(w, http.StatusOK, h.reloader.Current().Redacted())
+}
+
+// configYAMLHandler serves the running config, redacted as on
+// /admin/config, as a YAML download to diff against the source file.
+// Defaults are filled in, so it lists every setting, not just those the
+// file sets.
+func (h *Handler) configYAMLHandler(w http.ResponseWriter, _ *http.Request) {
+	out, err := yaml.Marshal(h.reloader.Current().Redacted())
+	if err != nil {
+		h.logger.Error("admin: failed to marshal config as YAML", "error", err)
+		h.writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "Internal Server Error",
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="gateway.yaml"`)
+	if _, err := w.Write(out); err != nil {
+		h.logger.Debug("admin: failed to write config YAML", "error", err)
+	}
+}
+
+// statusResponse is the response type for /admin/status.
+type statusResponse struct {
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Build         BuildInfo `json:"build"`
+	config.ReloadStatus
+	Routes   int  `json:"routes"`
+	Draining bool `json:"draining"`
+}
+
+func (h *Handler) statusHandler(w http.ResponseWriter, _ *http.Request) {
+	resp := statusResponse{
+		StartedAt:     h.status.StartedAt,
+		UptimeSeconds: int64(time.Since(h.status.StartedAt).Seconds()),
+		Build:         h.status.Build,
+		Routes:        len(h.reloader.Current().Routes),
+	}
+	if rs, ok := h.reloader.(ReloadStatusProvider); ok {
+		resp.ReloadStatus = rs.ReloadStatus()
+	}
+	if h.status.Draining != nil {
+		resp.Draining = h.status.Draining()
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) transportHandler(w http.ResponseWriter, _ *http.Request) {
+	stats := []proxy.TransportStats{}
+	if h.transports != nil {
+		stats = h.transports()
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"backends": stats})
+}
+
+// eventsKeepalive is how often /admin/events writes a comment line when
+// no breaker changes state, so idle-timeout proxies keep the stream open.
+const eventsKeepalive = 15 * time.Second
+
+// eventsHandler streams breaker state changes as server-sent events, one
+// "breaker" event per transition with a circuitbreaker.Event as JSON data,
+// until the client disconnects or the gateway shuts down.
+func (h *Handler) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		h.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not Found"})
+		return
+	}
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	// The stream is meant to outlive server.write_timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		h.logger.Warn("admin: events stream cannot flush", "error", err)
+		return
+	}
+
+	keepalive := time.NewTicker(eventsKeepalive)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			_, err = fmt.Fprintf(w, "event: breaker\ndata: %s\n\n", data)
+		case <-keepalive.C:
+			_, err = io.WriteString(w, ": keepalive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			h.logger.Debug("admin: events stream closed", "error", err)
+			return
+		}
+	}
 }
 
 func (h *Handler) limitersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		h.resetLimiters(w, r)
+		return
+	}
+
 	entries := h.limiter.Snapshot()
 
 	// Pagination: page/page_size from query params.
@@ -190,6 +614,36 @@ func (h *Handler) limitersHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// resetLimiters serves DELETE /admin/limiters. ?ip=<addr> clears that
+// client's buckets; ?all=true clears every client. One of the two is
+// required so a bare DELETE cannot wipe all state by accident.
+func (h *Handler) resetLimiters(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ip := q.Get("ip")
+	switch {
+	case ip != "":
+		if net.ParseIP(ip) == nil {
+			h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ip is not a valid IP address"})
+			return
+		}
+	case q.Get("all") == "true":
+	default:
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ip or all=true query parameter required"})
+		return
+	}
+
+	removed := h.limiter.Reset(ip)
+	h.logger.Info("admin: rate limiter reset",
+		"ip", ip,
+		"all", ip == "",
+		"removed", removed,
+		"remote_addr", r.RemoteAddr,
+	)
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"removed": removed,
+	})
+}
+
 func parseInt(s string) int {
 	s = strings.TrimSpace(s)
 	n := 0
@@ -1,16 +1,21 @@
 package admin
 
 import (
+	"bufio"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dskow/gateway-core/internal/circuitbreaker"
 	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/proxy"
 	"github.com/dskow/gateway-core/internal/ratelimit"
+	"gopkg.in/yaml.v3"
 )
 
 // mockConfigProvider implements ConfigProvider for testing.
@@ -37,10 +42,11 @@ func testHandler(t *testing.T, allowlist []string) (*Handler, *ratelimit.Limiter
 
 	cfg := &config.Config{
 		Auth: config.AuthConfig{
-			Enabled:   true,
-			JWTSecret: "super-secret-key",
-			Issuer:    "test",
-			Audience:  "test",
+			Enabled:    true,
+			JWTSecret:  "super-secret-key",
+			JWTSecrets: []string{"previous-secret-key"},
+			Issuer:     "test",
+			Audience:   "test",
 		},
 		Routes: routes,
 	}
@@ -98,6 +104,35 @@ func TestRoutesEndpoint(t *testing.T) {
 	}
 }
 
+// After UpdateRoutes, /admin/routes lists the new routes with their
+// breakers' state.
+func TestRoutesEndpoint_UpdateRoutes(t *testing.T) {
+	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
+	defer limiter.Stop()
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	h.UpdateRoutes([]config.RouteConfig{{PathPrefix: "/orders", Backend: "http://localhost:3002", TimeoutMs: 5000}}, nil)
+
+	req := httptest.NewRequest("GET", "/admin/routes", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var resp map[string][]routeStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	routes := resp["routes"]
+	if len(routes) != 1 || routes[0].PathPrefix != "/orders" {
+		t.Fatalf("routes = %+v, want only /orders", routes)
+	}
+	if routes[0].CircuitBreakerState != "unknown" {
+		t.Errorf("circuit_breaker_state = %q, want unknown without a breaker", routes[0].CircuitBreakerState)
+	}
+}
+
 func TestConfigEndpoint_RedactsSecret(t *testing.T) {
 	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
 	defer limiter.Stop()
@@ -121,6 +156,129 @@ func TestConfigEndpoint_RedactsSecret(t *testing.T) {
 	if contains(body, "super-secret-key") {
 		t.Error("jwt_secret was not redacted!")
 	}
+	if contains(body, "previous-secret-key") {
+		t.Error("jwt_secrets were not redacted!")
+	}
+}
+
+func TestConfigYAMLEndpoint(t *testing.T) {
+	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
+	defer limiter.Stop()
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest("GET", "/admin/config.yaml", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/yaml") {
+		t.Errorf("Content-Type = %q, want text/yaml", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want an attachment", cd)
+	}
+
+	var got config.Config
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("response is not valid YAML: %v", err)
+	}
+	if got.Auth.JWTSecret != "***" {
+		t.Errorf("jwt_secret = %q, want it redacted to ***", got.Auth.JWTSecret)
+	}
+	if contains(rec.Body.String(), "super-secret-key") {
+		t.Error("jwt_secret was not redacted!")
+	}
+	if len(got.Routes) == 0 {
+		t.Error("routes missing from the YAML config")
+	}
+}
+
+func TestRouteDetailEndpoint(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	sample := 0.25
+	routes := []config.RouteConfig{
+		{
+			PathPrefix:    "/api/users",
+			Backend:       "http://localhost:3001",
+			AuthRequired:  true,
+			TimeoutMs:     5000,
+			RateOverride:  &config.RateLimitConfig{RequestsPerSecond: 5, BurstSize: 2},
+			LogLevel:      "warn",
+			LogSampleRate: &sample,
+			BreakerScope:  "route",
+		},
+		{PathPrefix: "/public", Backend: "http://localhost:3002"},
+	}
+	cfg := &config.Config{
+		Auth:           config.AuthConfig{Enabled: true, Scopes: []string{"read"}},
+		RateLimit:      config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 50},
+		CircuitBreaker: config.CircuitBreakerConfig{WindowSize: 10, FailureThreshold: 0.5},
+		Routes:         routes,
+	}
+	h := New(&mockConfigProvider{cfg: cfg}, nil, nil, routes, []string{"127.0.0.0/8"}, logger)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	get := func(path string) (int, effectiveRoute) {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var got effectiveRoute
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode %s: %v", path, err)
+			}
+		}
+		return rec.Code, got
+	}
+
+	code, users := get("/admin/routes/%2Fapi%2Fusers")
+	if code != http.StatusOK {
+		t.Fatalf("encoded prefix: status = %d, want 200", code)
+	}
+	if users.RateLimit.Source != "route" || users.RateLimit.RequestsPerSecond != 5 || users.RateLimit.BurstSize != 2 {
+		t.Errorf("override rate_limit = %+v", users.RateLimit)
+	}
+	if users.LogLevel != "warn" || users.LogSampleRate != 0.25 {
+		t.Errorf("override logging = %q %v", users.LogLevel, users.LogSampleRate)
+	}
+	if !users.AuthEnforced || len(users.Scopes) != 1 {
+		t.Errorf("auth_enforced = %v, scopes = %v", users.AuthEnforced, users.Scopes)
+	}
+	if users.CircuitBreaker.Scope != "route" || users.CircuitBreaker.Key != "http://localhost:3001#/api/users" {
+		t.Errorf("circuit_breaker = %+v", users.CircuitBreaker)
+	}
+	if users.TimeoutMs != 5000 {
+		t.Errorf("timeout_ms = %d, want 5000", users.TimeoutMs)
+	}
+
+	code, public := get("/admin/routes/public")
+	if code != http.StatusOK {
+		t.Fatalf("unencoded prefix: status = %d, want 200", code)
+	}
+	if public.RateLimit.Source != "global" || public.RateLimit.RequestsPerSecond != 100 || public.RateLimit.BurstSize != 50 {
+		t.Errorf("default rate_limit = %+v", public.RateLimit)
+	}
+	if public.LogLevel != "info" || public.LogSampleRate != 1 {
+		t.Errorf("default logging = %q %v", public.LogLevel, public.LogSampleRate)
+	}
+	if public.AuthEnforced || public.CircuitBreaker.Scope != "backend" || public.RedirectPolicy != "passthrough" {
+		t.Errorf("defaults not applied: %+v", public)
+	}
+	if public.CircuitBreaker.WindowSize != 10 {
+		t.Errorf("circuit_breaker.window_size = %d, want global 10", public.CircuitBreaker.WindowSize)
+	}
+
+	if code, _ := get("/admin/routes/%2Fmissing"); code != http.StatusNotFound {
+		t.Errorf("unknown prefix: status = %d, want 404", code)
+	}
 }
 
 func TestIPAllowlist_Denied(t *testing.T) {
@@ -185,6 +343,198 @@ func TestLimitersEndpoint(t *testing.T) {
 	}
 }
 
+func TestTransportEndpoint(t *testing.T) {
+	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
+	defer limiter.Stop()
+	h.SetTransportStats(func() []proxy.TransportStats {
+		return []proxy.TransportStats{{Backend: "http://users:3001", Dials: 3, OpenConns: 2, ActiveRequests: 1, IdleConns: 1}}
+	})
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest("GET", "/admin/transport", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp struct {
+		Backends []proxy.TransportStats `json:"backends"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp.Backends) != 1 || resp.Backends[0].Dials != 3 || resp.Backends[0].IdleConns != 1 {
+		t.Errorf("backends = %+v, want the provided stats", resp.Backends)
+	}
+}
+
+func TestEventsEndpoint_StreamsBreakerTransitions(t *testing.T) {
+	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
+	defer limiter.Stop()
+	bus := circuitbreaker.NewEventBus()
+	h.SetEvents(bus)
+	breaker := h.breakers["http://localhost:3001"]
+	breaker.SetEvents(bus)
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/admin/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// The handler has subscribed by the time the headers arrive; a full
+	// window of failures opens the breaker.
+	for range 10 {
+		breaker.RecordFailure(time.Millisecond)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	var event, data string
+	for data == "" && lines.Scan() {
+		line := lines.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			event = v
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+	if event != "breaker" {
+		t.Errorf("event = %q, want breaker", event)
+	}
+	var ev circuitbreaker.Event
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatalf("unmarshal %q: %v", data, err)
+	}
+	if ev.Backend != "http://localhost:3001" || ev.From != "closed" || ev.To != "open" {
+		t.Errorf("event = %+v, want closed → open for http://localhost:3001", ev)
+	}
+
+	// Closing the bus, as shutdown does, ends the stream.
+	bus.Close()
+	for lines.Scan() {
+	}
+	if err := lines.Err(); err != nil {
+		t.Errorf("stream ended with %v, want EOF", err)
+	}
+}
+
+func TestLimitersEndpoint_ShowsRemainingTokens(t *testing.T) {
+	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
+	defer limiter.Stop()
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	proxied := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(ip string) {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.RemoteAddr = ip + ":4000"
+		proxied.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for i := 0; i < 60; i++ {
+		send("10.0.0.1") // well past the burst of 50
+	}
+	send("10.0.0.2")
+
+	req := httptest.NewRequest("GET", "/admin/limiters", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var resp struct {
+		Entries []ratelimit.LimiterEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	tokens := map[string]float64{}
+	for _, e := range resp.Entries {
+		tokens[e.IP] = e.Tokens
+		if e.Rate != 100 || e.Burst != 50 {
+			t.Errorf("%s: rate/burst = %v/%d, want 100/50", e.IP, e.Rate, e.Burst)
+		}
+		if e.LastSeenSeconds < 0 || e.LastSeenSeconds > 5 {
+			t.Errorf("%s: last_seen_seconds = %v", e.IP, e.LastSeenSeconds)
+		}
+	}
+	if got := tokens["10.0.0.1"]; got >= 5 {
+		t.Errorf("heavy client tokens = %v, want near 0", got)
+	}
+	if got := tokens["10.0.0.2"]; got < 45 {
+		t.Errorf("light client tokens = %v, want near 49", got)
+	}
+}
+
+func TestLimitersDelete_ResetsClient(t *testing.T) {
+	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
+	defer limiter.Stop()
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	proxied := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	hit := func() int {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.RemoteAddr = "10.0.0.9:4000"
+		rec := httptest.NewRecorder()
+		proxied.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Exhaust the burst of 50.
+	for i := 0; i < 50; i++ {
+		hit()
+	}
+	if code := hit(); code != http.StatusTooManyRequests {
+		t.Fatalf("client should be limited, got %d", code)
+	}
+
+	del := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/admin/limiters"+query, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := del(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("DELETE without ip or all: status = %d, want 400", rec.Code)
+	}
+
+	rec := del("?ip=10.0.0.9")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want 200", rec.Code)
+	}
+	var resp struct {
+		Removed int `json:"removed"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Removed != 1 {
+		t.Errorf("removed = %d, want 1", resp.Removed)
+	}
+
+	if code := hit(); code != http.StatusOK {
+		t.Errorf("after reset: status = %d, want 200", code)
+	}
+}
+
 func TestMethodNotAllowed(t *testing.T) {
 	h, limiter := testHandler(t, []string{"127.0.0.0/8"})
 	defer limiter.Stop()
@@ -214,3 +564,73 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+// statusConfigProvider adds reload history to mockConfigProvider, as
+// *config.Reloader does.
+type statusConfigProvider struct {
+	mockConfigProvider
+	status config.ReloadStatus
+}
+
+func (s *statusConfigProvider) ReloadStatus() config.ReloadStatus { return s.status }
+
+func TestStatusEndpoint(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	loaded := time.Now().Add(-time.Hour)
+	provider := &statusConfigProvider{
+		mockConfigProvider: mockConfigProvider{cfg: &config.Config{
+			Routes: []config.RouteConfig{{PathPrefix: "/a"}, {PathPrefix: "/b"}},
+		}},
+		status: config.ReloadStatus{ConfigLoadedAt: loaded, LastReloadAt: loaded, LastReloadOK: true},
+	}
+	h := New(provider, nil, nil, nil, []string{"10.0.0.0/8"}, logger)
+	h.SetStatusSource(StatusSource{
+		Build:     BuildInfo{Version: "v1.2.3", Commit: "abc123", BuildDate: "2026-01-01"},
+		StartedAt: time.Now().Add(-90 * time.Second),
+		Draining:  func() bool { return true },
+	})
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest("GET", "/admin/status", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-allowlisted status = %d, want 403", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/admin/status", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, field := range []string{
+		"started_at", "uptime_seconds", "build", "config_loaded_at",
+		"last_reload_at", "last_reload_ok", "routes", "draining",
+	} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("missing top-level field %q", field)
+		}
+	}
+	if body["routes"] != float64(2) {
+		t.Errorf("routes = %v, want 2", body["routes"])
+	}
+	if body["draining"] != true {
+		t.Errorf("draining = %v, want true", body["draining"])
+	}
+	if up, _ := body["uptime_seconds"].(float64); up < 90 {
+		t.Errorf("uptime_seconds = %v, want >= 90", up)
+	}
+	if build, _ := body["build"].(map[string]interface{}); build["version"] != "v1.2.3" {
+		t.Errorf("build = %v, want version v1.2.3", body["build"])
+	}
+}
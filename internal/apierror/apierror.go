@@ -27,6 +27,14 @@ const (
 	InternalError         ErrorCode = "GATEWAY_INTERNAL_ERROR"
 	BodyTooLarge          ErrorCode = "GATEWAY_BODY_TOO_LARGE"
 	DeadlineExceeded      ErrorCode = "GATEWAY_DEADLINE_EXCEEDED"
+	ClientCertRequired    ErrorCode = "GATEWAY_CLIENT_CERT_REQUIRED"
+	AmbiguousFraming      ErrorCode = "GATEWAY_AMBIGUOUS_FRAMING"
+	QueueTimeout          ErrorCode = "GATEWAY_QUEUE_TIMEOUT"
+	ClientBodyTimeout     ErrorCode = "GATEWAY_CLIENT_BODY_TIMEOUT"
+	BackendNotAllowed     ErrorCode = "GATEWAY_BACKEND_NOT_ALLOWED"
+	UpstreamError         ErrorCode = "GATEWAY_UPSTREAM_ERROR"
+	InvalidBody           ErrorCode = "GATEWAY_INVALID_BODY"
+	ScriptError           ErrorCode = "GATEWAY_SCRIPT_ERROR"
 )
 
 // ErrorResponse is the standardized gateway error body.
@@ -35,6 +43,9 @@ type ErrorResponse struct {
 	ErrorCode string `json:"error_code"`
 	Message   string `json:"message"`
 	RequestID string `json:"request_id,omitempty"`
+	// Details lists individual problems behind the error, such as each
+	// request body validation failure.
+	Details []string `json:"details,omitempty"`
 }
 
 // Pre-serialized JSON bodies for the most common error responses.
@@ -93,6 +104,27 @@ func WriteJSON(w http.ResponseWriter, r *http.Request, status int, code ErrorCod
 	}
 }
 
+// WriteJSONDetails is WriteJSON with a list of details, such as the
+// individual validation failures behind a rejected request body.
+func WriteJSONDetails(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, message string, details []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	requestID := ""
+	if r != nil {
+		requestID = r.Header.Get("X-Request-ID")
+	}
+	if err := json.NewEncoder(w).Encode(ErrorResponse{
+		Error:     http.StatusText(status),
+		ErrorCode: string(code),
+		Message:   message,
+		RequestID: requestID,
+		Details:   details,
+	}); err != nil {
+		slog.Debug("apierror: failed to encode error response", "code", code, "error", err)
+	}
+}
+
 // preSerialized returns a pre-built response body for common error
 // combinations, or nil if no match.
 func preSerialized(status int, code ErrorCode, message string) []byte {
@@ -4,10 +4,12 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/dskow/gateway-core/internal/apierror"
@@ -27,20 +29,31 @@ type Claims struct {
 	Issuer   string   `json:"iss"`
 	Audience string   `json:"aud"`
 	Scopes   []string `json:"scopes"`
+	// Raw holds every claim in the token as decoded from its payload, for
+	// handlers that need custom claims such as a tenant ID.
+	Raw map[string]interface{} `json:"-"`
 }
 
 // Middleware returns an HTTP middleware that validates JWT Bearer tokens.
 // Routes that do not require authentication are passed through. m may be nil
 // for tests that do not exercise the metrics path.
-func Middleware(cfg config.AuthConfig, routeRequiresAuth func(path string) bool, logger *slog.Logger, m *metrics.Metrics) func(http.Handler) http.Handler {
+func Middleware(cfg config.AuthConfig, routeRequiresAuth func(r *http.Request) bool, logger *slog.Logger, m *metrics.Metrics) func(http.Handler) http.Handler {
 	recordFailure := func(reason string) {
 		if m != nil {
 			m.AuthFailures.WithLabelValues(reason).Inc()
 		}
 	}
+	validate := func(_ context.Context, tokenStr string) (*Claims, error) {
+		return validateToken(tokenStr, cfg)
+	}
+	if cfg.IntrospectionURL != "" {
+		validate = newIntrospector(cfg).validate
+	} else if cfg.JWTCacheSize > 0 {
+		validate = newTokenCache(cfg.JWTCacheSize, cfg.JWTCacheTTL).wrap(validate)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !cfg.Enabled || !routeRequiresAuth(r.URL.Path) {
+			if !cfg.Enabled || !routeRequiresAuth(r) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -52,8 +65,17 @@ func Middleware(cfg config.AuthConfig, routeRequiresAuth func(path string) bool,
 				return
 			}
 
-			claims, err := validateToken(tokenStr, cfg)
+			claims, err := validate(r.Context(), tokenStr)
 			if err != nil {
+				var ie *IntrospectionError
+				if errors.As(err, &ie) {
+					// Fail closed: without an answer from the endpoint the
+					// token cannot be trusted.
+					logger.Error("auth introspection failed", "error", err, "path", r.URL.Path)
+					recordFailure("introspection_error")
+					apierror.WriteJSON(w, r, http.StatusUnauthorized, apierror.AuthInvalidToken, "token could not be verified")
+					return
+				}
 				logger.Warn("auth failure", "error", err, "path", r.URL.Path)
 				if isScopeError(err) {
 					recordFailure("insufficient_scope")
@@ -92,7 +114,14 @@ func validateToken(tokenStr string, cfg config.AuthConfig) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(cfg.JWTSecret), nil
+		// During a rotation several secrets are live; the parser accepts
+		// the token if any of them verifies the signature.
+		secrets := cfg.Secrets()
+		keys := make([]jwt.VerificationKey, len(secrets))
+		for i, s := range secrets {
+			keys[i] = []byte(s)
+		}
+		return jwt.VerificationKeySet{Keys: keys}, nil
 	},
 		jwt.WithValidMethods([]string{"HS256"}),
 		jwt.WithIssuer(cfg.Issuer),
@@ -108,7 +137,20 @@ func validateToken(tokenStr string, cfg config.AuthConfig) (*Claims, error) {
 		return nil, fmt.Errorf("invalid token claims")
 	}
 
-	claims := &Claims{}
+	claims := claimsFromMap(mapClaims)
+	if err := checkRequiredClaims(mapClaims, cfg); err != nil {
+		return nil, err
+	}
+	if err := checkScopes(claims, cfg); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// claimsFromMap builds Claims from a decoded JWT payload or introspection
+// response, which share the sub, iss, aud and scope members.
+func claimsFromMap(mapClaims map[string]interface{}) *Claims {
+	claims := &Claims{Raw: mapClaims}
 
 	if sub, ok := mapClaims["sub"].(string); ok {
 		claims.Subject = sub
@@ -134,7 +176,11 @@ func validateToken(tokenStr string, cfg config.AuthConfig) (*Claims, error) {
 		claims.Scopes = strings.Fields(scopeStr)
 	}
 
-	// Validate required scopes
+	return claims
+}
+
+// checkScopes reports the first of auth.scopes the token lacks.
+func checkScopes(claims *Claims, cfg config.AuthConfig) error {
 	if len(cfg.Scopes) > 0 {
 		scopeSet := make(map[string]bool, len(claims.Scopes))
 		for _, s := range claims.Scopes {
@@ -142,12 +188,50 @@ func validateToken(tokenStr string, cfg config.AuthConfig) (*Claims, error) {
 		}
 		for _, required := range cfg.Scopes {
 			if !scopeSet[required] {
-				return nil, &ScopeError{MissingScope: required}
+				return &ScopeError{MissingScope: required}
 			}
 		}
 	}
+	return nil
+}
 
-	return claims, nil
+// checkRequiredClaims enforces auth.required_claims_present and
+// auth.required_claims, reporting the first claim that fails.
+func checkRequiredClaims(mapClaims map[string]interface{}, cfg config.AuthConfig) error {
+	for _, name := range cfg.RequiredClaimsPresent {
+		if _, ok := mapClaims[name]; !ok {
+			return fmt.Errorf("missing required claim: %s", name)
+		}
+	}
+	names := make([]string, 0, len(cfg.RequiredClaims))
+	for name := range cfg.RequiredClaims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := cfg.RequiredClaims[name]
+		v, ok := mapClaims[name]
+		if !ok {
+			return fmt.Errorf("missing required claim: %s", name)
+		}
+		if claimString(v) != want {
+			return fmt.Errorf("claim %s must be %q", name, want)
+		}
+	}
+	return nil
+}
+
+// claimString returns a claim value as a string: strings as-is, anything
+// else as its JSON text, so 42 and true compare equal to "42" and "true".
+func claimString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
 }
 
 // ScopeError indicates the token is valid but lacks required scopes.
@@ -163,4 +247,3 @@ func isScopeError(err error) bool {
 	var se *ScopeError
 	return errors.As(err, &se)
 }
-
@@ -3,6 +3,7 @@ package auth
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +15,7 @@ import (
 
 const testSecret = "test-secret-key-for-hmac-256"
 
-func makeToken(t *testing.T, claims jwt.MapClaims) string {
+func makeToken(t testing.TB, claims jwt.MapClaims) string {
 	t.Helper()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	s, err := token.SignedString([]byte(testSecret))
@@ -51,7 +52,7 @@ func TestMiddleware_ValidToken(t *testing.T) {
 	token := makeToken(t, validClaims())
 
 	var capturedClaims *Claims
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			capturedClaims = r.Context().Value(ClaimsKey).(*Claims)
 			w.WriteHeader(http.StatusOK)
@@ -86,7 +87,7 @@ func TestMiddleware_ExpiredToken(t *testing.T) {
 	claims["exp"] = time.Now().Add(-time.Hour).Unix()
 	token := makeToken(t, claims)
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -111,7 +112,7 @@ func TestMiddleware_WrongAudience(t *testing.T) {
 	claims["aud"] = "wrong-audience"
 	token := makeToken(t, claims)
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -136,7 +137,7 @@ func TestMiddleware_WrongIssuer(t *testing.T) {
 	claims["iss"] = "wrong-issuer"
 	token := makeToken(t, claims)
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -161,7 +162,7 @@ func TestMiddleware_MissingScopes(t *testing.T) {
 	claims["scope"] = "read" // missing "write"
 	token := makeToken(t, claims)
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -182,7 +183,7 @@ func TestMiddleware_MalformedToken(t *testing.T) {
 	cfg := testAuthConfig()
 	logger := slog.Default()
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -218,7 +219,7 @@ func TestMiddleware_AuthNotRequired(t *testing.T) {
 	cfg := testAuthConfig()
 	logger := slog.Default()
 
-	handler := Middleware(cfg, func(string) bool { return false }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return false }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -239,7 +240,7 @@ func TestMiddleware_AuthDisabled(t *testing.T) {
 	cfg.Enabled = false
 	logger := slog.Default()
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -264,7 +265,7 @@ func TestMiddleware_WrongSigningMethod(t *testing.T) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS384, claims)
 	tokenStr, _ := token.SignedString([]byte(testSecret))
 
-	handler := Middleware(cfg, func(string) bool { return true }, logger, nil)(
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}),
@@ -280,3 +281,97 @@ func TestMiddleware_WrongSigningMethod(t *testing.T) {
 		t.Errorf("expected 401, got %d", rec.Code)
 	}
 }
+
+func TestMiddleware_SecretRotation(t *testing.T) {
+	cfg := testAuthConfig()
+	cfg.JWTSecret = "new-secret-key-for-hmac-256"
+	cfg.JWTSecrets = []string{testSecret}
+	logger := slog.Default()
+
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	sign := func(secret string) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	tests := []struct {
+		name   string
+		secret string
+		want   int
+	}{
+		{"current secret", cfg.JWTSecret, http.StatusOK},
+		{"previous secret", testSecret, http.StatusOK},
+		{"unknown secret", "some-other-secret-entirely", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("Authorization", "Bearer "+sign(tt.secret))
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestMiddleware_RequiredClaims(t *testing.T) {
+	cfg := testAuthConfig()
+	cfg.RequiredClaims = map[string]string{"token_use": "access", "level": "2"}
+	cfg.RequiredClaimsPresent = []string{"tenant_id"}
+	logger := slog.Default()
+
+	var captured *Claims
+	handler := Middleware(cfg, func(*http.Request) bool { return true }, logger, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured = r.Context().Value(ClaimsKey).(*Claims)
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	tests := []struct {
+		name     string
+		modify   func(jwt.MapClaims)
+		want     int
+		wantBody string
+	}{
+		{"all present and matching", func(jwt.MapClaims) {}, http.StatusOK, ""},
+		{"present claim absent", func(c jwt.MapClaims) { delete(c, "tenant_id") }, http.StatusUnauthorized, "missing required claim: tenant_id"},
+		{"valued claim absent", func(c jwt.MapClaims) { delete(c, "token_use") }, http.StatusUnauthorized, "missing required claim: token_use"},
+		{"valued claim mismatched", func(c jwt.MapClaims) { c["token_use"] = "id" }, http.StatusUnauthorized, `claim token_use must be \"access\"`},
+		{"numeric claim mismatched", func(c jwt.MapClaims) { c["level"] = 3 }, http.StatusUnauthorized, `claim level must be \"2\"`},
+	}
+	for _, tt := range tests {
+		claims := validClaims()
+		claims["tenant_id"] = "acme"
+		claims["token_use"] = "access"
+		claims["level"] = 2
+		tt.modify(claims)
+		captured = nil
+
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("Authorization", "Bearer "+makeToken(t, claims))
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+			continue
+		}
+		if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s: body %q does not mention %q", tt.name, rec.Body.String(), tt.wantBody)
+		}
+		if tt.want == http.StatusOK && (captured == nil || captured.Raw["tenant_id"] != "acme") {
+			t.Errorf("%s: expected tenant_id in Claims.Raw, got %+v", tt.name, captured)
+		}
+	}
+}
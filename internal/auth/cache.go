@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenCache is an LRU of validated JWTs, so a client repeating the same
+// token skips signature verification. An entry lives for at most ttl and
+// never past the token's exp. Cached *Claims are shared between requests
+// and must not be modified.
+type tokenCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // front: most recently used
+	items map[[sha256.Size]byte]*list.Element
+}
+
+type tokenCacheEntry struct {
+	key     [sha256.Size]byte
+	claims  *Claims
+	expires time.Time
+}
+
+func newTokenCache(size int, ttl time.Duration) *tokenCache {
+	return &tokenCache{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[[sha256.Size]byte]*list.Element, size),
+	}
+}
+
+// wrap returns a validator that answers from the cache and otherwise calls
+// next, caching what it accepts. Rejections are not cached.
+func (c *tokenCache) wrap(next func(context.Context, string) (*Claims, error)) func(context.Context, string) (*Claims, error) {
+	return func(ctx context.Context, tokenStr string) (*Claims, error) {
+		key := sha256.Sum256([]byte(tokenStr))
+		if claims, ok := c.get(key); ok {
+			return claims, nil
+		}
+		claims, err := next(ctx, tokenStr)
+		if err != nil {
+			return nil, err
+		}
+		if exp, err := jwt.MapClaims(claims.Raw).GetExpirationTime(); err == nil && exp != nil {
+			c.add(key, claims, exp.Time)
+		}
+		return claims, nil
+	}
+}
+
+func (c *tokenCache) get(key [sha256.Size]byte) (*Claims, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*tokenCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.claims, true
+}
+
+func (c *tokenCache) add(key [sha256.Size]byte, claims *Claims, exp time.Time) {
+	expires := c.now().Add(c.ttl)
+	if exp.Before(expires) {
+		expires = exp
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value = &tokenCacheEntry{key: key, claims: claims, expires: expires}
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*tokenCacheEntry).key)
+	}
+	c.items[key] = c.order.PushFront(&tokenCacheEntry{key: key, claims: claims, expires: expires})
+}
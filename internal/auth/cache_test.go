@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// countingValidator accepts every token with the given exp and counts how
+// often it is called.
+func countingValidator(calls *int, exp time.Time) func(context.Context, string) (*Claims, error) {
+	return func(_ context.Context, tokenStr string) (*Claims, error) {
+		*calls++
+		return &Claims{Subject: tokenStr, Raw: map[string]interface{}{"exp": float64(exp.Unix())}}, nil
+	}
+}
+
+func TestTokenCache_RevalidatesExpiredEntries(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	tests := []struct {
+		name    string
+		exp     time.Time
+		ttl     time.Duration
+		advance time.Duration
+	}{
+		{"ttl elapsed", now.Add(time.Hour), time.Minute, time.Minute},
+		{"token exp passed before ttl", now.Add(10 * time.Second), time.Minute, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		c := newTokenCache(10, tt.ttl)
+		clock := now
+		c.now = func() time.Time { return clock }
+		calls := 0
+		validate := c.wrap(countingValidator(&calls, tt.exp))
+
+		validate(context.Background(), "tok")
+		validate(context.Background(), "tok")
+		if calls != 1 {
+			t.Fatalf("%s: expected the second call to hit the cache, validated %d times", tt.name, calls)
+		}
+
+		clock = clock.Add(tt.advance - time.Second)
+		validate(context.Background(), "tok")
+		if calls != 1 {
+			t.Errorf("%s: entry expired early, validated %d times", tt.name, calls)
+		}
+
+		clock = clock.Add(time.Second)
+		validate(context.Background(), "tok")
+		if calls != 2 {
+			t.Errorf("%s: expired entry was not re-validated, validated %d times", tt.name, calls)
+		}
+	}
+}
+
+func TestTokenCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTokenCache(2, time.Minute)
+	calls := 0
+	validate := c.wrap(countingValidator(&calls, time.Now().Add(time.Hour)))
+
+	validate(context.Background(), "a")
+	validate(context.Background(), "b")
+	validate(context.Background(), "a") // a is now the most recent
+	validate(context.Background(), "c") // evicts b
+	if calls != 3 {
+		t.Fatalf("expected 3 validations, got %d", calls)
+	}
+	validate(context.Background(), "a")
+	if calls != 3 {
+		t.Errorf("recently used entry was evicted")
+	}
+	validate(context.Background(), "b")
+	if calls != 4 {
+		t.Errorf("least recently used entry was not evicted")
+	}
+}
+
+func TestTokenCache_DoesNotCacheRejections(t *testing.T) {
+	c := newTokenCache(10, time.Minute)
+	calls := 0
+	validate := c.wrap(func(context.Context, string) (*Claims, error) {
+		calls++
+		return nil, fmt.Errorf("invalid token")
+	})
+	validate(context.Background(), "bad")
+	validate(context.Background(), "bad")
+	if calls != 2 {
+		t.Errorf("rejected token was cached, validated %d times", calls)
+	}
+}
+
+func BenchmarkValidateToken(b *testing.B) {
+	cfg := testAuthConfig()
+	token := makeToken(b, validClaims())
+	uncached := func(_ context.Context, tokenStr string) (*Claims, error) {
+		return validateToken(tokenStr, cfg)
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := uncached(context.Background(), token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		validate := newTokenCache(1024, time.Minute).wrap(uncached)
+		for b.Loop() {
+			if _, err := validate(context.Background(), token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+const (
+	// introspectionTimeout bounds one call to the introspection endpoint.
+	introspectionTimeout = 5 * time.Second
+	// maxIntrospectionBody caps the introspection response read.
+	maxIntrospectionBody = 1 << 20
+	// maxIntrospectionCache caps cached results so a flood of distinct
+	// tokens cannot grow the cache without bound.
+	maxIntrospectionCache = 10000
+)
+
+// IntrospectionError reports that the introspection endpoint could not be
+// consulted (network failure, non-200 status, unreadable body), as opposed
+// to it answering that the token is inactive.
+type IntrospectionError struct {
+	Err error
+}
+
+func (e *IntrospectionError) Error() string {
+	return "token introspection failed: " + e.Err.Error()
+}
+
+func (e *IntrospectionError) Unwrap() error { return e.Err }
+
+// introspector validates opaque tokens against an RFC 7662 endpoint,
+// caching each answer for auth.cache_ttl.
+type introspector struct {
+	cfg    config.AuthConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]introspectionResult
+}
+
+// introspectionResult is a cached answer: claims for an accepted token, or
+// the error it was rejected with.
+type introspectionResult struct {
+	claims  *Claims
+	err     error
+	expires time.Time
+}
+
+func newIntrospector(cfg config.AuthConfig) *introspector {
+	return &introspector{
+		cfg:    cfg,
+		client: &http.Client{Timeout: introspectionTimeout},
+		now:    time.Now,
+		cache:  make(map[[sha256.Size]byte]introspectionResult),
+	}
+}
+
+// validate returns the token's claims, consulting the cache first. Tokens
+// are cached by their SHA-256 so the cache never holds a usable credential.
+// Endpoint failures are returned as *IntrospectionError and not cached.
+func (in *introspector) validate(ctx context.Context, token string) (*Claims, error) {
+	key := sha256.Sum256([]byte(token))
+	now := in.now()
+
+	in.mu.Lock()
+	res, ok := in.cache[key]
+	in.mu.Unlock()
+	if ok && now.Before(res.expires) {
+		return res.claims, res.err
+	}
+
+	resp, err := in.introspect(ctx, token)
+	if err != nil {
+		return nil, &IntrospectionError{Err: err}
+	}
+	res = in.evaluate(resp, now)
+
+	in.mu.Lock()
+	if len(in.cache) >= maxIntrospectionCache {
+		for k, v := range in.cache {
+			if !now.Before(v.expires) {
+				delete(in.cache, k)
+			}
+		}
+		if len(in.cache) >= maxIntrospectionCache {
+			clear(in.cache)
+		}
+	}
+	in.cache[key] = res
+	in.mu.Unlock()
+
+	return res.claims, res.err
+}
+
+// introspect POSTs the token to the endpoint and decodes the response.
+func (in *introspector) introspect(ctx context.Context, token string) (map[string]interface{}, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.cfg.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if in.cfg.ClientID != "" {
+		// RFC 6749 §2.3.1: the credentials are form-encoded before Basic auth.
+		req.SetBasicAuth(url.QueryEscape(in.cfg.ClientID), url.QueryEscape(in.cfg.ClientSecret))
+	}
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned %d", resp.StatusCode)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxIntrospectionBody)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding introspection response: %w", err)
+	}
+	return body, nil
+}
+
+// evaluate turns an introspection response into a cacheable result. An
+// active token is cached until the earlier of cache_ttl and its exp.
+func (in *introspector) evaluate(resp map[string]interface{}, now time.Time) introspectionResult {
+	res := introspectionResult{expires: now.Add(in.cfg.CacheTTL)}
+	if active, _ := resp["active"].(bool); !active {
+		res.err = errors.New("invalid token: token is not active")
+		return res
+	}
+	if exp, ok := resp["exp"].(float64); ok {
+		expiresAt := time.Unix(int64(exp), 0)
+		if !now.Before(expiresAt) {
+			res.err = errors.New("invalid token: token is expired")
+			return res
+		}
+		if expiresAt.Before(res.expires) {
+			res.expires = expiresAt
+		}
+	}
+
+	claims := claimsFromMap(resp)
+	if in.cfg.Issuer != "" && claims.Issuer != in.cfg.Issuer {
+		res.err = errors.New("invalid token: token has invalid issuer")
+		return res
+	}
+	if in.cfg.Audience != "" && !hasAudience(resp["aud"], in.cfg.Audience) {
+		res.err = errors.New("invalid token: token has invalid audience")
+		return res
+	}
+	if err := checkRequiredClaims(resp, in.cfg); err != nil {
+		res.err = err
+		return res
+	}
+	if err := checkScopes(claims, in.cfg); err != nil {
+		res.err = err
+		return res
+	}
+	res.claims = claims
+	return res
+}
+
+// hasAudience reports whether aud, a string or an array of strings, names want.
+func hasAudience(aud interface{}, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
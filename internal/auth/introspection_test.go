@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// newIntrospectionServer answers for two tokens: "good" is active with the
+// read and write scopes, anything else is inactive.
+func newIntrospectionServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "gateway" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		resp := map[string]interface{}{"active": false}
+		if r.PostForm.Get("token") == "good" {
+			resp = map[string]interface{}{
+				"active":    true,
+				"sub":       "user-123",
+				"aud":       []string{"test-audience"},
+				"scope":     "read write",
+				"exp":       time.Now().Add(time.Hour).Unix(),
+				"tenant_id": "acme",
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func introspectionConfig(url string) config.AuthConfig {
+	return config.AuthConfig{
+		Enabled:          true,
+		Audience:         "test-audience",
+		Scopes:           []string{"read"},
+		IntrospectionURL: url,
+		ClientID:         "gateway",
+		ClientSecret:     "s3cret",
+		CacheTTL:         time.Minute,
+	}
+}
+
+func TestMiddleware_Introspection(t *testing.T) {
+	var calls atomic.Int32
+	srv := newIntrospectionServer(t, &calls)
+
+	var captured *Claims
+	handler := Middleware(introspectionConfig(srv.URL), func(*http.Request) bool { return true }, slog.Default(), nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured = r.Context().Value(ClaimsKey).(*Claims)
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	do := func(token string) int {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do("good"); code != http.StatusOK {
+		t.Fatalf("active token: expected 200, got %d", code)
+	}
+	if captured.Subject != "user-123" || len(captured.Scopes) != 2 || captured.Raw["tenant_id"] != "acme" {
+		t.Errorf("claims not mapped from the introspection response: %+v", captured)
+	}
+	if code := do("good"); code != http.StatusOK {
+		t.Fatalf("cached active token: expected 200, got %d", code)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the second request to use the cache, endpoint called %d times", n)
+	}
+
+	if code := do("revoked"); code != http.StatusUnauthorized {
+		t.Errorf("inactive token: expected 401, got %d", code)
+	}
+	if code := do("revoked"); code != http.StatusUnauthorized {
+		t.Errorf("cached inactive token: expected 401, got %d", code)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected the inactive result to be cached, endpoint called %d times", n)
+	}
+}
+
+func TestMiddleware_IntrospectionFailsClosed(t *testing.T) {
+	var calls atomic.Int32
+	srv := newIntrospectionServer(t, &calls)
+
+	tests := map[string]config.AuthConfig{
+		"endpoint unreachable": introspectionConfig("http://127.0.0.1:1/introspect"),
+		"endpoint rejects the gateway's credentials": func() config.AuthConfig {
+			cfg := introspectionConfig(srv.URL)
+			cfg.ClientSecret = "wrong"
+			return cfg
+		}(),
+	}
+	for name, cfg := range tests {
+		handler := Middleware(cfg, func(*http.Request) bool { return true }, slog.Default(), nil)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestIntrospector_NeverCachesPastExp(t *testing.T) {
+	in := newIntrospector(introspectionConfig("http://unused"))
+	now := time.Unix(1_000_000, 0)
+	res := in.evaluate(map[string]interface{}{
+		"active": true,
+		"aud":    "test-audience",
+		"scope":  "read",
+		"exp":    float64(now.Add(10 * time.Second).Unix()),
+	}, now)
+	if res.err != nil {
+		t.Fatalf("unexpected error: %v", res.err)
+	}
+	if want := now.Add(10 * time.Second); !res.expires.Equal(want) {
+		t.Errorf("expires = %v, want the token's exp %v", res.expires, want)
+	}
+}
@@ -1,6 +1,7 @@
 package circuitbreaker
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/dskow/gateway-core/internal/metrics"
@@ -10,26 +11,59 @@ import (
 // backend. It wraps an inner Breaker and rejects requests when the concurrency
 // limit is reached, preventing goroutine pileups and resource starvation.
 type BulkheadBreaker struct {
-	inner   Breaker
-	sem     chan struct{}
-	backend string
-	metrics *metrics.Metrics
+	inner         Breaker
+	maxConcurrent int64
+	inFlight      *atomic.Int64 // may be shared; see newBulkhead
+	backend       string
+	metrics       *metrics.Metrics
 }
 
 // NewBulkheadBreaker creates a concurrency-limiting breaker that allows at most
 // maxConcurrent in-flight requests before rejecting. m may be nil for tests.
 func NewBulkheadBreaker(inner Breaker, maxConcurrent int, backend string, m *metrics.Metrics) *BulkheadBreaker {
+	return newBulkhead(inner, maxConcurrent, new(atomic.Int64), backend, m)
+}
+
+// newBulkhead is NewBulkheadBreaker counting in-flight requests in
+// inFlight, which a CompositeBreaker keeps across config reloads so a
+// replacement bulkhead starts from the requests already in flight.
+func newBulkhead(inner Breaker, maxConcurrent int, inFlight *atomic.Int64, backend string, m *metrics.Metrics) *BulkheadBreaker {
 	return &BulkheadBreaker{
-		inner:   inner,
-		sem:     make(chan struct{}, maxConcurrent),
-		backend: backend,
-		metrics: m,
+		inner:         inner,
+		maxConcurrent: int64(maxConcurrent),
+		inFlight:      inFlight,
+		backend:       backend,
+		metrics:       m,
 	}
 }
 
 func (b *BulkheadBreaker) recordInFlight() {
 	if b.metrics != nil {
-		b.metrics.BulkheadInFlight.WithLabelValues(b.backend).Set(float64(len(b.sem)))
+		b.metrics.BulkheadInFlight.WithLabelValues(b.backend).Set(float64(b.inFlight.Load()))
+	}
+}
+
+// acquire takes a slot if fewer than maxConcurrent are taken.
+func (b *BulkheadBreaker) acquire() bool {
+	for {
+		n := b.inFlight.Load()
+		if n >= b.maxConcurrent {
+			return false
+		}
+		if b.inFlight.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// releaseSlot decrements n, but never below zero, so an unpaired Release
+// cannot hand out an extra slot.
+func releaseSlot(n *atomic.Int64) {
+	for {
+		v := n.Load()
+		if v <= 0 || n.CompareAndSwap(v, v-1) {
+			return
+		}
 	}
 }
 
@@ -37,30 +71,28 @@ func (b *BulkheadBreaker) recordInFlight() {
 // If the concurrency limit is reached, returns false without blocking.
 // If Allow returns true, the caller MUST call Release when the request completes.
 func (b *BulkheadBreaker) Allow() bool {
-	select {
-	case b.sem <- struct{}{}:
-		// Acquired slot — check inner breaker.
-		b.recordInFlight()
-		if !b.inner.Allow() {
-			// Inner breaker rejected — release slot immediately.
-			<-b.sem
-			b.recordInFlight()
-			return false
-		}
-		return true
-	default:
+	if !b.acquire() {
 		// Concurrency limit reached.
 		if b.metrics != nil {
 			b.metrics.BulkheadRejections.WithLabelValues(b.backend).Inc()
 		}
 		return false
 	}
+	// Acquired slot — check inner breaker.
+	b.recordInFlight()
+	if !b.inner.Allow() {
+		// Inner breaker rejected — release slot immediately.
+		releaseSlot(b.inFlight)
+		b.recordInFlight()
+		return false
+	}
+	return true
 }
 
 // Release frees a concurrency slot after a request completes. Must be called
 // exactly once for every Allow() that returned true.
 func (b *BulkheadBreaker) Release() {
-	<-b.sem
+	releaseSlot(b.inFlight)
 	b.recordInFlight()
 }
 
@@ -84,5 +116,5 @@ func (b *BulkheadBreaker) Reset() {
 // would reject the next Allow() on capacity grounds alone. Informational
 // only — callers must still go through Allow() to acquire a slot.
 func (b *BulkheadBreaker) AtCapacity() bool {
-	return len(b.sem) >= cap(b.sem)
+	return b.inFlight.Load() >= b.maxConcurrent
 }
@@ -2,6 +2,8 @@ package circuitbreaker
 
 import (
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dskow/gateway-core/internal/metrics"
@@ -16,6 +18,7 @@ type Config struct {
 	FailureThreshold float64
 	ResetTimeout     time.Duration
 	HalfOpenMax      int
+	ResetJitter      float64 // fraction of ResetTimeout; see FailureRateBreaker.SetResetJitter
 
 	// Timeout breaker (active when SlowThreshold > 0)
 	SlowThreshold time.Duration
@@ -34,54 +37,117 @@ type Config struct {
 // transparent.
 type CompositeBreaker struct {
 	failureRate *FailureRateBreaker
-	bulkhead    *BulkheadBreaker // nil if bulkhead disabled
-	effective   Breaker          // outermost layer — what Allow/Record call
+	backend     string
+	metrics     *metrics.Metrics
+
+	// inFlight counts requests between Allow and Release, whether or not
+	// the bulkhead is enabled, so a bulkhead that UpdateConfig adds or
+	// resizes enforces its limit on the true count.
+	inFlight atomic.Int64
+
+	updateMu sync.Mutex // serializes UpdateConfig
+	stack    atomic.Pointer[breakerStack]
+}
+
+// breakerStack is the layers wrapped around the failure-rate breaker. It
+// is replaced, never modified, by UpdateConfig.
+type breakerStack struct {
+	cfg       Config
+	adaptive  *AdaptiveBreaker // nil if adaptive disabled
+	bulkhead  *BulkheadBreaker // nil if bulkhead disabled
+	effective Breaker          // outermost layer — what Allow/Record call
 }
 
 // NewComposite builds a composed breaker stack for the given backend.
 // Composition order (inside → out): FailureRate → Adaptive → Timeout → Bulkhead.
 // m may be nil for tests that do not exercise the metrics path.
 func NewComposite(backend string, cfg Config, logger *slog.Logger, m *metrics.Metrics) *CompositeBreaker {
-	fr := NewFailureRateBreaker(backend, cfg.WindowSize, cfg.FailureThreshold, cfg.ResetTimeout, cfg.HalfOpenMax, logger, m)
+	cb := &CompositeBreaker{
+		failureRate: NewFailureRateBreaker(backend, cfg.WindowSize, cfg.FailureThreshold, cfg.ResetTimeout, cfg.HalfOpenMax, logger, m),
+		backend:     backend,
+		metrics:     m,
+	}
+	cb.failureRate.SetResetJitter(cfg.ResetJitter, nil)
+	cb.stack.Store(cb.buildStack(cfg, nil))
+	return cb
+}
 
-	var current Breaker = fr
+// buildStack wraps the failure-rate breaker in the layers cfg enables. The
+// adaptive layer, and its latency average, is taken over from prev (which
+// may be nil) when its settings are unchanged.
+func (c *CompositeBreaker) buildStack(cfg Config, prev *breakerStack) *breakerStack {
+	s := &breakerStack{cfg: cfg}
+	var current Breaker = c.failureRate
 
 	// Wrap with adaptive if enabled (modifies the failure-rate breaker's threshold).
 	if cfg.Adaptive {
-		alpha := 0.3 // sensible default
-		current = NewAdaptiveBreaker(fr, cfg.FailureThreshold, cfg.MinThreshold, cfg.LatencyCeiling, alpha)
+		if prev != nil && prev.adaptive != nil && prev.cfg.FailureThreshold == cfg.FailureThreshold &&
+			prev.cfg.MinThreshold == cfg.MinThreshold && prev.cfg.LatencyCeiling == cfg.LatencyCeiling {
+			s.adaptive = prev.adaptive
+		} else {
+			alpha := 0.3 // sensible default
+			s.adaptive = NewAdaptiveBreaker(c.failureRate, cfg.FailureThreshold, cfg.MinThreshold, cfg.LatencyCeiling, alpha)
+		}
+		current = s.adaptive
 	}
 
 	// Wrap with timeout breaker if slow threshold is configured.
 	if cfg.SlowThreshold > 0 {
 		current = NewTimeoutBreaker(current, cfg.SlowThreshold)
 	}
-
-	cb := &CompositeBreaker{
-		failureRate: fr,
-		effective:   current,
-	}
+	s.effective = current
 
 	// Wrap with bulkhead if max concurrent is configured.
 	if cfg.MaxConcurrent > 0 {
-		bh := NewBulkheadBreaker(current, cfg.MaxConcurrent, backend, m)
-		cb.bulkhead = bh
-		cb.effective = bh
+		s.bulkhead = newBulkhead(current, cfg.MaxConcurrent, &c.inFlight, c.backend, c.metrics)
+		s.effective = s.bulkhead
 	}
-
-	return cb
+	return s
 }
 
 func (c *CompositeBreaker) Allow() bool {
-	return c.effective.Allow()
+	s := c.stack.Load()
+	if !s.effective.Allow() {
+		return false
+	}
+	if s.bulkhead == nil {
+		c.inFlight.Add(1) // the bulkhead counts its own
+	}
+	return true
+}
+
+// AllowFailOpen is Allow for a fail-open route: a request that only the
+// failure-rate breaker would reject (it is open) is admitted anyway, with
+// degraded true. The bulkhead still applies: at capacity both are false.
+// As with Allow, the caller must call Release when allowed is true.
+func (c *CompositeBreaker) AllowFailOpen() (allowed, degraded bool) {
+	if c.Allow() {
+		return true, false
+	}
+	s := c.stack.Load()
+	if s.bulkhead == nil {
+		c.inFlight.Add(1)
+		return true, true
+	}
+	if !s.bulkhead.acquire() {
+		return false, false
+	}
+	s.bulkhead.recordInFlight()
+	return true, true
 }
 
 func (c *CompositeBreaker) RecordSuccess(latency time.Duration) {
-	c.effective.RecordSuccess(latency)
+	c.stack.Load().effective.RecordSuccess(latency)
 }
 
 func (c *CompositeBreaker) RecordFailure(latency time.Duration) {
-	c.effective.RecordFailure(latency)
+	c.stack.Load().effective.RecordFailure(latency)
+}
+
+// SetEvents publishes the breaker's state changes on bus; see
+// FailureRateBreaker.SetEvents.
+func (c *CompositeBreaker) SetEvents(bus *EventBus) {
+	c.failureRate.SetEvents(bus)
 }
 
 // InnerState returns the core failure-rate breaker's state, ignoring any
@@ -96,7 +162,7 @@ func (c *CompositeBreaker) InnerState() State {
 // Health/readiness probes should use EffectiveState so a saturated
 // bulkhead does not appear "green" while the gateway is shedding load.
 func (c *CompositeBreaker) EffectiveState() State {
-	if c.bulkhead != nil && c.bulkhead.AtCapacity() {
+	if bh := c.stack.Load().bulkhead; bh != nil && bh.AtCapacity() {
 		return StateOpen
 	}
 	return c.InnerState()
@@ -109,25 +175,46 @@ func (c *CompositeBreaker) State() State {
 }
 
 func (c *CompositeBreaker) Reset() {
-	c.effective.Reset()
+	c.stack.Load().effective.Reset()
 }
 
 // Release frees a bulkhead concurrency slot. Must be called after every
-// Allow() that returned true. Safe to call when bulkhead is disabled (no-op).
+// Allow() that returned true, even if UpdateConfig ran in between; without
+// a bulkhead it only updates the in-flight count.
 func (c *CompositeBreaker) Release() {
-	if c.bulkhead != nil {
-		c.bulkhead.Release()
+	if bh := c.stack.Load().bulkhead; bh != nil {
+		bh.Release()
+		return
 	}
+	releaseSlot(&c.inFlight)
+}
+
+// Config returns the settings the breaker was last built or updated with.
+func (c *CompositeBreaker) Config() Config {
+	return c.stack.Load().cfg
 }
 
-// UpdateConfig updates the failure-rate breaker's core parameters at runtime
-// (e.g., on config hot-reload). Thread-safe.
+// UpdateConfig applies cfg at runtime (e.g., on config hot-reload).
+// Thread-safe. The failure-rate breaker keeps its state, except that a
+// resized window starts empty; the adaptive, timeout and bulkhead layers
+// are rebuilt, so they can be turned on or off and the bulkhead resized.
+// Requests in flight keep their bulkhead slots: a smaller limit rejects
+// new requests until enough of them finish.
 func (c *CompositeBreaker) UpdateConfig(cfg Config) {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.updateFailureRate(cfg)
+	c.stack.Store(c.buildStack(cfg, c.stack.Load()))
+}
+
+// updateFailureRate updates the failure-rate breaker's core parameters.
+func (c *CompositeBreaker) updateFailureRate(cfg Config) {
 	c.failureRate.mu.Lock()
 	defer c.failureRate.mu.Unlock()
 
 	c.failureRate.failureThreshold = cfg.FailureThreshold
 	c.failureRate.resetTimeout = cfg.ResetTimeout
+	c.failureRate.resetJitter = cfg.ResetJitter
 	c.failureRate.halfOpenMax = cfg.HalfOpenMax
 
 	// Resize the window if needed.
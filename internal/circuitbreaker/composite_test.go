@@ -144,6 +144,113 @@ func TestComposite_UpdateConfig(t *testing.T) {
 	}
 }
 
+// allowN calls cb.Allow n times and returns how many were admitted.
+func allowN(cb *CompositeBreaker, n int) int {
+	admitted := 0
+	for i := 0; i < n; i++ {
+		if cb.Allow() {
+			admitted++
+		}
+	}
+	return admitted
+}
+
+func TestComposite_UpdateConfigResizesBulkhead(t *testing.T) {
+	cfg := Config{
+		WindowSize:       10,
+		FailureThreshold: 0.9,
+		ResetTimeout:     30 * time.Second,
+		HalfOpenMax:      2,
+		MaxConcurrent:    2,
+	}
+	cb := NewComposite("http://test:8080", cfg, slog.Default(), nil)
+	if got := allowN(cb, 5); got != 2 {
+		t.Fatalf("admitted %d, want 2 with max_concurrent 2", got)
+	}
+
+	cfg.MaxConcurrent = 4
+	cb.UpdateConfig(cfg)
+	if got := allowN(cb, 5); got != 2 {
+		t.Fatalf("admitted %d more, want 2 after raising max_concurrent to 4", got)
+	}
+
+	// Lowering the limit below the requests in flight admits nothing new
+	// until enough of them release their slots.
+	cfg.MaxConcurrent = 1
+	cb.UpdateConfig(cfg)
+	for i := 0; i < 3; i++ {
+		cb.Release()
+	}
+	if cb.Allow() {
+		t.Fatal("admitted a request with 1 in flight and max_concurrent 1")
+	}
+	cb.Release()
+	if got := allowN(cb, 3); got != 1 {
+		t.Fatalf("admitted %d, want 1 once the in-flight requests finished", got)
+	}
+}
+
+func TestComposite_UpdateConfigTogglesBulkhead(t *testing.T) {
+	cfg := Config{
+		WindowSize:       10,
+		FailureThreshold: 0.9,
+		ResetTimeout:     30 * time.Second,
+		HalfOpenMax:      2,
+	}
+	cb := NewComposite("http://test:8080", cfg, slog.Default(), nil)
+	if got := allowN(cb, 3); got != 3 {
+		t.Fatalf("admitted %d, want 3 without a bulkhead", got)
+	}
+
+	// A bulkhead enabled by a reload counts the requests already in flight.
+	cfg.MaxConcurrent = 3
+	cb.UpdateConfig(cfg)
+	if cb.Allow() {
+		t.Fatal("admitted a request past max_concurrent 3 with 3 in flight")
+	}
+	if cb.EffectiveState() != StateOpen {
+		t.Errorf("EffectiveState = %v, want open at capacity", cb.EffectiveState())
+	}
+	cb.Release()
+	if !cb.Allow() {
+		t.Fatal("expected Allow() after Release()")
+	}
+
+	cfg.MaxConcurrent = 0
+	cb.UpdateConfig(cfg)
+	if got := allowN(cb, 5); got != 5 {
+		t.Fatalf("admitted %d, want 5 once the bulkhead is disabled", got)
+	}
+}
+
+func TestComposite_AllowFailOpen(t *testing.T) {
+	cfg := Config{
+		WindowSize:       1,
+		FailureThreshold: 0.5,
+		ResetTimeout:     time.Minute,
+		HalfOpenMax:      1,
+		MaxConcurrent:    1,
+	}
+	cb := NewComposite("http://test:8080", cfg, slog.Default(), nil)
+	if allowed, degraded := cb.AllowFailOpen(); !allowed || degraded {
+		t.Fatalf("closed breaker: AllowFailOpen = %v, %v; want true, false", allowed, degraded)
+	}
+	cb.RecordFailure(time.Millisecond)
+	cb.Release()
+
+	if cb.Allow() {
+		t.Fatal("expected Allow() to reject while open")
+	}
+	if allowed, degraded := cb.AllowFailOpen(); !allowed || !degraded {
+		t.Fatalf("open breaker: AllowFailOpen = %v, %v; want true, true", allowed, degraded)
+	}
+	// The bulkhead still applies to degraded requests.
+	if allowed, _ := cb.AllowFailOpen(); allowed {
+		t.Fatal("admitted a degraded request past max_concurrent")
+	}
+	cb.Release()
+}
+
 func TestComposite_EffectiveState_NoBulkhead(t *testing.T) {
 	cfg := Config{
 		WindowSize:       4,
@@ -0,0 +1,79 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before
+// further ones are dropped for it.
+const eventBuffer = 64
+
+// Event is a breaker state change, as published on an EventBus.
+type Event struct {
+	Backend string    `json:"backend"` // the breaker key: backend URL, or route ID for breaker_scope: route
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Time    time.Time `json:"time"`
+}
+
+// EventBus fans breaker state changes out to subscribers, such as the
+// /admin/events stream. Publishing never blocks: a subscriber too slow to
+// keep up misses events rather than stalling the breaker that changed.
+type EventBus struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+// NewEventBus returns an EventBus with no subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// and a function that unsubscribes and closes it. The channel is also
+// closed by Close; on a closed bus it is returned closed.
+func (b *EventBus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends e to every subscriber with room for it.
+func (b *EventBus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Close closes every subscriber's channel, ending their streams, and
+// makes later subscriptions return closed channels. Used at shutdown so
+// long-lived streams do not hold the server open.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
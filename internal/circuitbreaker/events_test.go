@@ -0,0 +1,47 @@
+package circuitbreaker
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestFailureRate_PublishesTransitions(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	b := NewFailureRateBreaker("http://orders", 1, 0.5, time.Minute, 1, slog.Default(), nil)
+	b.SetEvents(bus)
+	b.RecordFailure(time.Millisecond)
+	b.Reset()
+
+	for _, want := range []string{"open", "closed"} {
+		select {
+		case ev := <-events:
+			if ev.Backend != "http://orders" || ev.To != want {
+				t.Errorf("event = %+v, want a transition to %s", ev, want)
+			}
+		default:
+			t.Fatalf("no event for the transition to %s", want)
+		}
+	}
+}
+
+// A subscriber that stops reading misses events; the breaker never waits.
+func TestEventBus_DropsForSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+	for range eventBuffer + 10 {
+		bus.Publish(Event{To: "open"})
+	}
+	if n := len(events); n != eventBuffer {
+		t.Errorf("buffered = %d, want %d", n, eventBuffer)
+	}
+
+	unsubscribe()
+	unsubscribe() // idempotent
+	for range events {
+	}
+	bus.Publish(Event{To: "closed"}) // no subscribers: must not panic
+}
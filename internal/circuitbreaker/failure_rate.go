@@ -2,6 +2,7 @@ package circuitbreaker
 
 import (
 	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -35,8 +36,17 @@ type FailureRateBreaker struct {
 	resetTimeout     time.Duration
 	halfOpenMax      int
 
+	// resetJitter spreads each open period over resetTimeout ± this
+	// fraction, drawing from random (rand.Float64 when nil); see
+	// SetResetJitter.
+	resetJitter float64
+	random      func() float64
+
 	halfOpenSuccess int
 	openedAt        time.Time
+	openFor         time.Duration // resetTimeout, jittered, for this open period
+
+	events *EventBus // nil unless SetEvents
 }
 
 // NewFailureRateBreaker creates a failure-rate circuit breaker for the given
@@ -64,7 +74,7 @@ func (b *FailureRateBreaker) Allow() bool {
 	case StateClosed:
 		return true
 	case StateOpen:
-		if time.Since(b.openedAt) >= b.resetTimeout {
+		if time.Since(b.openedAt) >= b.openFor {
 			b.transitionTo(StateHalfOpen)
 			return true
 		}
@@ -122,6 +132,26 @@ func (b *FailureRateBreaker) Reset() {
 	b.transitionTo(StateClosed)
 }
 
+// SetResetJitter makes each open period last resetTimeout ± fraction of it,
+// chosen at random when the breaker opens, so breakers that opened together
+// (for one backend across replicas) do not all send their half-open probes
+// at the same instant. random returns values in [0, 1); nil means
+// rand.Float64. It is injectable for tests. A fraction of 0 disables jitter.
+func (b *FailureRateBreaker) SetResetJitter(fraction float64, random func() float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetJitter = fraction
+	b.random = random
+}
+
+// SetEvents publishes the breaker's state changes on bus; nil stops
+// publishing.
+func (b *FailureRateBreaker) SetEvents(bus *EventBus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = bus
+}
+
 // SetFailureThreshold dynamically updates the failure threshold. Used by the
 // adaptive breaker to tighten or relax the threshold at runtime.
 func (b *FailureRateBreaker) SetFailureThreshold(t float64) {
@@ -157,7 +187,8 @@ func (b *FailureRateBreaker) failureRate() float64 {
 	return float64(b.failures) / float64(b.count)
 }
 
-// transitionTo changes the breaker state, emitting metrics and logging.
+// transitionTo changes the breaker state, emitting metrics, logging and
+// publishing an Event.
 // Must be called with b.mu held.
 func (b *FailureRateBreaker) transitionTo(newState State) {
 	if b.state == newState {
@@ -177,6 +208,9 @@ func (b *FailureRateBreaker) transitionTo(newState State) {
 		"from", from.String(),
 		"to", newState.String(),
 	)
+	if b.events != nil {
+		b.events.Publish(Event{Backend: b.backend, From: from.String(), To: newState.String(), Time: time.Now()})
+	}
 
 	switch newState {
 	//goland:noinspection GoBoolExpressions,GoDfaConstantCondition
@@ -188,6 +222,14 @@ func (b *FailureRateBreaker) transitionTo(newState State) {
 		b.halfOpenSuccess = 0
 	case StateOpen:
 		b.openedAt = time.Now()
+		b.openFor = b.resetTimeout
+		if b.resetJitter > 0 {
+			random := b.random
+			if random == nil {
+				random = rand.Float64
+			}
+			b.openFor += time.Duration((2*random() - 1) * b.resetJitter * float64(b.resetTimeout))
+		}
 		b.halfOpenSuccess = 0
 	case StateHalfOpen:
 		b.halfOpenSuccess = 0
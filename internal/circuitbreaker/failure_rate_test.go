@@ -45,6 +45,48 @@ func TestFailureRate_ClosedToOpen(t *testing.T) {
 	}
 }
 
+func TestFailureRate_ResetJitterSpreadsHalfOpen(t *testing.T) {
+	early := newTestBreaker(1, 0.5, 200*time.Millisecond, 1)
+	early.SetResetJitter(0.5, func() float64 { return 0 }) // open for 100ms
+	late := newTestBreaker(1, 0.5, 200*time.Millisecond, 1)
+	late.SetResetJitter(0.5, func() float64 { return 0.99 }) // open for ~300ms
+
+	// Opened at the same moment...
+	early.RecordFailure(time.Millisecond)
+	late.RecordFailure(time.Millisecond)
+
+	// ...but only one is ready to probe once the shorter period is over.
+	time.Sleep(150 * time.Millisecond)
+	if !early.Allow() || early.State() != StateHalfOpen {
+		t.Fatalf("early breaker: expected half-open after its jittered period, got %v", early.State())
+	}
+	if late.Allow() {
+		t.Fatal("late breaker half-opened at the same time as the early one")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if !late.Allow() || late.State() != StateHalfOpen {
+		t.Fatalf("late breaker: expected half-open after its jittered period, got %v", late.State())
+	}
+}
+
+func TestFailureRate_ResetJitterBounds(t *testing.T) {
+	b := newTestBreaker(1, 0.5, time.Second, 1)
+	b.SetResetJitter(0.2, nil)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		b.RecordFailure(time.Millisecond)
+		if b.openFor < 800*time.Millisecond || b.openFor > 1200*time.Millisecond {
+			t.Fatalf("open period %v outside reset_timeout ± 20%%", b.openFor)
+		}
+		seen[b.openFor] = true
+		b.Reset()
+	}
+	if len(seen) < 2 {
+		t.Error("jittered open periods never varied")
+	}
+}
+
 func TestFailureRate_OpenToHalfOpen(t *testing.T) {
 	b := newTestBreaker(2, 0.5, 50*time.Millisecond, 1)
 
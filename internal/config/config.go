@@ -4,13 +4,21 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"reflect"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/dskow/gateway-core/internal/jsonschema"
+	"github.com/dskow/gateway-core/internal/routing"
+	"github.com/dskow/gateway-core/internal/script"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,7 +31,15 @@ type Config struct {
 	Auth           AuthConfig           `yaml:"auth" json:"auth"`
 	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker" json:"circuit_breaker"`
 	Admin          AdminConfig          `yaml:"admin" json:"admin"`
+	HealthCheck    HealthCheckConfig    `yaml:"health_check" json:"health_check"`
+	Readiness      ReadinessConfig      `yaml:"readiness" json:"readiness"`
 	Routes         []RouteConfig        `yaml:"routes" json:"routes"`
+	DefaultRoute   *DefaultRouteConfig  `yaml:"default_route" json:"default_route,omitempty"`
+
+	// StrictEnv turns any ${VAR} left unresolved in a config value into a
+	// load error instead of a warning, so a typo in a backend URL or header
+	// env reference fails startup (and is rejected on reload).
+	StrictEnv bool `yaml:"strict_env" json:"strict_env"` // default: false
 
 	// Warnings holds non-fatal config issues detected during loading.
 	// Stored on the Config itself (not a package-level var) so it is
@@ -31,11 +47,38 @@ type Config struct {
 	Warnings []string `yaml:"-" json:"-"`
 }
 
+// Redacted returns a shallow copy of the config with secrets masked, for
+// display by the admin API and the -validate CLI flag.
+func (c *Config) Redacted() Config {
+	redacted := *c
+	if redacted.Auth.JWTSecret != "" {
+		redacted.Auth.JWTSecret = "***"
+	}
+	if redacted.Auth.ClientSecret != "" {
+		redacted.Auth.ClientSecret = "***"
+	}
+	if len(redacted.Auth.JWTSecrets) > 0 {
+		masked := make([]string, len(redacted.Auth.JWTSecrets))
+		for i := range masked {
+			masked[i] = "***"
+		}
+		redacted.Auth.JWTSecrets = masked
+	}
+	return redacted
+}
+
 // MetricsConfig holds Prometheus metrics endpoint settings.
 // Enabled defaults to true; set to a value of false to disable metrics.
 type MetricsConfig struct {
 	Enabled *bool  `yaml:"enabled" json:"enabled"`
 	Path    string `yaml:"path" json:"path"`
+	// Protected restricts the metrics endpoint to admin.ip_allowlist;
+	// other clients get 403. Default false keeps /metrics open.
+	Protected bool `yaml:"protected" json:"protected"`
+	// Buckets are the upper bounds, in seconds and strictly increasing, of
+	// gateway_request_duration_seconds. Unset means Prometheus's default
+	// buckets (5ms to 10s). Read at startup only.
+	Buckets []float64 `yaml:"buckets" json:"buckets,omitempty"`
 }
 
 // IsEnabled returns whether metrics are enabled (defaults to true).
@@ -56,6 +99,70 @@ type ServerConfig struct {
 	MaxBodyBytes    int64         `yaml:"max_body_bytes" json:"max_body_bytes"`
 	GlobalTimeoutMs int           `yaml:"global_timeout_ms" json:"global_timeout_ms"`
 	TLS             TLSConfig     `yaml:"tls" json:"tls"`
+
+	// FailFastOnStartup dials every backend before serving and refuses to
+	// start if any is unreachable within StartupCheckTimeout. Opt-in, since
+	// backends that come up after the gateway would otherwise block it.
+	FailFastOnStartup   bool          `yaml:"fail_fast_on_startup" json:"fail_fast_on_startup"`
+	StartupCheckTimeout time.Duration `yaml:"startup_check_timeout" json:"startup_check_timeout"` // default: 5s
+
+	// HideVersion omits the Server and X-Gateway-Version response headers
+	// for deployments that do not want to advertise the build.
+	HideVersion bool `yaml:"hide_version" json:"hide_version"` // default: false
+
+	// ServerTiming adds a Server-Timing header to proxied responses that
+	// splits the time spent in the gateway into backend, gateway and
+	// retries. Off by default: it tells clients how slow each backend is.
+	ServerTiming bool `yaml:"server_timing" json:"server_timing"` // default: false
+
+	// StripResponseHeaders are removed from every backend response so
+	// they never reach clients; routes can add more with their own
+	// strip_response_headers. Set it to [] to strip nothing.
+	StripResponseHeaders []string `yaml:"strip_response_headers" json:"strip_response_headers"` // default: ["Server", "X-Powered-By"]
+
+	AcceptLimit AcceptLimitConfig `yaml:"accept_limit" json:"accept_limit"`
+
+	ProxyProtocol ProxyProtocolConfig `yaml:"proxy_protocol" json:"proxy_protocol"`
+
+	// CorrelationHeaders are inbound headers checked, in order, for a
+	// request ID when X-Request-ID is absent (e.g. X-Trace-Id). The first
+	// one present becomes X-Request-ID; otherwise a UUID is generated.
+	CorrelationHeaders []string `yaml:"correlation_headers" json:"correlation_headers,omitempty"`
+
+	// ReadHeaderTimeout bounds the time to read a request's headers, the
+	// slow-header (slowloris) defense; ReadTimeout then covers the body.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" json:"read_header_timeout"` // default: 10s, or read_timeout if shorter
+
+	// MaxConnections caps concurrent client connections. Past it the
+	// listener stops accepting and new connections wait in the kernel
+	// backlog.
+	MaxConnections int `yaml:"max_connections" json:"max_connections"` // 0 = unlimited; default: 0
+
+	// AdminPort and MetricsPort move the admin API and the metrics endpoint
+	// off the main port onto a separate plain-HTTP listener (one, when the
+	// ports are equal) that also serves liveness and readiness. It binds to
+	// InternalAddress, e.g. a private interface.
+	AdminPort       int    `yaml:"admin_port" json:"admin_port"`             // 0 = serve on the main port; default: 0
+	MetricsPort     int    `yaml:"metrics_port" json:"metrics_port"`         // 0 = serve on the main port; default: 0
+	InternalAddress string `yaml:"internal_address" json:"internal_address"` // default: "" (all interfaces)
+}
+
+// AcceptLimitConfig caps the global rate of accepted connections at the
+// listener, before TLS or any middleware runs. Independent of (and coarser
+// than) the per-client rate_limit.
+type AcceptLimitConfig struct {
+	ConnectionsPerSecond float64 `yaml:"connections_per_second" json:"connections_per_second"` // 0 = unlimited; default: 0
+	Burst                int     `yaml:"burst" json:"burst"`                                   // default: ceil(connections_per_second)
+	Mode                 string  `yaml:"mode" json:"mode"`                                     // "delay" (queue in backlog) or "reject" (close); default: "delay"
+}
+
+// ProxyProtocolConfig reads PROXY protocol v1/v2 headers from an L4 load
+// balancer, so rate limiting and logging see the original client address.
+// Only connections from TrustedCIDRs may carry a header.
+type ProxyProtocolConfig struct {
+	Enabled       bool          `yaml:"enabled" json:"enabled"`
+	TrustedCIDRs  []string      `yaml:"trusted_cidrs" json:"trusted_cidrs"`
+	HeaderTimeout time.Duration `yaml:"header_timeout" json:"header_timeout"` // default: 5s
 }
 
 // TLSConfig holds TLS termination settings.
@@ -64,22 +171,211 @@ type TLSConfig struct {
 	CertFile   string `yaml:"cert_file" json:"cert_file"`
 	KeyFile    string `yaml:"key_file" json:"key_file"`
 	MinVersion string `yaml:"min_version" json:"min_version"` // "1.2" or "1.3"; default: "1.2"
+	// OCSPStapling fetches and staples an OCSP response from the responder
+	// named in the certificate. The cert file must include the issuer.
+	OCSPStapling bool `yaml:"ocsp_stapling" json:"ocsp_stapling"`
+
+	// Mutual TLS. ClientCAFile is the PEM bundle used to verify client
+	// certificates; it is watched and reloaded like the server cert.
+	ClientCAFile   string `yaml:"client_ca_file" json:"client_ca_file"`
+	ClientAuthMode string `yaml:"client_auth_mode" json:"client_auth_mode"` // "none", "request", "require", "verify_if_given", "require_and_verify"; default: "none"
+
+	// MaxConcurrentHandshakes bounds in-flight TLS handshakes. Connections
+	// beyond the limit wait in the listen backlog until a slot frees up.
+	MaxConcurrentHandshakes int           `yaml:"max_concurrent_handshakes" json:"max_concurrent_handshakes"` // 0 = unlimited; default: 0
+	HandshakeTimeout        time.Duration `yaml:"handshake_timeout" json:"handshake_timeout"`                 // default: 10s
+}
+
+// ValidClientAuthModes are the accepted server.tls.client_auth_mode values.
+var ValidClientAuthModes = map[string]bool{
+	"none":               true,
+	"request":            true,
+	"require":            true,
+	"verify_if_given":    true,
+	"require_and_verify": true,
 }
 
 // LoggingConfig holds access log output and debug settings.
 type LoggingConfig struct {
-	Output          string `yaml:"output" json:"output"`                          // "stdout", "stderr", or file path; default: "stdout"
+	Output          string `yaml:"output" json:"output"`                         // "stdout", "stderr", "syslog", or file path; default: "stdout"
 	MaxSizeMB       int    `yaml:"max_size_mb" json:"max_size_mb"`               // max log file size before rotation; default: 100
 	MaxBackups      int    `yaml:"max_backups" json:"max_backups"`               // number of rotated files to keep; default: 3
 	MaxAgeDays      int    `yaml:"max_age_days" json:"max_age_days"`             // max days to retain rotated files; default: 30
+	RotateDaily     bool   `yaml:"rotate_daily" json:"rotate_daily"`             // also rotate at local midnight, naming files by date; default: false
+	Compress        bool   `yaml:"compress" json:"compress"`                     // gzip rotated files; default: false
+	AlsoStdout      bool   `yaml:"also_stdout" json:"also_stdout"`               // mirror file or syslog output to stdout; default: false
 	BodyLogging     bool   `yaml:"body_logging" json:"body_logging"`             // log request/response bodies; default: false
 	MaxBodyLogBytes int    `yaml:"max_body_log_bytes" json:"max_body_log_bytes"` // max bytes of body to log; default: 4096
+	// SampleRate is the fraction (0.0–1.0) of 2xx requests written to the
+	// access log; other statuses are always logged. The decision hashes the
+	// request ID, so a request is either logged in full or not at all.
+	// Routes override it with log_sample_rate.
+	SampleRate *float64 `yaml:"sample_rate" json:"sample_rate,omitempty"` // default: 1.0
+	// Fields lists the access-log fields to write, from ValidLogFields or
+	// "header:<Name>" for a request header. Routes override it with
+	// log_fields.
+	Fields []string `yaml:"fields" json:"fields,omitempty"` // default: method, path, status, latency_ms, client_ip, request_id
+	// Syslog configures the "syslog" output.
+	Syslog SyslogConfig `yaml:"syslog" json:"syslog"`
+	// SizeAnomaly warns about request and response bodies far larger than
+	// their route's recent average.
+	SizeAnomaly SizeAnomalyConfig `yaml:"size_anomaly" json:"size_anomaly"`
+
+	// ExternalRotation hands the log file to an external rotator such as
+	// logrotate: SIGHUP reopens the file at its configured path, and config
+	// reload moves from SIGHUP to SIGUSR1. Output must be a file path.
+	// Read at startup only.
+	ExternalRotation bool `yaml:"external_rotation" json:"external_rotation"` // default: false
+}
+
+// SizeAnomalyConfig flags proxied bodies more than Multiplier times their
+// route's rolling average size, to catch a backend whose responses
+// suddenly grow. Warnings are sampled: at most one per route and
+// direction per LogInterval, carrying the count of those skipped. Read at
+// startup only.
+type SizeAnomalyConfig struct {
+	Enabled     bool          `yaml:"enabled" json:"enabled"`           // default: false
+	Multiplier  float64       `yaml:"multiplier" json:"multiplier"`     // default: 10
+	MinSamples  int           `yaml:"min_samples" json:"min_samples"`   // requests seen before a route's average is trusted; default: 20
+	MinBytes    int64         `yaml:"min_bytes" json:"min_bytes"`       // bodies smaller than this are never flagged; default: 1024
+	LogInterval time.Duration `yaml:"log_interval" json:"log_interval"` // default: 1m
+}
+
+// SyslogConfig selects the syslog daemon for logging output "syslog".
+// Leave Network and Address empty to use the local daemon's socket.
+type SyslogConfig struct {
+	Network  string `yaml:"network" json:"network"`   // "", "udp", "tcp", or "unix"; default: "" (local)
+	Address  string `yaml:"address" json:"address"`   // e.g. "logs.internal:514"; required with network
+	Facility string `yaml:"facility" json:"facility"` // e.g. "local0", "daemon", "user"; default: "local0"
+	Tag      string `yaml:"tag" json:"tag"`           // default: "gateway"
+}
+
+// ValidSyslogFacilities lists the accepted logging.syslog.facility values.
+var ValidSyslogFacilities = map[string]bool{
+	"kern": true, "user": true, "mail": true, "daemon": true, "auth": true,
+	"syslog": true, "lpr": true, "news": true, "uucp": true, "cron": true,
+	"authpriv": true, "ftp": true,
+	"local0": true, "local1": true, "local2": true, "local3": true,
+	"local4": true, "local5": true, "local6": true, "local7": true,
+}
+
+// ValidLogFields are the accepted access-log field names, besides
+// "header:<Name>".
+var ValidLogFields = map[string]bool{
+	"method": true, "path": true, "query": true, "status": true,
+	"latency_ms": true, "client_ip": true, "request_id": true,
+	"host": true, "proto": true, "user_agent": true, "referer": true,
+}
+
+// AccessLogFields returns the access-log fields for route: its log_fields,
+// else logging.fields. nil means the middleware's default set.
+func (l LoggingConfig) AccessLogFields(route RouteConfig) []string {
+	if len(route.LogFields) > 0 {
+		return route.LogFields
+	}
+	return l.Fields
+}
+
+// validateLogFields checks an access-log field list; name is its config
+// path for error messages.
+func validateLogFields(name string, fields []string) error {
+	for _, f := range fields {
+		if header, ok := strings.CutPrefix(f, "header:"); ok {
+			if header == "" || strings.ContainsAny(header, " :\t") {
+				return fmt.Errorf("%s: invalid header field %q", name, f)
+			}
+			continue
+		}
+		if !ValidLogFields[f] {
+			return fmt.Errorf("%s: unknown field %q (want one of method, path, query, status, latency_ms, client_ip, request_id, host, proto, user_agent, referer, or header:<Name>)", name, f)
+		}
+	}
+	return nil
+}
+
+// AccessLogSampleRate returns the global access-log sample rate, 1.0 when
+// unset.
+func (l LoggingConfig) AccessLogSampleRate() float64 {
+	if l.SampleRate == nil {
+		return 1
+	}
+	return *l.SampleRate
 }
 
 // AdminConfig holds admin API settings.
 type AdminConfig struct {
 	Enabled     bool     `yaml:"enabled" json:"enabled"`           // default: false
 	IPAllowlist []string `yaml:"ip_allowlist" json:"ip_allowlist"` // CIDR notation
+	// RequestsPerSecond and BurstSize rate limit each client IP on the
+	// admin API and the metrics endpoint, which bypass the request-path
+	// middleware (and so rate_limit). Read at startup only.
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"` // 0 = unlimited; default: 0
+	BurstSize         int     `yaml:"burst_size" json:"burst_size"`                   // default: 2 × requests_per_second, at least 1
+}
+
+// RateLimit returns the per-client limit for the admin and metrics
+// endpoints, and false when they are unlimited.
+func (a AdminConfig) RateLimit() (RateLimitConfig, bool) {
+	if a.RequestsPerSecond <= 0 {
+		return RateLimitConfig{}, false
+	}
+	return RateLimitConfig{RequestsPerSecond: a.RequestsPerSecond, BurstSize: a.BurstSize}, true
+}
+
+// HealthCheckConfig holds active backend probe settings. When enabled, every
+// backend whose circuit breaker is open is probed on Interval; after
+// HealthyThreshold consecutive successful probes the breaker is closed
+// without waiting for live traffic to drive the half-open transition.
+type HealthCheckConfig struct {
+	Enabled          bool          `yaml:"enabled" json:"enabled"`                     // default: false
+	Type             string        `yaml:"type" json:"type"`                           // "tcp" or "http"; default: "tcp"
+	Path             string        `yaml:"path" json:"path"`                           // request path for "http" probes; default: "/"
+	Interval         time.Duration `yaml:"interval" json:"interval"`                   // probe cadence; default: 5s
+	Timeout          time.Duration `yaml:"timeout" json:"timeout"`                     // per-probe timeout; default: 2s
+	HealthyThreshold int           `yaml:"healthy_threshold" json:"healthy_threshold"` // consecutive successes to close; default: 2
+}
+
+// ReadinessConfig adapts the readiness probe to what a load balancer's
+// health check expects, and sets the pre-stop drain window: on shutdown,
+// readiness fails for PreStopDelay while liveness keeps passing and
+// traffic is still served, giving the LB time to deregister the instance
+// before in-flight requests are drained.
+type ReadinessConfig struct {
+	Path          string        `yaml:"path" json:"path"`                     // readiness endpoint; default: "/ready"
+	HealthyBody   string        `yaml:"healthy_body" json:"healthy_body"`     // plain-text body when ready; default: JSON backend detail
+	UnhealthyBody string        `yaml:"unhealthy_body" json:"unhealthy_body"` // plain-text body when not ready or draining; default: JSON backend detail
+	PreStopDelay  time.Duration `yaml:"pre_stop_delay" json:"pre_stop_delay"` // fail readiness this long before draining; default: 0
+	// Mode "strict" fails readiness when any backend is down. "degraded"
+	// stays ready, reporting "degraded", until a critical route's backend
+	// is down or more than MaxDownPercent of backends are.
+	Mode           string  `yaml:"mode" json:"mode"`                         // "strict" or "degraded"; default: "strict"
+	MaxDownPercent float64 `yaml:"max_down_percent" json:"max_down_percent"` // degraded mode only; default: 50
+	// CacheTTL is how long a readiness result is reused before backends
+	// are checked again. ?nocache=1 on the probe forces a fresh check.
+	CacheTTL time.Duration `yaml:"cache_ttl" json:"cache_ttl"` // default: 5s
+}
+
+// DefaultRouteConfig handles requests that match no route, in place of the
+// GATEWAY_ROUTE_NOT_FOUND 404: either proxied to Backend (e.g. the host
+// serving a single-page app) or answered with a static Status and Body.
+// The default route never requires auth.
+type DefaultRouteConfig struct {
+	Backend     string `yaml:"backend" json:"backend,omitempty"`
+	TimeoutMs   int    `yaml:"timeout_ms" json:"timeout_ms"`     // backend only; default: 30000
+	Status      int    `yaml:"status" json:"status"`             // static only; default: 404
+	Body        string `yaml:"body" json:"body,omitempty"`       // static only
+	ContentType string `yaml:"content_type" json:"content_type"` // static only; default: "text/html; charset=utf-8"
+}
+
+// Route returns the route unmatched requests are proxied through when
+// Backend is set. It matches every path; its metrics label is "default".
+func (d DefaultRouteConfig) Route() RouteConfig {
+	return RouteConfig{
+		PathPrefix:   "/",
+		Backend:      d.Backend,
+		TimeoutMs:    d.TimeoutMs,
+		MetricsLabel: "default",
+	}
 }
 
 // GlobalTimeout returns the global request deadline as a time.Duration.
@@ -95,34 +391,635 @@ func (s ServerConfig) GlobalTimeout() time.Duration {
 type RateLimitConfig struct {
 	RequestsPerSecond float64       `yaml:"requests_per_second" json:"requests_per_second"`
 	BurstSize         int           `yaml:"burst_size" json:"burst_size"`
-	IdleTTL           time.Duration `yaml:"idle_ttl" json:"idle_ttl"`                   // how long an unused client entry is kept before eviction; 0 = default
-	CleanupInterval   time.Duration `yaml:"cleanup_interval" json:"cleanup_interval"`   // janitor scan cadence; 0 = default
+	IdleTTL           time.Duration `yaml:"idle_ttl" json:"idle_ttl"`                 // how long an unused client entry is kept before eviction; 0 = default
+	CleanupInterval   time.Duration `yaml:"cleanup_interval" json:"cleanup_interval"` // janitor scan cadence; 0 = default
+	// BypassCIDRs lists client networks (health checkers, monitoring) that
+	// are never rate limited. The client IP is resolved through
+	// server.trusted_proxies like everywhere else.
+	BypassCIDRs []string `yaml:"bypass_cidrs" json:"bypass_cidrs,omitempty"`
+	// Algorithm selects the per-client accounting. "token_bucket" allows
+	// bursts of burst_size on top of requests_per_second; "sliding_window"
+	// admits at most requests_per_second × window requests in any window,
+	// with no burst (burst_size is ignored).
+	Algorithm string        `yaml:"algorithm" json:"algorithm"` // "token_bucket" or "sliding_window"; default: "token_bucket"
+	Window    time.Duration `yaml:"window" json:"window"`       // sliding_window only; default: 1m
+	// MaxConcurrentPerClient caps each client's in-flight requests
+	// (per client and route rate override); excess requests get 429.
+	// Global only: ignored inside a route's rate_override.
+	MaxConcurrentPerClient int `yaml:"max_concurrent_per_client" json:"max_concurrent_per_client"` // 0 = unlimited; default: 0
+	// RetryAfterFormat is how 429 responses give Retry-After: "seconds"
+	// (delay-seconds) or "http-date", for clients that only parse dates.
+	// Global only: ignored inside a route's rate_override.
+	RetryAfterFormat string `yaml:"retry_after_format" json:"retry_after_format"` // default: "seconds"
+}
+
+// ValidRateLimitAlgorithms are the accepted rate_limit.algorithm values.
+var ValidRateLimitAlgorithms = map[string]bool{
+	"token_bucket":   true,
+	"sliding_window": true,
 }
 
 // AuthConfig holds JWT/OAuth2 authentication settings.
 type AuthConfig struct {
-	Enabled   bool     `yaml:"enabled" json:"enabled"`
-	JWTSecret string   `yaml:"jwt_secret" json:"jwt_secret"`
-	Issuer    string   `yaml:"issuer" json:"issuer"`
-	Audience  string   `yaml:"audience" json:"audience"`
-	Scopes    []string `yaml:"scopes" json:"scopes"`
+	Enabled   bool   `yaml:"enabled" json:"enabled"`
+	JWTSecret string `yaml:"jwt_secret" json:"jwt_secret"`
+	// JWTSecrets are further HS256 secrets accepted during a key rotation,
+	// tried in order after JWTSecret. List the newest first.
+	JWTSecrets []string `yaml:"jwt_secrets" json:"jwt_secrets"`
+	Issuer     string   `yaml:"issuer" json:"issuer"`
+	Audience   string   `yaml:"audience" json:"audience"`
+	Scopes     []string `yaml:"scopes" json:"scopes"`
+	// RequiredClaims maps claim names to the value each must have, e.g.
+	// token_use: "access". Non-string claims are compared in their JSON
+	// text form.
+	RequiredClaims map[string]string `yaml:"required_claims" json:"required_claims"`
+	// RequiredClaimsPresent lists claims the token must carry, with any value.
+	RequiredClaimsPresent []string `yaml:"required_claims_present" json:"required_claims_present"`
+	// JWTCacheSize enables an LRU of this many validated JWTs, so repeated
+	// requests with the same token skip signature verification. 0 disables
+	// it. Not used with introspection_url, which has its own cache.
+	JWTCacheSize int           `yaml:"jwt_cache_size" json:"jwt_cache_size"`
+	JWTCacheTTL  time.Duration `yaml:"jwt_cache_ttl" json:"jwt_cache_ttl"` // default: 1m with jwt_cache_size; never past the token's exp
+
+	// IntrospectionURL switches validation from local JWT checks to an
+	// RFC 7662 introspection endpoint, for opaque tokens. jwt_secret,
+	// issuer and audience become optional; issuer and audience, when set,
+	// must match the response's iss and aud.
+	IntrospectionURL string `yaml:"introspection_url" json:"introspection_url"`
+	// ClientID and ClientSecret authenticate the gateway to the
+	// introspection endpoint with HTTP Basic auth.
+	ClientID     string `yaml:"client_id" json:"client_id"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret"`
+	// CacheTTL is how long an introspection result is reused; an active
+	// token is never cached past its exp.
+	CacheTTL time.Duration `yaml:"cache_ttl" json:"cache_ttl"` // default: 30s with introspection_url
+}
+
+// Secrets returns every accepted signing secret, JWTSecret first, then
+// JWTSecrets in order.
+func (a AuthConfig) Secrets() []string {
+	var secrets []string
+	if a.JWTSecret != "" {
+		secrets = append(secrets, a.JWTSecret)
+	}
+	return append(secrets, a.JWTSecrets...)
 }
 
 // RouteConfig defines a single proxy route.
 type RouteConfig struct {
-	PathPrefix     string               `yaml:"path_prefix" json:"path_prefix"`
-	Backend        string               `yaml:"backend" json:"backend"`
-	StripPrefix    bool                 `yaml:"strip_prefix" json:"strip_prefix"`
-	Methods        []string             `yaml:"methods" json:"methods"`
-	AuthRequired   bool                 `yaml:"auth_required" json:"auth_required"`
-	TimeoutMs      int                  `yaml:"timeout_ms" json:"timeout_ms"`
-	RetryAttempts  int                  `yaml:"retry_attempts" json:"retry_attempts"`
-	Headers        map[string]string    `yaml:"headers" json:"headers,omitempty"`
-	RateOverride   *RateLimitConfig     `yaml:"rate_override" json:"rate_override,omitempty"`
+	PathPrefix     string                `yaml:"path_prefix" json:"path_prefix"`
+	Backend        string                `yaml:"backend" json:"backend"`
+	StripPrefix    bool                  `yaml:"strip_prefix" json:"strip_prefix"`
+	Methods        []string              `yaml:"methods" json:"methods"`
+	AuthRequired   bool                  `yaml:"auth_required" json:"auth_required"`
+	TimeoutMs      int                   `yaml:"timeout_ms" json:"timeout_ms"`
+	RetryAttempts  int                   `yaml:"retry_attempts" json:"retry_attempts"`
+	Headers        map[string]string     `yaml:"headers" json:"headers,omitempty"`
+	RateOverride   *RateLimitConfig      `yaml:"rate_override" json:"rate_override,omitempty"`
 	ConnectionPool *ConnectionPoolConfig `yaml:"connection_pool" json:"connection_pool,omitempty"`
-	FallbackStatus int                  `yaml:"fallback_status" json:"fallback_status"`
-	FallbackBody   string               `yaml:"fallback_body" json:"fallback_body"`
-	LogLevel       string               `yaml:"log_level" json:"log_level"` // "debug", "info", "warn", "error", "none"; default: "info"
+	FallbackStatus int                   `yaml:"fallback_status" json:"fallback_status"`
+	FallbackBody   string                `yaml:"fallback_body" json:"fallback_body"`
+	LogLevel       string                `yaml:"log_level" json:"log_level"` // "debug", "info", "warn", "error", "none"; default: "info"
+	// LogSampleRate overrides logging.sample_rate for this route.
+	LogSampleRate *float64 `yaml:"log_sample_rate" json:"log_sample_rate,omitempty"`
+	// LogFields overrides logging.fields for this route.
+	LogFields []string `yaml:"log_fields" json:"log_fields,omitempty"`
+	// ClientCertRequired rejects requests that did not present a client
+	// certificate chaining to server.tls.client_ca_file.
+	ClientCertRequired bool `yaml:"client_cert_required" json:"client_cert_required"`
+	// RedirectPolicy controls backend 3xx responses whose Location points at
+	// the backend host: "passthrough" forwards them unchanged, "rewrite"
+	// points them at the gateway, and "follow" follows them internally up to
+	// MaxRedirects hops.
+	RedirectPolicy string `yaml:"redirect_policy" json:"redirect_policy"` // default: "passthrough"
+	MaxRedirects   int    `yaml:"max_redirects" json:"max_redirects"`     // default: 5 (follow only)
+	// BreakerScope "route" gives the route its own circuit breaker instead of
+	// sharing the backend's, so its failures cannot open the circuit for
+	// other routes on the same backend.
+	BreakerScope string `yaml:"breaker_scope" json:"breaker_scope"` // "backend" or "route"; default: "backend"
+	// TimeoutJitter shortens each attempt's timeout by a random fraction of
+	// up to this much, so requests stuck on a stalled backend do not all
+	// time out (and retry) in the same instant. timeout_ms stays the upper
+	// bound.
+	TimeoutJitter float64 `yaml:"timeout_jitter" json:"timeout_jitter"` // 0–0.5; default: 0 (off)
+	// MetricsLabel replaces path_prefix as the route label on per-route
+	// metrics; routes sharing a label are aggregated into one series.
+	// MetricsDisabled drops per-route metrics for the route entirely.
+	MetricsLabel    string `yaml:"metrics_label" json:"metrics_label,omitempty"`
+	MetricsDisabled bool   `yaml:"metrics_disabled" json:"metrics_disabled"`
+	// PathTemplating additionally counts requests in
+	// gateway_requests_by_template_total by request path, with numeric and
+	// UUID segments collapsed to {id} / {uuid}. Only enable it on routes
+	// whose remaining path segments are a small, fixed set.
+	PathTemplating bool `yaml:"path_templating" json:"path_templating"` // default: false
+	// ExcludeFromReadiness keeps the route's backend out of the readiness
+	// probe: it is not dialed and being down does not fail readiness. For
+	// third-party backends the gateway does not own.
+	ExcludeFromReadiness bool `yaml:"exclude_from_readiness" json:"exclude_from_readiness"` // default: false
+	// Critical fails readiness whenever this route's backend is down, even
+	// in readiness.mode "degraded".
+	Critical bool `yaml:"critical" json:"critical"` // default: false
+	// MaxConcurrent caps in-flight requests to the backend for this route.
+	// Unlike the backend bulkhead, which rejects at capacity, excess
+	// requests queue for up to QueueTimeoutMs before a 503.
+	MaxConcurrent  int `yaml:"max_concurrent" json:"max_concurrent"`     // 0 = unlimited; default: 0
+	QueueTimeoutMs int `yaml:"queue_timeout_ms" json:"queue_timeout_ms"` // default: 1000 when max_concurrent is set
+	// ClientBodyTimeoutMs reads the whole request body from the client,
+	// within this budget, before any backend attempt starts. A slow upload
+	// then gets 408 without touching the circuit breaker, and timeout_ms
+	// covers only the backend.
+	ClientBodyTimeoutMs int `yaml:"client_body_timeout_ms" json:"client_body_timeout_ms"` // 0 = stream the body to the backend; default: 0
+	// ResponseHeaderTimeoutMs bounds the wait for the backend's response
+	// headers after the request is sent. A backend that accepts the
+	// connection but never answers then fails fast (and is retried), while
+	// timeout_ms still covers a long body download. Set on the backend's
+	// transport; routes on the same backend with different values get
+	// separate transports.
+	ResponseHeaderTimeoutMs int `yaml:"response_header_timeout_ms" json:"response_header_timeout_ms"` // 0 = only timeout_ms applies; default: 0
+	// SkipRateLimit exempts the route from per-client rate limiting.
+	SkipRateLimit bool `yaml:"skip_rate_limit" json:"skip_rate_limit"` // default: false
+	// MatchType selects how PathPrefix is matched against the request
+	// path: "prefix" (boundary-aware prefix match), "exact" (the whole path
+	// and nothing below it), or "regex" (PathPrefix is a Go regular
+	// expression that must match the whole path). See MatchPriority for
+	// precedence when several routes match.
+	MatchType string `yaml:"match_type" json:"match_type"` // "prefix", "exact", "regex"; default: "prefix"
+
+	// Hosts restricts the route to requests whose Host header matches one
+	// of these names, exactly ("api.foo.com") or by wildcard ("*.foo.com",
+	// any subdomain). Case and port are ignored. Routes with hosts take
+	// precedence over routes without; see HostRank. Empty matches any host.
+	Hosts []string `yaml:"hosts" json:"hosts,omitempty"`
+	// MatchConditions are further requirements on the request, checked
+	// after the path matches; all must hold for the route to match. A
+	// route with conditions outranks a route without them at the same
+	// path, so it can split traffic off a plain route by header or query.
+	MatchConditions []MatchCondition `yaml:"match_conditions" json:"match_conditions,omitempty"`
+	// BackendAllowedHosts lists the hosts a templated Backend may resolve
+	// to, in the syntax of Hosts. A regex route's Backend may reference
+	// the pattern's named captures as {name} (e.g. "https://{svc}.internal"
+	// for (?P<svc>[a-z]+)); they are substituted per request, and a result
+	// outside this list is refused with 403. Required with a templated
+	// Backend.
+	BackendAllowedHosts []string `yaml:"backend_allowed_hosts" json:"backend_allowed_hosts,omitempty"`
+	// MethodRewrite changes the method sent to the backend, keyed by the
+	// client's method: {PATCH: POST} serves PATCH to a backend that only
+	// knows POST. Methods still applies to the client's method. Names are
+	// case-insensitive.
+	MethodRewrite map[string]string `yaml:"method_rewrite" json:"method_rewrite,omitempty"`
+	// WrapUpstreamErrors replaces the body of a backend 5xx response that
+	// is not already JSON (an HTML error page, say) with the gateway's
+	// JSON error, code GATEWAY_UPSTREAM_ERROR, keeping the status.
+	WrapUpstreamErrors bool `yaml:"wrap_upstream_errors" json:"wrap_upstream_errors"` // default: false
+	// StripResponseHeaders are removed from the backend's responses, in
+	// addition to server.strip_response_headers.
+	StripResponseHeaders []string `yaml:"strip_response_headers" json:"strip_response_headers,omitempty"`
+	// StripResponseFields are JSON keys removed, wherever they appear, from
+	// application/json responses of up to 1 MiB before they reach the
+	// client (e.g. "_debug", "internal_id"). Larger or compressed bodies
+	// pass through unfiltered.
+	StripResponseFields []string `yaml:"strip_response_fields" json:"strip_response_fields,omitempty"`
+	// RequestSchema is the path to a JSON Schema that JSON request bodies
+	// must match; others are refused with 400 GATEWAY_INVALID_BODY before
+	// reaching the backend. See package jsonschema for the keywords
+	// supported. The schema is compiled when the config is loaded.
+	RequestSchema string `yaml:"request_schema" json:"request_schema,omitempty"`
+
+	// ResponseHeaders are set on the backend's responses. By default they
+	// fill in headers the backend did not send; with
+	// ResponseHeadersOverride they replace the backend's values.
+	ResponseHeaders         map[string]string `yaml:"response_headers" json:"response_headers,omitempty"`
+	ResponseHeadersOverride bool              `yaml:"response_headers_override" json:"response_headers_override"` // default: false
+
+	// RetryMaxBufferBytes caps how much of a response is held in memory
+	// while its attempt may still be retried. A larger response is sent on
+	// to the client as it arrives, and is not retried.
+	RetryMaxBufferBytes int `yaml:"retry_max_buffer_bytes" json:"retry_max_buffer_bytes"` // default: 1048576 (1 MiB)
+
+	// CircuitBreaker overrides circuit_breaker for this route's breaker.
+	// Unset fields are taken from circuit_breaker, so adaptive can be
+	// turned on but not off; max_concurrent_retries is global only. Routes
+	// sharing a breaker (see BreakerKey) must agree on its settings.
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker" json:"circuit_breaker,omitempty"`
+
+	// FailOpen proxies requests while the route's circuit breaker is open
+	// instead of rejecting them (or serving the fallback): each goes to the
+	// backend once, without retries, and its response carries
+	// X-Gateway-Degraded: circuit-open. A full bulkhead still rejects. For
+	// non-critical backends, where shedding does more harm than the
+	// failures it avoids.
+	FailOpen bool `yaml:"fail_open" json:"fail_open"` // default: false
+
+	// ServeStaleOnOpen answers a GET the circuit breaker rejects with the
+	// last 200 the backend returned for the same host, path and query,
+	// marked X-Gateway-Stale: true, ahead of the fallback or 503. Only
+	// responses a shared cache could store are kept: none to requests with
+	// Authorization or Cookie, none setting cookies, marked private or
+	// no-store or with Vary: *. A response with Vary is served only to
+	// requests with the same values of the headers it names. At most 1024
+	// responses and 64 MiB are kept across routes.
+	ServeStaleOnOpen bool `yaml:"serve_stale_on_open" json:"serve_stale_on_open"` // default: false
+
+	// RequestScript is Lua run on each request before it is queued or
+	// proxied. It can set headers, rewrite the path sent to the backend (in
+	// place of strip_prefix) or answer the request itself; headers are
+	// applied after it. See package script for the sandbox and the request
+	// API. It is compiled when the config is loaded, and a run that outlasts
+	// ScriptTimeoutMs fails the request with a 500.
+	RequestScript   string `yaml:"request_script" json:"request_script,omitempty"`
+	ScriptTimeoutMs int    `yaml:"script_timeout_ms" json:"script_timeout_ms"` // default: 10
+
+	pathRegexp    *regexp.Regexp     // compiled PathPrefix for match_type "regex"; set by validate
+	requestSchema *jsonschema.Schema // compiled RequestSchema; set by validate
+	requestScript *script.Script     // compiled RequestScript; set by validate
+}
+
+// MatchCondition requires a request header or query parameter (set exactly
+// one of Header and Query) to be present and, when Equals is set, to have
+// that value. Header names are case-insensitive; query names are not.
+type MatchCondition struct {
+	Header string `yaml:"header" json:"header,omitempty"`
+	Query  string `yaml:"query" json:"query,omitempty"`
+	Equals string `yaml:"equals" json:"equals,omitempty"` // default: "" (any value)
+}
+
+// Matches reports whether req satisfies c. With several values for the
+// header or parameter, any one of them may equal Equals.
+func (c MatchCondition) Matches(req *http.Request) bool {
+	var values []string
+	var present bool
+	if c.Header != "" {
+		values = req.Header.Values(c.Header)
+		present = len(values) > 0
+	} else {
+		values, present = req.URL.Query()[c.Query]
+	}
+	if !present {
+		return false
+	}
+	return c.Equals == "" || slices.Contains(values, c.Equals)
+}
+
+// String renders c for route IDs and error messages, e.g.
+// "header:X-Api-Version=2" or "query:beta".
+func (c MatchCondition) String() string {
+	s := "query:" + c.Query
+	if c.Header != "" {
+		s = "header:" + http.CanonicalHeaderKey(c.Header)
+	}
+	if c.Equals != "" {
+		s += "=" + c.Equals
+	}
+	return s
+}
+
+// validHostPattern accepts a host name or a "*." wildcard followed by a
+// name, without port or scheme.
+func validHostPattern(h string) bool {
+	name := strings.TrimPrefix(h, "*.")
+	if name == "" || strings.ContainsAny(name, "*:/ ") {
+		return false
+	}
+	return !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, ".")
+}
+
+// validMethodName accepts an HTTP method name: letters only, any case.
+func validMethodName(m string) bool {
+	if m == "" {
+		return false
+	}
+	for _, c := range m {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidMatchTypes are the accepted route match_type values.
+var ValidMatchTypes = map[string]bool{
+	"prefix": true,
+	"exact":  true,
+	"regex":  true,
+}
+
+// compilePathRegexp compiles a regex route pattern, anchored so it must
+// match the whole path.
+func compilePathRegexp(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// CompilePaths returns a copy of routes with every regex pattern compiled,
+// so matching against the copy does not compile a pattern per request.
+// Routes from Load are compiled already; this is for routes built in code,
+// and is cheap for the rest. The error names the first route whose
+// pattern does not compile; that route is left to never match.
+func CompilePaths(routes []RouteConfig) ([]RouteConfig, error) {
+	out := make([]RouteConfig, len(routes))
+	copy(out, routes)
+	var firstErr error
+	for i := range out {
+		if out[i].MatchType != "regex" || out[i].pathRegexp != nil {
+			continue
+		}
+		re, err := compilePathRegexp(out[i].PathPrefix)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("route %q: invalid regex: %w", out[i].PathPrefix, err)
+			}
+			continue
+		}
+		out[i].pathRegexp = re
+	}
+	return out, firstErr
+}
+
+// compiledRegexp returns a regex route's compiled pattern, or nil if it
+// does not compile.
+func (r RouteConfig) compiledRegexp() *regexp.Regexp {
+	if r.pathRegexp != nil {
+		return r.pathRegexp
+	}
+	// Route built in code and not passed through Load or CompilePaths.
+	re, err := compilePathRegexp(r.PathPrefix)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// CompileRequestSchema returns the route's compiled RequestSchema, or nil
+// if it has none.
+func (r RouteConfig) CompileRequestSchema() (*jsonschema.Schema, error) {
+	if r.RequestSchema == "" || r.requestSchema != nil {
+		return r.requestSchema, nil
+	}
+	// Route built in code rather than through Load/validate.
+	return loadSchema(r.RequestSchema)
+}
+
+// CompileRequestScript returns the route's compiled RequestScript, or nil
+// if it has none.
+func (r RouteConfig) CompileRequestScript() (*script.Script, error) {
+	if r.RequestScript == "" || r.requestScript != nil {
+		return r.requestScript, nil
+	}
+	// Route built in code rather than through Load/validate.
+	return script.Compile(r.RequestScript)
+}
+
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.Compile(data)
+}
+
+// MatchesPath reports whether path selects r under its MatchType.
+func (r RouteConfig) MatchesPath(path string) bool {
+	switch r.MatchType {
+	case "exact":
+		return path == r.PathPrefix
+	case "regex":
+		re := r.compiledRegexp()
+		return re != nil && re.MatchString(path)
+	}
+	return routing.MatchesPrefix(path, r.PathPrefix)
+}
+
+// backendPlaceholder matches a {name} capture reference in a templated
+// backend URL, and also an unresolved ${VAR} environment reference so that
+// expandBackend can leave those alone.
+var backendPlaceholder = regexp.MustCompile(`\$?\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandBackend replaces each {name} placeholder in backend with
+// value(name).
+func expandBackend(backend string, value func(name string) string) string {
+	return backendPlaceholder.ReplaceAllStringFunc(backend, func(ph string) string {
+		if ph[0] == '$' {
+			return ph
+		}
+		return value(ph[1 : len(ph)-1])
+	})
+}
+
+// BackendTemplated reports whether r.Backend references path captures and
+// must be resolved per request with ResolveBackend.
+func (r RouteConfig) BackendTemplated() bool {
+	templated := false
+	expandBackend(r.Backend, func(string) string {
+		templated = true
+		return ""
+	})
+	return templated
+}
+
+// ResolveBackend substitutes the named captures of r's pattern, matched
+// against path, into its templated Backend. A capture may hold only
+// letters, digits, '.', '-' and '_', so it cannot smuggle a port,
+// credentials or path into the URL, and the resolved host must be in
+// BackendAllowedHosts.
+func (r RouteConfig) ResolveBackend(path string) (*url.URL, error) {
+	re := r.compiledRegexp()
+	if re == nil {
+		return nil, fmt.Errorf("invalid route pattern %q", r.PathPrefix)
+	}
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return nil, fmt.Errorf("path %q does not match %q", path, r.PathPrefix)
+	}
+
+	var capErr error
+	backend := expandBackend(r.Backend, func(name string) string {
+		i := re.SubexpIndex(name)
+		if i < 0 {
+			capErr = fmt.Errorf("no capture group named %q", name)
+			return ""
+		}
+		if v := m[i]; v != "" && strings.Trim(v, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.-_") == "" {
+			return v
+		}
+		if capErr == nil {
+			capErr = fmt.Errorf("capture %s=%q is empty or has disallowed characters", name, m[i])
+		}
+		return ""
+	})
+	if capErr != nil {
+		return nil, capErr
+	}
+
+	u, err := url.Parse(backend)
+	if err != nil {
+		return nil, err
+	}
+	host := routing.NormalizeHost(u.Host)
+	for _, pattern := range r.BackendAllowedHosts {
+		if routing.MatchesHost(host, pattern) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("backend host %q is not in backend_allowed_hosts", host)
+}
+
+// MatchesConditions reports whether req satisfies all of r's
+// MatchConditions; true when it has none.
+func (r RouteConfig) MatchesConditions(req *http.Request) bool {
+	for _, c := range r.MatchConditions {
+		if !c.Matches(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionsKey renders r's MatchConditions as "[c1,c2]", or "" when it
+// has none.
+func (r RouteConfig) conditionsKey() string {
+	if len(r.MatchConditions) == 0 {
+		return ""
+	}
+	parts := make([]string, len(r.MatchConditions))
+	for i, c := range r.MatchConditions {
+		parts[i] = c.String()
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// HostRank reports how specifically r's Hosts match the request host, 0
+// when they do not. A route without hosts matches any host at rank 1; a
+// wildcard match ranks higher, the longer the pattern the higher; an exact
+// match ranks highest. Route selection maximizes HostRank first and
+// MatchPriority second.
+func (r RouteConfig) HostRank(host string) int {
+	if len(r.Hosts) == 0 {
+		return 1
+	}
+	host = routing.NormalizeHost(host)
+	best := 0
+	for _, h := range r.Hosts {
+		if !routing.MatchesHost(host, h) {
+			continue
+		}
+		rank := math.MaxInt
+		if strings.HasPrefix(h, "*.") {
+			rank = 1 + len(h)
+		}
+		best = max(best, rank)
+	}
+	return best
+}
+
+// ID identifies r among the configured routes: its PathPrefix, qualified
+// by its Hosts and MatchConditions when it has any, since routes differing
+// in those may share a prefix.
+func (r RouteConfig) ID() string {
+	if len(r.Hosts) == 0 {
+		return r.PathPrefix + r.conditionsKey()
+	}
+	return strings.Join(r.Hosts, ",") + r.PathPrefix + r.conditionsKey()
+}
+
+// SelectRoute returns the route in routes that serves req: among those
+// matching its host, path and conditions, the highest HostRank, then the highest
+// MatchPriority, then the first in config order.
+func SelectRoute(routes []RouteConfig, req *http.Request) (RouteConfig, bool) {
+	var best RouteConfig
+	bestHost, bestPriority := 0, 0
+	for _, route := range routes {
+		hr := route.HostRank(req.Host)
+		if hr == 0 || !route.MatchesPath(req.URL.Path) || !route.MatchesConditions(req) {
+			continue
+		}
+		if p := route.MatchPriority(); hr > bestHost || (hr == bestHost && p > bestPriority) {
+			best, bestHost, bestPriority = route, hr, p
+		}
+	}
+	return best, bestHost > 0
+}
+
+// MatchPriority ranks r among the routes matching a request; the highest
+// wins. Exact routes outrank regex routes, which outrank prefix routes;
+// among prefix routes the longest prefix wins. At each of those levels a
+// route with MatchConditions outranks one without. Otherwise routes tie
+// (all regex routes, or conditioned routes on the same path), and the
+// first one in config order wins.
+func (r RouteConfig) MatchPriority() int {
+	cond := 0
+	if len(r.MatchConditions) > 0 {
+		cond = 1
+	}
+	switch r.MatchType {
+	case "exact":
+		return math.MaxInt - 1 + cond
+	case "regex":
+		return math.MaxInt - 3 + cond
+	}
+	return 2*len(r.PathPrefix) + cond
+}
+
+// RetryBufferLimit returns RetryMaxBufferBytes, or the 1 MiB default when
+// it is not set.
+func (r RouteConfig) RetryBufferLimit() int {
+	if r.RetryMaxBufferBytes <= 0 {
+		return 1 << 20
+	}
+	return r.RetryMaxBufferBytes
+}
+
+// ScriptTimeout returns the limit on one run of RequestScript, or the 10ms
+// default when ScriptTimeoutMs is not set.
+func (r RouteConfig) ScriptTimeout() time.Duration {
+	if r.ScriptTimeoutMs <= 0 {
+		return 10 * time.Millisecond
+	}
+	return time.Duration(r.ScriptTimeoutMs) * time.Millisecond
+}
+
+// ResponseHeaderTimeout returns the backend response header wait as a
+// time.Duration. Returns 0 (no separate limit) when not set.
+func (r RouteConfig) ResponseHeaderTimeout() time.Duration {
+	return time.Duration(r.ResponseHeaderTimeoutMs) * time.Millisecond
+}
+
+// ClientBodyTimeout returns the client body read budget as a time.Duration.
+// Returns 0 (disabled) when ClientBodyTimeoutMs is not set.
+func (r RouteConfig) ClientBodyTimeout() time.Duration {
+	return time.Duration(r.ClientBodyTimeoutMs) * time.Millisecond
+}
+
+// MetricsRoute returns the route label value for per-route metrics.
+func (r RouteConfig) MetricsRoute() string {
+	if r.MetricsLabel != "" {
+		return r.MetricsLabel
+	}
+	return r.PathPrefix
+}
+
+// BreakerKey returns the key of the circuit breaker guarding this route:
+// the backend URL, or backend URL plus path prefix for route-scoped breakers.
+func (r RouteConfig) BreakerKey() string {
+	if r.BreakerScope == "route" {
+		return r.Backend + "#" + r.ID()
+	}
+	return r.Backend
+}
+
+// Breaker returns the circuit breaker settings for the route's breaker:
+// its circuit_breaker block, merged with global by applyDefaults, or
+// global when it has none.
+func (r RouteConfig) Breaker(global CircuitBreakerConfig) CircuitBreakerConfig {
+	if r.CircuitBreaker != nil {
+		return *r.CircuitBreaker
+	}
+	return global
+}
+
+// ValidRedirectPolicies are the accepted route redirect_policy values.
+var ValidRedirectPolicies = map[string]bool{
+	"passthrough": true,
+	"rewrite":     true,
+	"follow":      true,
 }
 
 // ValidLogLevels are the accepted log level strings for routes.
@@ -146,6 +1043,15 @@ type CircuitBreakerConfig struct {
 	Adaptive         bool          `yaml:"adaptive" json:"adaptive"`
 	LatencyCeiling   time.Duration `yaml:"latency_ceiling" json:"latency_ceiling"`
 	MinThreshold     float64       `yaml:"min_threshold" json:"min_threshold"`
+	// MaxConcurrentRetries caps requests retrying at once across the whole
+	// gateway, so an outage cannot multiply backend load by the retry
+	// budget; past the cap failures are served without retrying.
+	MaxConcurrentRetries int `yaml:"max_concurrent_retries" json:"max_concurrent_retries"` // 0 = unlimited; default: 0
+
+	// ResetJitter randomizes each open period to reset_timeout ± this
+	// fraction of it, so breakers that opened together do not all probe
+	// the backend at once.
+	ResetJitter float64 `yaml:"reset_jitter" json:"reset_jitter"` // 0 to <1; default: 0 (none)
 }
 
 // ConnectionPoolConfig holds per-backend HTTP transport pool settings.
@@ -166,15 +1072,239 @@ func (r RouteConfig) Timeout() time.Duration {
 var envVarRe = regexp.MustCompile(`\$\{([^}]+)}`)
 
 // expandEnvVars replaces ${VAR_NAME} patterns in s with the corresponding
-// environment variable value.
-func expandEnvVars(s string) string {
-	return envVarRe.ReplaceAllStringFunc(s, func(match string) string {
+// environment variable value, and ${file:/path} with the contents of the
+// file (a mounted secret) trimmed of surrounding whitespace. As in the
+// shell, ${VAR:-default} falls back to default and ${VAR:?message} fails
+// with message when VAR is unset or empty. References that cannot be
+// resolved are left in place; refErrs records why each failing ${file:...}
+// or ${VAR:?...} reference failed, so load can report it if the reference
+// survives into a parsed value rather than a YAML comment.
+func expandEnvVars(s string) (expanded string, refErrs map[string]error) {
+	fail := func(match string, err error) string {
+		if refErrs == nil {
+			refErrs = make(map[string]error)
+		}
+		refErrs[match] = err
+		return match
+	}
+	expanded = envVarRe.ReplaceAllStringFunc(s, func(match string) string {
 		key := match[2 : len(match)-1]
+		if path, ok := strings.CutPrefix(key, "file:"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fail(match, fmt.Errorf("reading secret file: %w", err))
+			}
+			return strings.TrimSpace(string(data))
+		}
+		if name, def, ok := strings.Cut(key, ":-"); ok {
+			if val := os.Getenv(name); val != "" {
+				return val
+			}
+			return def
+		}
+		if name, msg, ok := strings.Cut(key, ":?"); ok {
+			if val := os.Getenv(name); val != "" {
+				return val
+			}
+			if msg == "" {
+				msg = "required environment variable is not set"
+			}
+			return fail(match, fmt.Errorf("%s: %s", name, msg))
+		}
 		if val, ok := os.LookupEnv(key); ok {
 			return val
 		}
 		return match
 	})
+	return expanded, refErrs
+}
+
+// failedRefs reports each parsed value in cfg that still holds a reference
+// from refErrs, as "<yaml path>: <error>".
+func failedRefs(cfg *Config, refErrs map[string]error) []string {
+	if len(refErrs) == 0 {
+		return nil
+	}
+	var found []string
+	walkStrings(reflect.ValueOf(cfg).Elem(), "", func(path, s string) {
+		for _, m := range envVarRe.FindAllString(s, -1) {
+			if err, ok := refErrs[m]; ok {
+				found = append(found, fmt.Sprintf("%s: %v", path, err))
+			}
+		}
+	})
+	return found
+}
+
+// unresolvedEnvRefs walks every string in cfg and reports each value that
+// still contains a ${VAR} reference after expansion, as
+// "<yaml path> contains unresolved environment variable ${VAR}". Only parsed
+// values are checked, so references in YAML comments are ignored.
+func unresolvedEnvRefs(cfg *Config) []string {
+	var found []string
+	walkStrings(reflect.ValueOf(cfg).Elem(), "", func(path, s string) {
+		for _, m := range envVarRe.FindAllString(s, -1) {
+			found = append(found, fmt.Sprintf("%s contains unresolved environment variable %s", path, m))
+		}
+	})
+	return found
+}
+
+// suspiciousRequestsPerSecond is the per-client rate above which
+// collectWarnings assumes a typo rather than an intended limit.
+const suspiciousRequestsPerSecond = 10000
+
+// collectWarnings reports settings that are valid but likely mistakes:
+// routes requiring auth while auth is disabled, implausibly high per-client
+// rate limits, retries that the circuit breaker will not stop, retries of
+// non-idempotent methods, prefix routes nested under an auth_required
+// route without requiring auth themselves, and routes shadowed by another
+// so that they never match.
+func collectWarnings(cfg *Config) []string {
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	if rps := cfg.RateLimit.RequestsPerSecond; rps > suspiciousRequestsPerSecond {
+		warn("rate_limit.requests_per_second is %v per client, which effectively disables rate limiting", rps)
+	}
+	for i, r := range cfg.Routes {
+		if r.AuthRequired && !cfg.Auth.Enabled {
+			warn("routes[%d] (%s) sets auth_required but auth.enabled is false: requests are not authenticated", i, r.PathPrefix)
+		}
+		if ro := r.RateOverride; ro != nil && ro.RequestsPerSecond > suspiciousRequestsPerSecond {
+			warn("routes[%d] (%s) rate_override.requests_per_second is %v per client, which effectively disables rate limiting", i, r.PathPrefix, ro.RequestsPerSecond)
+		}
+		if r.RetryAttempts == 0 {
+			continue
+		}
+		if r.Breaker(cfg.CircuitBreaker).FailureThreshold >= 1 {
+			warn("routes[%d] (%s) retries while circuit_breaker.failure_threshold is 1: the breaker only opens when every request fails, so retries multiply load on a failing backend", i, r.PathPrefix)
+		}
+		var unsafe []string
+		if len(r.Methods) == 0 {
+			unsafe = []string{"all methods"}
+		}
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, http.MethodPost) || strings.EqualFold(m, http.MethodPatch) {
+				unsafe = append(unsafe, m)
+			}
+		}
+		if len(unsafe) > 0 {
+			warn("routes[%d] (%s) sets retry_attempts for non-idempotent methods (%s): a retried request may be applied twice", i, r.PathPrefix, strings.Join(unsafe, ", "))
+		}
+	}
+
+	// A prefix route nested under an auth_required one takes its traffic
+	// (longest prefix wins) without inheriting the requirement.
+	for i, outer := range cfg.Routes {
+		if !outer.AuthRequired || outer.MatchType != "prefix" {
+			continue
+		}
+		for j, inner := range cfg.Routes {
+			if i == j || inner.AuthRequired || inner.MatchType != "prefix" || !slices.Equal(inner.Hosts, outer.Hosts) ||
+				len(inner.PathPrefix) <= len(outer.PathPrefix) || !routing.MatchesPrefix(inner.PathPrefix, outer.PathPrefix) {
+				continue
+			}
+			warn("routes[%d] (%s) is nested under auth_required routes[%d] (%s) but does not require auth", j, inner.PathPrefix, i, outer.PathPrefix)
+		}
+	}
+
+	for j, b := range cfg.Routes {
+		for i, a := range cfg.Routes {
+			if i != j && shadows(a, i < j, b) {
+				warn("routes[%d] (%s) is shadowed by routes[%d] (%s): every request it matches is routed to routes[%d] instead, whatever the method", j, b.ID(), i, a.ID(), i)
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// shadowProbes are path suffixes a regex route must match, on top of a
+// prefix route's prefix, to be taken as covering everything under it.
+var shadowProbes = []string{"", "/", "/x", "/x/y", "/A-z_0.9~"}
+
+// shadows reports whether route a takes every request route b matches, so
+// b never receives traffic; aFirst says a is listed before b. Methods play
+// no part: a route is selected before its methods are checked. Two cases
+// are recognized: a route on the same path and hosts whose conditions are
+// a subset of b's (equal rank, so the first listed wins), and a regex
+// route that matches everything under prefix route b (regex outranks
+// prefix).
+func shadows(a RouteConfig, aFirst bool, b RouteConfig) bool {
+	if !slices.Equal(a.Hosts, b.Hosts) || !conditionsSubset(a.MatchConditions, b.MatchConditions) {
+		return false
+	}
+	if a.MatchType == b.MatchType && a.PathPrefix == b.PathPrefix {
+		// Equal conditions are rejected as duplicates by validate.
+		return aFirst && len(a.MatchConditions) > 0
+	}
+	if a.MatchType != "regex" || b.MatchType != "prefix" {
+		return false
+	}
+	re := a.compiledRegexp()
+	if re == nil {
+		return false
+	}
+	base := strings.TrimSuffix(b.PathPrefix, "/")
+	for _, probe := range shadowProbes {
+		if !re.MatchString(base + probe) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionsSubset reports whether every condition in a is also in b.
+func conditionsSubset(a, b []MatchCondition) bool {
+	for _, c := range a {
+		if !slices.Contains(b, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// walkStrings calls fn for every string reachable from v, with its dotted
+// YAML path (e.g. routes[0].headers.X-Source). Fields tagged yaml:"-" are
+// skipped; map keys are visited in sorted order for stable output.
+func walkStrings(v reflect.Value, path string, fn func(path, s string)) {
+	switch v.Kind() {
+	case reflect.String:
+		fn(path, v.String())
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			walkStrings(v.Field(i), name, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			walkStrings(v.MapIndex(k), path+"."+k.String(), fn)
+		}
+	}
 }
 
 // Load reads and parses a YAML configuration file, applies environment
@@ -193,11 +1323,12 @@ func LoadFromBytes(data []byte) (*Config, error) {
 	return load(data)
 }
 
-// load is the shared pipeline behind Load and LoadFromBytes: expand env vars,
-// unmarshal, apply defaults, validate, collect warnings. Keeping it private
-// ensures both entry points stay in lockstep as the pipeline evolves.
+// load is the shared pipeline behind Load and LoadFromBytes: expand env vars
+// and secret files, unmarshal, apply defaults, check env and file references,
+// validate. Keeping it private ensures both entry points stay in lockstep as
+// the pipeline evolves.
 func load(data []byte) (*Config, error) {
-	expanded := expandEnvVars(string(data))
+	expanded, refErrs := expandEnvVars(string(data))
 
 	var cfg Config
 	if err := yaml.Unmarshal([]byte(expanded), &cfg); err != nil {
@@ -206,11 +1337,26 @@ func load(data []byte) (*Config, error) {
 
 	applyDefaults(&cfg)
 
+	if bad := failedRefs(&cfg, refErrs); len(bad) > 0 {
+		return nil, fmt.Errorf("validating config: %s", strings.Join(bad, "; "))
+	}
+
+	// Checked before validate so an unresolved reference is reported as
+	// such rather than as the invalid URL or value it produces.
+	unresolved := unresolvedEnvRefs(&cfg)
+	if cfg.StrictEnv && len(unresolved) > 0 {
+		return nil, fmt.Errorf("validating config: strict_env: %s", strings.Join(unresolved, "; "))
+	}
+
 	if err := validate(&cfg); err != nil {
+		if len(unresolved) > 0 {
+			// The invalid value is most likely the unexpanded reference.
+			return nil, fmt.Errorf("validating config: %w (%s)", err, strings.Join(unresolved, "; "))
+		}
 		return nil, fmt.Errorf("validating config: %w", err)
 	}
 
-	cfg.Warnings = collectWarnings(&cfg)
+	cfg.Warnings = append(unresolved, collectWarnings(&cfg)...)
 
 	return &cfg, nil
 }
@@ -222,6 +1368,12 @@ func applyDefaults(cfg *Config) {
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = 8080
 	}
+	if cfg.Auth.IntrospectionURL != "" && cfg.Auth.CacheTTL == 0 {
+		cfg.Auth.CacheTTL = 30 * time.Second
+	}
+	if cfg.Auth.JWTCacheSize > 0 && cfg.Auth.JWTCacheTTL == 0 {
+		cfg.Auth.JWTCacheTTL = time.Minute
+	}
 
 	// Logging defaults
 	if cfg.Logging.Output == "" {
@@ -236,6 +1388,12 @@ func applyDefaults(cfg *Config) {
 	if cfg.Logging.MaxAgeDays == 0 {
 		cfg.Logging.MaxAgeDays = 30
 	}
+	if cfg.Logging.Syslog.Facility == "" {
+		cfg.Logging.Syslog.Facility = "local0"
+	}
+	if cfg.Logging.Syslog.Tag == "" {
+		cfg.Logging.Syslog.Tag = "gateway"
+	}
 	if cfg.Logging.MaxBodyLogBytes == 0 {
 		cfg.Logging.MaxBodyLogBytes = 4096
 	}
@@ -244,12 +1402,38 @@ func applyDefaults(cfg *Config) {
 	if cfg.Server.TLS.Enabled && cfg.Server.TLS.MinVersion == "" {
 		cfg.Server.TLS.MinVersion = "1.2"
 	}
+	if cfg.Server.TLS.Enabled && cfg.Server.TLS.ClientAuthMode == "" {
+		cfg.Server.TLS.ClientAuthMode = "none"
+	}
+	if cfg.Server.TLS.Enabled && cfg.Server.TLS.HandshakeTimeout == 0 {
+		cfg.Server.TLS.HandshakeTimeout = 10 * time.Second
+	}
+	if al := &cfg.Server.AcceptLimit; al.ConnectionsPerSecond > 0 {
+		if al.Burst == 0 {
+			al.Burst = int(math.Ceil(al.ConnectionsPerSecond))
+		}
+		if al.Mode == "" {
+			al.Mode = "delay"
+		}
+	}
+	if cfg.Server.ProxyProtocol.Enabled && cfg.Server.ProxyProtocol.HeaderTimeout == 0 {
+		cfg.Server.ProxyProtocol.HeaderTimeout = 5 * time.Second
+	}
+	if cfg.Server.StripResponseHeaders == nil {
+		cfg.Server.StripResponseHeaders = []string{"Server", "X-Powered-By"}
+	}
+	if cfg.Server.FailFastOnStartup && cfg.Server.StartupCheckTimeout == 0 {
+		cfg.Server.StartupCheckTimeout = 5 * time.Second
+	}
 	if cfg.Server.ReadTimeout == 0 {
 		cfg.Server.ReadTimeout = 15 * time.Second
 	}
 	if cfg.Server.WriteTimeout == 0 {
 		cfg.Server.WriteTimeout = 15 * time.Second
 	}
+	if cfg.Server.ReadHeaderTimeout == 0 {
+		cfg.Server.ReadHeaderTimeout = min(10*time.Second, cfg.Server.ReadTimeout)
+	}
 	if cfg.Server.ShutdownTimeout == 0 {
 		cfg.Server.ShutdownTimeout = 10 * time.Second
 	}
@@ -262,8 +1446,19 @@ func applyDefaults(cfg *Config) {
 	if cfg.RateLimit.BurstSize == 0 {
 		cfg.RateLimit.BurstSize = 50
 	}
+	if cfg.RateLimit.RetryAfterFormat == "" {
+		cfg.RateLimit.RetryAfterFormat = "seconds"
+	}
+	if cfg.RateLimit.Algorithm == "" {
+		cfg.RateLimit.Algorithm = "token_bucket"
+	}
+	if cfg.RateLimit.Window == 0 {
+		cfg.RateLimit.Window = time.Minute
+	}
 	// Janitor defaults: TTL = max(10 minutes, 10 × burst-refill window),
-	// scan interval = TTL / 10 (capped at 1 minute minimum).
+	// scan interval = TTL / 10 (capped at 1 minute minimum). A sliding
+	// window's entry must also outlive the window, or eviction would reset
+	// the client's count early.
 	if cfg.RateLimit.IdleTTL <= 0 {
 		ttl := 10 * time.Minute
 		if cfg.RateLimit.RequestsPerSecond > 0 {
@@ -272,6 +1467,9 @@ func applyDefaults(cfg *Config) {
 				ttl = refill
 			}
 		}
+		if cfg.RateLimit.Algorithm == "sliding_window" && cfg.RateLimit.Window > ttl {
+			ttl = cfg.RateLimit.Window
+		}
 		cfg.RateLimit.IdleTTL = ttl
 	}
 	if cfg.RateLimit.CleanupInterval <= 0 {
@@ -302,12 +1500,168 @@ func applyDefaults(cfg *Config) {
 	if cb.Adaptive && cb.MinThreshold == 0 {
 		cb.MinThreshold = 0.2
 	}
+	for i := range cfg.Routes {
+		if rcb := cfg.Routes[i].CircuitBreaker; rcb != nil {
+			mergeCircuitBreaker(rcb, *cb)
+		}
+	}
+
+	// Size anomaly defaults
+	if sa := &cfg.Logging.SizeAnomaly; sa.Enabled {
+		if sa.Multiplier == 0 {
+			sa.Multiplier = 10
+		}
+		if sa.MinSamples == 0 {
+			sa.MinSamples = 20
+		}
+		if sa.MinBytes == 0 {
+			sa.MinBytes = 1024
+		}
+		if sa.LogInterval == 0 {
+			sa.LogInterval = time.Minute
+		}
+	}
+
+	// Active health check defaults
+	hc := &cfg.HealthCheck
+	if hc.Type == "" {
+		hc.Type = "tcp"
+	}
+	if hc.Path == "" {
+		hc.Path = "/"
+	}
+	if hc.Interval == 0 {
+		hc.Interval = 5 * time.Second
+	}
+	if hc.Timeout == 0 {
+		hc.Timeout = 2 * time.Second
+	}
+	if hc.HealthyThreshold == 0 {
+		hc.HealthyThreshold = 2
+	}
+
+	if cfg.Readiness.Path == "" {
+		cfg.Readiness.Path = "/ready"
+	}
+	if ad := &cfg.Admin; ad.RequestsPerSecond > 0 && ad.BurstSize == 0 {
+		ad.BurstSize = max(1, int(math.Ceil(2*ad.RequestsPerSecond)))
+	}
+	if cfg.Readiness.Mode == "" {
+		cfg.Readiness.Mode = "strict"
+	}
+	if cfg.Readiness.CacheTTL == 0 {
+		cfg.Readiness.CacheTTL = 5 * time.Second
+	}
+	if cfg.Readiness.Mode == "degraded" && cfg.Readiness.MaxDownPercent == 0 {
+		cfg.Readiness.MaxDownPercent = 50
+	}
+
+	if dr := cfg.DefaultRoute; dr != nil {
+		if dr.Backend == "" && dr.Status == 0 {
+			dr.Status = 404
+		}
+		if dr.Backend == "" && dr.ContentType == "" {
+			dr.ContentType = "text/html; charset=utf-8"
+		}
+	}
 
 	for i := range cfg.Routes {
 		if cfg.Routes[i].TimeoutMs == 0 {
 			cfg.Routes[i].TimeoutMs = 30000
 		}
+		if cfg.Routes[i].BreakerScope == "" {
+			cfg.Routes[i].BreakerScope = "backend"
+		}
+		if cfg.Routes[i].MatchType == "" {
+			cfg.Routes[i].MatchType = "prefix"
+		}
+		if cfg.Routes[i].RedirectPolicy == "" {
+			cfg.Routes[i].RedirectPolicy = "passthrough"
+		}
+		if cfg.Routes[i].RedirectPolicy == "follow" && cfg.Routes[i].MaxRedirects == 0 {
+			cfg.Routes[i].MaxRedirects = 5
+		}
+		if cfg.Routes[i].MaxConcurrent > 0 && cfg.Routes[i].QueueTimeoutMs == 0 {
+			cfg.Routes[i].QueueTimeoutMs = 1000
+		}
+	}
+}
+
+// mergeCircuitBreaker fills the fields rcb leaves unset from global, the
+// defaulted circuit_breaker section.
+func mergeCircuitBreaker(rcb *CircuitBreakerConfig, global CircuitBreakerConfig) {
+	if rcb.WindowSize == 0 {
+		rcb.WindowSize = global.WindowSize
+	}
+	if rcb.FailureThreshold == 0 {
+		rcb.FailureThreshold = global.FailureThreshold
+	}
+	if rcb.ResetTimeout == 0 {
+		rcb.ResetTimeout = global.ResetTimeout
+	}
+	if rcb.HalfOpenMax == 0 {
+		rcb.HalfOpenMax = global.HalfOpenMax
+	}
+	if rcb.ResetJitter == 0 {
+		rcb.ResetJitter = global.ResetJitter
+	}
+	if rcb.SlowThreshold == 0 {
+		rcb.SlowThreshold = global.SlowThreshold
+	}
+	if rcb.MaxConcurrent == 0 {
+		rcb.MaxConcurrent = global.MaxConcurrent
+	}
+	rcb.Adaptive = rcb.Adaptive || global.Adaptive
+	if rcb.Adaptive && rcb.LatencyCeiling == 0 {
+		rcb.LatencyCeiling = global.LatencyCeiling
+		if rcb.LatencyCeiling == 0 {
+			rcb.LatencyCeiling = 2 * time.Second
+		}
+	}
+	if rcb.Adaptive && rcb.MinThreshold == 0 {
+		rcb.MinThreshold = global.MinThreshold
+		if rcb.MinThreshold == 0 {
+			rcb.MinThreshold = 0.2
+		}
+	}
+}
+
+// validateCircuitBreaker checks a circuit breaker block; name is its
+// config path, e.g. "circuit_breaker" or "routes[0].circuit_breaker".
+func validateCircuitBreaker(name string, cb CircuitBreakerConfig) error {
+	if cb.WindowSize < 1 {
+		return fmt.Errorf("%s.window_size must be positive", name)
+	}
+	if cb.FailureThreshold <= 0 || cb.FailureThreshold > 1 {
+		return fmt.Errorf("%s.failure_threshold must be between 0 (exclusive) and 1 (inclusive)", name)
+	}
+	if cb.ResetTimeout <= 0 {
+		return fmt.Errorf("%s.reset_timeout must be positive", name)
+	}
+	if cb.HalfOpenMax < 1 {
+		return fmt.Errorf("%s.half_open_max must be positive", name)
+	}
+	if cb.ResetJitter < 0 || cb.ResetJitter >= 1 {
+		return fmt.Errorf("%s.reset_jitter must be at least 0 and less than 1", name)
+	}
+	if cb.SlowThreshold < 0 {
+		return fmt.Errorf("%s.slow_threshold must be non-negative", name)
+	}
+	if cb.MaxConcurrent < 0 {
+		return fmt.Errorf("%s.max_concurrent must be non-negative", name)
+	}
+	if cb.MaxConcurrentRetries < 0 {
+		return fmt.Errorf("%s.max_concurrent_retries must be non-negative", name)
+	}
+	if cb.Adaptive {
+		if cb.MinThreshold <= 0 || cb.MinThreshold >= cb.FailureThreshold {
+			return fmt.Errorf("%s.min_threshold must be between 0 and failure_threshold", name)
+		}
+		if cb.LatencyCeiling <= 0 {
+			return fmt.Errorf("%s.latency_ceiling must be positive when adaptive is enabled", name)
+		}
 	}
+	return nil
 }
 
 func validate(cfg *Config) error {
@@ -317,6 +1671,22 @@ func validate(cfg *Config) error {
 	if cfg.Server.MaxBodyBytes < 0 {
 		return fmt.Errorf("server.max_body_bytes must be positive")
 	}
+	for i, cidr := range cfg.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("server.trusted_proxies[%d]: invalid CIDR %q: %w", i, cidr, err)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		port int
+	}{{"admin_port", cfg.Server.AdminPort}, {"metrics_port", cfg.Server.MetricsPort}} {
+		if p.port < 0 || p.port > 65535 {
+			return fmt.Errorf("server.%s must be between 1 and 65535, got %d", p.name, p.port)
+		}
+		if p.port == cfg.Server.Port {
+			return fmt.Errorf("server.%s must differ from server.port", p.name)
+		}
+	}
 	if cfg.RateLimit.RequestsPerSecond <= 0 {
 		return fmt.Errorf("rate_limit.requests_per_second must be positive")
 	}
@@ -329,50 +1699,149 @@ func validate(cfg *Config) error {
 	if cfg.RateLimit.CleanupInterval < 0 {
 		return fmt.Errorf("rate_limit.cleanup_interval must be non-negative")
 	}
+	if cfg.RateLimit.MaxConcurrentPerClient < 0 {
+		return fmt.Errorf("rate_limit.max_concurrent_per_client must be non-negative")
+	}
+	if !ValidRateLimitAlgorithms[cfg.RateLimit.Algorithm] {
+		return fmt.Errorf("rate_limit.algorithm must be token_bucket or sliding_window, got %q", cfg.RateLimit.Algorithm)
+	}
+	if f := cfg.RateLimit.RetryAfterFormat; f != "seconds" && f != "http-date" {
+		return fmt.Errorf("rate_limit.retry_after_format must be seconds or http-date, got %q", f)
+	}
+	if cfg.RateLimit.Algorithm == "sliding_window" {
+		if cfg.RateLimit.Window <= 0 {
+			return fmt.Errorf("rate_limit.window must be positive")
+		}
+		if cfg.RateLimit.IdleTTL < cfg.RateLimit.Window {
+			return fmt.Errorf("rate_limit.idle_ttl (%s) must be at least rate_limit.window (%s) with sliding_window", cfg.RateLimit.IdleTTL, cfg.RateLimit.Window)
+		}
+	}
+	for i, cidr := range cfg.RateLimit.BypassCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("rate_limit.bypass_cidrs[%d]: invalid CIDR %q: %w", i, cidr, err)
+		}
+	}
 	if cfg.Auth.Enabled {
-		if cfg.Auth.JWTSecret == "" {
-			return fmt.Errorf("auth.jwt_secret is required when auth is enabled")
+		if cfg.Auth.IntrospectionURL != "" {
+			u, err := url.Parse(cfg.Auth.IntrospectionURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("auth.introspection_url must be an absolute http or https URL, got %q", cfg.Auth.IntrospectionURL)
+			}
+			if cfg.Auth.ClientSecret != "" && cfg.Auth.ClientID == "" {
+				return fmt.Errorf("auth.client_id is required with auth.client_secret")
+			}
+			if cfg.Auth.CacheTTL < 0 {
+				return fmt.Errorf("auth.cache_ttl must be non-negative")
+			}
+		} else if len(cfg.Auth.Secrets()) == 0 {
+			return fmt.Errorf("auth.jwt_secret (or auth.jwt_secrets) is required when auth is enabled")
+		}
+		for i, s := range cfg.Auth.JWTSecrets {
+			if s == "" {
+				return fmt.Errorf("auth.jwt_secrets[%d] must not be empty", i)
+			}
+		}
+		if cfg.Auth.JWTCacheSize < 0 {
+			return fmt.Errorf("auth.jwt_cache_size must be non-negative")
+		}
+		if cfg.Auth.JWTCacheTTL < 0 {
+			return fmt.Errorf("auth.jwt_cache_ttl must be non-negative")
 		}
-		if cfg.Auth.Issuer == "" {
+		for name := range cfg.Auth.RequiredClaims {
+			if name == "" {
+				return fmt.Errorf("auth.required_claims: claim name must not be empty")
+			}
+		}
+		for i, name := range cfg.Auth.RequiredClaimsPresent {
+			if name == "" {
+				return fmt.Errorf("auth.required_claims_present[%d] must not be empty", i)
+			}
+		}
+		if cfg.Auth.Issuer == "" && cfg.Auth.IntrospectionURL == "" {
 			return fmt.Errorf("auth.issuer is required when auth is enabled")
 		}
-		if cfg.Auth.Audience == "" {
+		if cfg.Auth.Audience == "" && cfg.Auth.IntrospectionURL == "" {
 			return fmt.Errorf("auth.audience is required when auth is enabled")
 		}
 	}
 
 	// Circuit breaker validation
-	cb := cfg.CircuitBreaker
-	if cb.WindowSize < 1 {
-		return fmt.Errorf("circuit_breaker.window_size must be positive")
+	if err := validateCircuitBreaker("circuit_breaker", cfg.CircuitBreaker); err != nil {
+		return err
 	}
-	if cb.FailureThreshold <= 0 || cb.FailureThreshold > 1 {
-		return fmt.Errorf("circuit_breaker.failure_threshold must be between 0 (exclusive) and 1 (inclusive)")
+
+	// Active health check validation
+	if hc := cfg.HealthCheck; hc.Enabled {
+		if hc.Type != "tcp" && hc.Type != "http" {
+			return fmt.Errorf("health_check.type must be \"tcp\" or \"http\", got %q", hc.Type)
+		}
+		if !strings.HasPrefix(hc.Path, "/") {
+			return fmt.Errorf("health_check.path must start with /")
+		}
+		if hc.Interval <= 0 {
+			return fmt.Errorf("health_check.interval must be positive")
+		}
+		if hc.Timeout <= 0 {
+			return fmt.Errorf("health_check.timeout must be positive")
+		}
+		if hc.HealthyThreshold < 1 {
+			return fmt.Errorf("health_check.healthy_threshold must be positive")
+		}
 	}
-	if cb.ResetTimeout <= 0 {
-		return fmt.Errorf("circuit_breaker.reset_timeout must be positive")
+
+	// Readiness validation
+	rd := cfg.Readiness
+	if !strings.HasPrefix(rd.Path, "/") {
+		return fmt.Errorf("readiness.path must start with /")
 	}
-	if cb.HalfOpenMax < 1 {
-		return fmt.Errorf("circuit_breaker.half_open_max must be positive")
+	if rd.Path == "/health" || rd.Path == cfg.Metrics.Path || strings.HasPrefix(rd.Path, "/admin/") {
+		return fmt.Errorf("readiness.path %q conflicts with another gateway endpoint", rd.Path)
 	}
-	if cb.SlowThreshold < 0 {
-		return fmt.Errorf("circuit_breaker.slow_threshold must be non-negative")
+	if rd.PreStopDelay < 0 {
+		return fmt.Errorf("readiness.pre_stop_delay must be non-negative")
 	}
-	if cb.MaxConcurrent < 0 {
-		return fmt.Errorf("circuit_breaker.max_concurrent must be non-negative")
+	if rd.CacheTTL < 0 {
+		return fmt.Errorf("readiness.cache_ttl must be non-negative")
 	}
-	if cb.Adaptive {
-		if cb.MinThreshold <= 0 || cb.MinThreshold >= cb.FailureThreshold {
-			return fmt.Errorf("circuit_breaker.min_threshold must be between 0 and failure_threshold")
-		}
-		if cb.LatencyCeiling <= 0 {
-			return fmt.Errorf("circuit_breaker.latency_ceiling must be positive when adaptive is enabled")
-		}
+	if rd.Mode != "strict" && rd.Mode != "degraded" {
+		return fmt.Errorf("readiness.mode must be \"strict\" or \"degraded\", got %q", rd.Mode)
+	}
+	if rd.MaxDownPercent < 0 || rd.MaxDownPercent > 100 {
+		return fmt.Errorf("readiness.max_down_percent must be between 0 and 100, got %v", rd.MaxDownPercent)
 	}
 
 	if cfg.Server.GlobalTimeoutMs < 0 {
 		return fmt.Errorf("server.global_timeout_ms must be non-negative")
 	}
+	al := cfg.Server.AcceptLimit
+	if al.ConnectionsPerSecond < 0 || al.Burst < 0 {
+		return fmt.Errorf("server.accept_limit.connections_per_second and burst must be non-negative")
+	}
+	if al.ConnectionsPerSecond > 0 && al.Mode != "delay" && al.Mode != "reject" {
+		return fmt.Errorf("server.accept_limit.mode must be \"delay\" or \"reject\", got %q", al.Mode)
+	}
+	if cfg.Server.StartupCheckTimeout < 0 {
+		return fmt.Errorf("server.startup_check_timeout must be positive")
+	}
+	if cfg.Server.ReadHeaderTimeout < 0 {
+		return fmt.Errorf("server.read_header_timeout must be non-negative")
+	}
+	if cfg.Server.MaxConnections < 0 {
+		return fmt.Errorf("server.max_connections must be non-negative")
+	}
+	if pp := cfg.Server.ProxyProtocol; pp.Enabled {
+		if len(pp.TrustedCIDRs) == 0 {
+			return fmt.Errorf("server.proxy_protocol.trusted_cidrs is required when proxy_protocol is enabled")
+		}
+		for i, cidr := range pp.TrustedCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("server.proxy_protocol.trusted_cidrs[%d]: invalid CIDR %q: %w", i, cidr, err)
+			}
+		}
+		if pp.HeaderTimeout < 0 {
+			return fmt.Errorf("server.proxy_protocol.header_timeout must be non-negative")
+		}
+	}
 
 	// TLS validation
 	if cfg.Server.TLS.Enabled {
@@ -385,20 +1854,90 @@ func validate(cfg *Config) error {
 		if cfg.Server.TLS.MinVersion != "1.2" && cfg.Server.TLS.MinVersion != "1.3" {
 			return fmt.Errorf("server.tls.min_version must be \"1.2\" or \"1.3\", got %q", cfg.Server.TLS.MinVersion)
 		}
+		mode := cfg.Server.TLS.ClientAuthMode
+		if !ValidClientAuthModes[mode] {
+			return fmt.Errorf("server.tls.client_auth_mode must be one of none, request, require, verify_if_given, require_and_verify; got %q", mode)
+		}
+		if (mode == "verify_if_given" || mode == "require_and_verify") && cfg.Server.TLS.ClientCAFile == "" {
+			return fmt.Errorf("server.tls.client_ca_file is required when client_auth_mode is %q", mode)
+		}
+		if cfg.Server.TLS.MaxConcurrentHandshakes < 0 {
+			return fmt.Errorf("server.tls.max_concurrent_handshakes must be non-negative")
+		}
+		if cfg.Server.TLS.HandshakeTimeout < 0 {
+			return fmt.Errorf("server.tls.handshake_timeout must be positive")
+		}
 	}
 
 	// Logging validation
-	if cfg.Logging.Output != "stdout" && cfg.Logging.Output != "stderr" {
+	switch cfg.Logging.Output {
+	case "stdout", "stderr":
+	case "syslog":
+		sl := cfg.Logging.Syslog
+		if !ValidSyslogFacilities[sl.Facility] {
+			return fmt.Errorf("logging.syslog.facility %q is not a known syslog facility", sl.Facility)
+		}
+		if (sl.Network == "") != (sl.Address == "") {
+			return fmt.Errorf("logging.syslog.network and address must be set together")
+		}
+	default:
 		if cfg.Logging.MaxSizeMB < 1 {
 			return fmt.Errorf("logging.max_size_mb must be positive when output is a file path")
 		}
 	}
+	if cfg.Logging.ExternalRotation {
+		switch cfg.Logging.Output {
+		case "stdout", "stderr", "syslog":
+			// Nothing would reopen on SIGHUP, and with reload moved to
+			// SIGUSR1 its default action would stop the gateway.
+			return fmt.Errorf("logging.external_rotation requires logging.output to be a file path, got %q", cfg.Logging.Output)
+		}
+	}
 	if cfg.Logging.BodyLogging && cfg.Logging.MaxBodyLogBytes < 1 {
 		return fmt.Errorf("logging.max_body_log_bytes must be positive when body_logging is enabled")
 	}
+	if sr := cfg.Logging.SampleRate; sr != nil && (*sr < 0 || *sr > 1) {
+		return fmt.Errorf("logging.sample_rate must be between 0.0 and 1.0, got %v", *sr)
+	}
+	if err := validateLogFields("logging.fields", cfg.Logging.Fields); err != nil {
+		return err
+	}
+	if sa := cfg.Logging.SizeAnomaly; sa.Enabled {
+		if sa.Multiplier <= 1 {
+			return fmt.Errorf("logging.size_anomaly.multiplier must be greater than 1, got %v", sa.Multiplier)
+		}
+		if sa.MinSamples < 1 {
+			return fmt.Errorf("logging.size_anomaly.min_samples must be positive")
+		}
+		if sa.MinBytes < 0 {
+			return fmt.Errorf("logging.size_anomaly.min_bytes must be non-negative")
+		}
+		if sa.LogInterval < 0 {
+			return fmt.Errorf("logging.size_anomaly.log_interval must be non-negative")
+		}
+	}
 
 	// Admin validation
-	if cfg.Admin.Enabled {
+	if cfg.Admin.RequestsPerSecond < 0 || cfg.Admin.BurstSize < 0 {
+		return fmt.Errorf("admin.requests_per_second and admin.burst_size must be non-negative")
+	}
+	if b := cfg.Metrics.Buckets; b != nil {
+		if len(b) == 0 {
+			return fmt.Errorf("metrics.buckets must not be empty; omit it for the default buckets")
+		}
+		for i, v := range b {
+			if math.IsNaN(v) || math.IsInf(v, 0) || v <= 0 {
+				return fmt.Errorf("metrics.buckets[%d] must be a positive number of seconds, got %v", i, v)
+			}
+			if i > 0 && v <= b[i-1] {
+				return fmt.Errorf("metrics.buckets must be strictly increasing: buckets[%d] (%v) follows %v", i, v, b[i-1])
+			}
+		}
+	}
+	if cfg.Metrics.Protected && len(cfg.Admin.IPAllowlist) == 0 {
+		return fmt.Errorf("admin.ip_allowlist is required when metrics.protected is set")
+	}
+	if cfg.Admin.Enabled || cfg.Metrics.Protected {
 		if len(cfg.Admin.IPAllowlist) == 0 {
 			return fmt.Errorf("admin.ip_allowlist is required when admin is enabled")
 		}
@@ -414,17 +1953,60 @@ func validate(cfg *Config) error {
 	}
 
 	seen := make(map[string]bool)
+	breakerOwner := make(map[string]int) // breaker key → first route using it
 	for i, r := range cfg.Routes {
 		if r.PathPrefix == "" {
 			return fmt.Errorf("routes[%d].path_prefix is required", i)
 		}
-		if !strings.HasPrefix(r.PathPrefix, "/") {
-			return fmt.Errorf("routes[%d].path_prefix must start with /", i)
+		switch r.MatchType {
+		case "regex":
+			re, err := compilePathRegexp(r.PathPrefix)
+			if err != nil {
+				return fmt.Errorf("routes[%d].path_prefix: invalid regex: %w", i, err)
+			}
+			cfg.Routes[i].pathRegexp = re
+			if r.StripPrefix {
+				return fmt.Errorf("routes[%d].strip_prefix cannot be used with match_type regex", i)
+			}
+		case "prefix", "exact":
+			if !strings.HasPrefix(r.PathPrefix, "/") {
+				return fmt.Errorf("routes[%d].path_prefix must start with /", i)
+			}
+		default:
+			return fmt.Errorf("routes[%d].match_type must be one of prefix, exact, regex; got %q", i, r.MatchType)
 		}
 		if r.Backend == "" {
 			return fmt.Errorf("routes[%d].backend is required", i)
 		}
-		u, err := url.Parse(r.Backend)
+		if r.BackendTemplated() {
+			re := cfg.Routes[i].pathRegexp
+			if re == nil {
+				return fmt.Errorf("routes[%d].backend: {name} placeholders require match_type regex", i)
+			}
+			missing := ""
+			expandBackend(r.Backend, func(name string) string {
+				if re.SubexpIndex(name) < 0 && missing == "" {
+					missing = name
+				}
+				return ""
+			})
+			if missing != "" {
+				return fmt.Errorf("routes[%d].backend: path_prefix has no capture group named %q", i, missing)
+			}
+			if len(r.BackendAllowedHosts) == 0 {
+				return fmt.Errorf("routes[%d].backend_allowed_hosts is required with a templated backend", i)
+			}
+		} else if len(r.BackendAllowedHosts) > 0 {
+			return fmt.Errorf("routes[%d].backend_allowed_hosts requires a templated backend", i)
+		}
+		for _, h := range r.BackendAllowedHosts {
+			if !validHostPattern(h) {
+				return fmt.Errorf("routes[%d].backend_allowed_hosts: invalid host %q (want a name or *.domain)", i, h)
+			}
+		}
+		// Placeholders stand in for host labels or path segments; check
+		// the URL with a sample value in their place.
+		u, err := url.Parse(expandBackend(r.Backend, func(string) string { return "x" }))
 		if err != nil {
 			return fmt.Errorf("routes[%d].backend: invalid URL: %w", i, err)
 		}
@@ -434,14 +2016,117 @@ func validate(cfg *Config) error {
 		if u.Host == "" {
 			return fmt.Errorf("routes[%d].backend: host is required", i)
 		}
-		if seen[r.PathPrefix] {
-			return fmt.Errorf("duplicate route path_prefix: %s", r.PathPrefix)
+		for j, c := range r.MatchConditions {
+			if (c.Header == "") == (c.Query == "") {
+				return fmt.Errorf("routes[%d].match_conditions[%d]: set exactly one of header and query", i, j)
+			}
+		}
+
+		// Routes may share a path_prefix only when their hosts or match
+		// conditions differ.
+		hosts := r.Hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		for _, h := range hosts {
+			if h != "" && !validHostPattern(h) {
+				return fmt.Errorf("routes[%d].hosts: invalid host %q (want a name or *.domain)", i, h)
+			}
+			key := strings.ToLower(h) + " " + r.PathPrefix + r.conditionsKey()
+			if seen[key] {
+				if h == "" {
+					return fmt.Errorf("duplicate route path_prefix: %s%s", r.PathPrefix, r.conditionsKey())
+				}
+				return fmt.Errorf("duplicate route path_prefix: %s%s for host %s", r.PathPrefix, r.conditionsKey(), h)
+			}
+			seen[key] = true
 		}
-		seen[r.PathPrefix] = true
 
 		if !ValidLogLevels[r.LogLevel] {
 			return fmt.Errorf("routes[%d].log_level must be one of debug, info, warn, error, none; got %q", i, r.LogLevel)
 		}
+		if sr := r.LogSampleRate; sr != nil && (*sr < 0 || *sr > 1) {
+			return fmt.Errorf("routes[%d].log_sample_rate must be between 0.0 and 1.0, got %v", i, *sr)
+		}
+		if err := validateLogFields(fmt.Sprintf("routes[%d].log_fields", i), r.LogFields); err != nil {
+			return err
+		}
+		if r.Critical && r.ExcludeFromReadiness {
+			return fmt.Errorf("routes[%d]: critical and exclude_from_readiness are mutually exclusive", i)
+		}
+		if r.ClientCertRequired {
+			if !cfg.Server.TLS.Enabled || cfg.Server.TLS.ClientAuthMode == "none" {
+				return fmt.Errorf("routes[%d].client_cert_required needs server.tls enabled with a client_auth_mode other than none", i)
+			}
+			if cfg.Server.TLS.ClientCAFile == "" {
+				return fmt.Errorf("routes[%d].client_cert_required needs server.tls.client_ca_file", i)
+			}
+		}
+		if r.TimeoutJitter < 0 || r.TimeoutJitter > 0.5 {
+			return fmt.Errorf("routes[%d].timeout_jitter must be between 0 and 0.5", i)
+		}
+		if r.MaxConcurrent < 0 || r.QueueTimeoutMs < 0 {
+			return fmt.Errorf("routes[%d].max_concurrent and queue_timeout_ms must be non-negative", i)
+		}
+		if r.ClientBodyTimeoutMs < 0 {
+			return fmt.Errorf("routes[%d].client_body_timeout_ms must be non-negative", i)
+		}
+		if r.RetryMaxBufferBytes < 0 {
+			return fmt.Errorf("routes[%d].retry_max_buffer_bytes must be non-negative", i)
+		}
+		if r.ResponseHeaderTimeoutMs < 0 || r.ResponseHeaderTimeout() > r.Timeout() {
+			return fmt.Errorf("routes[%d].response_header_timeout_ms must be between 0 and the route timeout", i)
+		}
+		if slices.Contains(r.StripResponseFields, "") {
+			return fmt.Errorf("routes[%d].strip_response_fields: empty field name", i)
+		}
+		if r.RequestSchema != "" {
+			s, err := loadSchema(r.RequestSchema)
+			if err != nil {
+				return fmt.Errorf("routes[%d].request_schema: %w", i, err)
+			}
+			cfg.Routes[i].requestSchema = s
+		}
+		if r.ScriptTimeoutMs < 0 {
+			return fmt.Errorf("routes[%d].script_timeout_ms must be non-negative", i)
+		}
+		if r.RequestScript != "" {
+			s, err := script.Compile(r.RequestScript)
+			if err != nil {
+				return fmt.Errorf("routes[%d].request_script: %w", i, err)
+			}
+			cfg.Routes[i].requestScript = s
+		}
+		for from, to := range r.MethodRewrite {
+			if !validMethodName(from) || !validMethodName(to) {
+				return fmt.Errorf("routes[%d].method_rewrite: invalid method in %q: %q", i, from, to)
+			}
+			if strings.EqualFold(to, http.MethodConnect) {
+				return fmt.Errorf("routes[%d].method_rewrite: cannot rewrite to CONNECT", i)
+			}
+		}
+		if r.BreakerScope != "backend" && r.BreakerScope != "route" {
+			return fmt.Errorf("routes[%d].breaker_scope must be \"backend\" or \"route\", got %q", i, r.BreakerScope)
+		}
+		if rcb := r.CircuitBreaker; rcb != nil {
+			if rcb.MaxConcurrentRetries != 0 {
+				return fmt.Errorf("routes[%d].circuit_breaker.max_concurrent_retries is not supported; set circuit_breaker.max_concurrent_retries", i)
+			}
+			if err := validateCircuitBreaker(fmt.Sprintf("routes[%d].circuit_breaker", i), *rcb); err != nil {
+				return err
+			}
+		}
+		if j, ok := breakerOwner[r.BreakerKey()]; !ok {
+			breakerOwner[r.BreakerKey()] = i
+		} else if r.Breaker(cfg.CircuitBreaker) != cfg.Routes[j].Breaker(cfg.CircuitBreaker) {
+			return fmt.Errorf("routes[%d].circuit_breaker conflicts with routes[%d]: routes on backend %s share its circuit breaker, so their circuit_breaker settings must match (or set breaker_scope: route)", i, j, r.Backend)
+		}
+		if !ValidRedirectPolicies[r.RedirectPolicy] {
+			return fmt.Errorf("routes[%d].redirect_policy must be one of passthrough, rewrite, follow; got %q", i, r.RedirectPolicy)
+		}
+		if r.MaxRedirects < 0 || r.MaxRedirects > 20 {
+			return fmt.Errorf("routes[%d].max_redirects must be between 0 and 20", i)
+		}
 		if r.FallbackStatus != 0 && (r.FallbackStatus < 200 || r.FallbackStatus > 599) {
 			return fmt.Errorf("routes[%d].fallback_status must be between 200 and 599", i)
 		}
@@ -459,13 +2144,31 @@ func validate(cfg *Config) error {
 		}
 	}
 
-	return nil
-}
-
-func collectWarnings(cfg *Config) []string {
-	var warnings []string
-	if cfg.Auth.Enabled && strings.Contains(cfg.Auth.JWTSecret, "${") {
-		warnings = append(warnings, "auth.jwt_secret contains unresolved environment variable")
+	if dr := cfg.DefaultRoute; dr != nil {
+		if seen[" /"] {
+			return fmt.Errorf("default_route is unreachable: a route with path_prefix \"/\" matches every request")
+		}
+		if dr.Backend != "" {
+			if dr.Status != 0 || dr.Body != "" {
+				return fmt.Errorf("default_route: set either backend or status/body, not both")
+			}
+			u, err := url.Parse(dr.Backend)
+			if err != nil {
+				return fmt.Errorf("default_route.backend: invalid URL: %w", err)
+			}
+			if u.Scheme != "http" && u.Scheme != "https" {
+				return fmt.Errorf("default_route.backend: scheme must be http or https, got %q", u.Scheme)
+			}
+			if u.Host == "" {
+				return fmt.Errorf("default_route.backend: host is required")
+			}
+		} else if dr.Status < 200 || dr.Status > 599 {
+			return fmt.Errorf("default_route.status must be between 200 and 599")
+		}
+		if dr.TimeoutMs < 0 {
+			return fmt.Errorf("default_route.timeout_ms must be non-negative")
+		}
 	}
-	return warnings
+
+	return nil
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -165,6 +166,363 @@ routes:
 	}
 }
 
+func TestLoadFromBytes_SecretFileReference(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(secret, []byte("file-secret-value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromBytes([]byte(`
+auth:
+  enabled: true
+  jwt_secret: "${file:` + secret + `}"
+  issuer: "iss"
+  audience: "aud"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Auth.JWTSecret != "file-secret-value" {
+		t.Errorf("expected the trimmed file contents, got %q", cfg.Auth.JWTSecret)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", cfg.Warnings)
+	}
+}
+
+func TestLoadFromBytes_MissingSecretFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "absent")
+	_, err := LoadFromBytes([]byte(`
+auth:
+  enabled: true
+  jwt_secret: "${file:` + missing + `}"
+  issuer: "iss"
+  audience: "aud"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`))
+	if err == nil {
+		t.Fatal("expected an error for a missing secret file")
+	}
+	if !strings.Contains(err.Error(), "auth.jwt_secret") || !strings.Contains(err.Error(), missing) {
+		t.Errorf("error %q does not name the field and file", err)
+	}
+
+	// A commented-out reference is never read.
+	if _, err := LoadFromBytes([]byte(`
+# jwt_secret: "${file:` + missing + `}"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`)); err != nil {
+		t.Errorf("commented-out file reference failed the load: %v", err)
+	}
+}
+
+func TestLoadFromBytes_EnvVarDefaults(t *testing.T) {
+	const body = `
+routes:
+  - path_prefix: "/api"
+    backend: "http://${GATEWAY_TEST_BACKEND_HOST:-localhost}:3000"
+`
+	t.Setenv("GATEWAY_TEST_BACKEND_HOST", "")
+	cfg, err := LoadFromBytes([]byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Routes[0].Backend; got != "http://localhost:3000" {
+		t.Errorf("expected the default for an unset variable, got %q", got)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", cfg.Warnings)
+	}
+
+	t.Setenv("GATEWAY_TEST_BACKEND_HOST", "users.internal")
+	cfg, err = LoadFromBytes([]byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Routes[0].Backend; got != "http://users.internal:3000" {
+		t.Errorf("expected the set variable to override the default, got %q", got)
+	}
+}
+
+func TestLoadFromBytes_RequiredEnvVar(t *testing.T) {
+	const body = `
+auth:
+  enabled: true
+  jwt_secret: "${GATEWAY_TEST_JWT_SECRET:?set it from the vault}"
+  issuer: "iss"
+  audience: "aud"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`
+	t.Setenv("GATEWAY_TEST_JWT_SECRET", "")
+	_, err := LoadFromBytes([]byte(body))
+	if err == nil {
+		t.Fatal("expected an error for a required variable that is unset")
+	}
+	if want := "auth.jwt_secret: GATEWAY_TEST_JWT_SECRET: set it from the vault"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+
+	t.Setenv("GATEWAY_TEST_JWT_SECRET", "s3cret")
+	cfg, err := LoadFromBytes([]byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Auth.JWTSecret != "s3cret" {
+		t.Errorf("expected the set variable, got %q", cfg.Auth.JWTSecret)
+	}
+}
+
+func TestLoadFromBytes_RouteCircuitBreaker(t *testing.T) {
+	cfg, err := LoadFromBytes([]byte(`
+circuit_breaker:
+  window_size: 20
+  failure_threshold: 0.5
+  adaptive: true
+routes:
+  - path_prefix: "/internal"
+    backend: "http://localhost:3000"
+    circuit_breaker:
+      failure_threshold: 0.9
+      max_concurrent: 100
+  - path_prefix: "/partner"
+    backend: "http://localhost:3001"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := cfg.Routes[0].Breaker(cfg.CircuitBreaker)
+	want := cfg.CircuitBreaker
+	want.FailureThreshold, want.MaxConcurrent = 0.9, 100
+	if got != want {
+		t.Errorf("routes[0] breaker = %+v, want the global settings with its overrides: %+v", got, want)
+	}
+	if got := cfg.Routes[1].Breaker(cfg.CircuitBreaker); got != cfg.CircuitBreaker {
+		t.Errorf("routes[1] breaker = %+v, want the global settings", got)
+	}
+
+	const shared = `
+routes:
+  - path_prefix: "/a"
+    backend: "http://localhost:3000"
+    circuit_breaker:
+      failure_threshold: 0.9
+  - path_prefix: "/b"
+    backend: "http://localhost:3000"
+`
+	_, err = LoadFromBytes([]byte(shared))
+	if err == nil || !strings.Contains(err.Error(), "routes[1].circuit_breaker conflicts with routes[0]") {
+		t.Errorf("error = %v, want a conflict between routes sharing a backend", err)
+	}
+	if _, err := LoadFromBytes([]byte(shared + "    breaker_scope: route\n")); err != nil {
+		t.Errorf("a route with its own breaker should not conflict: %v", err)
+	}
+}
+
+func TestLoadFromBytes_RiskySettingWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // substring of the expected warning; "" for none
+	}{
+		{
+			name: "auth_required with auth disabled",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    auth_required: true
+`,
+			want: "routes[0] (/api) sets auth_required but auth.enabled is false",
+		},
+		{
+			name: "implausible global rate limit",
+			yaml: `
+rate_limit:
+  requests_per_second: 1000000
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+			want: "rate_limit.requests_per_second is 1e+06 per client",
+		},
+		{
+			name: "implausible route rate override",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    rate_override:
+      requests_per_second: 50000
+      burst_size: 100
+`,
+			want: "routes[0] (/api) rate_override.requests_per_second is 50000",
+		},
+		{
+			name: "retries the breaker never stops",
+			yaml: `
+circuit_breaker:
+  failure_threshold: 1
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    methods: ["GET"]
+    retry_attempts: 2
+`,
+			want: "routes[0] (/api) retries while circuit_breaker.failure_threshold is 1",
+		},
+		{
+			name: "retries of POST",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    methods: ["GET", "POST"]
+    retry_attempts: 2
+`,
+			want: "routes[0] (/api) sets retry_attempts for non-idempotent methods (POST)",
+		},
+		{
+			name: "unauthenticated route nested under an authenticated one",
+			yaml: `
+auth:
+  enabled: true
+  jwt_secret: "secret"
+  issuer: "iss"
+  audience: "aud"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    auth_required: true
+  - path_prefix: "/api/public"
+    backend: "http://localhost:3000"
+`,
+			want: "routes[1] (/api/public) is nested under auth_required routes[0] (/api)",
+		},
+		{
+			name: "route shadowed by a catch-all regex",
+			yaml: `
+routes:
+  - path_prefix: "/api/.*"
+    match_type: "regex"
+    backend: "http://localhost:3000"
+  - path_prefix: "/api/users"
+    backend: "http://localhost:3001"
+`,
+			want: "routes[1] (/api/users) is shadowed by routes[0] (/api/.*)",
+		},
+		{
+			name: "route shadowed by an earlier route with fewer conditions",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    match_conditions:
+      - header: "X-Beta"
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+    match_conditions:
+      - header: "X-Beta"
+      - query: "v2"
+`,
+			want: "is shadowed by routes[0] (/api[header:X-Beta])",
+		},
+		{
+			name: "distinct overlapping routes",
+			yaml: `
+routes:
+  - path_prefix: "/api/v[0-9]+/.*"
+    match_type: "regex"
+    backend: "http://localhost:3000"
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+  - path_prefix: "/api/v1"
+    backend: "http://localhost:3002"
+    match_conditions:
+      - header: "X-Beta"
+  - path_prefix: "/api/v1"
+    backend: "http://localhost:3003"
+`,
+		},
+		{
+			name: "safe config",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    methods: ["GET"]
+    retry_attempts: 2
+  - path_prefix: "/apiv2"
+    backend: "http://localhost:3000"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadFromBytes([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if len(cfg.Warnings) != 0 {
+					t.Errorf("unexpected warnings: %q", cfg.Warnings)
+				}
+				return
+			}
+			if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], tt.want) {
+				t.Errorf("warnings = %q, want one containing %q", cfg.Warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromBytes_UnresolvedEnvVarInBackend(t *testing.T) {
+	const body = `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000/${GATEWAY_TEST_UNSET_BASE}"
+    headers:
+      X-Tenant: "${GATEWAY_TEST_UNSET_TENANT}"
+`
+	cfg, err := LoadFromBytes([]byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"routes[0].backend contains unresolved environment variable ${GATEWAY_TEST_UNSET_BASE}",
+		"routes[0].headers.X-Tenant contains unresolved environment variable ${GATEWAY_TEST_UNSET_TENANT}",
+	}
+	if strings.Join(cfg.Warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", cfg.Warnings, want)
+	}
+
+	_, err = LoadFromBytes([]byte("strict_env: true\n" + body))
+	if err == nil {
+		t.Fatal("expected strict_env to reject unresolved environment variables")
+	}
+	if !strings.Contains(err.Error(), "routes[0].backend") || !strings.Contains(err.Error(), "GATEWAY_TEST_UNSET_BASE") {
+		t.Errorf("error %q does not name the field and variable", err)
+	}
+
+	// An unresolved host makes the URL itself invalid; the validation
+	// error points at the reference that caused it.
+	_, err = LoadFromBytes([]byte(`
+routes:
+  - path_prefix: "/api"
+    backend: "http://${GATEWAY_TEST_UNSET_HOST}:3000"
+`))
+	if err == nil || !strings.Contains(err.Error(), "unresolved environment variable ${GATEWAY_TEST_UNSET_HOST}") {
+		t.Errorf("expected invalid-URL error naming the unresolved variable, got %v", err)
+	}
+}
+
 func TestLoadFromBytes_ValidationErrors(t *testing.T) {
 	tests := []struct {
 		name string
@@ -175,66 +533,546 @@ func TestLoadFromBytes_ValidationErrors(t *testing.T) {
 			yaml: `
 auth:
   enabled: false
-routes: []
+routes: []
+`,
+		},
+		{
+			name: "invalid port",
+			yaml: `
+server:
+  port: 99999
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "missing path_prefix",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "missing backend",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+`,
+		},
+		{
+			name: "path_prefix without leading slash",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "duplicate path_prefix",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "auth enabled without secret",
+			yaml: `
+auth:
+  enabled: true
+  issuer: "iss"
+  audience: "aud"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "auth enabled without issuer",
+			yaml: `
+auth:
+  enabled: true
+  jwt_secret: "secret"
+  audience: "aud"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "auth enabled without audience",
+			yaml: `
+auth:
+  enabled: true
+  jwt_secret: "secret"
+  issuer: "iss"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "backend with file scheme",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+    backend: "file:///etc/passwd"
+`,
+		},
+		{
+			name: "backend with ftp scheme",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+    backend: "ftp://evil.com/data"
+`,
+		},
+		{
+			name: "negative max_body_bytes",
+			yaml: `
+server:
+  max_body_bytes: -1
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "invalid health_check type",
+			yaml: `
+health_check:
+  enabled: true
+  type: "udp"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "client_cert_required without mTLS",
+			yaml: `
+routes:
+  - path_prefix: "/partners"
+    backend: "http://localhost:3000"
+    client_cert_required: true
+`,
+		},
+		{
+			name: "invalid redirect policy",
+			yaml: `
+routes:
+  - path_prefix: /a
+    backend: http://a:1
+    redirect_policy: bounce
+`,
+		},
+		{
+			name: "negative max concurrent handshakes",
+			yaml: `
+server:
+  tls:
+    enabled: true
+    cert_file: a
+    key_file: b
+    max_concurrent_handshakes: -1
+`,
+		},
+		{
+			name: "invalid breaker scope",
+			yaml: `
+routes:
+  - path_prefix: /a
+    backend: http://a:1
+    breaker_scope: global
+`,
+		},
+		{
+			name: "metrics protected without allowlist",
+			yaml: `
+metrics:
+  protected: true
+routes:
+  - path_prefix: /a
+    backend: http://a:1
+`,
+		},
+		{
+			name: "timeout jitter too large",
+			yaml: `
+routes:
+  - path_prefix: /a
+    backend: http://a:1
+    timeout_jitter: 0.9
+`,
+		},
+		{
+			name: "invalid accept limit mode",
+			yaml: `
+server:
+  port: 8080
+  accept_limit:
+    connections_per_second: 100
+    mode: drop
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`,
+		},
+		{
+			name: "logging sample rate above 1",
+			yaml: `
+server:
+  port: 8080
+logging:
+  sample_rate: 1.5
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`,
+		},
+		{
+			name: "negative route log sample rate",
+			yaml: `
+server:
+  port: 8080
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+    log_sample_rate: -0.1
+`,
+		},
+		{
+			name: "negative route max_concurrent",
+			yaml: `
+server:
+  port: 8080
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+    max_concurrent: -1
+`,
+		},
+		{
+			name: "readiness path collides with liveness",
+			yaml: `
+server:
+  port: 8080
+readiness:
+  path: /health
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`,
+		},
+		{
+			name: "negative readiness pre-stop delay",
+			yaml: `
+server:
+  port: 8080
+readiness:
+  pre_stop_delay: -1s
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`,
+		},
+		{
+			name: "negative client body timeout",
+			yaml: `
+server:
+  port: 8080
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+    client_body_timeout_ms: -1
+`,
+		},
+		{
+			name: "unknown syslog facility",
+			yaml: `
+server:
+  port: 8080
+logging:
+  output: syslog
+  syslog:
+    facility: local9
+routes:
+  - path_prefix: /api
+    backend: http://localhost:3001
+`,
+		},
+		{
+			name: "invalid rate_limit bypass CIDR",
+			yaml: `
+rate_limit:
+  requests_per_second: 10
+  burst_size: 5
+  bypass_cidrs: ["10.0.0.0/33"]
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "invalid rate_limit algorithm",
+			yaml: `
+rate_limit:
+  requests_per_second: 10
+  burst_size: 5
+  algorithm: "leaky_bucket"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "idle_ttl shorter than sliding window",
+			yaml: `
+rate_limit:
+  requests_per_second: 10
+  burst_size: 5
+  algorithm: "sliding_window"
+  window: 1h
+  idle_ttl: 10m
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "negative max_concurrent_per_client",
+			yaml: `
+rate_limit:
+  requests_per_second: 10
+  burst_size: 5
+  max_concurrent_per_client: -1
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "default_route with backend and body",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+default_route:
+  backend: "http://localhost:3002"
+  body: "<h1>oops</h1>"
+`,
+		},
+		{
+			name: "default_route shadowed by root route",
+			yaml: `
+routes:
+  - path_prefix: "/"
+    backend: "http://localhost:3001"
+default_route:
+  status: 404
+  body: "missing"
+`,
+		},
+		{
+			name: "invalid route regex",
+			yaml: `
+routes:
+  - path_prefix: "/v[0-9+/items"
+    backend: "http://localhost:3001"
+    match_type: regex
+`,
+		},
+		{
+			name: "unknown match_type",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+    match_type: glob
+`,
+		},
+		{
+			name: "invalid host pattern",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    hosts: ["api.*.com"]
 `,
 		},
 		{
-			name: "invalid port",
+			name: "duplicate path_prefix for the same host",
 			yaml: `
-server:
-  port: 99999
 auth:
   enabled: false
 routes:
   - path_prefix: "/api"
     backend: "http://localhost:3000"
+    hosts: ["api.foo.com"]
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+    hosts: ["API.foo.com", "api.bar.com"]
 `,
 		},
 		{
-			name: "missing path_prefix",
+			name: "match condition with both header and query",
 			yaml: `
 auth:
   enabled: false
 routes:
-  - backend: "http://localhost:3000"
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    match_conditions:
+      - header: "X-Api-Version"
+        query: "v"
 `,
 		},
 		{
-			name: "missing backend",
+			name: "duplicate path_prefix with the same match conditions",
 			yaml: `
 auth:
   enabled: false
 routes:
   - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    match_conditions: [{header: "X-Api-Version", equals: "2"}]
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+    match_conditions: [{header: "x-api-version", equals: "2"}]
 `,
 		},
 		{
-			name: "path_prefix without leading slash",
+			name: "templated backend without backend_allowed_hosts",
 			yaml: `
 auth:
   enabled: false
 routes:
-  - path_prefix: "api"
-    backend: "http://localhost:3000"
+  - path_prefix: "/proxy/(?P<svc>[a-z]+)/.*"
+    match_type: regex
+    backend: "http://{svc}.internal"
 `,
 		},
 		{
-			name: "duplicate path_prefix",
+			name: "templated backend placeholder without capture group",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/proxy/(?P<svc>[a-z]+)/.*"
+    match_type: regex
+    backend: "http://{service}.internal"
+    backend_allowed_hosts: ["*.internal"]
+`,
+		},
+		{
+			name: "templated backend on a prefix route",
+			yaml: `
+auth:
+  enabled: false
+routes:
+  - path_prefix: "/proxy"
+    backend: "http://{svc}.internal"
+    backend_allowed_hosts: ["*.internal"]
+`,
+		},
+		{
+			name: "response_header_timeout_ms above timeout_ms",
 			yaml: `
 auth:
   enabled: false
 routes:
   - path_prefix: "/api"
     backend: "http://localhost:3000"
+    timeout_ms: 1000
+    response_header_timeout_ms: 2000
+`,
+		},
+		{
+			name: "method rewrite to CONNECT",
+			yaml: `
+routes:
+  - path_prefix: "/legacy"
+    backend: "http://localhost:3001"
+    method_rewrite:
+      PATCH: "CONNECT"
+`,
+		},
+		{
+			name: "invalid method_rewrite method",
+			yaml: `
+routes:
+  - path_prefix: "/legacy"
+    backend: "http://localhost:3001"
+    method_rewrite:
+      PATCH: "PO ST"
+`,
+		},
+		{
+			name: "request_script syntax error",
+			yaml: `
+routes:
   - path_prefix: "/api"
     backend: "http://localhost:3001"
+    request_script: |
+      if method == "POST" then
+        respond(403)
 `,
 		},
 		{
-			name: "auth enabled without secret",
+			name: "negative script_timeout_ms",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+    script_timeout_ms: -1
+`,
+		},
+		{
+			name: "request_schema missing file",
+			yaml: `
+routes:
+  - path_prefix: "/orders"
+    backend: "http://localhost:3001"
+    request_schema: "/nonexistent/order.schema.json"
+`,
+		},
+		{
+			name: "empty strip_response_fields entry",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+    strip_response_fields: ["_debug", ""]
+`,
+		},
+		{
+			name: "auth jwt_secrets with an empty entry",
 			yaml: `
 auth:
   enabled: true
+  jwt_secret: "current"
+  jwt_secrets: ["previous", ""]
   issuer: "iss"
   audience: "aud"
 routes:
@@ -243,56 +1081,229 @@ routes:
 `,
 		},
 		{
-			name: "auth enabled without issuer",
+			name: "auth required_claims_present with an empty name",
 			yaml: `
 auth:
   enabled: true
   jwt_secret: "secret"
+  issuer: "iss"
   audience: "aud"
+  required_claims_present: ["tenant_id", ""]
 routes:
   - path_prefix: "/api"
     backend: "http://localhost:3000"
 `,
 		},
 		{
-			name: "auth enabled without audience",
+			name: "auth introspection_url not http",
 			yaml: `
 auth:
   enabled: true
-  jwt_secret: "secret"
-  issuer: "iss"
+  introspection_url: "ftp://idp.example.com/introspect"
 routes:
   - path_prefix: "/api"
     backend: "http://localhost:3000"
 `,
 		},
 		{
-			name: "backend with file scheme",
+			name: "negative retry_max_buffer_bytes",
 			yaml: `
-auth:
-  enabled: false
 routes:
   - path_prefix: "/api"
-    backend: "file:///etc/passwd"
+    backend: "http://localhost:3000"
+    retry_max_buffer_bytes: -1
 `,
 		},
 		{
-			name: "backend with ftp scheme",
+			name: "proxy_protocol without trusted_cidrs",
 			yaml: `
-auth:
-  enabled: false
+server:
+  proxy_protocol:
+    enabled: true
 routes:
   - path_prefix: "/api"
-    backend: "ftp://evil.com/data"
+    backend: "http://localhost:3000"
 `,
 		},
 		{
-			name: "negative max_body_bytes",
+			name: "negative max_connections",
 			yaml: `
 server:
-  max_body_bytes: -1
-auth:
-  enabled: false
+  max_connections: -1
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "unknown log field",
+			yaml: `
+logging:
+  fields: [method, bogus]
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "empty log header field",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    log_fields: ["header:"]
+`,
+		},
+		{
+			name: "unknown readiness mode",
+			yaml: `
+readiness:
+  mode: "lenient"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "critical route excluded from readiness",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    critical: true
+    exclude_from_readiness: true
+`,
+		},
+		{
+			name: "negative readiness cache ttl",
+			yaml: `
+readiness:
+  cache_ttl: -1s
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "admin port equals main port",
+			yaml: `
+server:
+  port: 8080
+  admin_port: 8080
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "negative admin rate limit",
+			yaml: `
+admin:
+  requests_per_second: -1
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "route circuit_breaker failure_threshold out of range",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    circuit_breaker:
+      failure_threshold: 1.5
+`,
+		},
+		{
+			name: "route circuit_breaker max_concurrent_retries",
+			yaml: `
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+    circuit_breaker:
+      max_concurrent_retries: 5
+`,
+		},
+		{
+			name: "circuit_breaker reset_jitter out of range",
+			yaml: `
+circuit_breaker:
+  reset_jitter: 1
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3000"
+`,
+		},
+		{
+			name: "metrics buckets not increasing",
+			yaml: `
+metrics:
+  buckets: [0.01, 0.1, 0.05]
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "metrics buckets empty",
+			yaml: `
+metrics:
+  buckets: []
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "size_anomaly multiplier not above 1",
+			yaml: `
+logging:
+  size_anomaly:
+    enabled: true
+    multiplier: 0.5
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "invalid rate_limit retry_after_format",
+			yaml: `
+rate_limit:
+  retry_after_format: "minutes"
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "external rotation with stdout output",
+			yaml: `
+logging:
+  output: stdout
+  external_rotation: true
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "external rotation with syslog output",
+			yaml: `
+logging:
+  output: syslog
+  external_rotation: true
+routes:
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`,
+		},
+		{
+			name: "invalid server.trusted_proxies entry",
+			yaml: `
+server:
+  trusted_proxies: ["10.0.0.0/8", "not-a-cidr"]
 routes:
   - path_prefix: "/api"
     backend: "http://localhost:3000"
@@ -377,3 +1388,104 @@ func TestRouteConfig_Timeout(t *testing.T) {
 		t.Errorf("expected 30s default, got %v", r2.Timeout())
 	}
 }
+
+func TestCompilePaths(t *testing.T) {
+	routes := []RouteConfig{
+		{PathPrefix: "/v[0-9]+/items", MatchType: "regex"},
+		{PathPrefix: "/api", MatchType: "prefix"},
+		{PathPrefix: "/bad[", MatchType: "regex"},
+	}
+	compiled, err := CompilePaths(routes)
+	if err == nil || !strings.Contains(err.Error(), `"/bad["`) {
+		t.Errorf("error = %v, want one naming the /bad[ route", err)
+	}
+	if compiled[0].pathRegexp == nil || !compiled[0].MatchesPath("/v2/items") {
+		t.Error("regex route not compiled")
+	}
+	if compiled[2].pathRegexp != nil || compiled[2].MatchesPath("/bad[") {
+		t.Error("route with an invalid pattern should never match")
+	}
+	if routes[0].pathRegexp != nil {
+		t.Error("CompilePaths modified its argument")
+	}
+}
+
+func TestRouteConfig_MatchesPath(t *testing.T) {
+	cfg, err := LoadFromBytes([]byte(`
+routes:
+  - path_prefix: "/health-proxy"
+    backend: "http://localhost:3001"
+    match_type: exact
+  - path_prefix: "/v[0-9]+/items/[^/]+"
+    backend: "http://localhost:3001"
+    match_type: regex
+  - path_prefix: "/api"
+    backend: "http://localhost:3001"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exact, re, prefix := cfg.Routes[0], cfg.Routes[1], cfg.Routes[2]
+
+	tests := []struct {
+		route RouteConfig
+		path  string
+		want  bool
+	}{
+		{exact, "/health-proxy", true},
+		{exact, "/health-proxy/x", false},
+		{exact, "/health-proxy2", false},
+		{re, "/v2/items/abc", true},
+		{re, "/v2/items/abc/extra", false}, // anchored: must match the whole path
+		{re, "/prefix/v2/items/abc", false},
+		{re, "/vX/items/abc", false},
+		{prefix, "/api/users", true},
+		{prefix, "/apiary", false},
+	}
+	for _, tt := range tests {
+		if got := tt.route.MatchesPath(tt.path); got != tt.want {
+			t.Errorf("%s route %q: MatchesPath(%q) = %v, want %v", tt.route.MatchType, tt.route.PathPrefix, tt.path, got, tt.want)
+		}
+	}
+
+	if !(exact.MatchPriority() > re.MatchPriority() && re.MatchPriority() > prefix.MatchPriority()) {
+		t.Errorf("priorities exact=%d regex=%d prefix=%d, want exact > regex > prefix",
+			exact.MatchPriority(), re.MatchPriority(), prefix.MatchPriority())
+	}
+}
+
+func TestSelectRoute_HostBeforePath(t *testing.T) {
+	cfg, err := LoadFromBytes([]byte(`
+routes:
+  - path_prefix: "/"
+    backend: "http://localhost:3001"
+  - path_prefix: "/api"
+    backend: "http://localhost:3002"
+    hosts: ["api.foo.com"]
+  - path_prefix: "/api"
+    backend: "http://localhost:3003"
+    hosts: ["*.foo.com"]
+`))
+	if err != nil {
+		t.Fatalf("same prefix on different hosts should load: %v", err)
+	}
+
+	tests := []struct {
+		host, path string
+		want       string
+	}{
+		{"api.foo.com", "/api/x", "http://localhost:3002"},
+		{"Api.Foo.Com.:443", "/api/x", "http://localhost:3002"},
+		{"www.foo.com", "/api/x", "http://localhost:3003"},
+		{"foo.com", "/api/x", "http://localhost:3001"},
+		{"api.foo.com", "/other", "http://localhost:3001"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		route, ok := SelectRoute(cfg.Routes, req)
+		if !ok || route.Backend != tt.want {
+			t.Errorf("%s%s: got %q (ok=%v), want %q", tt.host, tt.path, route.Backend, ok, tt.want)
+		}
+	}
+}
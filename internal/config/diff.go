@@ -0,0 +1,96 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Diff is what a reload changes: the top-level sections whose settings
+// differ, and the routes added, removed or changed, by ID. Observers use
+// it to leave alone the components a reload does not touch.
+type Diff struct {
+	Sections      []string `json:"sections,omitempty"` // YAML keys, in Config field order; routes are itemized below
+	RoutesAdded   []string `json:"routes_added,omitempty"`
+	RoutesRemoved []string `json:"routes_removed,omitempty"`
+	RoutesChanged []string `json:"routes_changed,omitempty"`
+	// RoutesReordered is set when the same routes are listed in a
+	// different order, which can change which regex route matches first.
+	RoutesReordered bool `json:"routes_reordered,omitempty"`
+}
+
+// Compare returns the Diff from old to new. Settings are compared as
+// they are loaded, defaults applied; state derived during validation is
+// ignored.
+func Compare(old, new *Config) Diff {
+	var d Diff
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := range ov.NumField() {
+		name, _, _ := strings.Cut(ov.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || name == "routes" {
+			continue
+		}
+		if !sameSettings(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			d.Sections = append(d.Sections, name)
+		}
+	}
+
+	oldRoutes := make(map[string]RouteConfig, len(old.Routes))
+	var oldOrder []string
+	for _, r := range old.Routes {
+		oldRoutes[r.ID()] = r
+		oldOrder = append(oldOrder, r.ID())
+	}
+	var newOrder []string
+	for _, r := range new.Routes {
+		id := r.ID()
+		prev, ok := oldRoutes[id]
+		switch {
+		case !ok:
+			d.RoutesAdded = append(d.RoutesAdded, id)
+		case !sameSettings(prev, r):
+			d.RoutesChanged = append(d.RoutesChanged, id)
+		}
+		if ok {
+			newOrder = append(newOrder, id)
+		}
+		delete(oldRoutes, id)
+	}
+	for _, r := range old.Routes {
+		if _, removed := oldRoutes[r.ID()]; removed {
+			d.RoutesRemoved = append(d.RoutesRemoved, r.ID())
+		}
+	}
+	kept := slices.DeleteFunc(oldOrder, func(id string) bool {
+		_, removed := oldRoutes[id]
+		return removed
+	})
+	d.RoutesReordered = !slices.Equal(kept, newOrder)
+	return d
+}
+
+// sameSettings compares two config values by their JSON form, which
+// covers every setting and leaves out the compiled state validate adds.
+func sameSettings(a, b any) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aj, bj)
+}
+
+// Empty reports whether the reload changes nothing.
+func (d Diff) Empty() bool {
+	return len(d.Sections) == 0 && !d.Routes()
+}
+
+// Routes reports whether the route list changed in any way.
+func (d Diff) Routes() bool {
+	return len(d.RoutesAdded)+len(d.RoutesRemoved)+len(d.RoutesChanged) > 0 || d.RoutesReordered
+}
+
+// Section reports whether the top-level section with YAML key name
+// changed.
+func (d Diff) Section(name string) bool {
+	return slices.Contains(d.Sections, name)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	load := func(yaml string) *Config {
+		t.Helper()
+		cfg, err := LoadFromBytes([]byte(yaml))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	old := load(`
+logging:
+  sample_rate: 1.0
+routes:
+  - path_prefix: "/users"
+    backend: "http://users:3001"
+  - path_prefix: "/orders"
+    backend: "http://orders:3002"
+  - path_prefix: "/legacy"
+    backend: "http://legacy:3003"
+`)
+
+	if d := Compare(old, load(`
+logging:
+  sample_rate: 1.0
+routes:
+  - path_prefix: "/users"
+    backend: "http://users:3001"
+  - path_prefix: "/orders"
+    backend: "http://orders:3002"
+  - path_prefix: "/legacy"
+    backend: "http://legacy:3003"
+`)); !d.Empty() {
+		t.Errorf("identical configs: diff = %+v, want empty", d)
+	}
+
+	d := Compare(old, load(`
+logging:
+  sample_rate: 0.5
+routes:
+  - path_prefix: "/orders"
+    backend: "http://orders:3002"
+    timeout_ms: 2000
+  - path_prefix: "/users"
+    backend: "http://users:3001"
+  - path_prefix: "/billing"
+    backend: "http://billing:3004"
+`))
+	if !slices.Equal(d.Sections, []string{"logging"}) || !d.Section("logging") || d.Section("rate_limit") {
+		t.Errorf("sections = %v, want [logging]", d.Sections)
+	}
+	if !slices.Equal(d.RoutesAdded, []string{"/billing"}) {
+		t.Errorf("routes added = %v, want [/billing]", d.RoutesAdded)
+	}
+	if !slices.Equal(d.RoutesRemoved, []string{"/legacy"}) {
+		t.Errorf("routes removed = %v, want [/legacy]", d.RoutesRemoved)
+	}
+	if !slices.Equal(d.RoutesChanged, []string{"/orders"}) {
+		t.Errorf("routes changed = %v, want [/orders]", d.RoutesChanged)
+	}
+	if !d.RoutesReordered || !d.Routes() {
+		t.Errorf("routes reordered = %v, want true: /orders now precedes /users", d.RoutesReordered)
+	}
+}
@@ -41,7 +41,7 @@ type RollbackRecorder interface {
 // It supports fsnotify file watching (cross-platform) and SIGHUP
 // (Unix only, registered in reload_unix.go).
 type Reloader struct {
-	mu sync.RWMutex
+	mu      sync.RWMutex
 	current *Config
 	path    string
 	logger  *slog.Logger
@@ -52,6 +52,19 @@ type Reloader struct {
 	rollbacks       RollbackRecorder
 	watcher         *fsnotify.Watcher
 	stopCh          chan struct{}
+	status          ReloadStatus
+	// reloadOnUSR1 moves the reload signal from SIGHUP to SIGUSR1.
+	reloadOnUSR1 bool
+}
+
+// ReloadStatus records when the active config was loaded and the outcome
+// of the most recent reload attempt. LastReloadAt is zero until the first
+// reload; LastReloadError is empty on success.
+type ReloadStatus struct {
+	ConfigLoadedAt  time.Time `json:"config_loaded_at"`
+	LastReloadAt    time.Time `json:"last_reload_at"`
+	LastReloadOK    bool      `json:"last_reload_ok"`
+	LastReloadError string    `json:"last_reload_error,omitempty"`
 }
 
 // NewReloader creates a Reloader for the given config file path.
@@ -61,6 +74,7 @@ func NewReloader(path string, initial *Config, logger *slog.Logger) *Reloader {
 		path:    path,
 		logger:  logger,
 		stopCh:  make(chan struct{}),
+		status:  ReloadStatus{ConfigLoadedAt: time.Now()},
 	}
 }
 
@@ -79,6 +93,30 @@ func (r *Reloader) Current() *Config {
 	return r.current
 }
 
+// ReloadStatus returns the config load time and last reload outcome
+// (thread-safe).
+func (r *Reloader) ReloadStatus() ReloadStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status
+}
+
+// recordReload stores the outcome of a reload attempt. err is nil on
+// success, in which case the config load time advances too.
+func (r *Reloader) recordReload(err error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.LastReloadAt = now
+	r.status.LastReloadOK = err == nil
+	r.status.LastReloadError = ""
+	if err != nil {
+		r.status.LastReloadError = err.Error()
+		return
+	}
+	r.status.ConfigLoadedAt = now
+}
+
 // SetPath updates the watched config file path. Intended for callers that
 // construct a Reloader before the final path is known (e.g. Gateway wiring
 // that accepts an in-memory Config in tests). Must be called before Start.
@@ -107,8 +145,15 @@ func (r *Reloader) RegisterObserver(obs Observer) {
 	r.observers = append(r.observers, obs)
 }
 
+// UseSIGUSR1 makes the reload signal SIGUSR1 instead of SIGHUP, leaving
+// SIGHUP to reopen log files (logging.external_rotation). Call before Start.
+func (r *Reloader) UseSIGUSR1() {
+	r.reloadOnUSR1 = true
+}
+
 // Start begins watching the config file for changes and listening for
-// SIGHUP (on Unix). Must be called once after NewReloader.
+// SIGHUP, or SIGUSR1 after UseSIGUSR1 (on Unix). Must be called once after
+// NewReloader.
 func (r *Reloader) Start() {
 	// Start fsnotify file watcher
 	watcher, err := fsnotify.NewWatcher()
@@ -158,6 +203,7 @@ func (r *Reloader) Reload() bool {
 	if err != nil {
 		r.logger.Error("config reload failed: invalid config, keeping current",
 			"path", r.path, "error", err)
+		r.recordReload(err)
 		return false
 	}
 
@@ -184,6 +230,7 @@ func (r *Reloader) Reload() bool {
 			if rollbacks != nil {
 				rollbacks.IncRollback(reason)
 			}
+			r.recordReload(fmt.Errorf("rolled back by observer %d: %s: %s", i, reason, detail))
 			return false
 		}
 	}
@@ -192,6 +239,7 @@ func (r *Reloader) Reload() bool {
 		cb(newCfg)
 	}
 
+	r.recordReload(nil)
 	r.logger.Info("configuration reloaded successfully")
 	return true
 }
@@ -246,8 +294,21 @@ func (r *Reloader) watchLoop() {
 	}
 }
 
-// logChanges logs a summary of what changed between the old and new config.
+// logChanges logs what changed between the old and new config: the Diff,
+// then details for the settings operators most often change.
 func (r *Reloader) logChanges(old, new *Config) {
+	if d := Compare(old, new); d.Empty() {
+		r.logger.Info("config unchanged")
+	} else {
+		r.logger.Info("config changes",
+			"sections", d.Sections,
+			"routes_added", d.RoutesAdded,
+			"routes_removed", d.RoutesRemoved,
+			"routes_changed", d.RoutesChanged,
+			"routes_reordered", d.RoutesReordered,
+		)
+	}
+
 	if old.RateLimit.RequestsPerSecond != new.RateLimit.RequestsPerSecond ||
 		old.RateLimit.BurstSize != new.RateLimit.BurstSize {
 		r.logger.Info("rate limit config changed",
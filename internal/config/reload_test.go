@@ -100,6 +100,14 @@ func TestReloader_Reload_ValidConfig(t *testing.T) {
 	if cfg.RateLimit.BurstSize != 100 {
 		t.Errorf("expected 100 burst after reload, got %v", cfg.RateLimit.BurstSize)
 	}
+
+	st := r.ReloadStatus()
+	if !st.LastReloadOK || st.LastReloadAt.IsZero() || st.LastReloadError != "" {
+		t.Errorf("expected successful reload status, got %+v", st)
+	}
+	if st.ConfigLoadedAt != st.LastReloadAt {
+		t.Errorf("expected config load time to advance to the reload, got %+v", st)
+	}
 }
 
 func TestReloader_Reload_InvalidConfig(t *testing.T) {
@@ -133,6 +141,14 @@ func TestReloader_Reload_InvalidConfig(t *testing.T) {
 	if !strings.Contains(logBuf.String(), "config reload failed") {
 		t.Error("expected error to be logged")
 	}
+
+	st := r.ReloadStatus()
+	if st.LastReloadOK || st.LastReloadError == "" {
+		t.Errorf("expected failed reload status, got %+v", st)
+	}
+	if !st.ConfigLoadedAt.Before(st.LastReloadAt) {
+		t.Errorf("expected config load time to stay at the initial load, got %+v", st)
+	}
 }
 
 func TestReloader_OnReload_Callback(t *testing.T) {
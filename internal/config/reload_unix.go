@@ -8,16 +8,22 @@ import (
 	"syscall"
 )
 
-// registerSignalHandler listens for SIGHUP and triggers a config reload.
+// registerSignalHandler listens for SIGHUP (SIGUSR1 after UseSIGUSR1) and
+// triggers a config reload.
 func (r *Reloader) registerSignalHandler() {
+	sig := syscall.SIGHUP
+	if r.reloadOnUSR1 {
+		sig = syscall.SIGUSR1
+	}
+	name := signalName(sig)
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGHUP)
+	signal.Notify(sigCh, sig)
 
 	go func() {
 		for {
 			select {
 			case <-sigCh:
-				r.logger.Info("SIGHUP received, reloading config")
+				r.logger.Info(name + " received, reloading config")
 				r.Reload()
 			case <-r.stopCh:
 				signal.Stop(sigCh)
@@ -26,5 +32,12 @@ func (r *Reloader) registerSignalHandler() {
 		}
 	}()
 
-	r.logger.Info("SIGHUP config reload handler registered")
+	r.logger.Info(name + " config reload handler registered")
+}
+
+func signalName(sig syscall.Signal) string {
+	if sig == syscall.SIGUSR1 {
+		return "SIGUSR1"
+	}
+	return "SIGHUP"
 }
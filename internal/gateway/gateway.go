@@ -6,14 +6,21 @@
 package gateway
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/dskow/gateway-core/internal/admin"
 	"github.com/dskow/gateway-core/internal/auth"
@@ -23,8 +30,8 @@ import (
 	"github.com/dskow/gateway-core/internal/metrics"
 	"github.com/dskow/gateway-core/internal/middleware"
 	"github.com/dskow/gateway-core/internal/proxy"
+	"github.com/dskow/gateway-core/internal/proxyproto"
 	"github.com/dskow/gateway-core/internal/ratelimit"
-	"github.com/dskow/gateway-core/internal/routing"
 	"github.com/dskow/gateway-core/internal/tlsutil"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -34,26 +41,47 @@ import (
 // production code nothing outside of this package should mutate them after
 // NewGateway returns.
 type Gateway struct {
-	Config   *config.Config
-	Logger   *slog.Logger
-	Metrics  *metrics.Metrics
-	Router   *proxy.Router
-	Limiter  *ratelimit.Limiter
-	Breakers map[string]*circuitbreaker.CompositeBreaker
+	Config  *config.Config
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+	Router  *proxy.Router
+	Limiter *ratelimit.Limiter
+	// Events carries every breaker's state changes to /admin/events.
+	Events   *circuitbreaker.EventBus
 	Reloader *config.Reloader
 	Health   *health.Handler
+	Prober   *health.Prober // nil unless health_check.enabled
 	Admin    *admin.Handler
 	Server   *http.Server
+	// AdminLimiter rate limits the admin and metrics endpoints per client;
+	// nil unless admin.requests_per_second is set.
+	AdminLimiter *ratelimit.Limiter
+	// InternalServers serve admin and metrics on server.admin_port and
+	// server.metrics_port; empty when both are on Server.
+	InternalServers []*http.Server
 
 	// handler is the top-level HTTP handler mounted on Server; it
 	// composes mux (bypass endpoints) with the request-path handler.
 	handler http.Handler
 
+	// applied is the config the subsystems were last brought in line
+	// with, at startup or by OnReload. Reloads are diffed against it, not
+	// the Reloader's previous config, which a rollback may have restored
+	// after OnReload had already applied the new one.
+	applied *config.Config
+
+	// breakers holds the circuit breakers by key; see Breakers.
+	breakers atomic.Pointer[map[string]*circuitbreaker.CompositeBreaker]
+
 	// routesRef lets request-path callbacks (log-level lookup) read
 	// the current route table lock-free, and lets the reload callback
 	// swap it atomically.
 	routesRef atomic.Value // []config.RouteConfig
 
+	// draining is set once Run begins graceful shutdown; reported on
+	// /admin/status.
+	draining atomic.Bool
+
 	certLoader *tlsutil.CertLoader
 }
 
@@ -68,6 +96,8 @@ type Options struct {
 	// Gatherer is what the /metrics endpoint exports. Defaults to
 	// prometheus.DefaultGatherer when nil.
 	Gatherer prometheus.Gatherer
+	// Build identifies the binary on /admin/status.
+	Build admin.BuildInfo
 }
 
 // NewGateway constructs a Gateway in strict dependency order: Metrics →
@@ -75,130 +105,215 @@ type Options struct {
 // Server. Every component that needs to be torn down is owned here, so
 // Run+Shutdown is a complete lifecycle.
 //
-// ctx is reserved for future deadline-bound initialization (e.g. TLS cert
-// preloading) and currently only gates the cert loader start. Pass a
-// fresh context if construction must respect a parent deadline.
+// ctx bounds deadline-bound initialization; currently only the
+// server.fail_fast_on_startup backend check. Pass a fresh context if
+// construction must respect a parent deadline.
 func NewGateway(ctx context.Context, cfg *config.Config, logger *slog.Logger, opts Options) (*Gateway, error) {
 	g := &Gateway{
 		Config: cfg,
 		Logger: logger,
 	}
 
+	if cfg.Server.FailFastOnStartup {
+		if err := health.CheckBackends(ctx, cfg.Routes, cfg.Server.StartupCheckTimeout, logger); err != nil {
+			return nil, fmt.Errorf("startup backend check failed: %w", err)
+		}
+		logger.Info("startup backend check passed", "routes", len(cfg.Routes))
+	}
+
 	if cfg.Metrics.IsEnabled() {
 		reg := opts.Registerer
 		if reg == nil {
 			reg = prometheus.DefaultRegisterer
 		}
-		g.Metrics = metrics.New(reg)
-	}
-
-	// Circuit breakers — one per unique backend URL.
-	cbCfg := circuitbreaker.Config{
-		WindowSize:       cfg.CircuitBreaker.WindowSize,
-		FailureThreshold: cfg.CircuitBreaker.FailureThreshold,
-		ResetTimeout:     cfg.CircuitBreaker.ResetTimeout,
-		HalfOpenMax:      cfg.CircuitBreaker.HalfOpenMax,
-		SlowThreshold:    cfg.CircuitBreaker.SlowThreshold,
-		MaxConcurrent:    cfg.CircuitBreaker.MaxConcurrent,
-		Adaptive:         cfg.CircuitBreaker.Adaptive,
-		LatencyCeiling:   cfg.CircuitBreaker.LatencyCeiling,
-		MinThreshold:     cfg.CircuitBreaker.MinThreshold,
-	}
-	g.Breakers = make(map[string]*circuitbreaker.CompositeBreaker)
-	for _, route := range cfg.Routes {
-		if _, exists := g.Breakers[route.Backend]; !exists {
-			g.Breakers[route.Backend] = circuitbreaker.NewComposite(route.Backend, cbCfg, logger, g.Metrics)
-			logger.Info("circuit breaker created", "backend", route.Backend)
+		g.Metrics = metrics.NewWithBuckets(reg, cfg.Metrics.Buckets)
+		g.Metrics.SetBuildInfo(buildVersion(opts.Build), opts.Build.Commit)
+	}
+
+	// Circuit breakers — one per unique backend URL, plus one per route
+	// with breaker_scope: route — each with its route's settings.
+	breakers := make(map[string]*circuitbreaker.CompositeBreaker)
+	g.breakers.Store(&breakers)
+	g.Events = circuitbreaker.NewEventBus()
+	breakerRoutes := cfg.Routes
+	if dr := cfg.DefaultRoute; dr != nil && dr.Backend != "" {
+		breakerRoutes = append(slices.Clip(breakerRoutes), dr.Route())
+	}
+	for _, route := range breakerRoutes {
+		key := route.BreakerKey()
+		if _, exists := breakers[key]; !exists {
+			breakers[key] = circuitbreaker.NewComposite(key, breakerConfig(route.Breaker(cfg.CircuitBreaker)), logger, g.Metrics)
+			breakers[key].SetEvents(g.Events)
+			logger.Info("circuit breaker created", "backend", route.Backend, "scope", route.BreakerScope)
 		}
 	}
 
-	router, err := proxy.New(cfg.Routes, g.Breakers, logger, g.Metrics)
+	router, err := proxy.New(cfg.Routes, breakers, logger, g.Metrics)
 	if err != nil {
 		return nil, fmt.Errorf("building proxy router: %w", err)
 	}
+	if err := router.SetDefaultRoute(cfg.DefaultRoute); err != nil {
+		return nil, fmt.Errorf("building proxy router: %w", err)
+	}
+	router.SetServerTiming(cfg.Server.ServerTiming)
+	router.SetStripResponseHeaders(cfg.Server.StripResponseHeaders)
+	router.SetMaxConcurrentRetries(cfg.CircuitBreaker.MaxConcurrentRetries)
+	router.SetSizeAnomaly(cfg.Logging.SizeAnomaly)
 	g.Router = router
 
 	g.Limiter = ratelimit.New(cfg.RateLimit, cfg.Routes, cfg.Server.TrustedProxies, logger, g.Metrics)
 
 	g.routesRef.Store(cfg.Routes)
+	g.applied = cfg
 
-	routeRequiresAuth := func(path string) bool {
-		route, ok := router.MatchRoute(path)
+	routeRequiresAuth := func(r *http.Request) bool {
+		route, ok := router.MatchRoute(r)
 		if !ok {
 			return false
 		}
 		return route.AuthRequired
 	}
-	routeLogLevel := func(path string) slog.Level {
-		routes := g.routesRef.Load().([]config.RouteConfig)
-		bestLen := 0
-		bestLevel := slog.LevelInfo
-		for _, route := range routes {
-			if routing.MatchesPrefix(path, route.PathPrefix) && len(route.PathPrefix) > bestLen {
-				bestLen = len(route.PathPrefix)
-				bestLevel = middleware.ParseLogLevel(route.LogLevel)
-			}
+	routeRequiresClientCert := func(r *http.Request) bool {
+		route, ok := router.MatchRoute(r)
+		return ok && route.ClientCertRequired
+	}
+	clientCARoots := func() *x509.CertPool {
+		if g.certLoader == nil {
+			return nil
 		}
-		return bestLevel
+		return g.certLoader.ClientCAs()
 	}
-
-	var bodyConfig *middleware.LoggingConfig
-	if cfg.Logging.BodyLogging {
-		bodyConfig = &middleware.LoggingConfig{
-			BodyLogging:     true,
-			MaxBodyLogBytes: cfg.Logging.MaxBodyLogBytes,
+	routeLogLevel := func(r *http.Request) slog.Level {
+		route, ok := g.loggedRoute(r)
+		if !ok {
+			return slog.LevelInfo
 		}
+		return middleware.ParseLogLevel(route.LogLevel)
+	}
+	globalSampleRate := cfg.Logging.AccessLogSampleRate()
+	routeSampleRate := func(r *http.Request) float64 {
+		if route, ok := g.loggedRoute(r); ok && route.LogSampleRate != nil {
+			return *route.LogSampleRate
+		}
+		return globalSampleRate
+	}
+	routeLogFields := func(r *http.Request) []string {
+		route, _ := g.loggedRoute(r)
+		return cfg.Logging.AccessLogFields(route)
+	}
+
+	logConfig := &middleware.LoggingConfig{
+		BodyLogging:     cfg.Logging.BodyLogging,
+		MaxBodyLogBytes: cfg.Logging.MaxBodyLogBytes,
+		SampleRate:      routeSampleRate,
+		Fields:          routeLogFields,
+		// X-Debug-Log is honored from the same peers we trust for
+		// X-Forwarded-For.
+		DebugTrustedProxies: cfg.Server.TrustedProxies,
 	}
 
 	// Middleware stack (inside-out assembly matches the original main()):
-	// Recovery → RequestID → Deadline → SecurityHeaders → Logging → CORS →
-	// BodyLimit → RateLimit → Auth → Proxy. Order is load-bearing — Recovery
-	// must wrap everything, Auth must be last before the proxy so claims
-	// are on the context the upstream sees.
+	// Recovery → RequestID → ClientIP → Framing → Deadline → VersionHeaders →
+	// SecurityHeaders → Logging → CORS → BodyLimit → RateLimit → ClientCert →
+	// Auth → Proxy. Order is
+	// load-bearing — Recovery must wrap everything, Auth must be last before
+	// the proxy so claims are on the context the upstream sees.
 	var handler http.Handler = router
 	handler = auth.Middleware(cfg.Auth, routeRequiresAuth, logger, g.Metrics)(handler)
+	handler = middleware.ClientCert(routeRequiresClientCert, clientCARoots)(handler)
 	handler = g.Limiter.Middleware()(handler)
 	handler = middleware.BodyLimit(cfg.Server.MaxBodyBytes)(handler)
 	handler = middleware.CORS(middleware.DefaultCORSConfig())(handler)
-	handler = middleware.Logging(logger, routeLogLevel, bodyConfig)(handler)
+	handler = middleware.Logging(logger, routeLogLevel, logConfig)(handler)
 	handler = middleware.SecurityHeaders()(handler)
+	if !cfg.Server.HideVersion {
+		handler = middleware.VersionHeaders(buildVersion(opts.Build))(handler)
+	}
 	handler = middleware.Deadline(cfg.Server.GlobalTimeout())(handler)
-	handler = middleware.RequestID(handler)
+	handler = middleware.Framing(g.Metrics)(handler)
+	handler = middleware.ClientIPResolver(cfg.Server.TrustedProxies)(handler)
+	handler = middleware.RequestIDFrom(cfg.Server.CorrelationHeaders)(handler)
 	handler = middleware.Recovery(logger)(handler)
 
 	// Separate mux for /health, /ready, /metrics, /admin — these bypass
 	// the request-path middleware stack entirely.
 	mux := http.NewServeMux()
-	g.Health = health.New(cfg.Routes, g.Breakers, logger)
+	g.Health = health.New(cfg.Routes, breakers, logger)
+	g.Health.Configure(cfg.Readiness)
 	g.Health.RegisterRoutes(mux)
 
+	// server.admin_port / server.metrics_port: a mux per internal port,
+	// each also serving liveness and readiness.
+	internalMuxes := make(map[int]*http.ServeMux)
+	muxFor := func(port int) *http.ServeMux {
+		if port == 0 {
+			return mux
+		}
+		m, ok := internalMuxes[port]
+		if !ok {
+			m = http.NewServeMux()
+			g.Health.RegisterRoutes(m)
+			internalMuxes[port] = m
+		}
+		return m
+	}
+
+	if cfg.HealthCheck.Enabled {
+		g.Prober = health.NewProber(cfg.HealthCheck, cfg.Routes, breakers, logger)
+	}
+
+	// Admin and metrics skip the request-path stack, rate_limit included,
+	// so they get a limiter of their own. Metrics are not passed in: its
+	// gauges belong to the main limiter.
+	limitInternal := func(h http.Handler) http.Handler { return h }
+	if rl, ok := cfg.Admin.RateLimit(); ok {
+		g.AdminLimiter = ratelimit.New(rl, nil, cfg.Server.TrustedProxies, logger, nil)
+		limitInternal = g.AdminLimiter.Middleware()
+	}
+
 	if cfg.Metrics.IsEnabled() {
 		gatherer := opts.Gatherer
 		if gatherer == nil {
 			gatherer = prometheus.DefaultGatherer
 		}
-		mux.Handle(cfg.Metrics.Path, metrics.Handler(gatherer))
-		logger.Info("metrics endpoint registered", "path", cfg.Metrics.Path)
+		var metricsHandler http.Handler = metrics.Handler(gatherer)
+		if cfg.Metrics.Protected {
+			metricsHandler = admin.IPAllowlist(cfg.Admin.IPAllowlist, logger)(metricsHandler)
+		}
+		muxFor(cfg.Server.MetricsPort).Handle(cfg.Metrics.Path, limitInternal(metricsHandler))
+		logger.Info("metrics endpoint registered", "path", cfg.Metrics.Path, "protected", cfg.Metrics.Protected, "port", cmp.Or(cfg.Server.MetricsPort, cfg.Server.Port))
 	}
 
 	// Reloader is constructed before admin so admin can reference it.
 	g.Reloader = config.NewReloader("", cfg, logger)
+	if cfg.Logging.ExternalRotation {
+		g.Reloader.UseSIGUSR1()
+	}
 	if g.Metrics != nil {
 		g.Reloader.SetRollbackRecorder(g.Metrics)
 	}
 
 	if cfg.Admin.Enabled {
-		g.Admin = admin.New(g.Reloader, g.Limiter, g.Breakers, cfg.Routes, cfg.Admin.IPAllowlist, logger)
-		g.Admin.RegisterRoutes(mux)
-		logger.Info("admin API enabled", "allowlist", cfg.Admin.IPAllowlist)
+		g.Admin = admin.New(g.Reloader, g.Limiter, breakers, cfg.Routes, cfg.Admin.IPAllowlist, logger)
+		g.Admin.SetTransportStats(g.Router.TransportStats)
+		g.Admin.SetEvents(g.Events)
+		g.Admin.SetStatusSource(admin.StatusSource{
+			Build:     opts.Build,
+			StartedAt: time.Now(),
+			Draining:  g.draining.Load,
+		})
+		adminMux := http.NewServeMux()
+		g.Admin.RegisterRoutes(adminMux)
+		muxFor(cfg.Server.AdminPort).Handle("/admin/", limitInternal(adminMux))
+		logger.Info("admin API enabled", "allowlist", cfg.Admin.IPAllowlist, "port", cmp.Or(cfg.Server.AdminPort, cfg.Server.Port))
 	}
 
-	bypassExact := map[string]bool{}
-	if cfg.Metrics.IsEnabled() {
+	bypassExact := map[string]bool{g.Health.ReadyPath(): true}
+	if cfg.Metrics.IsEnabled() && cfg.Server.MetricsPort == 0 {
 		bypassExact[cfg.Metrics.Path] = true
 	}
-	bypassPrefixes := []string{"/health", "/ready"}
-	if cfg.Admin.Enabled {
+	bypassPrefixes := []string{"/health"}
+	if cfg.Admin.Enabled && cfg.Server.AdminPort == 0 {
 		bypassPrefixes = append(bypassPrefixes, "/admin/")
 	}
 
@@ -219,15 +334,27 @@ func NewGateway(ctx context.Context, cfg *config.Config, logger *slog.Logger, op
 
 	// DP-001: the Gateway itself implements config.Observer so hot reloads
 	// go through the rollback-capable pipeline. OnReload is idempotent —
-	// it overwrites limiter rates, breaker thresholds, and the routes atom
-	// unconditionally — which is the contract the Reloader documents.
+	// it overwrites the router's routes, limiter rates, breaker thresholds,
+	// and the routes atom unconditionally — which is the contract the
+	// Reloader documents.
 	g.Reloader.RegisterObserver(g)
 
 	g.Server = &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      g.handler,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           g.handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+	}
+	for _, port := range slices.Sorted(maps.Keys(internalMuxes)) {
+		m := internalMuxes[port]
+		g.InternalServers = append(g.InternalServers, &http.Server{
+			Addr:              net.JoinHostPort(cfg.Server.InternalAddress, strconv.Itoa(port)),
+			Handler:           middleware.Recovery(logger)(m),
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+		})
 	}
 
 	if cfg.Server.TLS.Enabled {
@@ -236,6 +363,9 @@ func NewGateway(ctx context.Context, cfg *config.Config, logger *slog.Logger, op
 			return nil, fmt.Errorf("loading TLS certificate: %w", err)
 		}
 		g.certLoader = cl
+		if cfg.Server.TLS.OCSPStapling {
+			cl.EnableOCSPStapling()
+		}
 
 		minVersion := uint16(tls.VersionTLS12)
 		if cfg.Server.TLS.MinVersion == "1.3" {
@@ -244,13 +374,58 @@ func NewGateway(ctx context.Context, cfg *config.Config, logger *slog.Logger, op
 		g.Server.TLSConfig = &tls.Config{
 			GetCertificate: cl.GetCertificate,
 			MinVersion:     minVersion,
+			ClientAuth:     tlsutil.ClientAuthType(cfg.Server.TLS.ClientAuthMode),
+		}
+		if cfg.Server.TLS.ClientCAFile != "" {
+			if err := cl.LoadClientCAs(cfg.Server.TLS.ClientCAFile); err != nil {
+				cl.Stop()
+				return nil, fmt.Errorf("loading TLS client CA bundle: %w", err)
+			}
+			g.Server.TLSConfig.GetConfigForClient = cl.GetConfigForClient(g.Server.TLSConfig)
 		}
 	}
 
-	_ = ctx // reserved for future deadline-bound initialization
 	return g, nil
 }
 
+// breakerConfig converts circuit breaker settings from the config.
+func breakerConfig(cb config.CircuitBreakerConfig) circuitbreaker.Config {
+	return circuitbreaker.Config{
+		WindowSize:       cb.WindowSize,
+		FailureThreshold: cb.FailureThreshold,
+		ResetTimeout:     cb.ResetTimeout,
+		HalfOpenMax:      cb.HalfOpenMax,
+		ResetJitter:      cb.ResetJitter,
+		SlowThreshold:    cb.SlowThreshold,
+		MaxConcurrent:    cb.MaxConcurrent,
+		Adaptive:         cb.Adaptive,
+		LatencyCeiling:   cb.LatencyCeiling,
+		MinThreshold:     cb.MinThreshold,
+	}
+}
+
+// buildVersion returns the version to advertise, "dev" for builds without
+// version ldflags (and tests that leave Options.Build empty).
+func buildVersion(b admin.BuildInfo) string {
+	if b.Version == "" {
+		return "dev"
+	}
+	return b.Version
+}
+
+// loggedRoute returns the route serving r from the current
+// (hot-reloadable) route table, for per-route logging settings.
+func (g *Gateway) loggedRoute(r *http.Request) (config.RouteConfig, bool) {
+	return config.SelectRoute(g.routesRef.Load().([]config.RouteConfig), r)
+}
+
+// Breakers returns the circuit breakers by key: one per backend, plus one
+// per breaker_scope: route route. A reload stores a new map rather than
+// changing the current one, so a map once returned is safe to read.
+func (g *Gateway) Breakers() map[string]*circuitbreaker.CompositeBreaker {
+	return *g.breakers.Load()
+}
+
 // SetReloadPath configures the Reloader's watched file path. main() calls
 // this after NewGateway so the gateway can be constructed from an in-memory
 // Config (e.g. in tests) without a file on disk.
@@ -262,28 +437,82 @@ func (g *Gateway) SetReloadPath(path string) {
 // exercise requests in-process without binding a TCP listener.
 func (g *Gateway) Handler() http.Handler { return g.handler }
 
-// OnReload implements config.Observer. It is idempotent: every field
-// is rewritten from `newCfg` regardless of the current state, so a rollback
-// (which only restores the Reloader's current pointer) followed by a later
-// successful reload will always bring subsystems in line with the config.
+// OnReload implements config.Observer. It updates only the subsystems
+// whose settings differ from the config last applied (see applied), so a
+// reload that changes, say, logging leaves rate-limit buckets and breaker
+// windows alone. Diffing against what was applied rather than the
+// Reloader's previous config keeps it correct across rollbacks, which
+// only restore the Reloader's current pointer. The router is updated
+// first, as it is the only step that can fail.
 func (g *Gateway) OnReload(_, newCfg *config.Config) error {
-	g.Limiter.UpdateConfig(newCfg.RateLimit, newCfg.Routes)
-	newCbCfg := circuitbreaker.Config{
-		WindowSize:       newCfg.CircuitBreaker.WindowSize,
-		FailureThreshold: newCfg.CircuitBreaker.FailureThreshold,
-		ResetTimeout:     newCfg.CircuitBreaker.ResetTimeout,
-		HalfOpenMax:      newCfg.CircuitBreaker.HalfOpenMax,
-		SlowThreshold:    newCfg.CircuitBreaker.SlowThreshold,
-		MaxConcurrent:    newCfg.CircuitBreaker.MaxConcurrent,
-		Adaptive:         newCfg.CircuitBreaker.Adaptive,
-		LatencyCeiling:   newCfg.CircuitBreaker.LatencyCeiling,
-		MinThreshold:     newCfg.CircuitBreaker.MinThreshold,
-	}
-	for backend, cb := range g.Breakers {
-		cb.UpdateConfig(newCbCfg)
-		g.Logger.Info("circuit breaker config updated", "backend", backend)
+	diff := config.Compare(g.applied, newCfg)
+
+	// Each breaker's settings, by key: its routes' (validate makes routes
+	// sharing a breaker agree), or the global ones for breakers no route
+	// uses any more.
+	cbCfgs := make(map[string]circuitbreaker.Config, len(newCfg.Routes))
+	for _, route := range newCfg.Routes {
+		cbCfgs[route.BreakerKey()] = breakerConfig(route.Breaker(newCfg.CircuitBreaker))
+	}
+	cbCfgFor := func(key string) circuitbreaker.Config {
+		if c, ok := cbCfgs[key]; ok {
+			return c
+		}
+		return breakerConfig(newCfg.CircuitBreaker)
+	}
+
+	// Backends the reload adds, the default route's included, get a
+	// breaker; existing ones keep theirs, and their state, below.
+	oldBreakers := g.Breakers()
+	breakers := maps.Clone(oldBreakers)
+	breakerRoutes := newCfg.Routes
+	if dr := newCfg.DefaultRoute; dr != nil && dr.Backend != "" {
+		breakerRoutes = append(slices.Clip(breakerRoutes), dr.Route())
+	}
+	for _, route := range breakerRoutes {
+		key := route.BreakerKey()
+		if _, exists := breakers[key]; !exists {
+			breakers[key] = circuitbreaker.NewComposite(key, cbCfgFor(key), g.Logger, g.Metrics)
+			breakers[key].SetEvents(g.Events)
+			g.Logger.Info("circuit breaker created", "backend", route.Backend, "scope", route.BreakerScope)
+		}
+	}
+	// Routes and the default route go into one table, swapped in once; on
+	// error the gateway is left as it was.
+	defaultChanged := diff.Section("default_route")
+	if diff.Routes() || defaultChanged {
+		if err := g.Router.Update(newCfg.Routes, newCfg.DefaultRoute, breakers); err != nil {
+			return fmt.Errorf("updating routes: %w", err)
+		}
+	}
+	if defaultChanged {
+		g.Logger.Info("default route updated")
+	}
+
+	if diff.Section("rate_limit") || diff.Routes() {
+		g.Limiter.UpdateConfig(newCfg.RateLimit, newCfg.Routes)
+		g.Logger.Info("rate limiter config updated")
 	}
+	for backend, cb := range oldBreakers {
+		if cfg := cbCfgFor(backend); cfg != cb.Config() {
+			cb.UpdateConfig(cfg)
+			g.Logger.Info("circuit breaker config updated", "backend", backend)
+		}
+	}
+	if diff.Routes() || defaultChanged {
+		// The rest of the gateway that reads routes or breakers follows
+		// the router.
+		g.Health.UpdateRoutes(newCfg.Routes, breakers)
+		if g.Prober != nil {
+			g.Prober.UpdateTargets(newCfg.Routes, breakers)
+		}
+		if g.Admin != nil {
+			g.Admin.UpdateRoutes(newCfg.Routes, breakers)
+		}
+	}
+	g.breakers.Store(&breakers)
 	g.routesRef.Store(newCfg.Routes)
+	g.applied = newCfg
 	return nil
 }
 
@@ -294,43 +523,146 @@ func (g *Gateway) Run(ctx context.Context) error {
 	g.Reloader.Start()
 	defer g.Reloader.Stop()
 	defer g.Limiter.Close()
+	if g.AdminLimiter != nil {
+		defer g.AdminLimiter.Close()
+	}
 	if g.certLoader != nil {
 		defer g.certLoader.Stop()
 	}
+	if g.Prober != nil {
+		g.Prober.Start()
+		defer g.Prober.Stop()
+	}
+
+	internalErr := make(chan error, len(g.InternalServers))
+	for _, srv := range g.InternalServers {
+		go func() {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				internalErr <- err
+				return
+			}
+			g.Logger.Info("starting internal admin/metrics listener", "addr", srv.Addr)
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				internalErr <- err
+			}
+		}()
+	}
+	// Closing after a graceful Shutdown is a no-op; this covers the early
+	// returns below.
+	defer func() {
+		for _, srv := range g.InternalServers {
+			_ = srv.Close()
+		}
+	}()
 
 	serverErr := make(chan error, 1)
 	go func() {
+		defer close(serverErr)
+		ln, err := g.listen()
+		if err != nil {
+			serverErr <- err
+			return
+		}
 		if g.Config.Server.TLS.Enabled {
 			g.Logger.Info("starting gateway with TLS",
 				"addr", g.Server.Addr,
 				"min_tls", g.Config.Server.TLS.MinVersion,
 			)
-			err := g.Server.ListenAndServeTLS("", "")
-			if !errors.Is(err, http.ErrServerClosed) {
-				serverErr <- err
-			}
+			err = g.serveTLS(ln)
 		} else {
 			g.Logger.Info("starting gateway", "addr", g.Server.Addr)
-			err := g.Server.ListenAndServe()
-			if !errors.Is(err, http.ErrServerClosed) {
-				serverErr <- err
-			}
+			err = g.Server.Serve(ln)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
-		close(serverErr)
 	}()
 
 	select {
 	case err := <-serverErr:
 		return err
+	case err := <-internalErr:
+		_ = g.Server.Close()
+		return fmt.Errorf("internal listener: %w", err)
 	case <-ctx.Done():
 	}
 
+	g.draining.Store(true)
+	g.Health.StartDrain()
+	if delay := g.Config.Readiness.PreStopDelay; delay > 0 {
+		// Pre-stop window: readiness fails so the load balancer
+		// deregisters us, while liveness passes and requests still flow.
+		g.Logger.Info("readiness failing for pre-stop drain", "delay", delay)
+		select {
+		case err := <-serverErr:
+			return err
+		case <-time.After(delay):
+		}
+	}
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), g.Config.Server.ShutdownTimeout)
 	defer cancel()
 	g.Logger.Info("draining in-flight requests", "timeout", g.Config.Server.ShutdownTimeout)
-	if err := g.Server.Shutdown(shutdownCtx); err != nil {
-		return fmt.Errorf("forced shutdown: %w", err)
+	// End /admin/events streams, which would otherwise never drain.
+	g.Events.Close()
+	shutdownErr := g.Server.Shutdown(shutdownCtx)
+	// Internal listeners stop last so metrics and admin stay reachable
+	// while proxy traffic drains.
+	for _, srv := range g.InternalServers {
+		if err := srv.Shutdown(shutdownCtx); err != nil && shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+	if shutdownErr != nil {
+		return fmt.Errorf("forced shutdown: %w", shutdownErr)
 	}
 	g.Logger.Info("gateway stopped gracefully")
 	return nil
 }
+
+// listen binds the server's TCP listener, wrapped to count connections (and
+// cap them at server.max_connections), with the global accept
+// rate limit when server.accept_limit is configured and with PROXY protocol
+// parsing when server.proxy_protocol is enabled. The accept limit sees the
+// balancer's connections; PROXY parsing runs lazily on each connection's
+// serve goroutine so it never stalls the accept loop.
+func (g *Gateway) listen() (net.Listener, error) {
+	ln, err := net.Listen("tcp", g.Server.Addr)
+	if err != nil {
+		return nil, err
+	}
+	ln = ratelimit.NewConnLimitListener(ln, g.Config.Server.MaxConnections, g.Metrics)
+	al := g.Config.Server.AcceptLimit
+	if al.ConnectionsPerSecond > 0 {
+		ln = ratelimit.NewAcceptListener(ln, al.ConnectionsPerSecond, al.Burst, al.Mode == "reject", g.Logger, g.Metrics)
+		g.Logger.Info("global accept rate limit enabled",
+			"connections_per_second", al.ConnectionsPerSecond, "burst", al.Burst, "mode", al.Mode)
+	}
+	if pp := g.Config.Server.ProxyProtocol; pp.Enabled {
+		trusted := make([]*net.IPNet, 0, len(pp.TrustedCIDRs))
+		for _, cidr := range pp.TrustedCIDRs {
+			if _, n, err := net.ParseCIDR(cidr); err == nil {
+				trusted = append(trusted, n)
+			}
+		}
+		ln = proxyproto.NewListener(ln, trusted, pp.HeaderTimeout, g.Logger)
+		g.Logger.Info("PROXY protocol enabled", "trusted_cidrs", pp.TrustedCIDRs)
+	}
+	return ln, nil
+}
+
+// serveTLS serves TLS on ln. With server.tls.max_concurrent_handshakes
+// set, handshakes run in a bounded listener wrapper instead of on each
+// connection's serve goroutine, so a connection storm cannot fan out into
+// unbounded concurrent handshakes.
+func (g *Gateway) serveTLS(ln net.Listener) error {
+	tlsCfg := g.Config.Server.TLS
+	if tlsCfg.MaxConcurrentHandshakes == 0 {
+		return g.Server.ServeTLS(ln, "", "")
+	}
+	// ServeTLS would add these itself; Serve on pre-handshaken conns needs
+	// them set up front so HTTP/2 is still negotiated.
+	g.Server.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+	return g.Server.Serve(tlsutil.NewHandshakeLimitListener(ln, g.Server.TLSConfig,
+		tlsCfg.MaxConcurrentHandshakes, tlsCfg.HandshakeTimeout, g.Logger))
+}
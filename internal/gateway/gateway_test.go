@@ -1,13 +1,21 @@
 package gateway
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/dskow/gateway-core/internal/admin"
 	"github.com/dskow/gateway-core/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -156,3 +164,632 @@ func newTestGatewayWithRegistry(t *testing.T, reg *prometheus.Registry) (*Gatewa
 	t.Cleanup(gw.Limiter.Close)
 	return gw, upstream
 }
+
+func TestNewGateway_FailFastOnUnreachableBackend(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			MaxBodyBytes:        1 << 20,
+			FailFastOnStartup:   true,
+			StartupCheckTimeout: time.Second,
+		},
+		Metrics:   config.MetricsConfig{Path: "/metrics"},
+		Logging:   config.LoggingConfig{Output: "stdout"},
+		RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+		CircuitBreaker: config.CircuitBreakerConfig{
+			WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+		},
+		Routes: []config.RouteConfig{
+			{PathPrefix: "/up", Backend: up.URL, TimeoutMs: 5000},
+			{PathPrefix: "/down", Backend: downURL, TimeoutMs: 5000},
+		},
+	}
+
+	_, err := NewGateway(context.Background(), cfg, slog.Default(), Options{
+		Registerer: prometheus.NewRegistry(),
+		Gatherer:   prometheus.NewRegistry(),
+	})
+	if err == nil {
+		t.Fatal("expected NewGateway to fail with an unreachable backend")
+	}
+	if !strings.Contains(err.Error(), downURL) {
+		t.Errorf("expected error to name %s, got %v", downURL, err)
+	}
+	if strings.Contains(err.Error(), up.URL+" ") {
+		t.Errorf("expected reachable backend not to be listed, got %v", err)
+	}
+
+	cfg.Routes = cfg.Routes[:1]
+	gw, err := NewGateway(context.Background(), cfg, slog.Default(), Options{
+		Registerer: prometheus.NewRegistry(),
+		Gatherer:   prometheus.NewRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("expected startup to succeed with reachable backends: %v", err)
+	}
+	gw.Limiter.Close()
+}
+
+func TestGateway_ProtectedMetricsRequiresAllowlistedIP(t *testing.T) {
+	gw, _ := newTestGateway(t, func(backend string) *config.Config {
+		return &config.Config{
+			Server:    config.ServerConfig{MaxBodyBytes: 1 << 20},
+			Metrics:   config.MetricsConfig{Path: "/metrics", Protected: true},
+			Admin:     config.AdminConfig{IPAllowlist: []string{"10.0.0.0/8"}},
+			Logging:   config.LoggingConfig{Output: "stdout"},
+			RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+			},
+			Routes: []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend, TimeoutMs: 5000},
+			},
+		}
+	})
+
+	for _, tc := range []struct {
+		remoteAddr string
+		wantStatus int
+	}{
+		{"192.0.2.10:4000", http.StatusForbidden},
+		{"10.1.2.3:4000", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = tc.remoteAddr
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("GET /metrics from %s: status = %d, want %d", tc.remoteAddr, rec.Code, tc.wantStatus)
+		}
+	}
+}
+
+func TestGateway_VersionHeader(t *testing.T) {
+	build := func(hide bool) *config.Config {
+		return &config.Config{
+			Server:    config.ServerConfig{MaxBodyBytes: 1 << 20, HideVersion: hide},
+			Metrics:   config.MetricsConfig{Path: "/metrics"},
+			Logging:   config.LoggingConfig{Output: "stdout"},
+			RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+			},
+			Routes: []config.RouteConfig{{PathPrefix: "/api", Backend: "http://127.0.0.1:1", TimeoutMs: 5000}},
+		}
+	}
+
+	for _, hide := range []bool{false, true} {
+		gw, err := NewGateway(context.Background(), build(hide), slog.Default(), Options{
+			Registerer: prometheus.NewRegistry(),
+			Gatherer:   prometheus.NewRegistry(),
+			Build:      admin.BuildInfo{Version: "v9.9.9", Commit: "deadbeef"},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+		gw.Limiter.Close()
+
+		got := rec.Header().Get("X-Gateway-Version")
+		switch {
+		case !hide && got != "v9.9.9":
+			t.Errorf("X-Gateway-Version = %q, want v9.9.9", got)
+		case hide && (got != "" || rec.Header().Get("Server") != ""):
+			t.Errorf("expected version headers hidden, got %v", rec.Header())
+		}
+	}
+}
+
+func TestGateway_ReadHeaderTimeout(t *testing.T) {
+	gw, _ := newTestGateway(t, func(upstreamURL string) *config.Config {
+		return &config.Config{
+			Server:    config.ServerConfig{MaxBodyBytes: 1 << 20, ReadHeaderTimeout: 3 * time.Second},
+			Metrics:   config.MetricsConfig{Path: "/metrics"},
+			Logging:   config.LoggingConfig{Output: "stdout"},
+			RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+			},
+			Routes: []config.RouteConfig{{PathPrefix: "/api", Backend: upstreamURL, TimeoutMs: 5000}},
+		}
+	})
+	if gw.Server.ReadHeaderTimeout != 3*time.Second {
+		t.Errorf("http.Server.ReadHeaderTimeout = %v, want 3s", gw.Server.ReadHeaderTimeout)
+	}
+}
+
+// Upgrade requests must reach the proxy through every middleware wrapper
+// with the connection still hijackable.
+func TestGateway_UpgradeThroughMiddlewareStack(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\npong\n")
+		_ = brw.Flush()
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Server:    config.ServerConfig{MaxBodyBytes: 1 << 20, GlobalTimeoutMs: 5000},
+		Metrics:   config.MetricsConfig{Path: "/metrics"},
+		Logging:   config.LoggingConfig{Output: "stdout", BodyLogging: true, MaxBodyLogBytes: 4096},
+		RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+		CircuitBreaker: config.CircuitBreakerConfig{
+			WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+		},
+		Routes: []config.RouteConfig{{PathPrefix: "/ws", Backend: upstream.URL, TimeoutMs: 5000, RetryAttempts: 1}},
+	}
+	gw, err := NewGateway(context.Background(), cfg, slog.Default(), Options{
+		Registerer: prometheus.NewRegistry(),
+		Gatherer:   prometheus.NewRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("NewGateway: %v", err)
+	}
+	defer gw.Limiter.Close()
+	srv := httptest.NewServer(gw.Handler())
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: gateway\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n"))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	if line, err := br.ReadString('\n'); err != nil || line != "pong\n" {
+		t.Errorf("expected pong through the tunnel, got %q (%v)", line, err)
+	}
+}
+
+// Pre-stop drain: once Run's context is canceled, readiness fails for
+// readiness.pre_stop_delay while liveness passes and routes still serve,
+// then the server shuts down.
+func TestGateway_PreStopDrainFailsReadinessOnly(t *testing.T) {
+	gw, _ := newTestGateway(t, func(backend string) *config.Config {
+		return &config.Config{
+			Server:    config.ServerConfig{Port: 0, ShutdownTimeout: time.Second},
+			Metrics:   config.MetricsConfig{Path: "/metrics"},
+			RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: 30 * time.Second, HalfOpenMax: 2,
+			},
+			Readiness: config.ReadinessConfig{Path: "/lb-ready", PreStopDelay: 300 * time.Millisecond},
+			Routes: []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend, TimeoutMs: 5000},
+			},
+		}
+	})
+
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	if got := status("/lb-ready"); got != http.StatusOK {
+		t.Fatalf("readiness before shutdown = %d, want 200", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- gw.Run(ctx) }()
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for status("/lb-ready") != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("readiness never failed after shutdown began")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := status("/health"); got != http.StatusOK {
+		t.Errorf("liveness during pre-stop drain = %d, want 200", got)
+	}
+	if got := status("/api/x"); got != http.StatusOK {
+		t.Errorf("proxied request during pre-stop drain = %d, want 200", got)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned before the pre-stop delay elapsed: %v", err)
+	default:
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return after the pre-stop delay")
+	}
+}
+
+// A client in rate_limit.bypass_cidrs is never rate limited, but still goes
+// through auth: past the burst it keeps getting 401, not 429.
+func TestGateway_RateLimitBypassStillAuthenticates(t *testing.T) {
+	gw, _ := newTestGateway(t, func(backend string) *config.Config {
+		return &config.Config{
+			Server:  config.ServerConfig{Port: 0},
+			Metrics: config.MetricsConfig{Path: "/metrics"},
+			RateLimit: config.RateLimitConfig{
+				RequestsPerSecond: 1, BurstSize: 1, BypassCIDRs: []string{"192.0.2.0/24"},
+			},
+			Auth: config.AuthConfig{Enabled: true, JWTSecret: "secret", Issuer: "test", Audience: "test"},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: 30 * time.Second, HalfOpenMax: 2,
+			},
+			Routes: []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend, TimeoutMs: 5000, AuthRequired: true},
+			},
+		}
+	})
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/x", nil) // RemoteAddr 192.0.2.1
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: status = %d, want 401", i, rec.Code)
+		}
+	}
+}
+
+// freeAddr returns a loopback address with a port that was free a moment
+// ago.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// With server.admin_port and server.metrics_port set, admin and metrics are
+// served only on the internal listener, the main port keeps proxy traffic,
+// and shutdown stops both.
+func TestGateway_InternalListenerServesAdminAndMetrics(t *testing.T) {
+	gw, _ := newTestGateway(t, func(backend string) *config.Config {
+		return &config.Config{
+			Server: config.ServerConfig{
+				Port: 8080, AdminPort: 9090, MetricsPort: 9090,
+				MaxBodyBytes: 1 << 20, ShutdownTimeout: time.Second,
+			},
+			Metrics:   config.MetricsConfig{Path: "/metrics"},
+			Admin:     config.AdminConfig{Enabled: true, IPAllowlist: []string{"127.0.0.1/32"}},
+			RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+			},
+			Routes: []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend, TimeoutMs: 5000},
+			},
+		}
+	})
+	if len(gw.InternalServers) != 1 {
+		t.Fatalf("InternalServers = %d, want 1 for equal admin and metrics ports", len(gw.InternalServers))
+	}
+	mainAddr, internalAddr := freeAddr(t), freeAddr(t)
+	gw.Server.Addr = mainAddr
+	gw.InternalServers[0].Addr = internalAddr
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- gw.Run(ctx) }()
+
+	// No keep-alives: a spare pooled connection the server has not read
+	// from would hold up Shutdown.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(addr, path string) int {
+		t.Helper()
+		var resp *http.Response
+		var err error
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if resp, err = client.Get("http://" + addr + path); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			t.Fatalf("GET %s%s: %v", addr, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tc := range []struct {
+		addr, path string
+		want       int
+	}{
+		{internalAddr, "/admin/status", http.StatusOK},
+		{internalAddr, "/metrics", http.StatusOK},
+		{internalAddr, "/health", http.StatusOK},
+		{mainAddr, "/api/x", http.StatusOK},
+		{mainAddr, "/health", http.StatusOK},
+	} {
+		if got := get(tc.addr, tc.path); got != tc.want {
+			t.Errorf("GET %s on %s = %d, want %d", tc.path, tc.addr, got, tc.want)
+		}
+	}
+	for _, path := range []string{"/admin/status", "/metrics"} {
+		if got := get(mainAddr, path); got == http.StatusOK {
+			t.Errorf("GET %s on the main port = 200, want it not served there", path)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+	if conn, err := net.Dial("tcp", internalAddr); err == nil {
+		conn.Close()
+		t.Error("internal listener still accepting after shutdown")
+	}
+}
+
+func TestGateway_AdminRateLimitThrottlesMetricsScrapes(t *testing.T) {
+	gw, _ := newTestGateway(t, func(backend string) *config.Config {
+		return &config.Config{
+			Server:    config.ServerConfig{MaxBodyBytes: 1 << 20},
+			Metrics:   config.MetricsConfig{Path: "/metrics"},
+			Admin:     config.AdminConfig{RequestsPerSecond: 1, BurstSize: 2},
+			RateLimit: config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: time.Second, HalfOpenMax: 1,
+			},
+			Routes: []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend, TimeoutMs: 5000},
+			},
+		}
+	})
+	t.Cleanup(gw.AdminLimiter.Close)
+
+	status := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for i := range 2 {
+		if got := status("192.0.2.10:4000"); got != http.StatusOK {
+			t.Fatalf("scrape %d within the burst = %d, want 200", i+1, got)
+		}
+	}
+	if got := status("192.0.2.10:4000"); got != http.StatusTooManyRequests {
+		t.Errorf("scrape past the burst = %d, want 429", got)
+	}
+	if got := status("192.0.2.11:4000"); got != http.StatusOK {
+		t.Errorf("scrape from another client = %d, want 200: the limit is per client", got)
+	}
+}
+
+// A reload that adds a route makes it reachable for the very next request,
+// without rebuilding the gateway, and keeps the existing routes working.
+func TestGateway_ReloadAddsRoute(t *testing.T) {
+	gw, upstream := newTestGateway(t, func(backend string) *config.Config {
+		return &config.Config{
+			Server:  config.ServerConfig{Port: 0},
+			Metrics: config.MetricsConfig{Path: "/metrics"},
+			RateLimit: config.RateLimitConfig{
+				RequestsPerSecond: 1000, BurstSize: 1000,
+			},
+			CircuitBreaker: config.CircuitBreakerConfig{
+				WindowSize: 10, FailureThreshold: 0.5, ResetTimeout: 30 * time.Second, HalfOpenMax: 2,
+			},
+			Routes: []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend, TimeoutMs: 5000},
+			},
+		}
+	})
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	if code := get("/orders/1"); code != http.StatusNotFound {
+		t.Fatalf("before reload: /orders/1 status = %d, want 404", code)
+	}
+
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	yaml := fmt.Sprintf(`
+routes:
+  - path_prefix: "/api"
+    backend: %q
+  - path_prefix: "/orders"
+    backend: %q
+`, upstream.URL, upstream.URL+"/v2")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gw.SetReloadPath(path)
+	if !gw.Reloader.Reload() {
+		t.Fatalf("reload failed: %+v", gw.Reloader.ReloadStatus())
+	}
+
+	if code := get("/orders/1"); code != http.StatusOK {
+		t.Errorf("after reload: /orders/1 status = %d, want 200", code)
+	}
+	if code := get("/api/users"); code != http.StatusOK {
+		t.Errorf("after reload: /api/users status = %d, want 200", code)
+	}
+
+	// Readiness follows the reload too.
+	rec := httptest.NewRecorder()
+	gw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready?nocache=1", nil))
+	var ready struct {
+		Backends map[string]string `json:"backends"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &ready); err != nil {
+		t.Fatalf("/ready body %q: %v", rec.Body.String(), err)
+	}
+	if _, ok := ready.Backends["/orders"]; !ok {
+		t.Errorf("/ready backends = %v, want /orders checked after reload", ready.Backends)
+	}
+}
+
+// A reload that changes only default_route applies it: unmatched requests
+// go from the static page to the new default backend, breaker included.
+func TestGateway_ReloadChangesDefaultRoute(t *testing.T) {
+	configYAML := func(backend, defaultRoute string) string {
+		return fmt.Sprintf(`
+routes:
+  - path_prefix: "/api"
+    backend: %q
+default_route:
+%s`, backend, defaultRoute)
+	}
+	gw, upstream := newTestGateway(t, func(backend string) *config.Config {
+		cfg, err := config.LoadFromBytes([]byte(configYAML(backend, "  status: 418\n  body: teapot\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	})
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	if code := get("/app/home"); code != http.StatusTeapot {
+		t.Fatalf("before reload: /app/home status = %d, want 418", code)
+	}
+
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	spa := upstream.URL + "/spa"
+	if err := os.WriteFile(path, []byte(configYAML(upstream.URL, fmt.Sprintf("  backend: %q\n", spa))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gw.SetReloadPath(path)
+	if !gw.Reloader.Reload() {
+		t.Fatalf("reload failed: %+v", gw.Reloader.ReloadStatus())
+	}
+
+	if code := get("/app/home"); code != http.StatusOK {
+		t.Errorf("after reload: /app/home status = %d, want 200 from the default backend", code)
+	}
+	if _, ok := gw.Breakers()[config.DefaultRouteConfig{Backend: spa}.Route().BreakerKey()]; !ok {
+		t.Error("no circuit breaker for the new default backend")
+	}
+}
+
+// A reload that changes nothing rate limiting depends on keeps clients'
+// buckets: a throttled client stays throttled. Changing the limits does
+// start it afresh.
+func TestGateway_ReloadKeepsRateLimitBucketsOnUnrelatedChange(t *testing.T) {
+	configYAML := func(backend string, burst int, sampleRate float64) string {
+		return fmt.Sprintf(`
+logging:
+  sample_rate: %v
+rate_limit:
+  requests_per_second: 0.01
+  burst_size: %d
+routes:
+  - path_prefix: "/api"
+    backend: %q
+`, sampleRate, burst, backend)
+	}
+	gw, upstream := newTestGateway(t, func(backend string) *config.Config {
+		cfg, err := config.LoadFromBytes([]byte(configYAML(backend, 1, 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	})
+	get := func() int {
+		rec := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+		return rec.Code
+	}
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	gw.SetReloadPath(path)
+	reload := func(burst int, sampleRate float64) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(configYAML(upstream.URL, burst, sampleRate)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if !gw.Reloader.Reload() {
+			t.Fatalf("reload failed: %+v", gw.Reloader.ReloadStatus())
+		}
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", code)
+	}
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want 429", code)
+	}
+
+	reload(1, 0.5)
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Errorf("after a logging-only reload: status = %d, want 429 (bucket kept)", code)
+	}
+
+	reload(2, 0.5)
+	if code := get(); code != http.StatusOK {
+		t.Errorf("after raising burst_size: status = %d, want 200 (new limits, new bucket)", code)
+	}
+}
+
+// A route's circuit_breaker block configures its backend's breaker; other
+// backends keep the global settings.
+func TestGateway_RouteCircuitBreakerOverride(t *testing.T) {
+	other := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(other.Close)
+	gw, upstream := newTestGateway(t, func(backend string) *config.Config {
+		cfg, err := config.LoadFromBytes([]byte(fmt.Sprintf(`
+circuit_breaker:
+  max_concurrent: 3
+routes:
+  - path_prefix: "/slow"
+    backend: %q
+    circuit_breaker:
+      max_concurrent: 1
+  - path_prefix: "/api"
+    backend: %q
+`, backend, other.URL)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	})
+
+	for _, tt := range []struct {
+		backend string
+		want    int
+	}{{upstream.URL, 1}, {other.URL, 3}} {
+		cb := gw.Breakers()[tt.backend]
+		admitted := 0
+		for i := 0; i < 5; i++ {
+			if cb.Allow() {
+				admitted++
+			}
+		}
+		if admitted != tt.want {
+			t.Errorf("breaker for %s admitted %d concurrent requests, want %d", tt.backend, admitted, tt.want)
+		}
+	}
+}
@@ -4,11 +4,16 @@ package health
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dskow/gateway-core/internal/circuitbreaker"
@@ -18,32 +23,96 @@ import (
 // Pre-serialized liveness response avoids json.Encoder allocation.
 var livenessBody = []byte(`{"status":"ok"}` + "\n")
 
-const readinessCacheTTL = 5 * time.Second
+// drainingBody is the default readiness response during the pre-stop
+// drain window.
+var drainingBody = []byte(`{"status":"draining"}` + "\n")
+
+// defaultReadinessCacheTTL applies until Configure sets readiness.cache_ttl.
+const defaultReadinessCacheTTL = 5 * time.Second
 
 // Handler provides /health and /ready endpoints.
 type Handler struct {
-	routes   []config.RouteConfig
-	breakers map[string]*circuitbreaker.CompositeBreaker
-	logger   *slog.Logger
+	targets atomic.Pointer[targets] // replaced by UpdateRoutes
+	logger  *slog.Logger
+
+	// Load-balancer integration (config.ReadinessConfig). Set by Configure
+	// before RegisterRoutes; nil bodies keep the JSON detail response.
+	readyPath     string
+	healthyBody   []byte
+	unhealthyBody []byte
+	// degraded selects readiness.mode "degraded": non-critical backends
+	// may be down, up to maxDownPercent of them, without failing.
+	degraded       bool
+	maxDownPercent float64
+
+	// draining fails readiness while liveness keeps passing; set by
+	// StartDrain at the beginning of the pre-stop window.
+	draining atomic.Bool
 
 	// Cached readiness result to avoid TCP-dialing every backend on
-	// every /ready poll. Protected by cacheMu.
+	// every /ready poll, reused for cacheTTL. Protected by cacheMu.
+	cacheTTL     time.Duration
 	cacheMu      sync.RWMutex
 	cachedResult []byte
 	cachedStatus int
 	cachedAt     time.Time
 }
 
-// New creates a new health check Handler. breakers maps backend URLs to
-// their circuit breaker instances (it may be nil for backends without breakers).
+// targets are the routes readiness checks and their breakers.
+type targets struct {
+	routes   []config.RouteConfig
+	breakers map[string]*circuitbreaker.CompositeBreaker
+}
+
+// New creates a new health check Handler. breakers maps RouteConfig.BreakerKey
+// values to their circuit breaker instances (it may be nil for backends without breakers).
 func New(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker, logger *slog.Logger) *Handler {
-	return &Handler{routes: routes, breakers: breakers, logger: logger}
+	h := &Handler{logger: logger, readyPath: "/ready", cacheTTL: defaultReadinessCacheTTL}
+	h.targets.Store(&targets{routes: routes, breakers: breakers})
+	return h
+}
+
+// UpdateRoutes makes readiness check routes, with breakers, from now on,
+// and drops the cached result. Safe to call while serving; a config reload
+// uses it.
+func (h *Handler) UpdateRoutes(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) {
+	h.targets.Store(&targets{routes: routes, breakers: breakers})
+	h.cacheMu.Lock()
+	h.cachedResult = nil
+	h.cacheMu.Unlock()
+}
+
+// Configure applies the readiness path and response bodies from cfg. Call
+// it before RegisterRoutes.
+func (h *Handler) Configure(cfg config.ReadinessConfig) {
+	if cfg.Path != "" {
+		h.readyPath = cfg.Path
+	}
+	if cfg.HealthyBody != "" {
+		h.healthyBody = []byte(cfg.HealthyBody)
+	}
+	if cfg.UnhealthyBody != "" {
+		h.unhealthyBody = []byte(cfg.UnhealthyBody)
+	}
+	if cfg.CacheTTL > 0 {
+		h.cacheTTL = cfg.CacheTTL
+	}
+	h.degraded = cfg.Mode == "degraded"
+	h.maxDownPercent = cfg.MaxDownPercent
 }
 
+// ReadyPath returns the path the readiness probe is served on.
+func (h *Handler) ReadyPath() string { return h.readyPath }
+
+// StartDrain makes readiness report unhealthy from now on, regardless of
+// backend state, so a load balancer stops routing new traffic here.
+// Liveness is unaffected. There is no way back: it is called on shutdown.
+func (h *Handler) StartDrain() { h.draining.Store(true) }
+
 // RegisterRoutes adds health check routes to the given mux.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/health", h.liveness)
-	mux.HandleFunc("/ready", h.readiness)
+	mux.HandleFunc(h.readyPath, h.readiness)
 }
 
 func (h *Handler) liveness(w http.ResponseWriter, _ *http.Request) {
@@ -54,107 +123,149 @@ func (h *Handler) liveness(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-func (h *Handler) readiness(w http.ResponseWriter, r *http.Request) {
-	// Serve from cache if fresh.
-	h.cacheMu.RLock()
-	if h.cachedResult != nil && time.Since(h.cachedAt) < readinessCacheTTL {
-		body := h.cachedResult
-		status := h.cachedStatus
-		h.cacheMu.RUnlock()
+// writeReadiness writes a readiness response, substituting the configured
+// plain-text body for the JSON detail when one is set for status.
+func (h *Handler) writeReadiness(w http.ResponseWriter, status int, jsonBody []byte) {
+	custom := h.healthyBody
+	if status != http.StatusOK {
+		custom = h.unhealthyBody
+	}
+	body := jsonBody
+	if custom != nil {
+		body = custom
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		if _, err := w.Write(body); err != nil {
-			h.logger.Debug("health: failed to write cached readiness response", "error", err)
-		}
+	}
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		h.logger.Debug("health: failed to write readiness response", "error", err)
+	}
+}
+
+func (h *Handler) readiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		h.writeReadiness(w, http.StatusServiceUnavailable, drainingBody)
 		return
 	}
-	h.cacheMu.RUnlock()
+
+	// Serve from cache if fresh, unless ?nocache=1 asks for a fresh check
+	// (whose result is still cached).
+	if nocache, _ := strconv.ParseBool(r.URL.Query().Get("nocache")); !nocache {
+		h.cacheMu.RLock()
+		if h.cachedResult != nil && time.Since(h.cachedAt) < h.cacheTTL {
+			body := h.cachedResult
+			status := h.cachedStatus
+			h.cacheMu.RUnlock()
+			h.writeReadiness(w, status, body)
+			return
+		}
+		h.cacheMu.RUnlock()
+	}
 
 	type backendResult struct {
-		prefix  string
-		backend string
-		status  string
-		ok      bool
+		prefix   string
+		status   string
+		ok       bool
+		excluded bool
+		critical bool
 	}
 
-	ch := make(chan backendResult, len(h.routes))
-	for _, route := range h.routes {
+	t := h.targets.Load()
+	ch := make(chan backendResult, len(t.routes))
+	for _, route := range t.routes {
 		go func(route config.RouteConfig) {
+			if route.ExcludeFromReadiness {
+				ch <- backendResult{prefix: route.PathPrefix, status: excludedStatus(t.breakers[route.BreakerKey()]), ok: true, excluded: true}
+				return
+			}
+			send := func(status string, ok bool) {
+				ch <- backendResult{prefix: route.PathPrefix, status: status, ok: ok, critical: route.Critical}
+			}
+
 			// Fast path: use circuit breaker state if available.
 			// EffectiveState (not InnerState) so a saturated bulkhead flips
 			// readiness to unhealthy even when the failure-rate breaker is
 			// closed — a bulkhead at capacity is actively shedding load.
-			if cb, exists := h.breakers[route.Backend]; exists && cb != nil {
+			if cb := t.breakers[route.BreakerKey()]; cb != nil {
 				st := cb.EffectiveState()
 				switch st {
 				case circuitbreaker.StateOpen:
-					ch <- backendResult{prefix: route.PathPrefix, backend: route.Backend, status: "circuit-open", ok: false}
+					send("circuit-open", false)
 					return
 				case circuitbreaker.StateHalfOpen:
-					ch <- backendResult{prefix: route.PathPrefix, backend: route.Backend, status: "circuit-half-open", ok: true}
+					send("circuit-half-open", true)
 					return
 				default:
 					// StateClosed — fall through to TCP dial for definitive check.
 				}
 			}
 
-			u, err := url.Parse(route.Backend)
-			if err != nil {
-				ch <- backendResult{prefix: route.PathPrefix, backend: route.Backend, status: "invalid URL", ok: false}
+			if route.BackendTemplated() {
+				// No fixed host to dial; the breaker state is all we know.
+				send("ok", true)
 				return
 			}
-
-			host := u.Host
-			if !hasPort(host) {
-				switch u.Scheme {
-				case "https":
-					host += ":443"
-				default:
-					host += ":80"
-				}
+			host, err := backendHostPort(route.Backend)
+			if err != nil {
+				send("invalid URL", false)
+				return
 			}
 
 			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
-			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+			err = dialHost(ctx, host, h.logger)
 			cancel()
 
 			if err != nil {
 				h.logger.Warn("backend unreachable", "route", route.PathPrefix, "backend", route.Backend, "error", err)
-				ch <- backendResult{prefix: route.PathPrefix, backend: route.Backend, status: "unreachable", ok: false}
+				send("unreachable", false)
 				return
 			}
-			if cerr := conn.Close(); cerr != nil {
-				h.logger.Debug("health: failed to close probe connection", "backend", route.Backend, "error", cerr)
-			}
-			ch <- backendResult{prefix: route.PathPrefix, backend: route.Backend, status: "ok", ok: true}
+			send("ok", true)
 		}(route)
 	}
 
 	// Collect results and group by backend to determine readiness.
 	// New logic: 503 only when ALL backends for any given route are down.
 	// (Currently each route maps to one backend, but this is forward-compatible.)
-	results := make(map[string]string, len(h.routes))
-	anyRouteFullyDown := false
+	// In degraded mode, 503 only when a critical backend is down or more
+	// than maxDownPercent of the checked (non-excluded) ones are.
+	results := make(map[string]string, len(t.routes))
+	var checked, down int
+	criticalDown := false
 
-	for range h.routes {
+	for range t.routes {
 		res := <-ch
 		results[res.prefix] = res.status
+		if res.excluded {
+			continue
+		}
+		checked++
 		if !res.ok {
-			anyRouteFullyDown = true
+			down++
+			criticalDown = criticalDown || res.critical
 		}
 	}
 
 	httpStatus := http.StatusOK
 	statusStr := "ready"
-	if anyRouteFullyDown {
+	switch {
+	case down == 0:
+	case h.degraded && !criticalDown && float64(down)*100 <= h.maxDownPercent*float64(checked):
+		statusStr = "degraded"
+	default:
 		httpStatus = http.StatusServiceUnavailable
 		statusStr = "not ready"
 	}
 
-	body, _ := json.Marshal(map[string]interface{}{
+	detail := map[string]interface{}{
 		"status":   statusStr,
 		"backends": results,
-	})
+	}
+	if h.degraded {
+		detail["degraded"] = statusStr == "degraded"
+	}
+	body, _ := json.Marshal(detail)
 	body = append(body, '\n')
 
 	// Cache the result.
@@ -164,11 +275,98 @@ func (h *Handler) readiness(w http.ResponseWriter, r *http.Request) {
 	h.cachedAt = time.Now()
 	h.cacheMu.Unlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(httpStatus)
-	if _, err := w.Write(body); err != nil {
-		h.logger.Debug("health: failed to write readiness response", "error", err)
+	h.writeReadiness(w, httpStatus, body)
+}
+
+// excludedStatus describes a route excluded from readiness in the detail
+// response: "excluded", plus the state of its breaker cb, which may be
+// nil, when it is not closed. The backend is never dialed.
+func excludedStatus(cb *circuitbreaker.CompositeBreaker) string {
+	if cb == nil {
+		return "excluded"
+	}
+	switch cb.EffectiveState() {
+	case circuitbreaker.StateOpen:
+		return "excluded (circuit-open)"
+	case circuitbreaker.StateHalfOpen:
+		return "excluded (circuit-half-open)"
+	}
+	return "excluded"
+}
+
+// CheckBackends dials every distinct backend referenced by routes and
+// returns an error naming each one that could not be reached within
+// timeout. Used by server.fail_fast_on_startup to refuse to start with a
+// mistyped or missing backend. Templated backends have no fixed host and
+// are skipped, as are those of exclude_from_readiness routes, which must
+// not keep the gateway from starting either.
+func CheckBackends(ctx context.Context, routes []config.RouteConfig, timeout time.Duration, logger *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		backend string
+		err     error
+	}
+	seen := make(map[string]bool, len(routes))
+	ch := make(chan result, len(routes))
+	for _, route := range routes {
+		if seen[route.Backend] || route.BackendTemplated() || route.ExcludeFromReadiness {
+			continue
+		}
+		seen[route.Backend] = true
+		go func(backend string) {
+			host, err := backendHostPort(backend)
+			if err == nil {
+				err = dialHost(ctx, host, logger)
+			}
+			ch <- result{backend: backend, err: err}
+		}(route.Backend)
+	}
+
+	var failed []string
+	for range seen {
+		if res := <-ch; res.err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", res.backend, res.err))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("unreachable backends: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// dialHost opens and immediately closes a TCP connection to host. Shared by
+// the readiness probe and the active health checker.
+func dialHost(ctx context.Context, host string, logger *slog.Logger) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	if cerr := conn.Close(); cerr != nil {
+		logger.Debug("health: failed to close probe connection", "host", host, "error", cerr)
+	}
+	return nil
+}
+
+// backendHostPort returns the host:port to dial for a backend URL, filling
+// in the scheme's default port when none is given.
+func backendHostPort(backend string) (string, error) {
+	u, err := url.Parse(backend)
+	if err != nil {
+		return "", err
+	}
+	host := u.Host
+	if !hasPort(host) {
+		switch u.Scheme {
+		case "https":
+			host += ":443"
+		default:
+			host += ":80"
+		}
 	}
+	return host, nil
 }
 
 func hasPort(host string) bool {
@@ -1,11 +1,14 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dskow/gateway-core/internal/config"
 )
@@ -117,3 +120,249 @@ func TestReadiness_JSONResponse(t *testing.T) {
 		t.Errorf("expected application/json, got %q", ct)
 	}
 }
+
+func TestReadiness_ConfiguredPathAndBodies(t *testing.T) {
+	h := New(nil, nil, slog.Default())
+	h.Configure(config.ReadinessConfig{Path: "/lb/ready", HealthyBody: "UP", UnhealthyBody: "DOWN"})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/lb/ready", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "UP" {
+		t.Errorf("ready: got %d %q, want 200 \"UP\"", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain for a configured body, got %q", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("default /ready still served after path change: %d", rec.Code)
+	}
+}
+
+func TestReadiness_FailsDuringDrainWhileLivenessPasses(t *testing.T) {
+	h := New(nil, nil, slog.Default())
+	h.Configure(config.ReadinessConfig{UnhealthyBody: "DOWN"})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	// Warm the cache with a healthy result; draining must override it.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ready before drain: got %d, want 200", rec.Code)
+	}
+
+	h.StartDrain()
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "DOWN" {
+		t.Errorf("ready while draining: got %d %q, want 503 \"DOWN\"", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("liveness while draining: got %d, want 200", rec.Code)
+	}
+}
+
+func TestReadiness_ExcludedBackendDoesNotFailReadiness(t *testing.T) {
+	routes := []config.RouteConfig{
+		{PathPrefix: "/partner", Backend: "http://localhost:19999", ExcludeFromReadiness: true}, // nothing listening
+	}
+
+	h := New(routes, nil, slog.Default())
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 with only an excluded backend down, got %d", rec.Code)
+	}
+	var body struct {
+		Status   string            `json:"status"`
+		Backends map[string]string `json:"backends"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "ready" {
+		t.Errorf("expected ready, got %q", body.Status)
+	}
+	if got := body.Backends["/partner"]; got != "excluded" {
+		t.Errorf("expected the excluded backend listed as \"excluded\", got %q", got)
+	}
+}
+
+func TestCheckBackends_SkipsExcludedBackends(t *testing.T) {
+	routes := []config.RouteConfig{
+		{PathPrefix: "/partner", Backend: "http://localhost:19999", ExcludeFromReadiness: true}, // nothing listening
+	}
+	if err := CheckBackends(context.Background(), routes, time.Second, slog.Default()); err != nil {
+		t.Errorf("CheckBackends with only an excluded backend down: %v", err)
+	}
+
+	// A backend also used by a route that is not excluded is still dialed.
+	routes = append(routes, config.RouteConfig{PathPrefix: "/own", Backend: "http://localhost:19999"})
+	if err := CheckBackends(context.Background(), routes, time.Second, slog.Default()); err == nil {
+		t.Error("CheckBackends passed with a non-excluded backend down")
+	}
+}
+
+// UpdateRoutes changes the backends readiness checks and drops the cached
+// result, so a route added by a reload counts at once.
+func TestReadiness_UpdateRoutes(t *testing.T) {
+	h := New(nil, nil, slog.Default())
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+		return rec.Code
+	}
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("with no routes: status = %d, want 200", code)
+	}
+
+	h.UpdateRoutes([]config.RouteConfig{{PathPrefix: "/orders", Backend: "http://localhost:19999"}}, nil) // nothing listening
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("after adding a route to a down backend: status = %d, want 503", code)
+	}
+}
+
+func TestReadiness_Modes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	tests := []struct {
+		name       string
+		mode       string
+		critical   bool
+		wantCode   int
+		wantStatus string
+	}{
+		{"strict fails on any backend down", "strict", false, http.StatusServiceUnavailable, "not ready"},
+		{"degraded tolerates a non-critical backend down", "degraded", false, http.StatusOK, "degraded"},
+		{"degraded fails on a critical backend down", "degraded", true, http.StatusServiceUnavailable, "not ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := []config.RouteConfig{
+				{PathPrefix: "/api", Backend: backend.URL},
+				{PathPrefix: "/search", Backend: "http://localhost:19999", Critical: tt.critical}, // nothing listening
+			}
+			h := New(routes, nil, slog.Default())
+			h.Configure(config.ReadinessConfig{Mode: tt.mode, MaxDownPercent: 50})
+			mux := http.NewServeMux()
+			h.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, rec.Code)
+			}
+			var body struct {
+				Status   string            `json:"status"`
+				Degraded *bool             `json:"degraded"`
+				Backends map[string]string `json:"backends"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, body.Status)
+			}
+			if tt.mode == "degraded" && (body.Degraded == nil || *body.Degraded != (tt.wantStatus == "degraded")) {
+				t.Errorf("expected degraded flag %v, got %v", tt.wantStatus == "degraded", body.Degraded)
+			}
+			if body.Backends["/api"] != "ok" || body.Backends["/search"] != "unreachable" {
+				t.Errorf("expected per-backend status, got %v", body.Backends)
+			}
+		})
+	}
+}
+
+func TestReadiness_DegradedFailsPastMaxDownPercent(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL},
+		{PathPrefix: "/search", Backend: "http://localhost:19999"},
+		{PathPrefix: "/reports", Backend: "http://localhost:19998"},
+	}
+	h := New(routes, nil, slog.Default())
+	h.Configure(config.ReadinessConfig{Mode: "degraded", MaxDownPercent: 50})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with 2 of 3 backends down, got %d", rec.Code)
+	}
+}
+
+func TestReadiness_Cache(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		wait     time.Duration
+		query    string
+		wantCode int
+	}{
+		{"cached within ttl", time.Minute, 0, "", http.StatusOK},
+		{"nocache forces a fresh check", time.Minute, 0, "?nocache=1", http.StatusServiceUnavailable},
+		{"configured ttl expires", 20 * time.Millisecond, 30 * time.Millisecond, "", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			routes := []config.RouteConfig{{PathPrefix: "/api", Backend: backend.URL}}
+			h := New(routes, nil, slog.Default())
+			h.Configure(config.ReadinessConfig{CacheTTL: tt.ttl})
+			mux := http.NewServeMux()
+			h.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200 while the backend is up, got %d", rec.Code)
+			}
+
+			backend.Close()
+			time.Sleep(tt.wait)
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready"+tt.query, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected %d after the backend went down, got %d", tt.wantCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestReadiness_NocacheUpdatesTheCache(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	routes := []config.RouteConfig{{PathPrefix: "/api", Backend: backend.URL}}
+	h := New(routes, nil, slog.Default())
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	backend.Close()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready?nocache=1", nil))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the nocache result to be cached, got %d", rec.Code)
+	}
+}
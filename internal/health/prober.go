@@ -0,0 +1,191 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/circuitbreaker"
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// Prober actively probes backends whose circuit breaker is open. The
+// failure-rate breaker only learns from live traffic, so a low-traffic route
+// whose backend has recovered can sit open until a real request happens to
+// land on the half-open probe. Prober closes the breaker once the backend has
+// answered HealthyThreshold consecutive probes.
+type Prober struct {
+	cfg    config.HealthCheckConfig
+	client *http.Client
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	targets   map[string]string // breaker key → backend URL; replaced by UpdateTargets
+	breakers  map[string]*circuitbreaker.CompositeBreaker
+	successes map[string]int // consecutive successful probes per breaker key
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewProber creates a Prober for every breaker referenced by routes, except
+// those of templated backends, which have no fixed host to probe. Call
+// Start to begin probing and Stop to terminate the background loop.
+func NewProber(cfg config.HealthCheckConfig, routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker, logger *slog.Logger) *Prober {
+	targets := probeTargets(routes, breakers)
+	return &Prober{
+		cfg:       cfg,
+		targets:   targets,
+		breakers:  breakers,
+		client:    &http.Client{Timeout: cfg.Timeout},
+		logger:    logger,
+		successes: make(map[string]int, len(targets)),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// probeTargets maps the key of each breaker in breakers that routes use
+// to the backend it guards, leaving out templated backends.
+func probeTargets(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) map[string]string {
+	targets := make(map[string]string, len(breakers))
+	for _, route := range routes {
+		if key := route.BreakerKey(); breakers[key] != nil && !route.BackendTemplated() {
+			targets[key] = route.Backend
+		}
+	}
+	return targets
+}
+
+// UpdateTargets makes the Prober probe the backends of routes, with
+// breakers, from the next round on. Backends it already probed keep their
+// success streak. Safe to call while probing; a config reload uses it.
+func (p *Prober) UpdateTargets(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) {
+	targets := probeTargets(routes, breakers)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets, p.breakers = targets, breakers
+	for key := range p.successes {
+		if _, ok := targets[key]; !ok {
+			delete(p.successes, key)
+		}
+	}
+}
+
+// Start launches the probe loop in a background goroutine.
+func (p *Prober) Start() {
+	go p.loop()
+	p.logger.Info("active health checker started",
+		"type", p.cfg.Type, "interval", p.cfg.Interval, "healthy_threshold", p.cfg.HealthyThreshold)
+}
+
+// Stop terminates the probe loop and waits for it to exit. Safe to call
+// more than once.
+func (p *Prober) Stop() {
+	select {
+	case <-p.stopCh:
+	default:
+		close(p.stopCh)
+	}
+	<-p.doneCh
+}
+
+func (p *Prober) loop() {
+	defer close(p.doneCh)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.probeOnce()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// probeOnce probes every backend whose breaker is currently open, in
+// parallel, and closes breakers that have reached the healthy threshold.
+// Backends with a closed or half-open breaker have their success streak
+// cleared so a later trip starts counting from zero.
+func (p *Prober) probeOnce() {
+	p.mu.Lock()
+	targets, breakers := p.targets, p.breakers
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for key, backend := range targets {
+		cb := breakers[key]
+		if cb == nil {
+			continue
+		}
+		if cb.InnerState() != circuitbreaker.StateOpen {
+			p.mu.Lock()
+			delete(p.successes, key)
+			p.mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(key, backend string, cb *circuitbreaker.CompositeBreaker) {
+			defer wg.Done()
+			err := p.probe(backend)
+
+			p.mu.Lock()
+			if err != nil {
+				delete(p.successes, key)
+				p.mu.Unlock()
+				p.logger.Debug("active health check failed", "backend", backend, "error", err)
+				return
+			}
+			p.successes[key]++
+			healthy := p.successes[key] >= p.cfg.HealthyThreshold
+			if healthy {
+				delete(p.successes, key)
+			}
+			p.mu.Unlock()
+
+			if healthy {
+				p.logger.Info("active health check passed, closing circuit breaker", "backend", backend)
+				cb.Reset()
+			}
+		}(key, backend, cb)
+	}
+	wg.Wait()
+}
+
+// probe performs a single TCP or HTTP check against backend. HTTP probes
+// treat any status below 500 as healthy — a 404 on the probe path still
+// proves the backend is serving.
+func (p *Prober) probe(backend string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+	defer cancel()
+
+	if p.cfg.Type != "http" {
+		host, err := backendHostPort(backend)
+		if err != nil {
+			return err
+		}
+		return dialHost(ctx, host, p.logger)
+	}
+
+	target := strings.TrimRight(backend, "/") + p.cfg.Path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if cerr := resp.Body.Close(); cerr != nil {
+		p.logger.Debug("health: failed to close probe response body", "backend", backend, "error", cerr)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("probe returned status %d", resp.StatusCode)
+	}
+	return nil
+}
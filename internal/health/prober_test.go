@@ -0,0 +1,128 @@
+package health
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/circuitbreaker"
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// openBreaker returns a composite breaker for backend that has already
+// tripped open and will not move to half-open on its own during the test.
+func openBreaker(t *testing.T, backend string) *circuitbreaker.CompositeBreaker {
+	t.Helper()
+	cb := circuitbreaker.NewComposite(backend, circuitbreaker.Config{
+		WindowSize:       2,
+		FailureThreshold: 0.5,
+		ResetTimeout:     time.Hour,
+		HalfOpenMax:      1,
+	}, slog.Default(), nil)
+	cb.RecordFailure(time.Millisecond)
+	cb.RecordFailure(time.Millisecond)
+	if cb.InnerState() != circuitbreaker.StateOpen {
+		t.Fatalf("expected breaker to be open, got %s", cb.InnerState())
+	}
+	return cb
+}
+
+func TestProber_ClosesOpenBreakerAfterHealthyProbes(t *testing.T) {
+	for _, probeType := range []string{"tcp", "http"} {
+		t.Run(probeType, func(t *testing.T) {
+			var probed string
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				probed = r.URL.Path
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer backend.Close()
+
+			cb := openBreaker(t, backend.URL)
+			breakers := map[string]*circuitbreaker.CompositeBreaker{backend.URL: cb}
+			routes := []config.RouteConfig{{PathPrefix: "/api", Backend: backend.URL}}
+
+			p := NewProber(config.HealthCheckConfig{
+				Enabled:          true,
+				Type:             probeType,
+				Path:             "/healthz",
+				Interval:         time.Hour,
+				Timeout:          time.Second,
+				HealthyThreshold: 2,
+			}, routes, breakers, slog.Default())
+
+			p.probeOnce()
+			if cb.InnerState() != circuitbreaker.StateOpen {
+				t.Fatalf("expected breaker to stay open after one probe, got %s", cb.InnerState())
+			}
+
+			p.probeOnce()
+			if cb.InnerState() != circuitbreaker.StateClosed {
+				t.Errorf("expected breaker closed after healthy probes, got %s", cb.InnerState())
+			}
+			if probeType == "http" && probed != "/healthz" {
+				t.Errorf("expected probe on /healthz, got %q", probed)
+			}
+		})
+	}
+}
+
+func TestProber_UnhealthyBackendStaysOpen(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	cb := openBreaker(t, backend.URL)
+	breakers := map[string]*circuitbreaker.CompositeBreaker{backend.URL: cb}
+	routes := []config.RouteConfig{{PathPrefix: "/api", Backend: backend.URL}}
+
+	p := NewProber(config.HealthCheckConfig{
+		Enabled:          true,
+		Type:             "http",
+		Path:             "/",
+		Interval:         time.Hour,
+		Timeout:          time.Second,
+		HealthyThreshold: 1,
+	}, routes, breakers, slog.Default())
+
+	for i := 0; i < 3; i++ {
+		p.probeOnce()
+	}
+	if cb.InnerState() != circuitbreaker.StateOpen {
+		t.Errorf("expected breaker to stay open, got %s", cb.InnerState())
+	}
+}
+
+// After UpdateTargets the Prober probes the backends of the new routes,
+// not those of the routes it was built with.
+func TestProber_UpdateTargets(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	p := NewProber(config.HealthCheckConfig{
+		Enabled:          true,
+		Type:             "tcp",
+		Interval:         time.Hour,
+		Timeout:          time.Second,
+		HealthyThreshold: 1,
+	}, nil, nil, slog.Default())
+
+	cb := openBreaker(t, backend.URL)
+	p.UpdateTargets(
+		[]config.RouteConfig{{PathPrefix: "/added", Backend: backend.URL}},
+		map[string]*circuitbreaker.CompositeBreaker{backend.URL: cb},
+	)
+	p.probeOnce()
+	if cb.InnerState() != circuitbreaker.StateClosed {
+		t.Errorf("breaker of a route added by UpdateTargets = %s, want closed after a healthy probe", cb.InnerState())
+	}
+}
+
+func TestProber_StartStop(t *testing.T) {
+	p := NewProber(config.HealthCheckConfig{Interval: time.Millisecond, Timeout: time.Second, HealthyThreshold: 1}, nil, nil, slog.Default())
+	p.Start()
+	p.Stop()
+	p.Stop() // idempotent
+}
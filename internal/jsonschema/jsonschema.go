@@ -0,0 +1,403 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema used for request body checks at the gateway: type, enum, const,
+// the object, array, string and number constraints, and allOf, anyOf,
+// oneOf and not. References ($ref), format and the remaining keywords are
+// not implemented; Compile rejects them rather than ignore them, so a
+// schema never silently checks less than it says.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// annotations are keywords that carry no validation and are accepted as-is.
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+// Schema is a compiled schema. The zero value accepts any document.
+type Schema struct {
+	reject bool // the boolean schema false
+
+	types    []string
+	enum     []any
+	hasConst bool
+	constVal any
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema // nil: any additional property is allowed
+
+	items              *Schema
+	minItems, maxItems int // maxItems -1: unbounded
+
+	minLength, maxLength int // maxLength -1: unbounded
+	pattern              *regexp.Regexp
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+
+	allOf, anyOf, oneOf []*Schema
+	not                 *Schema
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	return compile(v, "")
+}
+
+func compile(v any, at string) (*Schema, error) {
+	switch v := v.(type) {
+	case bool:
+		return &Schema{reject: !v, maxItems: -1, maxLength: -1}, nil
+	case map[string]any:
+		s := &Schema{maxItems: -1, maxLength: -1}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if annotations[k] {
+				continue
+			}
+			if err := s.keyword(k, v[k], at+"/"+k); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("%s: schema must be an object or a boolean", pointer(at))
+}
+
+// keyword compiles one keyword of an object schema into s.
+func (s *Schema) keyword(k string, v any, at string) error {
+	var err error
+	switch k {
+	case "type":
+		s.types, err = typeList(v, at)
+	case "enum":
+		list, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an array", pointer(at))
+		}
+		s.enum = list
+	case "const":
+		s.hasConst, s.constVal = true, v
+	case "properties":
+		props, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an object", pointer(at))
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			if s.properties[name], err = compile(sub, at+"/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	case "required":
+		s.required, err = stringList(v, at)
+	case "additionalProperties":
+		s.additionalProperties, err = compile(v, at)
+	case "items":
+		s.items, err = compile(v, at)
+	case "minItems":
+		s.minItems, err = count(v, at)
+	case "maxItems":
+		s.maxItems, err = count(v, at)
+	case "minLength":
+		s.minLength, err = count(v, at)
+	case "maxLength":
+		s.maxLength, err = count(v, at)
+	case "pattern":
+		p, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", pointer(at))
+		}
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return fmt.Errorf("%s: %w", pointer(at), err)
+		}
+	case "minimum":
+		s.minimum, err = number(v, at)
+	case "maximum":
+		s.maximum, err = number(v, at)
+	case "exclusiveMinimum":
+		s.exclusiveMinimum, err = number(v, at)
+	case "exclusiveMaximum":
+		s.exclusiveMaximum, err = number(v, at)
+	case "allOf":
+		s.allOf, err = schemaList(v, at)
+	case "anyOf":
+		s.anyOf, err = schemaList(v, at)
+	case "oneOf":
+		s.oneOf, err = schemaList(v, at)
+	case "not":
+		s.not, err = compile(v, at)
+	default:
+		return fmt.Errorf("%s: unsupported keyword %q", pointer(at), k)
+	}
+	return err
+}
+
+var validTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+func typeList(v any, at string) ([]string, error) {
+	if t, ok := v.(string); ok {
+		v = []any{t}
+	}
+	types, err := stringList(v, at)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if !validTypes[t] {
+			return nil, fmt.Errorf("%s: unknown type %q", pointer(at), t)
+		}
+	}
+	return types, nil
+}
+
+func stringList(v any, at string) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array of strings", pointer(at))
+	}
+	out := make([]string, 0, len(list))
+	for _, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be an array of strings", pointer(at))
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func schemaList(v any, at string) ([]*Schema, error) {
+	list, ok := v.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array of schemas", pointer(at))
+	}
+	out := make([]*Schema, len(list))
+	for i, e := range list {
+		s, err := compile(e, at+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func count(v any, at string) (int, error) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, fmt.Errorf("%s: must be a non-negative integer", pointer(at))
+	}
+	return int(f), nil
+}
+
+func number(v any, at string) (*float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", pointer(at))
+	}
+	return &f, nil
+}
+
+// Validate checks doc, a value decoded by encoding/json into any, and
+// returns one message per violation, each prefixed with the JSON Pointer
+// of the offending value ("/" for the document itself). A valid document
+// returns nil.
+func (s *Schema) Validate(doc any) []string {
+	var errs []string
+	s.validate(doc, "", &errs)
+	return errs
+}
+
+func (s *Schema) valid(v any) bool {
+	var errs []string
+	s.validate(v, "", &errs)
+	return len(errs) == 0
+}
+
+func (s *Schema) validate(v any, at string, errs *[]string) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, pointer(at)+": "+fmt.Sprintf(format, args...))
+	}
+	if s.reject {
+		fail("not allowed")
+		return
+	}
+	if len(s.types) > 0 && !hasType(v, s.types) {
+		fail("expected %s, got %s", strings.Join(s.types, " or "), typeOf(v))
+		return
+	}
+	if s.enum != nil && !contains(s.enum, v) {
+		fail("must be one of the enumerated values")
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constVal, v) {
+		fail("must equal the constant value")
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := s.properties[name]; ok {
+				sub.validate(v[name], at+"/"+escape(name), errs)
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.validate(v[name], at+"/"+escape(name), errs)
+			}
+		}
+	case []any:
+		if len(v) < s.minItems {
+			fail("must have at least %d items", s.minItems)
+		}
+		if s.maxItems >= 0 && len(v) > s.maxItems {
+			fail("must have at most %d items", s.maxItems)
+		}
+		if s.items != nil {
+			for i, e := range v {
+				s.items.validate(e, at+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if n < s.minLength {
+			fail("must be at least %d characters", s.minLength)
+		}
+		if s.maxLength >= 0 && n > s.maxLength {
+			fail("must be at most %d characters", s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match pattern %q", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail("must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail("must be < %v", *s.exclusiveMaximum)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, at, errs)
+	}
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.valid(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must match at least one schema in anyOf")
+		}
+	}
+	if s.oneOf != nil {
+		n := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(v) {
+				n++
+			}
+		}
+		if n != 1 {
+			fail("must match exactly one schema in oneOf, matched %d", n)
+		}
+	}
+	if s.not != nil && s.not.valid(v) {
+		fail("must not match the schema in not")
+	}
+}
+
+func hasType(v any, types []string) bool {
+	got := typeOf(v)
+	for _, t := range types {
+		if t == got || (t == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of v; whole numbers are "integer".
+func typeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func contains(list []any, v any) bool {
+	for _, e := range list {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// escape encodes a property name as a JSON Pointer reference token.
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func pointer(at string) string {
+	if at == "" {
+		return "/"
+	}
+	return at
+}
@@ -0,0 +1,79 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const userSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 20},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "user"]},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 3},
+		"id": {"oneOf": [{"type": "string"}, {"type": "integer"}]}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Compile([]byte(userSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		doc  string
+		want []string // substrings, one per expected error
+	}{
+		{`{"name": "ann", "age": 30, "role": "admin", "tags": ["a"], "id": 7}`, nil},
+		{`{"name": "ann"}`, []string{`/: missing required property "age"`}},
+		{`[]`, []string{"/: expected object, got array"}},
+		{`{"name": "", "age": 1.5}`, []string{"/age: expected integer, got number", "/name: must be at least 1 characters"}},
+		{`{"name": "ann", "age": 150}`, []string{"/age: must be < 150"}},
+		{`{"name": "ann", "age": 1, "role": "root"}`, []string{"/role: must be one of"}},
+		{`{"name": "ann", "age": 1, "email": "nope"}`, []string{"/email: must match pattern"}},
+		{`{"name": "ann", "age": 1, "tags": ["a", 2, "c", "d"]}`, []string{"/tags: must have at most 3 items", "/tags/1: expected string"}},
+		{`{"name": "ann", "age": 1, "extra": true}`, []string{"/extra: not allowed"}},
+		{`{"name": "ann", "age": 1, "id": 1.5}`, []string{"/id: must match exactly one schema in oneOf, matched 0"}},
+	}
+	for _, tt := range tests {
+		var doc any
+		if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+			t.Fatal(err)
+		}
+		got := s.Validate(doc)
+		if len(got) != len(tt.want) {
+			t.Errorf("Validate(%s) = %q, want %d errors", tt.doc, got, len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(got[i], want) {
+				t.Errorf("Validate(%s)[%d] = %q, want it to contain %q", tt.doc, i, got[i], want)
+			}
+		}
+	}
+}
+
+func TestCompile_RejectsUnsupportedAndInvalid(t *testing.T) {
+	tests := map[string]string{
+		`{"$ref": "#/defs/x"}`:                       `unsupported keyword "$ref"`,
+		`{"properties": {"a": {"format": "email"}}}`: `/properties/a/format: unsupported keyword`,
+		`{"type": "strin"}`:                          `unknown type "strin"`,
+		`{"minLength": -1}`:                          "non-negative integer",
+		`{"pattern": "("}`:                           "/pattern",
+		`{"anyOf": []}`:                              "non-empty array",
+		`"object"`:                                   "must be an object or a boolean",
+		`{`:                                          "parsing schema",
+	}
+	for schema, want := range tests {
+		_, err := Compile([]byte(schema))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%s) error = %v, want it to contain %q", schema, err, want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+// NewSyslogWriter always fails on platforms without log/syslog; callers
+// fall back to stdout.
+func NewSyslogWriter(network, address, facility, tag string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
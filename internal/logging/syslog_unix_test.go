@@ -0,0 +1,49 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogWriter_DeliversToServer(t *testing.T) {
+	// Mock syslog server: a UDP socket that captures one datagram.
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	w, err := NewSyslogWriter("udp", conn.LocalAddr().String(), "local3", "gateway-test")
+	if err != nil {
+		t.Fatalf("NewSyslogWriter: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if _, err := w.Write([]byte(`{"msg":"request","status":200}` + "\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	msg := string(buf[:n])
+	// local3 (19) * 8 + info (6) = 158.
+	for _, want := range []string{"<158>", "gateway-test", `{"msg":"request","status":200}`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("syslog message %q missing %q", msg, want)
+		}
+	}
+}
+
+func TestSyslogWriter_UnknownFacility(t *testing.T) {
+	if _, err := NewSyslogWriter("udp", "127.0.0.1:514", "local9", "gateway"); err == nil {
+		t.Fatal("expected an error for an unknown facility")
+	}
+}
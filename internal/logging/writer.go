@@ -1,10 +1,14 @@
-// Package logging provides a rotating file writer for structured log output.
-// It implements io.WriteCloser and rotates log files by size, keeping a
-// configurable number of backups and removing files older than a maximum age.
+// Package logging provides a rotating file writer for structured log output,
+// and a syslog writer for the "syslog" output on platforms that have one.
+// It implements io.WriteCloser and rotates log files by size (and optionally
+// at local midnight), keeping a configurable number of backups and removing
+// files older than a maximum age. Rotated files can optionally be gzipped.
 package logging
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -13,7 +17,8 @@ import (
 	"time"
 )
 
-// RotatingWriter is an io.WriteCloser that rotates log files by size.
+// RotatingWriter is an io.WriteCloser that rotates log files by size and,
+// with EnableDailyRotation, at local midnight.
 type RotatingWriter struct {
 	mu         sync.Mutex
 	file       *os.File
@@ -22,6 +27,19 @@ type RotatingWriter struct {
 	maxBytes   int64
 	maxBackups int
 	maxAgeDays int
+
+	// daily enables time-based rotation. periodStart is when the current
+	// file's content began (its mtime when reopened); the file rotates on the
+	// first write at or after nextRotate, the local midnight that follows.
+	daily       bool
+	periodStart time.Time
+	nextRotate  time.Time
+
+	// compress gzips each rotated file to <name>.gz in the background.
+	compress bool
+
+	now      func() time.Time                    // time source; replaced in tests
+	openFunc func(name string) (*os.File, error) // opens the active file; replaced in tests
 }
 
 // NewRotatingWriter opens the log file (creating it if needed) and returns a
@@ -34,6 +52,8 @@ func NewRotatingWriter(filePath string, maxSizeMB, maxBackups, maxAgeDays int) (
 		maxBytes:   int64(maxSizeMB) * 1024 * 1024,
 		maxBackups: maxBackups,
 		maxAgeDays: maxAgeDays,
+		now:        time.Now,
+		openFunc:   openAppend,
 	}
 
 	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
@@ -47,8 +67,12 @@ func NewRotatingWriter(filePath string, maxSizeMB, maxBackups, maxAgeDays int) (
 	return rw, nil
 }
 
+func openAppend(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
+
 func (rw *RotatingWriter) openFile() error {
-	f, err := os.OpenFile(rw.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	f, err := rw.openFunc(rw.filePath)
 	if err != nil {
 		return fmt.Errorf("opening log file: %w", err)
 	}
@@ -63,17 +87,63 @@ func (rw *RotatingWriter) openFile() error {
 
 	rw.file = f
 	rw.size = info.Size()
+	rw.periodStart = rw.now()
+	if rw.size > 0 && info.ModTime().Before(rw.periodStart) {
+		// Reopened an existing file: it belongs to the day it was last
+		// written, so a restart after midnight still rotates yesterday's log.
+		rw.periodStart = info.ModTime()
+	}
+	rw.nextRotate = nextMidnight(rw.periodStart)
 	return nil
 }
 
+// EnableDailyRotation makes the writer also rotate at local midnight, even
+// when the file is under the size limit. Daily files are named
+// <base>-<YYYYMMDD><ext> after the day their content was written.
+func (rw *RotatingWriter) EnableDailyRotation() {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.daily = true
+}
+
+// EnableCompression makes the writer gzip each rotated file in the
+// background after it is renamed, replacing <name> with <name>.gz. The
+// active file is never compressed.
+func (rw *RotatingWriter) EnableCompression() {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.compress = true
+}
+
+// nextMidnight returns the first local midnight strictly after t.
+func nextMidnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
 // Write implements io.Writer. It rotates the file if writing would exceed the
-// size limit.
+// size limit or, with daily rotation enabled, if a day boundary has passed
+// since the file was started.
 func (rw *RotatingWriter) Write(p []byte) (int, error) {
 	rw.mu.Lock()
 	defer rw.mu.Unlock()
 
-	if rw.size+int64(len(p)) > rw.maxBytes {
-		if err := rw.rotate(); err != nil {
+	if rw.file == nil {
+		// A previous rotation could not reopen the file; retry so the
+		// writer recovers once the cause (full disk, permissions) clears.
+		if err := rw.openFile(); err != nil {
+			return 0, fmt.Errorf("log file unavailable after failed rotation: %w", err)
+		}
+	}
+
+	now := rw.now()
+	switch {
+	case rw.daily && !now.Before(rw.nextRotate):
+		if err := rw.rotate(rw.dailyName()); err != nil {
+			return 0, err
+		}
+	case rw.size+int64(len(p)) > rw.maxBytes:
+		if err := rw.rotate(rw.rotatedName(now.Format("20060102-150405"))); err != nil {
 			return 0, err
 		}
 	}
@@ -83,6 +153,22 @@ func (rw *RotatingWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// Reopen closes the log file and opens its path again, for external
+// rotators such as logrotate: once the file has been renamed, later writes
+// go to a new file at the configured path instead of the renamed one. If
+// the path cannot be opened, the next Write retries.
+func (rw *RotatingWriter) Reopen() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.file != nil {
+		if err := rw.file.Close(); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "logging: failed to close log file before reopening: %v\n", err)
+		}
+		rw.file = nil
+	}
+	return rw.openFile()
+}
+
 // Close closes the underlying file.
 func (rw *RotatingWriter) Close() error {
 	rw.mu.Lock()
@@ -93,35 +179,141 @@ func (rw *RotatingWriter) Close() error {
 	return nil
 }
 
-func (rw *RotatingWriter) rotate() error {
+// rotatedName returns <base>-<suffix><ext> for the current log path.
+func (rw *RotatingWriter) rotatedName(suffix string) string {
+	ext := filepath.Ext(rw.filePath)
+	base := strings.TrimSuffix(rw.filePath, ext)
+	if ext == "" {
+		ext = ".log"
+	}
+	return fmt.Sprintf("%s-%s%s", base, suffix, ext)
+}
+
+// dailyName returns the date-stamped name for the file being closed at a
+// day boundary. If that name is taken (the gateway restarted and rotated
+// the same day twice), it falls back to a timestamped name for the last
+// second of that day so the file still sorts with its date.
+func (rw *RotatingWriter) dailyName() string {
+	name := rw.rotatedName(rw.periodStart.Format("20060102"))
+	if exists(name) || exists(name+".gz") {
+		name = rw.rotatedName(rw.periodStart.Format("20060102") + "-235959")
+	}
+	return name
+}
+
+// rotate closes the current file, renames it to rotatedName (unless it is
+// empty, as after an idle day) and opens a fresh one. If the fresh file
+// cannot be opened, the rename is undone and the original file reopened so
+// logging continues in place; only if that also fails is an error returned,
+// and the next Write retries the open.
+func (rw *RotatingWriter) rotate(rotatedName string) error {
 	if rw.file != nil {
 		if err := rw.file.Close(); err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "logging: failed to close log file before rotate: %v\n", err)
 		}
+		rw.file = nil
 	}
 
-	// Rename current file to <base>-<timestamp><ext>
-	ext := filepath.Ext(rw.filePath)
-	base := strings.TrimSuffix(rw.filePath, ext)
-	if ext == "" {
-		ext = ".log"
-	}
-	rotatedName := fmt.Sprintf("%s-%s%s", base, time.Now().Format("20060102-150405"), ext)
-	if err := os.Rename(rw.filePath, rotatedName); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logging: failed to rename log file %q -> %q: %v\n", rw.filePath, rotatedName, err)
+	renamed := ""
+	if rw.size > 0 {
+		if err := os.Rename(rw.filePath, rotatedName); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "logging: failed to rename log file %q -> %q: %v\n", rw.filePath, rotatedName, err)
+		} else {
+			renamed = rotatedName
+		}
 	}
 
 	// Open a new file
 	if err := rw.openFile(); err != nil {
-		return err
+		return rw.recoverRotate(renamed, err)
 	}
 
-	// Cleanup old files in background (non-blocking)
-	go rw.cleanup()
+	// Compress and cleanup old files in background (non-blocking)
+	compress := rw.compress && renamed != ""
+	go func() {
+		if compress {
+			rw.compressFile(renamed)
+		}
+		rw.cleanup()
+	}()
+
+	return nil
+}
 
+// recoverRotate restores the pre-rotation file after openFile failed with
+// openErr: it moves renamed back to the active path and reopens it.
+func (rw *RotatingWriter) recoverRotate(renamed string, openErr error) error {
+	if renamed != "" && !exists(rw.filePath) {
+		if err := os.Rename(renamed, rw.filePath); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "logging: failed to restore log file %q -> %q: %v\n", renamed, rw.filePath, err)
+		}
+	}
+	if err := rw.openFile(); err != nil {
+		return fmt.Errorf("rotating log file %q: %w (reopen also failed: %v)", rw.filePath, openErr, err)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "logging: rotation of %q failed, continuing in the current file: %v\n", rw.filePath, openErr)
 	return nil
 }
 
+// compressFile gzips path to path.gz, keeping the original modification
+// time so age-based cleanup is unaffected, then removes path. Failures are
+// reported on stderr and leave the uncompressed file in place.
+func (rw *RotatingWriter) compressFile(path string) {
+	if path == rw.filePath {
+		return // never touch the active file
+	}
+	if err := gzipFile(path); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "logging: failed to compress rotated log %q: %v\n", path, err)
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "logging: failed to remove compressed log %q: %v\n", path, err)
+	}
+}
+
+// gzipFile writes a gzip copy of path to path.gz via a temporary file, so a
+// partial archive is never left under the final name.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func (rw *RotatingWriter) cleanup() {
 	ext := filepath.Ext(rw.filePath)
 	base := strings.TrimSuffix(filepath.Base(rw.filePath), ext)
@@ -135,12 +327,17 @@ func (rw *RotatingWriter) cleanup() {
 		return
 	}
 
-	// Collect rotated files matching the pattern <base>-<timestamp><ext>
+	// Collect rotated files matching the pattern <base>-<timestamp><ext>,
+	// compressed (<ext>.gz) or not. In-progress .gz.tmp files are skipped.
+	// Daily (<base>-YYYYMMDD) and size (<base>-YYYYMMDD-HHMMSS) names sort
+	// together chronologically: a day's size-rotated files sort before the
+	// daily file that closed that day.
 	prefix := base + "-"
 	var rotated []string
 	for _, e := range entries {
 		name := e.Name()
-		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) && name != filepath.Base(rw.filePath) {
+		if strings.HasPrefix(name, prefix) && (strings.HasSuffix(name, ext) || strings.HasSuffix(name, ext+".gz")) &&
+			name != filepath.Base(rw.filePath) {
 			rotated = append(rotated, name)
 		}
 	}
@@ -148,7 +345,7 @@ func (rw *RotatingWriter) cleanup() {
 	// Sort ascending (oldest first)
 	sort.Strings(rotated)
 
-	cutoff := time.Now().AddDate(0, 0, -rw.maxAgeDays)
+	cutoff := rw.now().AddDate(0, 0, -rw.maxAgeDays)
 
 	// Remove files exceeding max backups (keep the newest maxBackups)
 	for len(rotated) > rw.maxBackups {
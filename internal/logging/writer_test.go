@@ -1,10 +1,15 @@
 package logging
 
 import (
+	"compress/gzip"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestRotatingWriter_CreateFile(t *testing.T) {
@@ -146,3 +151,318 @@ func TestRotatingWriter_CreatesDirectory(t *testing.T) {
 		t.Error("log file was not created")
 	}
 }
+
+// fakeClock is a settable time source safe to read from the background
+// cleanup goroutine.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.t = t
+	c.mu.Unlock()
+}
+
+func TestRotatingWriter_RotatesAtDayBoundary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	clock := &fakeClock{t: time.Date(2026, 3, 14, 23, 59, 0, 0, time.Local)}
+	rw, err := NewRotatingWriter(path, 1, 3, 30)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	// Reset the period as if the writer had been opened at the fake time.
+	rw.now = clock.Now
+	rw.periodStart = clock.Now()
+	rw.nextRotate = nextMidnight(rw.periodStart)
+	rw.EnableDailyRotation()
+	defer func() {
+		if err := rw.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}()
+
+	if _, err := rw.Write([]byte("day one\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// Well under the size limit, so only the clock can trigger rotation.
+	clock.Set(time.Date(2026, 3, 15, 0, 0, 1, 0, time.Local))
+	if _, err := rw.Write([]byte("day two\n")); err != nil {
+		t.Fatalf("Write after midnight: %v", err)
+	}
+
+	rotated, err := os.ReadFile(filepath.Join(dir, "test-20260314.log"))
+	if err != nil {
+		t.Fatalf("dated file missing: %v", err)
+	}
+	if string(rotated) != "day one\n" {
+		t.Errorf("dated file = %q, want %q", rotated, "day one\n")
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(current) != "day two\n" {
+		t.Errorf("current file = %q, want %q", current, "day two\n")
+	}
+
+	// A second write the same day must not rotate again.
+	clock.Set(time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local))
+	if _, err := rw.Write([]byte("still day two\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(rw.rotatedName("20260315")); !os.IsNotExist(err) {
+		t.Errorf("rotated again before the next midnight (stat err = %v)", err)
+	}
+}
+
+func TestRotatingWriter_CleanupAcrossSizeAndDailyFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	rw, err := NewRotatingWriter(path, 1, 2, 30)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer func() {
+		if err := rw.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}()
+
+	now := time.Now()
+	files := []struct {
+		name string
+		age  time.Duration
+	}{
+		{"test-20260101-120000.log", 72 * time.Hour}, // size-rotated, day 1
+		{"test-20260101.log", 48 * time.Hour},        // daily, closes day 1
+		{"test-20260102-080000.log", 40 * time.Hour}, // size-rotated, day 2
+		{"test-20260102.log", 24 * time.Hour},        // daily, closes day 2
+	}
+	for _, f := range files {
+		p := filepath.Join(dir, f.name)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+
+	rw.cleanup()
+
+	for _, f := range files[:2] {
+		if _, err := os.Stat(filepath.Join(dir, f.name)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed (maxBackups=2)", f.name)
+		}
+	}
+	for _, f := range files[2:] {
+		if _, err := os.Stat(filepath.Join(dir, f.name)); err != nil {
+			t.Errorf("%s should have been kept: %v", f.name, err)
+		}
+	}
+}
+
+func TestRotatingWriter_CompressesRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	rw, err := NewRotatingWriter(path, 0, 3, 30)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	rw.maxBytes = 100
+	rw.EnableCompression()
+	defer func() {
+		if err := rw.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}()
+
+	first := strings.Repeat("a", 60)
+	if _, err := rw.Write([]byte(first)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := rw.Write([]byte(strings.Repeat("b", 60))); err != nil { // rotates
+		t.Fatalf("Write (rotation): %v", err)
+	}
+
+	// Compression runs in the background cleanup goroutine.
+	var gz string
+	deadline := time.Now().Add(2 * time.Second)
+	for gz == "" {
+		matches, _ := filepath.Glob(filepath.Join(dir, "test-*.log.gz"))
+		if len(matches) > 0 {
+			gz = matches[0]
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no .log.gz file after rotation with compression enabled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	f, err := os.Open(gz)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+	if string(data) != first {
+		t.Errorf("archive content = %q, want %q", data, first)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(gz, ".gz")); !os.IsNotExist(err) {
+		t.Errorf("uncompressed rotated file left behind (stat err = %v)", err)
+	}
+	// The active file stays plain text.
+	if active, err := os.ReadFile(path); err != nil || string(active) != strings.Repeat("b", 60) {
+		t.Errorf("active file = %q, %v", active, err)
+	}
+}
+
+func TestRotatingWriter_RecoversFromOpenFailureDuringRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	rw, err := NewRotatingWriter(path, 0, 3, 30)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	rw.maxBytes = 100
+	defer func() {
+		if err := rw.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}()
+
+	first := strings.Repeat("a", 60)
+	if _, err := rw.Write([]byte(first)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// Fail exactly one open: the fresh file after the rename. The writer
+	// must move the old file back and keep logging into it.
+	failures := 1
+	rw.openFunc = func(name string) (*os.File, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("injected open failure")
+		}
+		return openAppend(name)
+	}
+	second := strings.Repeat("b", 60)
+	if _, err := rw.Write([]byte(second)); err != nil {
+		t.Fatalf("Write after recovered rotation: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != first+second {
+		t.Errorf("active file = %q, want both writes", data)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "test-*.log")); len(matches) != 0 {
+		t.Errorf("rotated file left behind after failed rotation: %v", matches)
+	}
+}
+
+func TestRotatingWriter_ReturnsErrorUntilReopenSucceeds(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	rw, err := NewRotatingWriter(path, 0, 3, 30)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	rw.maxBytes = 100
+	defer func() {
+		if err := rw.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}()
+
+	if _, err := rw.Write([]byte(strings.Repeat("a", 60))); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	broken := true
+	rw.openFunc = func(name string) (*os.File, error) {
+		if broken {
+			return nil, errors.New("injected open failure")
+		}
+		return openAppend(name)
+	}
+	if _, err := rw.Write([]byte(strings.Repeat("b", 60))); err == nil {
+		t.Fatal("expected an error when neither the new nor the old file can be opened")
+	}
+	if _, err := rw.Write([]byte("c")); err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("expected 'log file unavailable' error while broken, got %v", err)
+	}
+
+	broken = false
+	if _, err := rw.Write([]byte("recovered\n")); err != nil {
+		t.Fatalf("Write after the cause cleared: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.HasSuffix(string(data), "recovered\n") {
+		t.Errorf("active file = %q, want the recovered write", data)
+	}
+}
+
+func TestRotatingWriter_ReopenAfterRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	rw, err := NewRotatingWriter(path, 100, 3, 30)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer rw.Close()
+
+	if _, err := rw.Write([]byte("before\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// What logrotate does before signalling the process.
+	rotated := filepath.Join(dir, "test.log.1")
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := rw.Reopen(); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	if _, err := rw.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	for file, want := range map[string]string{rotated: "before\n", path: "after\n"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", file, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), data, want)
+		}
+	}
+}
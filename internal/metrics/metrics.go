@@ -8,6 +8,8 @@ package metrics
 
 import (
 	"net/http"
+	"runtime"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,22 +20,71 @@ import (
 // concrete fields are exported so emit sites read the same as before —
 // `m.RequestsTotal.WithLabelValues(...)` — only the prefix changes.
 type Metrics struct {
-	RequestsTotal              *prometheus.CounterVec
-	RequestDuration            *prometheus.HistogramVec
-	ActiveConnections          prometheus.Gauge
-	RateLimitHits              *prometheus.CounterVec
-	AuthFailures               *prometheus.CounterVec
-	BackendErrors              *prometheus.CounterVec
-	RetryTotal                 *prometheus.CounterVec
+	RequestsTotal     *prometheus.CounterVec
+	RequestDuration   *prometheus.HistogramVec
+	BodySizeBytes     *prometheus.HistogramVec
+	ActiveConnections prometheus.Gauge
+	RateLimitHits     *prometheus.CounterVec
+	AuthFailures      *prometheus.CounterVec
+	BackendErrors     *prometheus.CounterVec
+	RetryTotal        *prometheus.CounterVec
+	// RetryAttempts observes how many retries each proxied request took,
+	// so occasional retries can be told apart from routes that always
+	// retry.
+	RetryAttempts *prometheus.HistogramVec
+	// RetriesSkipped counts retryable failures served without a retry
+	// because circuit_breaker.max_concurrent_retries was reached.
+	RetriesSkipped *prometheus.CounterVec
+	// ClientCancellations counts proxied requests abandoned because the
+	// client disconnected; they are not counted as backend errors.
+	ClientCancellations        *prometheus.CounterVec
 	CircuitBreakerStateChanges *prometheus.CounterVec
 	CircuitBreakerState        *prometheus.GaugeVec
 	BulkheadRejections         *prometheus.CounterVec
 	BulkheadInFlight           *prometheus.GaugeVec
-	RateLimitClientsTracked    prometheus.Gauge
-	RateLimitClientsEvicted    prometheus.Counter
+	// RouteQueued counts requests that waited for a slot under a route's
+	// max_concurrent limit; RouteQueueRejections counts those that gave up
+	// after queue_timeout_ms.
+	RouteQueued             *prometheus.CounterVec
+	RouteQueueRejections    *prometheus.CounterVec
+	RateLimitClientsTracked prometheus.Gauge
+	RateLimitClientsEvicted prometheus.Counter
+	// RateLimitClientsThrottled is the number of tracked clients with less
+	// than one token left as of the last janitor pass.
+	RateLimitClientsThrottled prometheus.Gauge
+	// RateLimitEvictionsPerCleanup observes how many idle clients each
+	// janitor pass evicted.
+	RateLimitEvictionsPerCleanup prometheus.Histogram
 	// ConfigReloadRollbacks counts rollbacks triggered when a config.Observer
 	// returned an error or panicked during a reload (DP-001).
 	ConfigReloadRollbacks *prometheus.CounterVec
+	// SmugglingRejections counts requests rejected for ambiguous framing
+	// (Transfer-Encoding with Content-Length, duplicate or malformed
+	// Content-Length).
+	SmugglingRejections *prometheus.CounterVec
+	// RequestsByTemplate counts requests on routes with path_templating
+	// enabled, keyed by the templated request path.
+	RequestsByTemplate *prometheus.CounterVec
+	// ListenerRejections counts connections closed by the global accept
+	// rate limit (server.accept_limit with mode reject).
+	ListenerRejections prometheus.Counter
+	// ActiveTCPConnections is the number of client connections currently
+	// open on the listener.
+	ActiveTCPConnections prometheus.Gauge
+	// UpstreamDials counts new connections dialed to each backend;
+	// UpstreamActiveRequests is the number of requests each backend is
+	// currently serving, from send until the response body is closed.
+	UpstreamDials          *prometheus.CounterVec
+	UpstreamActiveRequests *prometheus.GaugeVec
+	// BuildInfo is a constant 1 labeled with the running build; set once
+	// at startup via SetBuildInfo.
+	BuildInfo *prometheus.GaugeVec
+
+	// FallbackResponses counts requests answered with a route's
+	// fallback_status because its circuit breaker rejected them;
+	// CircuitOpenRejections counts those answered with the plain 503.
+	FallbackResponses     *prometheus.CounterVec
+	CircuitOpenRejections *prometheus.CounterVec
 }
 
 // New constructs a Metrics bundle and registers every collector with reg.
@@ -42,6 +93,17 @@ type Metrics struct {
 // prometheus.DefaultRegisterer for normal use, or prometheus.NewRegistry()
 // in tests that need isolation from other suites.
 func New(reg prometheus.Registerer) *Metrics {
+	return NewWithBuckets(reg, nil)
+}
+
+// NewWithBuckets is New with durationBuckets as the bounds of
+// gateway_request_duration_seconds; nil means prometheus.DefBuckets. The
+// bounds must be strictly increasing (config validation ensures this for
+// metrics.buckets).
+func NewWithBuckets(reg prometheus.Registerer, durationBuckets []float64) *Metrics {
+	if durationBuckets == nil {
+		durationBuckets = prometheus.DefBuckets
+	}
 	m := &Metrics{
 		RequestsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -54,10 +116,18 @@ func New(reg prometheus.Registerer) *Metrics {
 			prometheus.HistogramOpts{
 				Name:    "gateway_request_duration_seconds",
 				Help:    "Request latency in seconds",
-				Buckets: prometheus.DefBuckets,
+				Buckets: durationBuckets,
 			},
 			[]string{"route", "method"},
 		),
+		BodySizeBytes: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "gateway_body_size_bytes",
+				Help:    "Proxied request and response body sizes in bytes",
+				Buckets: prometheus.ExponentialBuckets(256, 4, 9), // 256 B … 16 MiB
+			},
+			[]string{"route", "direction"},
+		),
 		ActiveConnections: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "gateway_active_connections",
@@ -92,6 +162,28 @@ func New(reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"route", "backend"},
 		),
+		RetryAttempts: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "gateway_retry_attempts",
+				Help:    "Retries per proxied request",
+				Buckets: prometheus.LinearBuckets(0, 1, 6), // 0 … 5
+			},
+			[]string{"route"},
+		),
+		RetriesSkipped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_retries_skipped_total",
+				Help: "Retryable failures served without a retry because the global retry cap was reached",
+			},
+			[]string{"route", "backend"},
+		),
+		ClientCancellations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_client_cancellations_total",
+				Help: "Total proxied requests abandoned because the client disconnected",
+			},
+			[]string{"route"},
+		),
 		CircuitBreakerStateChanges: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "gateway_circuit_breaker_state_changes_total",
@@ -120,6 +212,34 @@ func New(reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"backend"},
 		),
+		RouteQueued: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_route_queued_total",
+				Help: "Total requests that queued for a route concurrency slot",
+			},
+			[]string{"route"},
+		),
+		RouteQueueRejections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_route_queue_rejections_total",
+				Help: "Total requests rejected after waiting queue_timeout_ms for a route concurrency slot",
+			},
+			[]string{"route"},
+		),
+		FallbackResponses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_fallback_responses_total",
+				Help: "Total requests answered with the route's fallback response because its circuit breaker was open or bulkhead full",
+			},
+			[]string{"route"},
+		),
+		CircuitOpenRejections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_circuit_open_rejections_total",
+				Help: "Total requests rejected with 503 because the route's circuit breaker was open or bulkhead full, on routes without a fallback",
+			},
+			[]string{"route"},
+		),
 		RateLimitClientsTracked: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "gateway_ratelimit_clients_tracked",
@@ -132,6 +252,19 @@ func New(reg prometheus.Registerer) *Metrics {
 				Help: "Total rate-limiter client entries evicted for idleness",
 			},
 		),
+		RateLimitClientsThrottled: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_ratelimit_clients_throttled",
+				Help: "Rate-limiter clients with no whole token left at the last janitor pass",
+			},
+		),
+		RateLimitEvictionsPerCleanup: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "gateway_ratelimit_evictions_per_cleanup",
+				Help:    "Rate-limiter client entries evicted per janitor pass",
+				Buckets: prometheus.ExponentialBuckets(1, 4, 8), // 1 … 16384
+			},
+		),
 		ConfigReloadRollbacks: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "gateway_config_reload_rollbacks_total",
@@ -139,31 +272,120 @@ func New(reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"reason"},
 		),
+		SmugglingRejections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_smuggling_rejections_total",
+				Help: "Total requests rejected for ambiguous message framing",
+			},
+			[]string{"reason"},
+		),
+		RequestsByTemplate: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_requests_by_template_total",
+				Help: "Total HTTP requests by templated path, for routes with path templating enabled",
+			},
+			[]string{"route", "template", "method", "status"},
+		),
+		ListenerRejections: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "gateway_listener_rejections_total",
+				Help: "Total connections closed by the global accept rate limit",
+			},
+		),
+		ActiveTCPConnections: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_active_tcp_connections",
+				Help: "Client TCP connections currently open",
+			},
+		),
+		UpstreamDials: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_upstream_dials_total",
+				Help: "Total new connections dialed per backend",
+			},
+			[]string{"backend"},
+		),
+		UpstreamActiveRequests: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_upstream_active_requests",
+				Help: "Current number of requests in flight per backend",
+			},
+			[]string{"backend"},
+		),
+		BuildInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_build_info",
+				Help: "Build information for the running gateway; value is always 1",
+			},
+			[]string{"version", "commit", "go_version"},
+		),
 	}
 
 	reg.MustRegister(
 		m.RequestsTotal,
 		m.RequestDuration,
+		m.BodySizeBytes,
 		m.ActiveConnections,
 		m.RateLimitHits,
 		m.AuthFailures,
 		m.BackendErrors,
 		m.RetryTotal,
+		m.RetryAttempts,
+		m.RetriesSkipped,
+		m.ClientCancellations,
 		m.CircuitBreakerStateChanges,
 		m.CircuitBreakerState,
 		m.BulkheadRejections,
 		m.BulkheadInFlight,
+		m.RouteQueued,
+		m.RouteQueueRejections,
+		m.FallbackResponses,
+		m.CircuitOpenRejections,
 		m.RateLimitClientsTracked,
 		m.RateLimitClientsEvicted,
+		m.RateLimitClientsThrottled,
+		m.RateLimitEvictionsPerCleanup,
 		m.ConfigReloadRollbacks,
+		m.SmugglingRejections,
+		m.RequestsByTemplate,
+		m.ListenerRejections,
+		m.ActiveTCPConnections,
+		m.UpstreamDials,
+		m.UpstreamActiveRequests,
+		m.BuildInfo,
 	)
 	return m
 }
 
 // Handler returns an http.Handler that exports metrics gathered from g.
 // Pass prometheus.DefaultGatherer to match the pre-DP-002 behavior.
+// Scrapers that ask for OpenMetrics get exemplars too.
 func Handler(g prometheus.Gatherer) http.Handler {
-	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// maxExemplarIDRunes caps the request ID attached as an exemplar.
+// client_golang rejects exemplars whose labels exceed 128 runes in total,
+// and request IDs can come from clients.
+const maxExemplarIDRunes = 100
+
+// ObserveWithRequestID observes v on o with requestID as a request_id
+// exemplar, linking the sample to the request's logs. Without an ID, with
+// one too long or not UTF-8, or when o does not take exemplars, it is a
+// plain Observe.
+func ObserveWithRequestID(o prometheus.Observer, v float64, requestID string) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && requestID != "" &&
+		utf8.ValidString(requestID) && utf8.RuneCountInString(requestID) <= maxExemplarIDRunes {
+		eo.ObserveWithExemplar(v, prometheus.Labels{"request_id": requestID})
+		return
+	}
+	o.Observe(v)
+}
+
+// SetBuildInfo publishes gateway_build_info for the given version and
+// commit, labeled with the Go toolchain the binary was built with.
+func (m *Metrics) SetBuildInfo(version, commit string) {
+	m.BuildInfo.WithLabelValues(version, commit, runtime.Version()).Set(1)
 }
 
 // IncRollback records a single config reload rollback with the given
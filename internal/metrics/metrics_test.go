@@ -42,18 +42,34 @@ func TestMetrics_ExposesExpectedCollectorNames(t *testing.T) {
 	// Exercise every collector so at least one sample exists per family.
 	m.RequestsTotal.WithLabelValues("/x", "GET", "200").Inc()
 	m.RequestDuration.WithLabelValues("/x", "GET").Observe(0.1)
+	m.BodySizeBytes.WithLabelValues("/x", "request").Observe(512)
 	m.ActiveConnections.Inc()
 	m.RateLimitHits.WithLabelValues("/x").Inc()
 	m.AuthFailures.WithLabelValues("invalid_token").Inc()
 	m.BackendErrors.WithLabelValues("/x", "http://b", "502").Inc()
 	m.RetryTotal.WithLabelValues("/x", "http://b").Inc()
+	m.RetryAttempts.WithLabelValues("/x").Observe(1)
 	m.CircuitBreakerStateChanges.WithLabelValues("http://b", "closed", "open").Inc()
 	m.CircuitBreakerState.WithLabelValues("http://b").Set(1)
 	m.BulkheadRejections.WithLabelValues("http://b").Inc()
 	m.BulkheadInFlight.WithLabelValues("http://b").Set(0)
+	m.RouteQueued.WithLabelValues("/x").Inc()
+	m.RouteQueueRejections.WithLabelValues("/x").Inc()
+	m.FallbackResponses.WithLabelValues("/x").Inc()
+	m.CircuitOpenRejections.WithLabelValues("/x").Inc()
 	m.RateLimitClientsTracked.Set(7)
 	m.RateLimitClientsEvicted.Inc()
+	m.RateLimitClientsThrottled.Set(1)
+	m.RateLimitEvictionsPerCleanup.Observe(3)
 	m.ConfigReloadRollbacks.WithLabelValues("observer_error").Inc()
+	m.SmugglingRejections.WithLabelValues("te_and_cl").Inc()
+	m.SetBuildInfo("v1.0.0", "abc123")
+	m.ListenerRejections.Inc()
+	m.RequestsByTemplate.WithLabelValues("/x", "/x/{id}", "GET", "200").Inc()
+	m.RetriesSkipped.WithLabelValues("/x", "http://b").Inc()
+	m.ClientCancellations.WithLabelValues("/x").Inc()
+	m.UpstreamDials.WithLabelValues("http://b").Inc()
+	m.UpstreamActiveRequests.WithLabelValues("http://b").Set(1)
 
 	rec := httptest.NewRecorder()
 	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
@@ -64,18 +80,34 @@ func TestMetrics_ExposesExpectedCollectorNames(t *testing.T) {
 	wanted := []string{
 		"gateway_requests_total",
 		"gateway_request_duration_seconds",
+		"gateway_body_size_bytes",
 		"gateway_active_connections",
 		"gateway_rate_limit_hits_total",
 		"gateway_auth_failures_total",
 		"gateway_backend_errors_total",
 		"gateway_retries_total",
+		"gateway_retry_attempts",
+		"gateway_retries_skipped_total",
+		"gateway_client_cancellations_total",
 		"gateway_circuit_breaker_state_changes_total",
 		"gateway_circuit_breaker_state",
 		"gateway_bulkhead_rejections_total",
 		"gateway_bulkhead_in_flight",
+		"gateway_route_queued_total",
+		"gateway_route_queue_rejections_total",
+		"gateway_fallback_responses_total",
+		"gateway_circuit_open_rejections_total",
 		"gateway_ratelimit_clients_tracked",
 		"gateway_ratelimit_clients_evicted_total",
+		"gateway_ratelimit_clients_throttled",
+		"gateway_ratelimit_evictions_per_cleanup",
 		"gateway_config_reload_rollbacks_total",
+		"gateway_smuggling_rejections_total",
+		"gateway_listener_rejections_total",
+		"gateway_requests_by_template_total",
+		"gateway_upstream_dials_total",
+		"gateway_upstream_active_requests",
+		`gateway_build_info{commit="abc123",go_version="go`,
 	}
 	for _, name := range wanted {
 		if !strings.Contains(out, name) {
@@ -86,3 +118,60 @@ func TestMetrics_ExposesExpectedCollectorNames(t *testing.T) {
 		t.Errorf("handler status = %d, want 200", rec.Code)
 	}
 }
+
+// Request IDs client_golang would reject as exemplar labels are dropped,
+// not allowed to panic the observation.
+func TestObserveWithRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{"with ID", "req-1", "req-1"},
+		{"no ID", "", ""},
+		{"ID too long", strings.Repeat("x", 200), ""},
+		{"invalid UTF-8", "req-\xff", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "h", Buckets: []float64{1}})
+			ObserveWithRequestID(h, 0.5, tt.id)
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(h)
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := families[0].GetMetric()[0]
+			if n := m.GetHistogram().GetSampleCount(); n != 1 {
+				t.Errorf("sample count = %d, want 1", n)
+			}
+			got := ""
+			for _, lp := range m.GetHistogram().GetBucket()[0].GetExemplar().GetLabel() {
+				got = lp.GetValue()
+			}
+			if got != tt.want {
+				t.Errorf("exemplar request_id = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWithBuckets_ExposesCustomBuckets(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewWithBuckets(reg, []float64{0.0005, 0.002, 0.5})
+	m.RequestDuration.WithLabelValues("/x", "GET").Observe(0.001)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, le := range []string{`le="0.0005"`, `le="0.002"`, `le="0.5"`, `le="+Inf"`} {
+		if !strings.Contains(body, `gateway_request_duration_seconds_bucket{method="GET",route="/x",`+le+`}`) {
+			t.Errorf("exposition lacks the %s bucket", le)
+		}
+	}
+	if strings.Contains(body, `gateway_request_duration_seconds_bucket{method="GET",route="/x",le="0.005"}`) {
+		t.Error("exposition still has a default bucket")
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testCA is an in-memory certificate authority for issuing client certs.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA cert: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA cert: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.cert)
+	return p
+}
+
+func (ca *testCA) issueClient(t *testing.T, cn string, dnsNames ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create client cert: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse client cert: %v", err)
+	}
+	return cert
+}
+
+func TestClientCert_VerifiedCertInjectsIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	client := ca.issueClient(t, "partner-a", "partner-a.example.com")
+
+	var gotCN, gotSAN string
+	var ctxCert *x509.Certificate
+	handler := ClientCert(func(*http.Request) bool { return true }, ca.pool)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.Header.Get(ClientCertCNHeader)
+		gotSAN = r.Header.Get(ClientCertSANHeader)
+		ctxCert = GetClientCert(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/partners", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if gotCN != "partner-a" {
+		t.Errorf("expected CN header partner-a, got %q", gotCN)
+	}
+	if gotSAN != "partner-a.example.com" {
+		t.Errorf("expected SAN header partner-a.example.com, got %q", gotSAN)
+	}
+	if ctxCert != client {
+		t.Error("expected client certificate in request context")
+	}
+}
+
+func TestClientCert_RequiredRouteRejectsMissingOrUntrustedCert(t *testing.T) {
+	trusted := newTestCA(t)
+	untrusted := newTestCA(t)
+
+	handler := ClientCert(func(*http.Request) bool { return true }, trusted.pool)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not be reached")
+	}))
+
+	tests := []struct {
+		name string
+		tls  *tls.ConnectionState
+	}{
+		{"no TLS", nil},
+		{"no client cert", &tls.ConnectionState{}},
+		{"untrusted CA", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{untrusted.issueClient(t, "mallory")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/partners", nil)
+			req.TLS = tt.tls
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestClientCert_OptionalRouteStripsSpoofedHeaders(t *testing.T) {
+	var gotCN string
+	handler := ClientCert(func(*http.Request) bool { return false }, func() *x509.CertPool { return nil })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.Header.Get(ClientCertCNHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/public", nil)
+	req.Header.Set(ClientCertCNHeader, "admin")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if gotCN != "" {
+		t.Errorf("expected spoofed CN header to be stripped, got %q", gotCN)
+	}
+}
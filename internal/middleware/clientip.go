@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPKey is the context key used to store the resolved client IP.
+const ClientIPKey ctxKey = "client_ip"
+
+// ParseTrustedProxies converts CIDR strings, such as server.trusted_proxies
+// or rate_limit.bypass_cidrs, to networks, skipping invalid entries (config
+// validation rejects them before they get here).
+func ParseTrustedProxies(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// ResolveClientIP returns the real client IP for r. X-Forwarded-For is only
+// consulted when the direct peer is a trusted proxy; it is then walked right
+// to left, skipping trusted hops, and the first untrusted address wins. That
+// handles any number of trusted hops (CDN → LB → gateway) while ignoring
+// whatever the client itself put at the left of the header.
+func ResolveClientIP(r *http.Request, trusted []*net.IPNet) string {
+	peer := peerIP(r)
+	if len(trusted) == 0 || !ipInNets(peer, trusted) {
+		return peer
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		for i := len(parts) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(parts[i])
+			if ip != "" && !ipInNets(ip, trusted) {
+				return ip
+			}
+		}
+	}
+	return peer
+}
+
+// ClientIPResolver returns middleware that resolves the client IP once per
+// request (see ResolveClientIP) and stores it in the request context for
+// ClientIP, so later middleware does not re-parse X-Forwarded-For.
+func ClientIPResolver(trustedProxies []string) func(http.Handler) http.Handler {
+	trusted := ParseTrustedProxies(trustedProxies)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ClientIPKey, ResolveClientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIP extracts the resolved client IP from a context. Returns empty
+// string if ClientIPResolver has not run.
+func GetClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(ClientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
+// ClientIP returns the client IP resolved by ClientIPResolver, or the direct
+// peer address when the resolver is not in the chain.
+func ClientIP(r *http.Request) string {
+	if ip := GetClientIP(r.Context()); ip != "" {
+		return ip
+	}
+	return peerIP(r)
+}
+
+// peerIP returns the host part of r.RemoteAddr.
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ipInNets(ipStr string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
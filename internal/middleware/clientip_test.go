@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	// CDN edge → CDN shield → LB → gateway: three trusted hops.
+	trusted := ParseTrustedProxies([]string{"10.0.0.0/8", "198.51.100.0/24"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"three trusted hops", "10.0.0.5:443", "203.0.113.7, 198.51.100.10, 198.51.100.20", "203.0.113.7"},
+		{"spoofed left entries ignored", "10.0.0.5:443", "1.2.3.4, 203.0.113.7, 198.51.100.10, 198.51.100.20", "203.0.113.7"},
+		{"untrusted peer ignores XFF", "203.0.113.99:443", "1.2.3.4", "203.0.113.99"},
+		{"all hops trusted falls back to peer", "10.0.0.5:443", "10.1.1.1, 198.51.100.10", "10.0.0.5"},
+		{"no XFF", "10.0.0.5:443", "", "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if got := ResolveClientIP(r, trusted); got != tt.want {
+				t.Errorf("ResolveClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPResolver_StoresInContext(t *testing.T) {
+	var got string
+	handler := ClientIPResolver([]string{"10.0.0.0/8"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Later middleware must see the resolved IP even if XFF changes.
+		r.Header.Set("X-Forwarded-For", "9.9.9.9")
+		got = ClientIP(r)
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.5:443"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.2.2.2")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != "203.0.113.7" {
+		t.Errorf("ClientIP = %q, want 203.0.113.7", got)
+	}
+}
+
+func TestClientIP_FallsBackToPeer(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.1:5555"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := ClientIP(r); got != "192.0.2.1" {
+		t.Errorf("ClientIP without resolver = %q, want peer 192.0.2.1", got)
+	}
+}
@@ -25,9 +25,19 @@ func Deadline(timeout time.Duration) func(http.Handler) http.Handler {
 			done := make(chan struct{})
 			tw := &deadlineWriter{ResponseWriter: w}
 
+			// A panic in the handler goroutine would crash the process, so
+			// it is captured and re-raised on the serving goroutine where
+			// Recovery (and net/http) can see it.
+			var panicVal interface{}
 			go func() {
+				defer close(done)
+				defer func() { panicVal = recover() }()
 				next.ServeHTTP(tw, r.WithContext(ctx))
-				close(done)
+			}()
+			defer func() {
+				if panicVal != nil {
+					panic(panicVal)
+				}
 			}()
 
 			select {
@@ -74,3 +84,8 @@ func (dw *deadlineWriter) Write(b []byte) (int, error) {
 	dw.claimed.Store(true)
 	return dw.ResponseWriter.Write(b)
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (dw *deadlineWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
@@ -77,3 +77,21 @@ func TestDeadline_NegativeDisabled(t *testing.T) {
 		t.Errorf("expected 200 (passthrough), got %d", rec.Code)
 	}
 }
+
+func TestDeadline_PanicReachesCaller(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler panic")
+	})
+
+	handler := Deadline(1 * time.Second)(inner)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+	defer func() {
+		if err := recover(); err != "handler panic" {
+			t.Errorf("expected panic re-raised on the serving goroutine, got %v", err)
+		}
+	}()
+	handler.ServeHTTP(rec, req)
+	t.Error("expected ServeHTTP to panic")
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFraming_RejectsAmbiguousRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		te      []string
+		reason  string
+	}{
+		{"TE header and CL", map[string][]string{"Transfer-Encoding": {"chunked"}, "Content-Length": {"5"}}, nil, "te_and_cl"},
+		{"parsed TE and CL", map[string][]string{"Content-Length": {"5"}}, []string{"chunked"}, "te_and_cl"},
+		{"duplicate CL", map[string][]string{"Content-Length": {"5", "5"}}, nil, "duplicate_cl"},
+		{"conflicting CL", map[string][]string{"Content-Length": {"5", "10"}}, nil, "duplicate_cl"},
+		{"comma-joined CL", map[string][]string{"Content-Length": {"5, 5"}}, nil, "invalid_cl"},
+		{"signed CL", map[string][]string{"Content-Length": {"+5"}}, nil, "invalid_cl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := metrics.New(prometheus.NewRegistry())
+			handler := Framing(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler must not be reached")
+			}))
+
+			req := httptest.NewRequest("POST", "/api", nil)
+			for k, v := range tt.headers {
+				req.Header[k] = v
+			}
+			req.TransferEncoding = tt.te
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rec.Code)
+			}
+			if got := testutil.ToFloat64(m.SmugglingRejections.WithLabelValues(tt.reason)); got != 1 {
+				t.Errorf("expected %s rejection counted once, got %v", tt.reason, got)
+			}
+		})
+	}
+}
+
+func TestFraming_AllowsUnambiguousRequests(t *testing.T) {
+	handler := Framing(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for name, headers := range map[string]map[string][]string{
+		"no body":    {},
+		"CL only":    {"Content-Length": {"42"}},
+		"TE only":    {"Transfer-Encoding": {"chunked"}},
+		"CL of zero": {"Content-Length": {"0"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api", nil)
+			for k, v := range headers {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rec.Code)
+			}
+		})
+	}
+}
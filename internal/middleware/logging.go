@@ -4,8 +4,13 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"hash/fnv"
 	"io"
 	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -45,20 +50,57 @@ func (sr *statusRecorder) WriteHeader(code int) {
 	sr.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
 // LoggingConfig holds the runtime options for the Logging middleware.
 type LoggingConfig struct {
 	BodyLogging     bool
 	MaxBodyLogBytes int
+	// SampleRate maps a request to the fraction of 2xx responses to log;
+	// non-2xx responses are always logged. nil logs every request.
+	SampleRate func(r *http.Request) float64
+	// DebugTrustedProxies lists the CIDRs whose direct connections may
+	// send DebugLogHeader. Empty ignores the header from everyone.
+	DebugTrustedProxies []string
+	// Fields maps a request to the access-log fields to write (see
+	// logFieldValue). nil, or an empty result, writes DefaultLogFields.
+	Fields func(r *http.Request) []string
+}
+
+// DefaultLogFields are the access-log fields written when none are
+// configured.
+var DefaultLogFields = []string{"method", "path", "status", "latency_ms", "client_ip", "request_id"}
+
+// LogHeaderPrefix marks an access-log field naming a request header, as in
+// "header:X-Tenant-ID".
+const LogHeaderPrefix = "header:"
+
+// redactedLogHeaders are never written in the clear when configured as a
+// "header:" field.
+var redactedLogHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"Cookie":              {},
 }
 
+// DebugLogHeader forces full logging of a single request when set to
+// "true" by a peer in LoggingConfig.DebugTrustedProxies: the access-log
+// entry is written regardless of the route's "none" level or sampling,
+// at no less than info so the handler does not filter it, and includes
+// request and response bodies even when body logging is off.
+const DebugLogHeader = "X-Debug-Log"
+
 // Logging returns middleware that logs each request as structured JSON
 // including method, path, status code, latency, and client IP.
-// routeLogLevel maps a request path to its configured log level; pass nil
+// routeLogLevel maps a request to its route's configured log level; pass nil
 // for the default (Info for all requests). bodyConfig enables opt-in body
-// logging when non-nil.
-func Logging(logger *slog.Logger, routeLogLevel func(string) slog.Level, bodyConfig *LoggingConfig) func(http.Handler) http.Handler {
+// logging and access-log sampling when non-nil.
+func Logging(logger *slog.Logger, routeLogLevel func(*http.Request) slog.Level, bodyConfig *LoggingConfig) func(http.Handler) http.Handler {
 	if routeLogLevel == nil {
-		routeLogLevel = func(string) slog.Level { return slog.LevelInfo }
+		routeLogLevel = func(*http.Request) slog.Level { return slog.LevelInfo }
 	}
 
 	logBody := bodyConfig != nil && bodyConfig.BodyLogging
@@ -66,10 +108,24 @@ func Logging(logger *slog.Logger, routeLogLevel func(string) slog.Level, bodyCon
 	if bodyConfig != nil && bodyConfig.MaxBodyLogBytes > 0 {
 		maxBody = bodyConfig.MaxBodyLogBytes
 	}
+	var sampleRate func(*http.Request) float64
+	var debugPeers []*net.IPNet
+	fields := func(*http.Request) []string { return nil }
+	if bodyConfig != nil {
+		sampleRate = bodyConfig.SampleRate
+		debugPeers = ParseTrustedProxies(bodyConfig.DebugTrustedProxies)
+		if bodyConfig.Fields != nil {
+			fields = bodyConfig.Fields
+		}
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			level := routeLogLevel(r.URL.Path)
+			level := routeLogLevel(r)
+			debug := debugRequested(r, debugPeers)
+			if debug && (level == LogLevelNone || level < slog.LevelInfo) {
+				level = slog.LevelInfo
+			}
 
 			// Skip logging entirely for "none" routes.
 			if level == LogLevelNone {
@@ -78,16 +134,23 @@ func Logging(logger *slog.Logger, routeLogLevel func(string) slog.Level, bodyCon
 			}
 
 			start := time.Now()
+			captureBodies := logBody || debug
+
+			var extra *logAttrs
+			if debug || level <= slog.LevelDebug {
+				extra = &logAttrs{}
+				r = r.WithContext(context.WithValue(r.Context(), logAttrsKey, extra))
+			}
 
 			var reqBody string
-			if logBody && shouldLogBody(r.Header.Get("Content-Type")) && r.Body != nil {
+			if captureBodies && shouldLogBody(r.Header.Get("Content-Type")) && r.Body != nil {
 				reqBody = captureRequestBody(r, maxBody)
 			}
 
 			var recorder *statusRecorder
 			var respCapture *bodyCapture
 
-			if logBody && shouldLogBody("") { // we don't know response content-type yet
+			if captureBodies && shouldLogBody("") { // we don't know response content-type yet
 				respCapture = bodyCapturePool.Get().(*bodyCapture)
 				respCapture.Reset()
 				respCapture.maxBytes = maxBody
@@ -98,13 +161,28 @@ func Logging(logger *slog.Logger, routeLogLevel func(string) slog.Level, bodyCon
 
 			next.ServeHTTP(recorder, r)
 
-			attrs := []any{
-				"method", r.Method,
-				"path", r.URL.Path,
-				"status", recorder.statusCode,
-				"latency_ms", time.Since(start).Milliseconds(),
-				"client_ip", r.RemoteAddr,
-				"request_id", GetRequestID(r.Context()),
+			if !debug && sampleRate != nil && recorder.statusCode >= 200 && recorder.statusCode < 300 &&
+				!sampled(GetRequestID(r.Context()), sampleRate(r)) {
+				if respCapture != nil {
+					bodyCapturePool.Put(respCapture)
+				}
+				return
+			}
+
+			names := fields(r)
+			if len(names) == 0 {
+				names = DefaultLogFields
+			}
+			latency := time.Since(start)
+			attrs := make([]any, 0, 2*len(names)+4)
+			for _, name := range names {
+				attrs = append(attrs, logFieldKey(name), logFieldValue(name, r, recorder.statusCode, latency))
+			}
+			if debug {
+				attrs = append(attrs, "debug_log", true)
+			}
+			if extra != nil {
+				attrs = append(attrs, extra.get()...)
 			}
 
 			if reqBody != "" {
@@ -127,6 +205,114 @@ func Logging(logger *slog.Logger, routeLogLevel func(string) slog.Level, bodyCon
 	}
 }
 
+// logFieldKey returns the attribute key for an access-log field: the name
+// itself, or header_<name> in snake case for a "header:" field.
+func logFieldKey(name string) string {
+	header, ok := strings.CutPrefix(name, LogHeaderPrefix)
+	if !ok {
+		return name
+	}
+	return "header_" + strings.ReplaceAll(strings.ToLower(header), "-", "_")
+}
+
+// logFieldValue returns the value of one access-log field. Names outside
+// the known set (config validation rejects them) log as an empty string.
+func logFieldValue(name string, r *http.Request, status int, latency time.Duration) any {
+	switch name {
+	case "method":
+		return r.Method
+	case "path":
+		return r.URL.Path
+	case "query":
+		return r.URL.RawQuery
+	case "status":
+		return status
+	case "latency_ms":
+		return latency.Milliseconds()
+	case "client_ip":
+		return ClientIP(r)
+	case "request_id":
+		return GetRequestID(r.Context())
+	case "host":
+		return r.Host
+	case "proto":
+		return r.Proto
+	case "user_agent":
+		return r.UserAgent()
+	case "referer":
+		return r.Referer()
+	}
+	if header, ok := strings.CutPrefix(name, LogHeaderPrefix); ok {
+		v := r.Header.Get(header)
+		if _, secret := redactedLogHeaders[http.CanonicalHeaderKey(header)]; secret && v != "" {
+			return redactedValue
+		}
+		return v
+	}
+	return ""
+}
+
+// logAttrsKey carries the *logAttrs that inner middleware append to via
+// AddLogAttrs. It is only set for requests logged at debug level.
+const logAttrsKey ctxKey = "log_attrs"
+
+// logAttrs collects extra key-value pairs for a request's access-log entry.
+type logAttrs struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+func (a *logAttrs) add(kv ...any) {
+	a.mu.Lock()
+	a.attrs = append(a.attrs, kv...)
+	a.mu.Unlock()
+}
+
+func (a *logAttrs) get() []any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.attrs
+}
+
+// AddLogAttrs appends key-value pairs to the access-log entry of the
+// request carrying ctx. It is a no-op unless the request is logged at
+// debug level (by route log_level or DebugLogHeader), so callers can use
+// it for tuning detail without paying for it on every request.
+func AddLogAttrs(ctx context.Context, kv ...any) {
+	if a, ok := ctx.Value(logAttrsKey).(*logAttrs); ok {
+		a.add(kv...)
+	}
+}
+
+// debugRequested reports whether r carries DebugLogHeader from a direct
+// peer inside trusted. The header is only believed from the proxies we
+// trust, so arbitrary clients cannot turn on body logging.
+func debugRequested(r *http.Request, trusted []*net.IPNet) bool {
+	if len(trusted) == 0 || !strings.EqualFold(r.Header.Get(DebugLogHeader), "true") {
+		return false
+	}
+	return ipInNets(peerIP(r), trusted)
+}
+
+// sampled reports whether the request with the given ID falls inside the
+// sample rate. Hashing the ID keeps the decision stable for a request, so
+// it is logged in full or not at all; requests without an ID fall back to
+// a random draw.
+func sampled(requestID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	if requestID == "" {
+		return rand.Float64() < rate
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(requestID))
+	return float64(h.Sum64()>>11)/(1<<53) < rate
+}
+
 // shouldLogBody returns true if the content type is text-based.
 func shouldLogBody(contentType string) bool {
 	if contentType == "" {
@@ -156,28 +342,91 @@ func captureRequestBody(r *http.Request, maxBytes int) string {
 	return redactSensitive(s)
 }
 
+// redactedValue replaces the value of every sensitive field in logged bodies.
+const redactedValue = "***"
+
+// maxRedactParseBytes caps the body size redactSensitive will decode as
+// JSON. Larger bodies (and anything that is not a complete JSON document,
+// such as a truncated capture) fall back to sensitiveFieldRe.
+const maxRedactParseBytes = 64 << 10
+
+// sensitiveKeys are the field names, compared case-insensitively, whose
+// values are redacted wherever they appear in a JSON document.
+var sensitiveKeys = map[string]struct{}{
+	"password":      {},
+	"secret":        {},
+	"token":         {},
+	"key":           {},
+	"authorization": {},
+}
+
 // sensitiveFieldRe matches JSON key-value pairs for common sensitive fields.
-// Compiled once at package init — single-pass replacement avoids the O(n·k²)
-// cost of the previous approach that re-lowered the entire string per field.
+// The value may be a string (escaped quotes included, and possibly cut off
+// by truncation), a number, or a literal. Compiled once at package init —
+// single-pass replacement avoids the O(n·k²) cost of the previous approach
+// that re-lowered the entire string per field.
 var sensitiveFieldRe = regexp.MustCompile(
-	`(?i)"(?:password|secret|token|key|authorization)"\s*:\s*"[^"]*"`,
+	`(?i)("(?:password|secret|token|key|authorization)"\s*:\s*)` +
+		`(?:"(?:[^"\\]|\\.)*(?:"|\\?$)|-?[0-9][0-9.eE+-]*|true|false|null)`,
 )
 
 // redactSensitive replaces common sensitive field values in log output.
-// Uses a compiled regex for single-pass replacement instead of iterating
-// per-field with repeated ToLower calls.
+// Small bodies that parse as JSON are walked so values of any type, at any
+// depth, are redacted; everything else goes through a single-pass regex.
 func redactSensitive(s string) string {
-	return sensitiveFieldRe.ReplaceAllStringFunc(s, func(match string) string {
-		// Find the last `:"` pattern to locate the value portion.
-		colonQuote := strings.LastIndex(match, `"`)
-		// Walk backwards to find the opening quote of the value.
-		inner := match[:colonQuote]
-		valueOpen := strings.LastIndex(inner, `"`)
-		if valueOpen == -1 {
-			return match
+	if len(s) <= maxRedactParseBytes {
+		if out, ok := redactJSON(s); ok {
+			return out
+		}
+	}
+	return sensitiveFieldRe.ReplaceAllString(s, `${1}"`+redactedValue+`"`)
+}
+
+// redactJSON decodes s as a single JSON document, redacts sensitive keys
+// and re-encodes it with keys in sorted order. Returns false if s is not
+// valid JSON.
+func redactJSON(s string) (string, bool) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return "", false
+	}
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "", false // trailing data after the document
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(v)); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// redactValue walks a decoded JSON value, replacing the value of every
+// sensitive key — scalar, object or array — with redactedValue.
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if _, ok := sensitiveKeys[strings.ToLower(k)]; ok {
+				t[k] = redactedValue
+				continue
+			}
+			t[k] = redactValue(child)
 		}
-		return match[:valueOpen+1] + "***" + `"`
-	})
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactValue(child)
+		}
+	}
+	return v
 }
 
 // bodyCapturePool reuses bodyCapture structs to reduce GC pressure in the
@@ -238,3 +487,8 @@ func (br *bodyRecorder) Write(b []byte) (int, error) {
 	br.capture.Write(b)
 	return br.ResponseWriter.Write(b)
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (br *bodyRecorder) Unwrap() http.ResponseWriter {
+	return br.ResponseWriter
+}
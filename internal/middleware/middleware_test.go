@@ -3,6 +3,8 @@ package middleware
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -55,6 +57,229 @@ func TestLogging_CapturesStatusCode(t *testing.T) {
 	}
 }
 
+func TestLogging_SampleRate(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	cfg := &LoggingConfig{SampleRate: func(*http.Request) float64 { return 0.25 }}
+	handler := RequestID(Logging(logger, nil, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	const n = 2000
+	for i := 0; i < n; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	}
+	logged := strings.Count(buf.String(), `"status":200`)
+	if logged < n*20/100 || logged > n*30/100 {
+		t.Errorf("expected ~25%% of %d successful requests logged, got %d", n, logged)
+	}
+
+	buf.Reset()
+	for i := 0; i < 100; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	}
+	if got := strings.Count(buf.String(), `"status":500`); got != 100 {
+		t.Errorf("expected every 500 logged, got %d of 100", got)
+	}
+}
+
+func TestLogging_SampleRateDeterministicPerRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	cfg := &LoggingConfig{SampleRate: func(*http.Request) float64 { return 0.5 }}
+	handler := RequestID(Logging(logger, nil, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		var counts [2]int
+		for j := range counts {
+			buf.Reset()
+			req := httptest.NewRequest("GET", "/ok", nil)
+			req.Header.Set("X-Request-ID", id)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			counts[j] = strings.Count(buf.String(), id)
+		}
+		if counts[0] != counts[1] {
+			t.Errorf("request ID %s: sampling decision changed between identical requests", id)
+		}
+	}
+}
+
+func TestLogging_DebugHeaderFromTrustedPeer(t *testing.T) {
+	cfg := &LoggingConfig{
+		DebugTrustedProxies: []string{"10.0.0.0/8"},
+		SampleRate:          func(*http.Request) float64 { return 0 },
+	}
+	none := func(*http.Request) slog.Level { return LogLevelNone }
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		wantLogged bool
+	}{
+		{"trusted peer", "10.1.2.3:4000", "true", true},
+		{"untrusted peer", "203.0.113.9:4000", "true", false},
+		{"trusted peer without header", "10.1.2.3:4000", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			handler := Logging(logger, none, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"echo":` + string(body) + `}`))
+			}))
+
+			req := httptest.NewRequest("POST", "/quiet", strings.NewReader(`"hello"`))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				req.Header.Set(DebugLogHeader, tt.header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			out := buf.String()
+			if !tt.wantLogged {
+				if out != "" {
+					t.Errorf("expected no log output, got %s", out)
+				}
+				return
+			}
+			for _, want := range []string{`"debug_log":true`, `"request_body":"\"hello\""`, `"response_body":"{\"echo\":\"hello\"}"`} {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected %s in log, got %s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestLogging_SampleRateRespectsNoneLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	cfg := &LoggingConfig{SampleRate: func(*http.Request) float64 { return 1 }}
+	none := func(*http.Request) slog.Level { return LogLevelNone }
+	handler := Logging(logger, none, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/quiet", nil))
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no log output for a none-level route, got %s", buf.String())
+	}
+}
+
+func TestLogging_CustomFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	cfg := &LoggingConfig{Fields: func(r *http.Request) []string {
+		if strings.HasPrefix(r.URL.Path, "/default") {
+			return nil
+		}
+		return []string{"method", "query", "status", "header:X-Tenant-ID", "header:Authorization"}
+	}}
+	handler := Logging(logger, nil, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/orders?page=2", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode log entry: %v", err)
+	}
+	want := map[string]any{
+		"method":               "GET",
+		"query":                "page=2",
+		"status":               float64(200),
+		"header_x_tenant_id":   "acme",
+		"header_authorization": "***",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	for _, k := range []string{"path", "client_ip", "latency_ms", "request_id"} {
+		if _, ok := entry[k]; ok {
+			t.Errorf("%s logged but not in the configured fields", k)
+		}
+	}
+
+	buf.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/default", nil))
+	for _, k := range DefaultLogFields {
+		if !strings.Contains(buf.String(), `"`+k+`":`) {
+			t.Errorf("expected default field %s when no fields are configured, got %s", k, buf.String())
+		}
+	}
+}
+
+func TestRedactSensitive(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		leaked []string
+		want   string
+	}{
+		{
+			name:   "numeric secret",
+			in:     `{"user":"alice","password":12345}`,
+			leaked: []string{"12345"},
+			want:   `{"password":"***","user":"alice"}`,
+		},
+		{
+			name:   "nested object",
+			in:     `{"auth":{"Token":"abc","scope":"read"},"items":[{"key":{"id":7}}]}`,
+			leaked: []string{"abc", `"id"`},
+			want:   `{"auth":{"Token":"***","scope":"read"},"items":[{"key":"***"}]}`,
+		},
+		{
+			name:   "escaped quotes",
+			in:     `{"secret":"a\"b\"c","ok":true}`,
+			leaked: []string{`b\"c`},
+			want:   `{"ok":true,"secret":"***"}`,
+		},
+		{
+			name:   "truncated body falls back to regex",
+			in:     `{"password":"p\"w","key":false,"secret":-1.5e3,"token":"abcdef...[truncated]`,
+			leaked: []string{"p\\\"w", "false", "-1.5e3", "abcdef"},
+			want:   `{"password":"***","key":"***","secret":"***","token":"***"`,
+		},
+		{
+			name: "non-sensitive body unchanged",
+			in:   `name=alice&role=admin`,
+			want: `name=alice&role=admin`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactSensitive(tt.in)
+			if got != tt.want {
+				t.Errorf("redactSensitive(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+			for _, s := range tt.leaked {
+				if strings.Contains(got, s) {
+					t.Errorf("output %s still contains %q", got, s)
+				}
+			}
+		})
+	}
+}
+
 func TestCORS_Headers(t *testing.T) {
 	cfg := DefaultCORSConfig()
 	handler := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -180,6 +405,103 @@ func TestRecovery_NoPanic(t *testing.T) {
 	}
 }
 
+// headerCountingWriter counts WriteHeader calls on the underlying recorder.
+type headerCountingWriter struct {
+	*httptest.ResponseRecorder
+	writeHeaders int
+}
+
+func (w *headerCountingWriter) WriteHeader(code int) {
+	w.writeHeaders++
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func TestRecovery_PanicAfterCommitAborts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := Recovery(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		panic("mid-stream panic")
+	}))
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	rec := &headerCountingWriter{ResponseRecorder: httptest.NewRecorder()}
+
+	func() {
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Errorf("expected re-panic with http.ErrAbortHandler, got %v", err)
+			}
+		}()
+		handler.ServeHTTP(rec, req)
+	}()
+
+	if rec.writeHeaders != 1 {
+		t.Errorf("expected exactly one WriteHeader, got %d", rec.writeHeaders)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected committed status 200 unchanged, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); body != "partial" {
+		t.Errorf("expected body to be left as written, got %q", body)
+	}
+	if !strings.Contains(buf.String(), "mid-stream panic") || !strings.Contains(buf.String(), `"response_committed":true`) {
+		t.Errorf("expected committed panic to be logged, got %s", buf.String())
+	}
+}
+
+func TestRecovery_AbortHandlerPassesThrough(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := Recovery(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	req := httptest.NewRequest("GET", "/abort", nil)
+	rec := httptest.NewRecorder()
+
+	func() {
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Errorf("expected http.ErrAbortHandler to propagate, got %v", err)
+			}
+		}()
+		handler.ServeHTTP(rec, req)
+	}()
+
+	if strings.Contains(buf.String(), "panic recovered") {
+		t.Error("expected deliberate abort not to be logged as a panic")
+	}
+}
+
+func TestRecovery_PanicAfterCommitClosesConnection(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(Recovery(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		_ = http.NewResponseController(w).Flush()
+		panic("mid-stream panic")
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("expected truncated body error, got full body %q", body)
+	}
+	if strings.Contains(string(body), "GATEWAY_INTERNAL_ERROR") {
+		t.Errorf("expected no error envelope appended to the stream, got %q", body)
+	}
+}
+
 // --- BodyLimit tests ---
 
 func TestBodyLimit_UnderLimit(t *testing.T) {
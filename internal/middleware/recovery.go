@@ -10,23 +10,79 @@ import (
 
 // Recovery returns middleware that recovers from panics, logs the stack trace,
 // and returns a 500 Internal Server Error JSON response.
+//
+// If the response was already committed (status line or body bytes sent)
+// when the panic happened, a clean 500 is impossible; Recovery logs the panic
+// and re-panics with http.ErrAbortHandler so net/http drops the connection
+// instead of appending an error body to a half-written response. Panics that
+// are already http.ErrAbortHandler (e.g. from httputil.ReverseProxy when the
+// backend fails mid-stream) are passed through the same way.
 func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &commitWriter{ResponseWriter: w}
 			defer func() {
-				if err := recover(); err != nil {
-					stack := string(debug.Stack())
-					logger.Error("panic recovered",
-						"error", err,
-						"stack", stack,
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					logger.Debug("request aborted",
 						"method", r.Method,
 						"path", r.URL.Path,
 						"request_id", GetRequestID(r.Context()),
 					)
-					apierror.WriteJSON(w, r, http.StatusInternalServerError, apierror.InternalError, "an unexpected error occurred")
+					panic(err)
+				}
+				stack := string(debug.Stack())
+				logger.Error("panic recovered",
+					"error", err,
+					"stack", stack,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request_id", GetRequestID(r.Context()),
+					"response_committed", cw.committed,
+				)
+				if cw.committed {
+					panic(http.ErrAbortHandler)
 				}
+				apierror.WriteJSON(w, r, http.StatusInternalServerError, apierror.InternalError, "an unexpected error occurred")
 			}()
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(cw, r)
 		})
 	}
 }
+
+// commitWriter records whether the response has been committed to the
+// client, i.e. whether headers can still be changed.
+type commitWriter struct {
+	http.ResponseWriter
+	committed bool
+}
+
+func (cw *commitWriter) WriteHeader(code int) {
+	// 1xx informational responses do not commit the final status.
+	if code >= 200 {
+		cw.committed = true
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *commitWriter) Write(b []byte) (int, error) {
+	cw.committed = true
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush commits the response as well; it forwards to the underlying writer
+// when it supports flushing (streaming and SSE responses).
+func (cw *commitWriter) Flush() {
+	cw.committed = true
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (cw *commitWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
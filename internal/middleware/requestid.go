@@ -19,18 +19,34 @@ const RequestIDKey ctxKey = "request_id"
 // UUID v4 is generated. The ID is set on the response header, the request
 // header (for backend propagation), and stored in the request context.
 func RequestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := r.Header.Get("X-Request-ID")
-		if id == "" {
-			id = newUUID()
-		}
+	return RequestIDFrom(nil)(next)
+}
+
+// RequestIDFrom is RequestID with additional inbound correlation headers:
+// when X-Request-ID is absent, the first of correlationHeaders present on
+// the request (in order) becomes the request ID before a new UUID is
+// generated. The chosen value is propagated as X-Request-ID.
+func RequestIDFrom(correlationHeaders []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			for _, h := range correlationHeaders {
+				if id != "" {
+					break
+				}
+				id = r.Header.Get(h)
+			}
+			if id == "" {
+				id = newUUID()
+			}
 
-		w.Header().Set("X-Request-ID", id)
-		r.Header.Set("X-Request-ID", id)
+			w.Header().Set("X-Request-ID", id)
+			r.Header.Set("X-Request-ID", id)
 
-		ctx := context.WithValue(r.Context(), RequestIDKey, id)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			ctx := context.WithValue(r.Context(), RequestIDKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 // GetRequestID extracts the request ID from a context. Returns empty string
@@ -107,3 +107,40 @@ func TestGetRequestID_EmptyContext(t *testing.T) {
 		t.Errorf("expected empty string for context without request ID, got %q", id)
 	}
 }
+
+func TestRequestIDFrom_UsesFirstCorrelationHeader(t *testing.T) {
+	var capturedID string
+	handler := RequestIDFrom([]string{"X-Trace-Id", "X-Correlation-Id"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedID = GetRequestID(r.Context())
+		if got := r.Header.Get("X-Request-ID"); got != capturedID {
+			t.Errorf("backend X-Request-ID = %q, want %q", got, capturedID)
+		}
+	}))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"trace id", map[string]string{"X-Trace-Id": "trace-123"}, "trace-123"},
+		{"order wins", map[string]string{"X-Correlation-Id": "corr-1", "X-Trace-Id": "trace-2"}, "trace-2"},
+		{"request id wins", map[string]string{"X-Request-ID": "req-9", "X-Trace-Id": "trace-3"}, "req-9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if capturedID != tt.want {
+				t.Errorf("request ID = %q, want %q", capturedID, tt.want)
+			}
+			if got := rec.Header().Get("X-Request-ID"); got != tt.want {
+				t.Errorf("response X-Request-ID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// Response headers identifying the gateway build that served a request.
+const (
+	ServerHeader         = "Server"
+	GatewayVersionHeader = "X-Gateway-Version"
+)
+
+// VersionHeaders returns middleware that sets Server ("gateway-core/<version>")
+// and X-Gateway-Version on every response. The headers are applied when the
+// response header is written, so they replace any Server header the proxy
+// copied from the backend.
+func VersionHeaders(version string) func(http.Handler) http.Handler {
+	server := "gateway-core/" + version
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&versionWriter{ResponseWriter: w, server: server, version: version}, r)
+		})
+	}
+}
+
+// versionWriter stamps the version headers just before the status line.
+type versionWriter struct {
+	http.ResponseWriter
+	server, version string
+	wrote           bool
+}
+
+func (vw *versionWriter) WriteHeader(code int) {
+	if !vw.wrote {
+		vw.wrote = true
+		vw.Header().Set(ServerHeader, vw.server)
+		vw.Header().Set(GatewayVersionHeader, vw.version)
+	}
+	vw.ResponseWriter.WriteHeader(code)
+}
+
+func (vw *versionWriter) Write(b []byte) (int, error) {
+	if !vw.wrote {
+		vw.WriteHeader(http.StatusOK)
+	}
+	return vw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (vw *versionWriter) Unwrap() http.ResponseWriter {
+	return vw.ResponseWriter
+}
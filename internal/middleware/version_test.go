@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionHeaders_InjectedVersion(t *testing.T) {
+	handler := VersionHeaders("v1.4.2")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Simulate a backend Server header copied by the reverse proxy.
+		w.Header().Set("Server", "nginx/1.25")
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if got := rec.Header().Get(GatewayVersionHeader); got != "v1.4.2" {
+		t.Errorf("%s = %q, want v1.4.2", GatewayVersionHeader, got)
+	}
+	if got := rec.Header().Values(ServerHeader); len(got) != 1 || got[0] != "gateway-core/v1.4.2" {
+		t.Errorf("%s = %v, want [gateway-core/v1.4.2]", ServerHeader, got)
+	}
+}
+
+func TestVersionHeaders_ExplicitWriteHeader(t *testing.T) {
+	handler := VersionHeaders("dev")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if got := rec.Header().Get(GatewayVersionHeader); got != "dev" {
+		t.Errorf("%s = %q, want dev", GatewayVersionHeader, got)
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// errClientBodyTimeout is returned by readClientBody when the client did not
+// finish sending the body within the route's client_body_timeout_ms.
+var errClientBodyTimeout = errors.New("client body read timed out")
+
+// readClientBody reads r's body in full under a read deadline of timeout,
+// then replaces it with an in-memory copy so backend attempts never wait
+// on the client. The deadline is set on the connection through
+// http.ResponseController; writers that cannot set one (e.g. in tests) fall
+// back to a timer that closes the body. Errors other than the timeout —
+// an oversized body, a client disconnect — are returned unchanged.
+func readClientBody(w http.ResponseWriter, r *http.Request, timeout time.Duration) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	rc := http.NewResponseController(w)
+	var timedOut func() bool
+	if err := rc.SetReadDeadline(time.Now().Add(timeout)); err == nil {
+		defer func() { _ = rc.SetReadDeadline(time.Time{}) }()
+		timedOut = func() bool { return false }
+	} else {
+		body := r.Body
+		timer := time.AfterFunc(timeout, func() { _ = body.Close() })
+		defer timer.Stop()
+		timedOut = func() bool { return !timer.Stop() }
+	}
+
+	var buf bytes.Buffer
+	if r.ContentLength > 0 {
+		buf.Grow(int(r.ContentLength))
+	}
+	_, err := io.Copy(&buf, r.Body)
+	if err != nil {
+		var ne net.Error
+		if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) || timedOut() {
+			return errClientBodyTimeout
+		}
+		return err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(buf.Bytes()))
+	r.ContentLength = int64(buf.Len())
+	r.TransferEncoding = nil
+	return nil
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// hopHeaders are the hop-by-hop headers of RFC 9110 §7.6.1, which apply
+// to one connection and must not be forwarded.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopByHopHeaders deletes the hop-by-hop headers from h, including
+// any named in its Connection header. ReverseProxy does this for the
+// responses it receives; responses fetched by the gateway itself, such as
+// followed redirects, need it too.
+func removeHopByHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// stripResponseHeaders deletes the gateway-wide and route's
+// strip_response_headers from h.
+func stripResponseHeaders(h http.Header, info *routeInfo) {
+	for _, name := range info.stripHeaders {
+		h.Del(name)
+	}
+	for _, name := range info.route.StripResponseHeaders {
+		h.Del(name)
+	}
+}
+
+// setResponseHeaders applies route's response_headers to h: headers the
+// backend sent are kept unless response_headers_override is set.
+func setResponseHeaders(h http.Header, route config.RouteConfig) {
+	for k, v := range route.ResponseHeaders {
+		if route.ResponseHeadersOverride || h.Get(k) == "" {
+			h.Set(k, v)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/routing"
+)
+
+// hostIndex groups routes by the hosts they serve so a request is matched
+// against only the routes for its Host. Lookup tries, in order: routes
+// naming the host exactly, routes with a matching wildcard (longest
+// pattern first), then routes without hosts. Within a group routes keep
+// MatchPriority order, and the first group with a match on path and
+// match conditions wins — the
+// same result as config.SelectRoute, without scanning every route.
+type hostIndex struct {
+	exact     map[string][]config.RouteConfig // normalized host → routes
+	wildcards []wildcardRoutes                // longest suffix first
+	anyHost   []config.RouteConfig
+}
+
+type wildcardRoutes struct {
+	pattern string // "*.foo.com", lower-cased
+	routes  []config.RouteConfig
+}
+
+// newHostIndex builds the index from routes already sorted by
+// MatchPriority.
+func newHostIndex(sorted []config.RouteConfig) *hostIndex {
+	idx := &hostIndex{exact: make(map[string][]config.RouteConfig)}
+	wild := make(map[string][]config.RouteConfig)
+	for _, route := range sorted {
+		if len(route.Hosts) == 0 {
+			idx.anyHost = append(idx.anyHost, route)
+			continue
+		}
+		for _, h := range route.Hosts {
+			h = strings.ToLower(h)
+			if strings.HasPrefix(h, "*.") {
+				wild[h] = append(wild[h], route)
+			} else {
+				idx.exact[h] = append(idx.exact[h], route)
+			}
+		}
+	}
+	for pattern, routes := range wild {
+		idx.wildcards = append(idx.wildcards, wildcardRoutes{pattern: pattern, routes: routes})
+	}
+	sort.Slice(idx.wildcards, func(i, j int) bool {
+		return len(idx.wildcards[i].pattern) > len(idx.wildcards[j].pattern)
+	})
+	return idx
+}
+
+// match returns the route serving r.
+func (idx *hostIndex) match(r *http.Request) (config.RouteConfig, bool) {
+	host := routing.NormalizeHost(r.Host)
+	if route, ok := firstMatch(idx.exact[host], r); ok {
+		return route, true
+	}
+	for _, w := range idx.wildcards {
+		if routing.MatchesHost(host, w.pattern) {
+			if route, ok := firstMatch(w.routes, r); ok {
+				return route, true
+			}
+		}
+	}
+	return firstMatch(idx.anyHost, r)
+}
+
+// firstMatch returns the first of routes whose path, then conditions,
+// match r.
+func firstMatch(routes []config.RouteConfig, r *http.Request) (config.RouteConfig, bool) {
+	for _, route := range routes {
+		if route.MatchesPath(r.URL.Path) && route.MatchesConditions(r) {
+			return route, true
+		}
+	}
+	return config.RouteConfig{}, false
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+type ctxKey int
+
+// routeInfoKey carries the matched route and the client-facing origin from
+// ServeHTTP to ModifyResponse. Proxies are shared across routes with the same
+// backend, so per-route response policy cannot be captured in the closure.
+const routeInfoKey ctxKey = iota
+
+// routeInfo is the per-request state ModifyResponse and the ErrorHandler
+// need.
+type routeInfo struct {
+	client         context.Context // the client request's context, without the attempt timeout
+	route          config.RouteConfig
+	target         *url.URL // resolved backend for a templated route, else nil
+	externalScheme string
+	externalHost   string
+	stripHeaders   []string // server.strip_response_headers
+	stale          *staleStore
+	staleKey       string // key for a serve_stale_on_open response; "" when not stored
+}
+
+// withRouteInfo stores the matched route, its resolved backend target (nil
+// unless the backend is templated), the gateway-wide response headers to
+// strip and the gateway's external origin, as seen by the client, on the
+// request context.
+func withRouteInfo(r *http.Request, route config.RouteConfig, target *url.URL, stripHeaders []string) *http.Request {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	info := &routeInfo{client: r.Context(), route: route, target: target, externalScheme: scheme, externalHost: r.Host, stripHeaders: stripHeaders}
+	return r.WithContext(context.WithValue(r.Context(), routeInfoKey, info))
+}
+
+func routeInfoFrom(ctx context.Context) *routeInfo {
+	info, _ := ctx.Value(routeInfoKey).(*routeInfo)
+	return info
+}
+
+// modifyResponse returns the ReverseProxy.ModifyResponse hook for a backend.
+// It applies the matched route's redirect policy to 3xx responses, then
+// works on the response the client will get: it strips the configured
+// response headers and sets the route's response_headers and, with
+// wrap_upstream_errors, replaces non-JSON 5xx bodies; with
+// strip_response_fields, it removes those keys from JSON bodies. Last, it
+// keeps a copy of a good response for serve_stale_on_open. logger gets
+// the errors of cleanup that does not fail the response.
+func modifyResponse(target *url.URL, transport http.RoundTripper, logger *slog.Logger) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		info := routeInfoFrom(resp.Request.Context())
+		if info == nil {
+			return nil
+		}
+		if isRedirect(resp.StatusCode) {
+			switch info.route.RedirectPolicy {
+			case "rewrite":
+				rewriteLocation(resp, target, info)
+			case "follow":
+				if err := followRedirects(resp, target, transport, info, logger); err != nil {
+					return err
+				}
+			}
+		}
+		stripResponseHeaders(resp.Header, info)
+		setResponseHeaders(resp.Header, info.route)
+		if info.route.WrapUpstreamErrors {
+			wrapUpstreamError(resp)
+		}
+		if len(info.route.StripResponseFields) > 0 {
+			if err := stripResponseFields(resp, info.route.StripResponseFields); err != nil {
+				return err
+			}
+		}
+		captureLastGood(resp, info)
+		return nil
+	}
+}
@@ -5,8 +5,12 @@ package proxy
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math/rand/v2"
+	"mime"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -15,13 +19,17 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dskow/gateway-core/internal/apierror"
 	"github.com/dskow/gateway-core/internal/circuitbreaker"
 	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/jsonschema"
 	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/dskow/gateway-core/internal/middleware"
 	"github.com/dskow/gateway-core/internal/routing"
+	"github.com/dskow/gateway-core/internal/script"
 )
 
 // responseBufferPool reuses responseBuffer structs across retry attempts
@@ -36,24 +44,65 @@ var responseBufferPool = sync.Pool{
 // them to the appropriate backend.
 //
 // Proxies are keyed by backend identity (normalized scheme://host:port[/path])
-// rather than by PathPrefix, so two routes sharing a backend reuse the same
-// *httputil.ReverseProxy — and therefore the same Transport and connection
-// pool — instead of each allocating its own. routeBackendKey lets the request
-// path resolve route → backend key → proxy.
+// and pool settings rather than by PathPrefix, so two routes sharing a
+// backend reuse the same *httputil.ReverseProxy instead of each allocating
+// its own. Transports, and with them connection pools, are shared more
+// widely still: by origin (scheme://host:port) and pool settings, whatever
+// the backend path. routeBackendKey lets the request path resolve route →
+// backend key → proxy.
+//
+// Per-route state is keyed by RouteConfig.ID, which is the path prefix for
+// routes without hosts. It lives in a routeTable that UpdateRoutes replaces
+// as a whole, so routes can be hot-reloaded while requests are in flight.
 type Router struct {
-	routes          []config.RouteConfig
+	table         atomic.Pointer[routeTable]
+	updateMu      sync.Mutex // serializes table rebuilds
+	logger        *slog.Logger
+	metrics       *metrics.Metrics
+	upgradeWarned sync.Map // route ID → true once warnUpgradeRetries logged
+
+	serverTiming bool     // add Server-Timing to proxied responses; see SetServerTiming
+	stripHeaders []string // removed from every backend response; see SetStripResponseHeaders
+
+	// retrySlots caps requests retrying at once across all routes; nil
+	// means no cap. See SetMaxConcurrentRetries.
+	retrySlots chan struct{}
+
+	// sizes flags unusually large bodies; nil unless
+	// logging.size_anomaly is enabled. See SetSizeAnomaly.
+	sizes *sizeTracker
+
+	// stale holds last-good responses for serve_stale_on_open routes. It
+	// outlives the route table, so a reload keeps what was stored.
+	stale *staleStore
+}
+
+// routeTable is the per-route state derived from a route list. It is never
+// modified once published: a request loads the current table once and uses
+// it throughout, so a reload never mixes old and new routes mid-request.
+type routeTable struct {
+	routes          []config.RouteConfig // as passed to New or UpdateRoutes
+	index           *hostIndex
 	proxies         map[string]*httputil.ReverseProxy
-	routeBackendKey map[string]string // pathPrefix → backend key into proxies
+	transports      map[string]*upstreamTransport // transport key → shared Transport
+	routeBackendKey map[string]string             // route ID → backend key into proxies
 	breakers        map[string]*circuitbreaker.CompositeBreaker
-	methodSets      map[string]map[string]bool // pathPrefix → allowed methods (upper-case)
-	logger          *slog.Logger
-	metrics         *metrics.Metrics
+	methodSets      map[string]map[string]bool    // route ID → allowed methods (upper-case)
+	methodRewrites  map[string]map[string]string  // route ID → client method → backend method (upper-case)
+	schemas         map[string]*jsonschema.Schema // route ID → compiled request_schema
+	scripts         map[string]*script.Script     // route ID → compiled request_script
+	queues          map[string]*routeQueue        // route ID → queue, for routes with max_concurrent
+
+	// Unmatched requests: proxied through defaultRoute, or answered with
+	// defaultStatic; both nil means 404. See SetDefaultRoute.
+	def           *config.DefaultRouteConfig
+	defaultRoute  *config.RouteConfig
+	defaultStatic *config.DefaultRouteConfig
 }
 
-// backendKey returns a stable identity key for a backend URL. Two routes
-// whose parsed backend URLs agree on scheme, host, port, and path produce
-// the same key and will share a single *httputil.ReverseProxy.
-func backendKey(u *url.URL) string {
+// originKey returns scheme://host:port for a backend URL, with the
+// scheme's default port made explicit.
+func originKey(u *url.URL) string {
 	host := u.Host
 	if !strings.Contains(host, ":") {
 		switch u.Scheme {
@@ -63,56 +112,152 @@ func backendKey(u *url.URL) string {
 			host += ":80"
 		}
 	}
+	return u.Scheme + "://" + host
+}
+
+// backendKey returns a stable identity key for a backend URL. Two routes
+// whose parsed backend URLs agree on scheme, host, port, and path produce
+// the same key and will share a single *httputil.ReverseProxy.
+func backendKey(u *url.URL) string {
 	// Preserve path: routes targeting the same host:port but different
 	// backend paths must keep separate proxies because the Director
-	// prepends the target's path to each request.
+	// prepends the target's path to each request. They still share a
+	// Transport; see sharedTransport.
 	path := strings.TrimRight(u.Path, "/")
-	return u.Scheme + "://" + host + path
+	return originKey(u) + path
+}
+
+// poolKey fingerprints the route settings that configure a backend's
+// Transport: "" for the defaults.
+func poolKey(route config.RouteConfig) string {
+	var k string
+	if p := route.ConnectionPool; p != nil {
+		k = fmt.Sprintf("pool=%d/%d/%s", p.MaxIdleConns, p.MaxIdlePerHost, p.IdleTimeout)
+	}
+	if d := route.ResponseHeaderTimeout(); d > 0 {
+		k += fmt.Sprintf(" header_timeout=%s", d)
+	}
+	return strings.TrimSpace(k)
+}
+
+// withPool qualifies key with route's poolKey, so routes whose pool
+// settings differ do not share what key identifies.
+func withPool(key string, route config.RouteConfig) string {
+	if pk := poolKey(route); pk != "" {
+		return key + " " + pk
+	}
+	return key
 }
 
 // New creates a Router from the given route configurations. Routes are
-// sorted by path prefix length (longest first) for correct matching.
-// breakers maps backend URLs to their circuit breaker instances. m may be
+// indexed by host, then sorted by MatchPriority (exact, then regex in
+// config order, then prefix routes longest first, with routes that have
+// match conditions ahead of those without) so the first match wins.
+// breakers maps RouteConfig.BreakerKey values to circuit breaker instances. m may be
 // nil for tests that do not exercise the metrics path.
 func New(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker, logger *slog.Logger, m *metrics.Metrics) (*Router, error) {
-	sorted := make([]config.RouteConfig, len(routes))
-	copy(sorted, routes)
-	sort.Slice(sorted, func(i, j int) bool {
-		return len(sorted[i].PathPrefix) > len(sorted[j].PathPrefix)
+	rt := &Router{logger: logger, metrics: m, stale: newStaleStore(staleMaxEntries, staleMaxBytes)}
+	t, err := rt.buildTable(routes, breakers, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	rt.table.Store(t)
+	return rt, nil
+}
+
+// Update replaces the router's routes, default route and breakers, for a
+// config reload. The new table is built in full and swapped in once, so
+// on error nothing changes and no request sees the new routes with the old
+// default route or the reverse. Requests already in flight finish on the
+// proxies they started with; later ones see the new table. Connection
+// pools are kept for backends still in use, and idle connections to the
+// others are closed. See SetDefaultRoute for def.
+func (rt *Router) Update(routes []config.RouteConfig, def *config.DefaultRouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) error {
+	rt.updateMu.Lock()
+	defer rt.updateMu.Unlock()
+	return rt.update(routes, def, breakers)
+}
+
+// UpdateRoutes is Update keeping the default route.
+func (rt *Router) UpdateRoutes(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) error {
+	rt.updateMu.Lock()
+	defer rt.updateMu.Unlock()
+	return rt.update(routes, rt.table.Load().def, breakers)
+}
+
+// update builds and swaps in a table for routes, def and breakers. The
+// caller holds updateMu.
+func (rt *Router) update(routes []config.RouteConfig, def *config.DefaultRouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker) error {
+	old := rt.table.Load()
+	t, err := rt.buildTable(routes, breakers, def, old)
+	if err != nil {
+		return err
+	}
+	rt.swapTable(old, t)
+	if rt.sizes != nil {
+		rt.sizes.prune(routes)
+	}
+	return nil
+}
+
+// swapTable publishes t and closes the idle connections of old's
+// transports that t no longer uses.
+func (rt *Router) swapTable(old, t *routeTable) {
+	rt.table.Store(t)
+	for key, tr := range old.transports {
+		if t.transports[key] != tr {
+			tr.closeIdleConnections()
+		}
+	}
+}
+
+// buildTable derives a routeTable from routes and the default route def.
+// Transports and route queues whose settings are unchanged are carried
+// over from prev, which may be nil, so a reload keeps connection pools and
+// the slots held by in-flight requests.
+func (rt *Router) buildTable(routes []config.RouteConfig, breakers map[string]*circuitbreaker.CompositeBreaker, def *config.DefaultRouteConfig, prev *routeTable) (*routeTable, error) {
+	logger, m := rt.logger, rt.metrics
+	var prevTransports map[string]*upstreamTransport
+	var prevQueues map[string]*routeQueue
+	if prev != nil {
+		prevTransports, prevQueues = prev.transports, prev.queues
+	}
+
+	// Regex patterns are compiled here, once per table, rather than on
+	// every match.
+	sorted, err := config.CompilePaths(routes)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MatchPriority() > sorted[j].MatchPriority()
 	})
 
 	proxies := make(map[string]*httputil.ReverseProxy, len(routes))
 	routeBackendKey := make(map[string]string, len(sorted))
+	transports := make(map[string]*upstreamTransport, len(routes))
 	for _, route := range sorted {
+		if route.BackendTemplated() {
+			// The target is only known per request: one proxy and
+			// Transport per route, pointed at the resolved backend by its
+			// Director.
+			key := "template:" + route.ID()
+			routeBackendKey[route.ID()] = key
+			transports[key] = newUpstreamTransport(route, route.Backend, m)
+			proxies[key] = newTemplatedProxy(route, logger, transports[key])
+			continue
+		}
 		target, err := url.Parse(route.Backend)
 		if err != nil {
 			return nil, fmt.Errorf("invalid backend URL %q for route %q: %w", route.Backend, route.PathPrefix, err)
 		}
-		key := backendKey(target)
-		routeBackendKey[route.PathPrefix] = key
-		if _, exists := proxies[key]; exists {
-			// Another route already built this proxy. Reusing it is the
-			// whole point — one Transport and one connection pool per
-			// backend. If a later route specified a different
-			// ConnectionPool, the first wins; warn so the config error
-			// is visible instead of silently ignored.
-			if route.ConnectionPool != nil {
-				logger.Warn("ignoring connection_pool override for shared backend",
-					"path_prefix", route.PathPrefix, "backend", route.Backend)
-			}
-			continue
-		}
-		rte := route // capture for closure
-		proxy := httputil.NewSingleHostReverseProxy(target)
-
-		// Configure per-backend connection pool via custom Transport.
-		proxy.Transport = buildTransport(route.ConnectionPool)
-
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			logger.Error("proxy error", "error", err, "backend", rte.Backend, "path", r.URL.Path)
-			apierror.WriteJSON(w, r, http.StatusBadGateway, apierror.UpstreamUnavailable, "upstream service unavailable")
+		// Reusing proxies and Transports is the whole point — one
+		// connection pool per backend and pool configuration.
+		key := withPool(backendKey(target), route)
+		routeBackendKey[route.ID()] = key
+		if _, exists := proxies[key]; !exists {
+			proxies[key] = newBackendProxy(route, target, logger, sharedTransport(transports, prevTransports, route, target, m))
 		}
-		proxies[key] = proxy
 	}
 
 	// Pre-build method sets for O(1) method validation (P7).
@@ -123,24 +268,244 @@ func New(routes []config.RouteConfig, breakers map[string]*circuitbreaker.Compos
 			for _, m := range route.Methods {
 				ms[strings.ToUpper(m)] = true
 			}
-			methodSets[route.PathPrefix] = ms
+			methodSets[route.ID()] = ms
+		}
+	}
+	schemas := make(map[string]*jsonschema.Schema)
+	for _, route := range sorted {
+		schema, err := route.CompileRequestSchema()
+		if err != nil {
+			return nil, fmt.Errorf("invalid request schema for route %q: %w", route.PathPrefix, err)
+		}
+		if schema != nil {
+			schemas[route.ID()] = schema
+		}
+	}
+
+	scripts := make(map[string]*script.Script)
+	for _, route := range sorted {
+		s, err := route.CompileRequestScript()
+		if err != nil {
+			return nil, fmt.Errorf("invalid request script for route %q: %w", route.PathPrefix, err)
+		}
+		if s != nil {
+			scripts[route.ID()] = s
 		}
 	}
 
-	return &Router{
-		routes:          sorted,
+	methodRewrites := make(map[string]map[string]string)
+	for _, route := range sorted {
+		if len(route.MethodRewrite) > 0 {
+			mr := make(map[string]string, len(route.MethodRewrite))
+			for from, to := range route.MethodRewrite {
+				mr[strings.ToUpper(from)] = strings.ToUpper(to)
+			}
+			methodRewrites[route.ID()] = mr
+		}
+	}
+
+	queues := make(map[string]*routeQueue)
+	for _, route := range sorted {
+		if route.MaxConcurrent > 0 {
+			maxWait := time.Duration(route.QueueTimeoutMs) * time.Millisecond
+			if q := prevQueues[route.ID()]; q != nil && cap(q.sem) == route.MaxConcurrent && q.maxWait == maxWait {
+				queues[route.ID()] = q
+				continue
+			}
+			queues[route.ID()] = newRouteQueue(route.MaxConcurrent, maxWait)
+		}
+	}
+
+	t := &routeTable{
+		routes:          routes,
+		index:           newHostIndex(sorted),
 		proxies:         proxies,
+		transports:      transports,
 		routeBackendKey: routeBackendKey,
 		breakers:        breakers,
 		methodSets:      methodSets,
-		logger:          logger,
-		metrics:         m,
-	}, nil
+		methodRewrites:  methodRewrites,
+		schemas:         schemas,
+		scripts:         scripts,
+		queues:          queues,
+	}
+	if err := t.setDefaultRoute(def, prevTransports, logger, m); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newBackendProxy builds the reverse proxy for route's backend at target,
+// sending through transport (the backend's connection pool), with JSON
+// error responses.
+func newBackendProxy(route config.RouteConfig, target *url.URL, logger *slog.Logger, transport *upstreamTransport) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+	proxy.ModifyResponse = modifyResponse(target, transport, logger)
+	proxy.ErrorHandler = proxyErrorHandler(route, logger)
+	return proxy
+}
+
+// newTemplatedProxy builds the reverse proxy for a route with a templated
+// backend. The Director and redirect handling take the target ServeHTTP
+// resolved for the request from its routeInfo; the Transport is shared by
+// every host the template resolves to.
+func newTemplatedProxy(route config.RouteConfig, logger *slog.Logger, transport *upstreamTransport) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			target := routeInfoFrom(req.Context()).target
+			httputil.NewSingleHostReverseProxy(target).Director(req)
+		},
+		Transport: transport,
+		ModifyResponse: func(resp *http.Response) error {
+			target := routeInfoFrom(resp.Request.Context()).target
+			return modifyResponse(target, transport, logger)(resp)
+		},
+		ErrorHandler: proxyErrorHandler(route, logger),
+	}
+}
+
+// degradedHeader marks responses to requests a fail_open route let
+// through while its circuit breaker was open.
+const degradedHeader = "X-Gateway-Degraded"
+
+// statusClientClosedRequest is the non-standard status (nginx's 499)
+// recorded for requests whose client disconnected before the backend
+// answered. Nothing is sent: the client is gone.
+const statusClientClosedRequest = 499
+
+// clientGone reports whether the client behind a proxied request has
+// disconnected, as opposed to the attempt's own timeout or cancellation.
+func clientGone(r *http.Request) bool {
+	info := routeInfoFrom(r.Context())
+	return info != nil && errors.Is(info.client.Err(), context.Canceled)
+}
+
+// proxyErrorHandler answers transport failures for route's backend with a
+// JSON 502, or, when the client disconnected, records 499 without
+// counting a backend error.
+func proxyErrorHandler(route config.RouteConfig, logger *slog.Logger) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if clientGone(r) {
+			// Not the backend's fault, and nobody is left to answer.
+			logger.Debug("client cancelled request", "error", err, "backend", route.Backend, "path", r.URL.Path)
+			w.WriteHeader(statusClientClosedRequest)
+			return
+		}
+		logger.Error("proxy error", "error", err, "backend", route.Backend, "path", r.URL.Path)
+		apierror.WriteJSON(w, r, http.StatusBadGateway, apierror.UpstreamUnavailable, "upstream service unavailable")
+	}
+}
+
+// SetDefaultRoute makes requests that match no route go to def instead of
+// the GATEWAY_ROUTE_NOT_FOUND 404: proxied to def.Backend like any other
+// route (breaker, retries and metrics included), or answered with def's
+// static status and body. nil restores the 404. Like Update it is safe to
+// call while serving; a reload changing both routes and the default route
+// uses Update instead.
+func (rt *Router) SetDefaultRoute(def *config.DefaultRouteConfig) error {
+	rt.updateMu.Lock()
+	defer rt.updateMu.Unlock()
+	old := rt.table.Load()
+	return rt.update(old.routes, def, old.breakers)
+}
+
+// setDefaultRoute adds def to a table under construction; see
+// SetDefaultRoute.
+func (t *routeTable) setDefaultRoute(def *config.DefaultRouteConfig, prevTransports map[string]*upstreamTransport, logger *slog.Logger, m *metrics.Metrics) error {
+	t.def = def
+	if def == nil {
+		return nil
+	}
+	if def.Backend == "" {
+		t.defaultStatic = def
+		return nil
+	}
+
+	route := def.Route()
+	target, err := url.Parse(route.Backend)
+	if err != nil {
+		return fmt.Errorf("invalid default_route backend URL %q: %w", route.Backend, err)
+	}
+	key := withPool(backendKey(target), route)
+	if _, exists := t.proxies[key]; !exists {
+		t.proxies[key] = newBackendProxy(route, target, logger, sharedTransport(t.transports, prevTransports, route, target, m))
+	}
+	t.routeBackendKey[route.ID()] = key
+	t.defaultRoute = &route
+	return nil
+}
+
+// SetServerTiming makes proxied responses carry a Server-Timing header
+// splitting the request's time in the proxy into backend, gateway and
+// retries. It is off by default because it exposes backend latency to
+// clients. Call it before serving.
+func (rt *Router) SetServerTiming(on bool) {
+	rt.serverTiming = on
+}
+
+// SetStripResponseHeaders sets the headers removed from every backend
+// response, in addition to each route's strip_response_headers. Call it
+// before serving.
+func (rt *Router) SetStripResponseHeaders(names []string) {
+	rt.stripHeaders = names
+}
+
+// SetMaxConcurrentRetries caps how many requests, across all routes, may
+// be retrying at once. A request takes a slot for its first retry and
+// holds it until it completes; when none is free it is not retried and the
+// failed response is served instead. n <= 0 removes the cap. Call it
+// before serving.
+func (rt *Router) SetMaxConcurrentRetries(n int) {
+	rt.retrySlots = nil
+	if n > 0 {
+		rt.retrySlots = make(chan struct{}, n)
+	}
+}
+
+// SetSizeAnomaly turns on logging.size_anomaly: a warning, sampled per
+// route, for request and response bodies far larger than the route's
+// rolling average. cfg.Enabled false turns it off. Call it before serving.
+func (rt *Router) SetSizeAnomaly(cfg config.SizeAnomalyConfig) {
+	rt.sizes = nil
+	if cfg.Enabled {
+		rt.sizes = newSizeTracker(cfg, rt.logger)
+	}
+}
+
+// acquireRetrySlot takes a global retry slot without waiting, reporting
+// whether one was free.
+func (rt *Router) acquireRetrySlot() bool {
+	if rt.retrySlots == nil {
+		return true
+	}
+	select {
+	case rt.retrySlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (rt *Router) releaseRetrySlot() {
+	if rt.retrySlots != nil {
+		<-rt.retrySlots
+	}
+}
+
+// writeDefaultStatic serves the static default_route response def.
+func (rt *Router) writeDefaultStatic(w http.ResponseWriter, def *config.DefaultRouteConfig) {
+	w.Header().Set("Content-Type", def.ContentType)
+	w.WriteHeader(def.Status)
+	if _, err := io.WriteString(w, def.Body); err != nil {
+		rt.logger.Debug("proxy: failed to write default_route body", "error", err)
+	}
 }
 
 // buildTransport creates an http.Transport with connection pool settings.
-// Uses sensible defaults when no config is provided.
-func buildTransport(pool *config.ConnectionPoolConfig) *http.Transport {
+// Uses sensible defaults when no config is provided. headerTimeout bounds
+// the wait for response headers; 0 leaves only the per-route timeout.
+func buildTransport(pool *config.ConnectionPoolConfig, headerTimeout time.Duration) *http.Transport {
 	maxIdle := 100
 	maxPerHost := 10
 	idleTimeout := 90 * time.Second
@@ -166,33 +531,173 @@ func buildTransport(pool *config.ConnectionPoolConfig) *http.Transport {
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
 		TLSHandshakeTimeout:   5 * time.Second,
-		ResponseHeaderTimeout: 0, // per-route timeout handles this
+		ResponseHeaderTimeout: headerTimeout, // 0: the per-route timeout handles this
 	}
 }
 
+// methodAllowed reports whether route accepts method. CONNECT and TRACE
+// are refused unless listed in the route's methods: proxied, CONNECT would
+// open a tunnel through the backend and TRACE echoes the request,
+// credentials included, back to the client.
+func (t *routeTable) methodAllowed(route config.RouteConfig, method string) bool {
+	if ms := t.methodSets[route.ID()]; ms != nil {
+		return ms[method]
+	}
+	return method != http.MethodConnect && method != http.MethodTrace
+}
+
 // ServeHTTP implements http.Handler. It matches the request to a route,
 // validates the HTTP method, checks the circuit breaker, injects headers,
 // and proxies with retries.
 func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
+	t := rt.table.Load()
 
-	route, ok := rt.matchRoute(r.URL.Path)
+	route, ok := t.index.match(r)
 	if !ok {
-		apierror.WriteJSON(w, r, http.StatusNotFound, apierror.RouteNotFound, "no matching route")
-		return
+		switch {
+		case t.defaultRoute != nil:
+			route = *t.defaultRoute
+		case t.defaultStatic != nil:
+			rt.writeDefaultStatic(w, t.defaultStatic)
+			return
+		default:
+			apierror.WriteJSON(w, r, http.StatusNotFound, apierror.RouteNotFound, "no matching route")
+			return
+		}
 	}
 
-	if ms := rt.methodSets[route.PathPrefix]; ms != nil && !ms[r.Method] {
+	if !t.methodAllowed(route, r.Method) {
 		apierror.WriteJSON(w, r, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, fmt.Sprintf("method %s not allowed for %s", r.Method, route.PathPrefix))
 		return
 	}
 
-	// Circuit breaker check.
-	breaker := rt.breakers[route.Backend]
+	// Templated backend: resolve it from the path captures, refusing hosts
+	// outside the route's allowlist before anything is sent upstream.
+	var target *url.URL
+	if route.BackendTemplated() {
+		var err error
+		if target, err = route.ResolveBackend(r.URL.Path); err != nil {
+			rt.logger.Warn("templated backend refused", "path_prefix", route.PathPrefix, "path", r.URL.Path, "error", err)
+			apierror.WriteJSON(w, r, http.StatusForbidden, apierror.BackendNotAllowed, "resolved backend is not allowed")
+			return
+		}
+	}
+
+	// Slow clients: with client_body_timeout_ms set, the body is read here,
+	// before the queue and breaker, so a slow upload is the client's 408
+	// rather than a backend timeout counted against the breaker.
+	if timeout := route.ClientBodyTimeout(); timeout > 0 {
+		if err := readClientBody(w, r, timeout); err != nil {
+			var maxErr *http.MaxBytesError
+			switch {
+			case errors.Is(err, errClientBodyTimeout):
+				rt.logger.Warn("client body read timed out", "path", r.URL.Path, "timeout", timeout)
+				w.Header().Set("Connection", "close")
+				apierror.WriteJSON(w, r, http.StatusRequestTimeout, apierror.ClientBodyTimeout, "request body not received in time")
+			case errors.As(err, &maxErr):
+				apierror.WriteJSON(w, r, http.StatusRequestEntityTooLarge, apierror.BodyTooLarge, "request body exceeds maximum allowed size")
+			default:
+				apierror.WriteJSON(w, r, http.StatusGatewayTimeout, apierror.RequestCancelled, "request cancelled")
+			}
+			return
+		}
+	}
+
+	// Request schema: check a JSON body before the queue and breaker, so a
+	// malformed request never reaches, or counts against, the backend.
+	if schema := t.schemas[route.ID()]; schema != nil && hasJSONBody(r) {
+		problems, err := validateBody(r, schema)
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			apierror.WriteJSON(w, r, http.StatusRequestEntityTooLarge, apierror.BodyTooLarge, "request body exceeds maximum allowed size")
+			return
+		case err != nil:
+			apierror.WriteJSON(w, r, http.StatusGatewayTimeout, apierror.RequestCancelled, "request cancelled")
+			return
+		case len(problems) > 0:
+			apierror.WriteJSONDetails(w, r, http.StatusBadRequest, apierror.InvalidBody, "request body does not match the route's schema", problems)
+			return
+		}
+	}
+
+	// Request script: like the schema, before the queue and breaker, so a
+	// request the script answers never reaches the backend.
+	scriptPath := ""
+	if s := t.scripts[route.ID()]; s != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), route.ScriptTimeout())
+		res, err := s.Run(ctx, r)
+		cancel()
+		switch {
+		case err != nil && r.Context().Err() != nil:
+			apierror.WriteJSON(w, r, http.StatusGatewayTimeout, apierror.RequestCancelled, "request cancelled")
+			return
+		case err != nil:
+			rt.logger.Warn("request script failed", "path_prefix", route.PathPrefix, "path", r.URL.Path, "error", err)
+			apierror.WriteJSON(w, r, http.StatusInternalServerError, apierror.ScriptError, "request script failed")
+			return
+		case res.Status != 0:
+			if res.Body != "" {
+				w.Header().Set("Content-Type", res.ContentType)
+			}
+			w.WriteHeader(res.Status)
+			if _, err := io.WriteString(w, res.Body); err != nil {
+				rt.logger.Debug("proxy: failed to write script response", "path_prefix", route.PathPrefix, "error", err)
+			}
+			return
+		}
+		r.Header = res.Header
+		scriptPath = res.Path
+	}
+
+	// Route concurrency limit: wait for a slot before touching the breaker,
+	// so queued requests do not hold bulkhead slots while they wait.
+	if q := t.queues[route.ID()]; q != nil {
+		queued, err := q.acquire(r.Context())
+		if queued && rt.metrics != nil && !route.MetricsDisabled {
+			rt.metrics.RouteQueued.WithLabelValues(route.MetricsRoute()).Inc()
+		}
+		switch {
+		case errors.Is(err, errQueueTimeout):
+			if rt.metrics != nil && !route.MetricsDisabled {
+				rt.metrics.RouteQueueRejections.WithLabelValues(route.MetricsRoute()).Inc()
+			}
+			apierror.WriteJSON(w, r, http.StatusServiceUnavailable, apierror.QueueTimeout, "route concurrency limit reached, queue wait exceeded")
+			return
+		case err != nil:
+			apierror.WriteJSON(w, r, http.StatusGatewayTimeout, apierror.RequestCancelled, "request cancelled")
+			return
+		}
+		defer q.release()
+	}
+
+	// Circuit breaker check. A fail-open route is admitted while the
+	// breaker is open, degraded: no retries, and a header saying so.
+	breaker := t.breakers[route.BreakerKey()]
+	degraded := false
+	lastGood := ""
+	if route.ServeStaleOnOpen {
+		lastGood = staleKey(route.ID(), r)
+	}
 	if breaker != nil {
-		if !breaker.Allow() {
-			// Circuit is open — serve fallback or 503.
+		allowed := false
+		if route.FailOpen {
+			allowed, degraded = breaker.AllowFailOpen()
+		} else {
+			allowed = breaker.Allow()
+		}
+		if !allowed {
+			// Circuit is open — serve the last good response, the
+			// fallback or a 503.
+			if rt.serveStale(w, r, lastGood) {
+				return
+			}
+			countRejection := rt.metrics != nil && !route.MetricsDisabled
 			if route.FallbackStatus != 0 {
+				if countRejection {
+					rt.metrics.FallbackResponses.WithLabelValues(route.MetricsRoute()).Inc()
+				}
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(route.FallbackStatus)
 				if route.FallbackBody != "" {
@@ -204,11 +709,17 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					}
 				}
 			} else {
+				if countRejection {
+					rt.metrics.CircuitOpenRejections.WithLabelValues(route.MetricsRoute()).Inc()
+				}
 				apierror.WriteJSON(w, r, http.StatusServiceUnavailable, apierror.CircuitOpen, "circuit breaker open")
 			}
 			return
 		}
 		defer breaker.Release()
+		if degraded {
+			w.Header().Set(degradedHeader, "circuit-open")
+		}
 	}
 
 	if rt.metrics != nil {
@@ -216,11 +727,30 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		defer rt.metrics.ActiveConnections.Dec()
 	}
 
-	proxy := rt.proxies[rt.routeBackendKey[route.PathPrefix]]
+	proxy := t.proxies[t.routeBackendKey[route.ID()]]
 
 	for k, v := range route.Headers {
 		r.Header.Set(k, v)
 	}
+	r = withRouteInfo(r, route, target, rt.stripHeaders)
+	if lastGood != "" {
+		info := routeInfoFrom(r.Context())
+		info.stale, info.staleKey = rt.stale, lastGood
+	}
+
+	// Metrics keep the client's method; only the backend sees a rewrite.
+	method := r.Method
+	if to, ok := t.methodRewrites[route.ID()][method]; ok {
+		r.Method = to
+	}
+
+	// Count request body bytes as the proxy streams them upstream so the
+	// size metric works for chunked uploads with no Content-Length.
+	var reqBody *countingBody
+	if r.Body != nil && r.Body != http.NoBody {
+		reqBody = &countingBody{ReadCloser: r.Body}
+		r.Body = reqBody
+	}
 
 	originalPath := r.URL.Path
 	if route.StripPrefix {
@@ -229,15 +759,47 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			r.URL.Path = "/"
 		}
 	}
+	if scriptPath != "" {
+		r.URL.Path, r.URL.RawPath = scriptPath, ""
+	}
 
 	maxAttempts := route.RetryAttempts + 1
-	if maxAttempts < 1 {
+	if maxAttempts < 1 || degraded {
 		maxAttempts = 1
 	}
 
 	// Wrap the response writer to capture the status code for metrics.
 	recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
 
+	var timing *serverTiming
+	if rt.serverTiming {
+		timing = &serverTiming{}
+	}
+
+	holdsRetrySlot := false
+	retryAfter := "" // Retry-After of the previous attempt, if it failed
+	defer func() {
+		if holdsRetrySlot {
+			rt.releaseRetrySlot()
+		}
+	}()
+
+	// Protocol upgrades bypass retries and response buffering: the single
+	// attempt writes straight to the client so the proxy can hijack the
+	// connection. The route timeout bounds only the handshake; once the
+	// connection is hijacked the tunnel lives until either side closes it.
+	upgrade := isUpgradeRequest(r)
+	if upgrade && maxAttempts > 1 {
+		rt.warnUpgradeRetries(route)
+		maxAttempts = 1
+	}
+
+	// The route timeout is a budget shared by all attempts: each attempt
+	// gets at most what is left of it, so retries never stretch a request
+	// past the timeout the route promises.
+	budgetEnd := time.Now().Add(route.Timeout())
+
+	retries := 0
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		// Check for context cancellation before each attempt (clean propagation).
 		if r.Context().Err() != nil {
@@ -245,7 +807,20 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), route.Timeout())
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if upgrade {
+			var cancelCtx context.CancelFunc
+			ctx, cancelCtx = context.WithCancel(r.Context())
+			handshakeTimer := time.AfterFunc(attemptTimeout(route), cancelCtx)
+			recorder.onHijack = func() { handshakeTimer.Stop() }
+			cancel = func() {
+				handshakeTimer.Stop()
+				cancelCtx()
+			}
+		} else {
+			ctx, cancel = context.WithTimeout(r.Context(), min(attemptTimeout(route), time.Until(budgetEnd)))
+		}
 		rWithCtx := r.WithContext(ctx)
 
 		attemptStart := time.Now()
@@ -253,12 +828,13 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		if isFinal {
 			// Final attempt: write directly to the real client.
-			lw := &latencyWriter{ResponseWriter: recorder, start: start}
+			lw := &latencyWriter{ResponseWriter: recorder, start: start, timing: timing, attemptStart: attemptStart, retryAfter: retryAfter}
 			proxy.ServeHTTP(lw, rWithCtx)
 			cancel()
 
 			latency := time.Since(attemptStart)
-			if breaker != nil {
+			// A client that disconnected says nothing about the backend.
+			if breaker != nil && recorder.statusCode != statusClientClosedRequest {
 				if isRetryable(recorder.statusCode) {
 					breaker.RecordFailure(latency)
 				} else {
@@ -268,72 +844,155 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 
-		// Non-final attempt: buffer the full response.
+		// Non-final attempt: buffer the response, up to the route's limit.
 		buf := responseBufferPool.Get().(*responseBuffer)
 		buf.Reset()
+		buf.streamTo = &latencyWriter{ResponseWriter: recorder, start: start, timing: timing, attemptStart: attemptStart}
+		buf.maxBuffer = route.RetryBufferLimit()
 		proxy.ServeHTTP(buf, rWithCtx)
 		cancel()
 
 		latency := time.Since(attemptStart)
 
-		if !isRetryable(buf.statusCode) {
-			// Success or non-retryable error — replay buffered response.
-			if breaker != nil {
+		retryable := isRetryable(buf.statusCode)
+		if breaker != nil && buf.statusCode != statusClientClosedRequest {
+			if retryable {
+				breaker.RecordFailure(latency)
+			} else {
 				breaker.RecordSuccess(latency)
 			}
-			w.Header().Set("X-Gateway-Latency", time.Since(start).String())
+		}
+		if buf.streaming {
+			// Already sent on to the client; too late to retry.
+			responseBufferPool.Put(buf)
+			break
+		}
+
+		var backoff time.Duration
+		if retryable {
+			// Each hint is for the attempt that gave it: a later failure
+			// without one backs off normally.
+			retryAfter = buf.header.Get("Retry-After")
+			backoff = time.Duration(100*(1<<(attempt-1))) * time.Millisecond
+			if d := retryAfterDelay(retryAfter, time.Now()); d > 0 {
+				// The backend said when to come back; honor it, within reason.
+				backoff = min(d, maxRetryAfter)
+			}
+		}
+		// A retry that could only start once the budget is spent would
+		// fail anyway; serve the failure instead.
+		outOfBudget := retryable && backoff >= time.Until(budgetEnd)
+
+		// A retry needs a global retry slot, held until this request
+		// finishes; with none free the failure is served as is.
+		skipRetry := false
+		if retryable && !outOfBudget && !holdsRetrySlot {
+			holdsRetrySlot = rt.acquireRetrySlot()
+			skipRetry = !holdsRetrySlot
+			if skipRetry && rt.metrics != nil && !route.MetricsDisabled {
+				rt.metrics.RetriesSkipped.WithLabelValues(route.MetricsRoute(), route.Backend).Inc()
+			}
+		}
+
+		if !retryable || skipRetry || outOfBudget {
+			// Success, non-retryable error, or a failure we may not
+			// retry — replay buffered response.
+			lw := &latencyWriter{ResponseWriter: w, start: start, timing: timing, attemptStart: attemptStart}
+			lw.setHeaders(buf.statusCode)
 			if err := buf.replayTo(recorder); err != nil {
 				rt.logger.Debug("proxy: failed to replay response body", "backend", route.Backend, "error", err)
 			}
 			responseBufferPool.Put(buf)
 			break
 		}
-
-		// Retryable failure — record it.
-		if breaker != nil {
-			breaker.RecordFailure(latency)
-		}
+		status := buf.statusCode
 		responseBufferPool.Put(buf)
 
-		if rt.metrics != nil {
-			rt.metrics.RetryTotal.WithLabelValues(route.PathPrefix, route.Backend).Inc()
+		retries++
+		if rt.metrics != nil && !route.MetricsDisabled {
+			rt.metrics.RetryTotal.WithLabelValues(route.MetricsRoute(), route.Backend).Inc()
 		}
 
 		rt.logger.Warn("retrying request",
 			"path", originalPath,
 			"backend", route.Backend,
 			"attempt", attempt,
-			"status", buf.statusCode,
+			"status", status,
+			"backoff", backoff,
 		)
 
-		backoff := time.Duration(100*(1<<(attempt-1))) * time.Millisecond
-		time.Sleep(backoff)
+		backoffTimer := time.NewTimer(backoff)
+		select {
+		case <-backoffTimer.C:
+		case <-r.Context().Done():
+			backoffTimer.Stop() // the next iteration reports the cancellation
+		}
+		if timing != nil {
+			timing.backend += latency
+			timing.retries += time.Since(attemptStart)
+		}
 	}
 
 	totalLatency := time.Since(start)
 
 	statusStr := strconv.Itoa(recorder.statusCode)
-	if rt.metrics != nil {
-		rt.metrics.RequestsTotal.WithLabelValues(route.PathPrefix, r.Method, statusStr).Inc()
-		rt.metrics.RequestDuration.WithLabelValues(route.PathPrefix, r.Method).Observe(totalLatency.Seconds())
+	var reqBytes int64
+	if reqBody != nil {
+		reqBytes = reqBody.n
+	}
+	if rt.sizes != nil {
+		rt.sizes.observe(r, route, originalPath, reqBytes, recorder.bytes)
+	}
+	if rt.metrics != nil && !route.MetricsDisabled {
+		label := route.MetricsRoute()
+		rt.metrics.RequestsTotal.WithLabelValues(label, method, statusStr).Inc()
+		metrics.ObserveWithRequestID(rt.metrics.RequestDuration.WithLabelValues(label, method), totalLatency.Seconds(), middleware.GetRequestID(r.Context()))
+		rt.metrics.RetryAttempts.WithLabelValues(label).Observe(float64(retries))
+		if route.PathTemplating {
+			rt.metrics.RequestsByTemplate.WithLabelValues(label, routing.TemplatePath(originalPath), method, statusStr).Inc()
+		}
 		if recorder.statusCode >= 500 {
-			rt.metrics.BackendErrors.WithLabelValues(route.PathPrefix, route.Backend, statusStr).Inc()
+			rt.metrics.BackendErrors.WithLabelValues(label, route.Backend, statusStr).Inc()
+		}
+		if recorder.statusCode == statusClientClosedRequest {
+			rt.metrics.ClientCancellations.WithLabelValues(label).Inc()
 		}
+		rt.metrics.BodySizeBytes.WithLabelValues(label, "request").Observe(float64(reqBytes))
+		rt.metrics.BodySizeBytes.WithLabelValues(label, "response").Observe(float64(recorder.bytes))
 	}
 }
 
-func (rt *Router) matchRoute(path string) (config.RouteConfig, bool) {
-	for _, route := range rt.routes {
-		if routing.MatchesPrefix(path, route.PathPrefix) {
-			return route, true
-		}
+// MatchRoute exposes route matching for use by other packages (e.g., auth middleware).
+func (rt *Router) MatchRoute(r *http.Request) (config.RouteConfig, bool) {
+	return rt.table.Load().index.match(r)
+}
+
+// attemptTimeout returns the timeout for one proxy attempt: route.Timeout()
+// reduced by a random fraction in [0, route.TimeoutJitter).
+func attemptTimeout(route config.RouteConfig) time.Duration {
+	base := route.Timeout()
+	if route.TimeoutJitter <= 0 {
+		return base
 	}
-	return config.RouteConfig{}, false
+	return base - time.Duration(rand.Float64()*route.TimeoutJitter*float64(base))
 }
 
-// MatchRoute exposes route matching for use by other packages (e.g., auth middleware).
-func (rt *Router) MatchRoute(path string) (config.RouteConfig, bool) {
-	return rt.matchRoute(path)
+// maxRetryAfter caps how long a backend's Retry-After may delay a retry.
+const maxRetryAfter = 5 * time.Second
+
+// retryAfterDelay returns the wait a Retry-After value asks for, given as
+// delay-seconds or an HTTP date, or 0 when v is empty or malformed.
+func retryAfterDelay(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
 }
 
 func isRetryable(status int) bool {
@@ -342,38 +1001,59 @@ func isRetryable(status int) bool {
 		status == http.StatusGatewayTimeout
 }
 
-
 // latencyWriter wraps an http.ResponseWriter and injects the
-// X-Gateway-Latency header just before the first WriteHeader call.
-// This ensures the header is set before the response is committed.
+// X-Gateway-Latency header (and Server-Timing, when timing is set) just
+// before the first WriteHeader call. This ensures the headers are set
+// before the response is committed.
 type latencyWriter struct {
 	http.ResponseWriter
 	start   time.Time
 	written bool
+
+	timing       *serverTiming // nil unless server.server_timing is on
+	attemptStart time.Time     // start of the attempt being written
+
+	// retryAfter is an earlier attempt's Retry-After, passed on with a
+	// retryable final status that carries none of its own.
+	retryAfter string
+}
+
+func (lw *latencyWriter) setHeaders(code int) {
+	lw.written = true
+	h := lw.ResponseWriter.Header()
+	total := time.Since(lw.start)
+	h.Set("X-Gateway-Latency", total.String())
+	if lw.timing != nil {
+		h.Add("Server-Timing", lw.timing.header(total, time.Since(lw.attemptStart)))
+	}
+	if lw.retryAfter != "" && isRetryable(code) && h.Get("Retry-After") == "" {
+		h.Set("Retry-After", lw.retryAfter)
+	}
 }
 
 func (lw *latencyWriter) WriteHeader(code int) {
 	if !lw.written {
-		lw.written = true
-		lw.ResponseWriter.Header().Set("X-Gateway-Latency", time.Since(lw.start).String())
+		lw.setHeaders(code)
 	}
 	lw.ResponseWriter.WriteHeader(code)
 }
 
 func (lw *latencyWriter) Write(b []byte) (int, error) {
 	if !lw.written {
-		lw.written = true
-		lw.ResponseWriter.Header().Set("X-Gateway-Latency", time.Since(lw.start).String())
+		lw.setHeaders(http.StatusOK)
 	}
 	return lw.ResponseWriter.Write(b)
 }
 
-// responseRecorder wraps http.ResponseWriter to capture the status code
-// while still writing to the real client. Used for metrics reporting.
+// responseRecorder wraps http.ResponseWriter to capture the status code and
+// body byte count while still writing to the real client. Used for metrics
+// reporting.
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int
 	written    bool
+	bytes      int64
+	onHijack   func() // called after a successful Hijack; may be nil
 }
 
 func (rr *responseRecorder) WriteHeader(code int) {
@@ -389,18 +1069,42 @@ func (rr *responseRecorder) Write(b []byte) (int, error) {
 		rr.statusCode = http.StatusOK
 		rr.written = true
 	}
-	return rr.ResponseWriter.Write(b)
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+// countingBody wraps a request body and counts the bytes read from it.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // responseBuffer captures the full response (status, headers, body) in memory
 // so it can be replayed to the real client on a successful non-final retry
 // attempt. This replaces the old discard+re-send approach that hit the
 // backend twice on every successful request with retries enabled.
+//
+// A streaming response (see isStreamingResponse) with a status that will
+// not be retried is not buffered: once its headers arrive it is committed
+// to streamTo and the body passes straight through, so Server-Sent Events
+// and large chunked downloads reach the client as they are produced. Any
+// response is committed the same way once its body outgrows maxBuffer.
 type responseBuffer struct {
 	header     http.Header
 	body       bytes.Buffer
 	statusCode int
 	written    bool
+
+	streamTo  http.ResponseWriter // where to commit a streaming response; nil buffers everything
+	maxBuffer int                 // body bytes buffered before committing; 0 = unbounded
+	streaming bool                // committed to streamTo
 }
 
 // Reset clears the buffer for reuse via the pool.
@@ -411,25 +1115,74 @@ func (b *responseBuffer) Reset() {
 	b.body.Reset()
 	b.statusCode = http.StatusOK
 	b.written = false
+	b.streamTo = nil
+	b.maxBuffer = 0
+	b.streaming = false
 }
 
 func (b *responseBuffer) Header() http.Header { return b.header }
 
 func (b *responseBuffer) WriteHeader(code int) {
-	if !b.written {
-		b.statusCode = code
-		b.written = true
+	if b.written {
+		return
+	}
+	b.statusCode = code
+	b.written = true
+	if b.streamTo != nil && !isRetryable(code) && isStreamingResponse(b.header) {
+		b.commit()
 	}
 }
 
 func (b *responseBuffer) Write(p []byte) (int, error) {
 	if !b.written {
-		b.statusCode = http.StatusOK
-		b.written = true
+		b.WriteHeader(http.StatusOK)
+	}
+	if !b.streaming && b.streamTo != nil && b.maxBuffer > 0 && b.body.Len()+len(p) > b.maxBuffer {
+		if err := b.commit(); err != nil {
+			return 0, err
+		}
+	}
+	if b.streaming {
+		return b.streamTo.Write(p)
 	}
 	return b.body.Write(p)
 }
 
+// commit sends the status and headers to streamTo, followed by any body
+// buffered so far, and switches the buffer to pass-through.
+func (b *responseBuffer) commit() error {
+	b.streaming = true
+	dst := b.streamTo.Header()
+	for k, vals := range b.header {
+		dst[k] = append(dst[k], vals...)
+	}
+	b.streamTo.WriteHeader(b.statusCode)
+	if b.body.Len() == 0 {
+		return nil
+	}
+	_, err := b.streamTo.Write(b.body.Bytes())
+	b.body.Reset()
+	return err
+}
+
+// Flush passes the reverse proxy's flushes through to the client once the
+// response is streaming; a buffered response has nothing to flush.
+func (b *responseBuffer) Flush() {
+	if b.streaming {
+		_ = http.NewResponseController(b.streamTo).Flush()
+	}
+}
+
+// isStreamingResponse reports whether a response's length is open-ended:
+// an event stream, or a body without Content-Length (chunked, or read to
+// EOF).
+func isStreamingResponse(h http.Header) bool {
+	if mt, _, _ := mime.ParseMediaType(h.Get("Content-Type")); mt == "text/event-stream" {
+		return true
+	}
+	return h.Get("Content-Length") == ""
+}
+
 // replayTo copies the buffered response (headers, status, body) to a real
 // ResponseWriter. The recorder captures the status code for metrics.
 // Returns any error from writing the body to the underlying connection;
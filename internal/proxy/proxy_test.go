@@ -1,13 +1,29 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/dskow/gateway-core/internal/apierror"
+	"github.com/dskow/gateway-core/internal/circuitbreaker"
 	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/dskow/gateway-core/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func echoHandler() http.Handler {
@@ -55,6 +71,187 @@ func TestRouter_RouteMatching(t *testing.T) {
 	}
 }
 
+// backendNamed starts a backend that answers every request with its name.
+func backendNamed(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, name)
+	}))
+}
+
+// Exact routes win over regex routes, which win over prefix routes of any
+// length; an exact route does not match below its path.
+func TestRouter_MatchTypePrecedence(t *testing.T) {
+	prefix, re, exact := backendNamed("prefix"), backendNamed("regex"), backendNamed("exact")
+	defer prefix.Close()
+	defer re.Close()
+	defer exact.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api/v1/users", Backend: prefix.URL, TimeoutMs: 5000},
+		{PathPrefix: "/api/v[0-9]+/users/[0-9]+", Backend: re.URL, MatchType: "regex", TimeoutMs: 5000},
+		{PathPrefix: "/api/v1/users/me", Backend: exact.URL, MatchType: "exact", TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/users/me", "exact"},
+		{"/api/v1/users/me/settings", "prefix"},
+		{"/api/v1/users/42", "regex"},
+		{"/api/v1/users/42/orders", "prefix"},
+		{"/api/v1/users", "prefix"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Body.String() != tt.want {
+			t.Errorf("%s: routed to %q, want %q", tt.path, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestRouter_HostRouting(t *testing.T) {
+	foo, bar, wild, deep, shared := backendNamed("foo"), backendNamed("bar"), backendNamed("wild"), backendNamed("deep"), backendNamed("shared")
+	for _, s := range []*httptest.Server{foo, bar, wild, deep, shared} {
+		defer s.Close()
+	}
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/v1", Backend: shared.URL, TimeoutMs: 5000},
+		{PathPrefix: "/v1", Hosts: []string{"api.foo.com"}, Backend: foo.URL, TimeoutMs: 5000},
+		{PathPrefix: "/v1", Hosts: []string{"api.bar.com"}, Backend: bar.URL, TimeoutMs: 5000},
+		{PathPrefix: "/v1", Hosts: []string{"*.foo.com"}, Backend: wild.URL, TimeoutMs: 5000},
+		{PathPrefix: "/", Hosts: []string{"*.eu.foo.com"}, Backend: deep.URL, TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"api.foo.com", "/v1/users", "foo"},
+		{"API.FOO.com:8443", "/v1/users", "foo"}, // case and port ignored
+		{"api.bar.com", "/v1/users", "bar"},
+		{"web.foo.com", "/v1/users", "wild"},
+		{"x.eu.foo.com", "/v1/users", "deep"}, // longer wildcard wins over *.foo.com
+		{"foo.com", "/v1/users", "shared"},    // wildcard does not match the apex
+		{"other.com", "/v1/users", "shared"},
+		{"api.bar.com", "/v2", ""}, // host routes without a path match fall through to host-less ones: none here
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if tt.want == "" {
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%s%s: status = %d, want 404", tt.host, tt.path, rec.Code)
+			}
+			continue
+		}
+		if rec.Body.String() != tt.want {
+			t.Errorf("%s%s: routed to %q, want %q", tt.host, tt.path, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestRouter_MatchConditions(t *testing.T) {
+	plain, v2, beta := backendNamed("plain"), backendNamed("v2"), backendNamed("beta")
+	defer plain.Close()
+	defer v2.Close()
+	defer beta.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: plain.URL, TimeoutMs: 5000},
+		{PathPrefix: "/api", Backend: v2.URL, TimeoutMs: 5000, MatchConditions: []config.MatchCondition{
+			{Header: "X-Api-Version", Equals: "2"},
+		}},
+		{PathPrefix: "/api", Backend: beta.URL, TimeoutMs: 5000, MatchConditions: []config.MatchCondition{
+			{Query: "beta"},
+			{Header: "X-Tester", Equals: "yes"},
+		}},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		want    string
+	}{
+		{"header equals", "/api/items", map[string]string{"x-api-version": "2"}, "v2"},
+		{"header differs", "/api/items", map[string]string{"X-Api-Version": "3"}, "plain"},
+		{"no conditions met", "/api/items", nil, "plain"},
+		{"all conditions met", "/api/items?beta", map[string]string{"X-Tester": "yes"}, "beta"},
+		{"one of two conditions met", "/api/items?beta=1", nil, "plain"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Body.String() != tt.want {
+			t.Errorf("%s: routed to %q, want %q", tt.name, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestRouter_TemplatedBackend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "reached "+r.URL.Path)
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+
+	routes := []config.RouteConfig{{
+		PathPrefix:          `/proxy/(?P<host>[^/]+)/.*`,
+		MatchType:           "regex",
+		Backend:             "http://{host}:" + u.Port(),
+		BackendAllowedHosts: []string{"127.0.0.1", "*.internal.example"},
+		TimeoutMs:           5000,
+	}}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/proxy/127.0.0.1/items", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "reached /proxy/127.0.0.1/items" {
+		t.Fatalf("allowed host: status %d body %q", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{
+		"/proxy/10.0.0.1/items",    // not in the allowlist
+		"/proxy/localhost/items",   // resolves to an allowed address, but the name is not allowed
+		"/proxy/x@127.0.0.1/items", // userinfo smuggling
+		"/proxy/metadata.internal.example.evil.com/items",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403", path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), string(apierror.BackendNotAllowed)) {
+			t.Errorf("%s: body %q lacks %s", path, rec.Body.String(), apierror.BackendNotAllowed)
+		}
+	}
+}
+
 func TestRouter_NoMatchingRoute(t *testing.T) {
 	routes := []config.RouteConfig{
 		{PathPrefix: "/api", Backend: "http://localhost:9999", TimeoutMs: 5000},
@@ -96,242 +293,1102 @@ func TestRouter_MethodNotAllowed(t *testing.T) {
 	}
 }
 
-func TestRouter_PrefixStripping(t *testing.T) {
-	var receivedPath string
-	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		receivedPath = r.URL.Path
-		w.WriteHeader(http.StatusOK)
-	}))
+func TestRouter_RejectsConnectAndTraceByDefault(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
 	defer backend.Close()
 
 	routes := []config.RouteConfig{
-		{PathPrefix: "/api/users", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000},
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/diag", Backend: backend.URL, Methods: []string{"GET", "TRACE"}, TimeoutMs: 5000},
 	}
-	logger := slog.Default()
-	router, err := New(routes, nil, logger, nil)
+	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	req := httptest.NewRequest("GET", "/api/users/123", nil)
-	rec := httptest.NewRecorder()
-	router.ServeHTTP(rec, req)
+	for _, method := range []string{http.MethodConnect, http.MethodTrace} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, "/api/test", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s on a route without methods: expected 405, got %d", method, rec.Code)
+		}
+	}
 
-	if receivedPath != "/123" {
-		t.Errorf("expected stripped path /123, got %q", receivedPath)
+	// A route that lists TRACE opts in.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodTrace, "/diag/test", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("TRACE on an opted-in route: expected 200, got %d", rec.Code)
 	}
 }
 
-func TestRouter_PrefixStripping_RootPath(t *testing.T) {
-	var receivedPath string
-	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		receivedPath = r.URL.Path
-		w.WriteHeader(http.StatusOK)
-	}))
+func TestRouter_MethodRewrite(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
 	defer backend.Close()
 
 	routes := []config.RouteConfig{
-		{PathPrefix: "/api/users", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000},
+		{PathPrefix: "/legacy", Backend: backend.URL, Methods: []string{"GET", "PATCH"},
+			MethodRewrite: map[string]string{"patch": "post"}, TimeoutMs: 5000},
 	}
-	logger := slog.Default()
-	router, err := New(routes, nil, logger, nil)
+	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	req := httptest.NewRequest("GET", "/api/users", nil)
-	rec := httptest.NewRecorder()
-	router.ServeHTTP(rec, req)
+	for method, want := range map[string]string{"PATCH": "POST", "GET": "GET"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, "/legacy/items/1", strings.NewReader("{}")))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", method, rec.Code)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["method"] != want {
+			t.Errorf("client %s: backend saw %v, want %s", method, body["method"], want)
+		}
+	}
 
-	if receivedPath != "/" {
-		t.Errorf("expected stripped path /, got %q", receivedPath)
+	// Methods applies to the client's method, not the rewritten one.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/legacy/items", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST not in methods: expected 405, got %d", rec.Code)
 	}
 }
 
-func TestRouter_HeaderInjection(t *testing.T) {
-	var receivedHeaders http.Header
+func TestRouter_ResponseHeaders(t *testing.T) {
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		receivedHeaders = r.Header
+		w.Header().Set("Cache-Control", "max-age=60")
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer backend.Close()
 
+	headers := map[string]string{"Cache-Control": "no-store", "X-Service-Name": "users"}
 	routes := []config.RouteConfig{
-		{
-			PathPrefix: "/api",
-			Backend:    backend.URL,
-			TimeoutMs:  5000,
-			Headers:    map[string]string{"X-Source": "gateway", "X-Custom": "value"},
-		},
+		{PathPrefix: "/fill", Backend: backend.URL, ResponseHeaders: headers, TimeoutMs: 5000},
+		{PathPrefix: "/override", Backend: backend.URL, ResponseHeaders: headers, ResponseHeadersOverride: true, TimeoutMs: 5000},
 	}
-	logger := slog.Default()
-	router, err := New(routes, nil, logger, nil)
+	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	req := httptest.NewRequest("GET", "/api/test", nil)
-	rec := httptest.NewRecorder()
-	router.ServeHTTP(rec, req)
-
-	if receivedHeaders.Get("X-Source") != "gateway" {
-		t.Errorf("expected X-Source=gateway, got %q", receivedHeaders.Get("X-Source"))
+	tests := []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/fill/x", "max-age=60"},   // the backend's value wins
+		{"/override/x", "no-store"}, // the configured value wins
 	}
-	if receivedHeaders.Get("X-Custom") != "value" {
-		t.Errorf("expected X-Custom=value, got %q", receivedHeaders.Get("X-Custom"))
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if got := rec.Header().Get("X-Service-Name"); got != "users" {
+			t.Errorf("%s: X-Service-Name = %q, want users", tt.path, got)
+		}
+		if got := rec.Header().Values("Cache-Control"); len(got) != 1 || got[0] != tt.cacheControl {
+			t.Errorf("%s: Cache-Control = %q, want [%s]", tt.path, got, tt.cacheControl)
+		}
 	}
 }
 
-// Note: X-Request-ID generation and preservation tests moved to
-// middleware/requestid_test.go (RequestID middleware now handles this).
-
-func TestRouter_XForwardedFor(t *testing.T) {
-	var receivedXFF string
+func TestRouter_StripResponseHeaders(t *testing.T) {
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		receivedXFF = r.Header.Get("X-Forwarded-For")
+		if r.URL.Path == "/follow/start" {
+			http.Redirect(w, r, "/follow/final", http.StatusFound)
+			return
+		}
+		h := w.Header()
+		h.Set("Server", "nginx/1.25.3")
+		h.Set("X-Powered-By", "PHP/8.3")
+		h.Set("X-Internal-Trace", "node-7")
+		h.Set("Connection", "X-Hop")
+		h.Set("X-Hop", "1")
+		h.Set("X-Request-Cost", "3")
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer backend.Close()
 
 	routes := []config.RouteConfig{
-		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/plain", Backend: backend.URL, StripResponseHeaders: []string{"X-Internal-Trace"}, TimeoutMs: 5000},
+		{PathPrefix: "/follow", Backend: backend.URL, RedirectPolicy: "follow", MaxRedirects: 5, TimeoutMs: 5000},
 	}
-	logger := slog.Default()
-	router, err := New(routes, nil, logger, nil)
+	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	router.SetStripResponseHeaders([]string{"Server", "X-Powered-By"})
 
-	req := httptest.NewRequest("GET", "/api/test", nil)
-	req.RemoteAddr = "192.168.1.1:54321"
-	rec := httptest.NewRecorder()
-	router.ServeHTTP(rec, req)
-
-	if receivedXFF != "192.168.1.1" {
-		t.Errorf("expected X-Forwarded-For=192.168.1.1, got %q", receivedXFF)
+	tests := []struct {
+		path    string
+		removed []string
+	}{
+		{"/plain/x", []string{"Server", "X-Powered-By", "X-Internal-Trace", "X-Hop", "Connection"}},
+		// A followed redirect's response bypasses ReverseProxy's own
+		// hop-by-hop cleanup.
+		{"/follow/start", []string{"Server", "X-Powered-By", "X-Hop", "Connection"}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tt.path, rec.Code)
+		}
+		for _, name := range tt.removed {
+			if v := rec.Header().Get(name); v != "" {
+				t.Errorf("%s: %s = %q reached the client", tt.path, name, v)
+			}
+		}
+		if got := rec.Header().Get("X-Request-Cost"); got != "3" {
+			t.Errorf("%s: X-Request-Cost = %q, want it passed through", tt.path, got)
+		}
 	}
 }
 
-func TestRouter_GatewayLatencyHeader(t *testing.T) {
-	backend := httptest.NewServer(echoHandler())
+func TestRouter_WrapUpstreamErrors(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/json") {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"detail":"db down"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "<html><body>Service Unavailable</body></html>")
+	}))
 	defer backend.Close()
 
 	routes := []config.RouteConfig{
-		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/wrapped", Backend: backend.URL, WrapUpstreamErrors: true, TimeoutMs: 5000},
+		{PathPrefix: "/raw", Backend: backend.URL, TimeoutMs: 5000},
 	}
-	logger := slog.Default()
-	router, err := New(routes, nil, logger, nil)
+	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	req := httptest.NewRequest("GET", "/api/test", nil)
+	req := httptest.NewRequest("GET", "/wrapped/html", nil)
+	req.Header.Set("X-Request-ID", "req-123")
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
-
-	latency := rec.Header().Get("X-Gateway-Latency")
-	if latency == "" {
-		t.Error("expected X-Gateway-Latency header")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the backend's 503, got %d", rec.Code)
 	}
-}
-
-func TestRouter_InvalidBackendURL(t *testing.T) {
-	routes := []config.RouteConfig{
-		{PathPrefix: "/api", Backend: "://bad-url", TimeoutMs: 5000},
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
 	}
-	logger := slog.Default()
-	_, err := New(routes, nil, logger, nil)
-	if err == nil {
-		t.Error("expected error for invalid backend URL")
+	var body apierror.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("wrapped body is not JSON: %v: %q", err, rec.Body.String())
+	}
+	if body.ErrorCode != string(apierror.UpstreamError) || body.RequestID != "req-123" {
+		t.Errorf("wrapped body = %+v, want GATEWAY_UPSTREAM_ERROR with request_id req-123", body)
 	}
-}
 
-// Note: newUUID test moved to middleware/requestid_test.go.
+	// A JSON error body is the backend's own detail: passed through.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/wrapped/json", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != `{"detail":"db down"}` {
+		t.Errorf("JSON error: %d %q, want the backend's body unchanged", rec.Code, rec.Body.String())
+	}
 
-// Note: matchesPrefix tests moved to internal/routing/match_test.go
-// since the function was extracted into the shared routing package.
+	// Without the option the HTML page reaches the client.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/raw/html", nil))
+	if !strings.Contains(rec.Body.String(), "<html>") {
+		t.Errorf("unwrapped route: body %q, want the backend's HTML", rec.Body.String())
+	}
+}
 
-func TestRouter_PathBoundaryEnforcement(t *testing.T) {
-	backend := httptest.NewServer(echoHandler())
+func TestRouter_RequestSchema(t *testing.T) {
+	var backendHits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
 	defer backend.Close()
 
+	schemaPath := filepath.Join(t.TempDir(), "order.json")
+	schema := `{"type": "object", "required": ["sku"], "properties": {"sku": {"type": "string"}, "qty": {"type": "integer", "minimum": 1}}}`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
 	routes := []config.RouteConfig{
-		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/orders", Backend: backend.URL, RequestSchema: schemaPath, TimeoutMs: 5000},
 	}
-	logger := slog.Default()
-	router, err := New(routes, nil, logger, nil)
+	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// /api/test should match /api
-	req := httptest.NewRequest("GET", "/api/test", nil)
-	rec := httptest.NewRecorder()
-	router.ServeHTTP(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Errorf("/api/test: expected 200, got %d", rec.Code)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
 	}
 
-	// /api.evil.com should NOT match /api
-	req2 := httptest.NewRequest("GET", "/api.evil.com/steal", nil)
-	rec2 := httptest.NewRecorder()
-	router.ServeHTTP(rec2, req2)
-	if rec2.Code != http.StatusNotFound {
-		t.Errorf("/api.evil.com/steal: expected 404, got %d", rec2.Code)
+	// Valid: proxied with the body intact.
+	valid := `{"sku": "A-1", "qty": 2}`
+	rec := post(valid)
+	if rec.Code != http.StatusOK || rec.Body.String() != valid {
+		t.Errorf("valid body: %d %q, want 200 echoing the body", rec.Code, rec.Body.String())
 	}
 
-	// /apiary should NOT match /api
-	req3 := httptest.NewRequest("GET", "/apiary", nil)
-	rec3 := httptest.NewRecorder()
-	router.ServeHTTP(rec3, req3)
-	if rec3.Code != http.StatusNotFound {
-		t.Errorf("/apiary: expected 404, got %d", rec3.Code)
+	// Invalid: refused at the gateway with each failure listed.
+	rec = post(`{"qty": 0}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid body: expected 400, got %d", rec.Code)
+	}
+	var resp apierror.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ErrorCode != string(apierror.InvalidBody) || len(resp.Details) != 2 {
+		t.Errorf("invalid body response = %+v, want GATEWAY_INVALID_BODY with 2 details", resp)
+	}
+	if got := backendHits.Load(); got != 1 {
+		t.Errorf("backend saw %d requests, want only the valid one", got)
 	}
 }
 
-// DP-008: Two routes pointing to the same backend must share exactly one
-// *httputil.ReverseProxy so they share a Transport and its connection pool.
-func TestRouter_SharesProxyAcrossRoutesWithSameBackend(t *testing.T) {
+func TestRouter_RequestScriptInjectsHeader(t *testing.T) {
 	backend := httptest.NewServer(echoHandler())
 	defer backend.Close()
 
-	routes := []config.RouteConfig{
-		{PathPrefix: "/api/users", Backend: backend.URL, TimeoutMs: 5000},
-		{PathPrefix: "/api/orders", Backend: backend.URL, TimeoutMs: 5000},
-		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
-	}
+	routes := []config.RouteConfig{{
+		PathPrefix: "/api", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000,
+		RequestScript: `
+			set_header("X-Tenant", (header("X-Tenant-Name") or "default"):lower())
+			if path:find("^/api/v1/") then
+				set_path("/legacy" .. path)
+			end
+		`,
+	}}
 	router, err := New(routes, nil, slog.Default(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if got := len(router.proxies); got != 1 {
-		t.Fatalf("expected 1 shared proxy for identical backends, got %d", got)
-	}
-
-	// All three PathPrefixes must resolve to the same backend key.
-	keys := map[string]struct{}{}
-	for _, pp := range []string{"/api/users", "/api/orders", "/api"} {
-		keys[router.routeBackendKey[pp]] = struct{}{}
+	tests := []struct {
+		path, tenantName       string
+		wantPath, wantTenantID string
+	}{
+		{"/api/users", "ACME", "/users", "acme"},
+		{"/api/v1/users", "", "/legacy/api/v1/users", "default"},
 	}
-	if len(keys) != 1 {
-		t.Fatalf("expected all three routes to share one backend key, got %d distinct", len(keys))
-	}
-
-	// Sanity: requests still route correctly through the shared proxy.
-	for _, path := range []string{"/api", "/api/users/1", "/api/orders/9"} {
-		req := httptest.NewRequest("GET", path, nil)
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.tenantName != "" {
+			req.Header.Set("X-Tenant-Name", tt.tenantName)
+		}
 		rec := httptest.NewRecorder()
 		router.ServeHTTP(rec, req)
 		if rec.Code != http.StatusOK {
-			t.Errorf("path %s: expected 200, got %d", path, rec.Code)
+			t.Fatalf("%s: expected 200, got %d", tt.path, rec.Code)
+		}
+		var got struct {
+			Path    string            `json:"path"`
+			Headers map[string]string `json:"headers"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Path != tt.wantPath {
+			t.Errorf("%s: backend path = %q, want %q", tt.path, got.Path, tt.wantPath)
+		}
+		if got.Headers["X-Tenant"] != tt.wantTenantID {
+			t.Errorf("%s: backend X-Tenant = %q, want %q", tt.path, got.Headers["X-Tenant"], tt.wantTenantID)
 		}
 	}
 }
 
-// Different backends still get distinct proxies — the 1:1 case is unchanged.
-func TestRouter_DistinctProxiesForDistinctBackends(t *testing.T) {
-	a := httptest.NewServer(echoHandler())
+func TestRouter_RequestScriptShortCircuits(t *testing.T) {
+	var backendHits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{{
+		PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000,
+		RequestScript: `
+			if method == "DELETE" and header("X-Admin") ~= "yes" then
+				respond(403, '{"error":"admins only"}', "application/json")
+			end
+		`,
+	}}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/users/1", nil))
+	if rec.Code != http.StatusForbidden || rec.Body.String() != `{"error":"admins only"}` {
+		t.Errorf("DELETE: %d %q, want the script's 403", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if got := backendHits.Load(); got != 0 {
+		t.Errorf("backend saw %d requests, want none", got)
+	}
+
+	req := httptest.NewRequest("DELETE", "/api/users/1", nil)
+	req.Header.Set("X-Admin", "yes")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || backendHits.Load() != 1 {
+		t.Errorf("admin DELETE: %d with %d backend hits, want it proxied", rec.Code, backendHits.Load())
+	}
+}
+
+func TestRouter_RequestScriptTimeout(t *testing.T) {
+	var backendHits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHits.Add(1)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{{
+		PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, ScriptTimeoutMs: 5,
+		RequestScript: "while true do end",
+	}}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	var resp apierror.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ErrorCode != string(apierror.ScriptError) {
+		t.Errorf("error_code = %q, want %q", resp.ErrorCode, apierror.ScriptError)
+	}
+	if got := backendHits.Load(); got != 0 {
+		t.Errorf("backend saw %d requests, want none", got)
+	}
+}
+
+func TestRouter_DefaultRouteBackend(t *testing.T) {
+	api := httptest.NewServer(echoHandler())
+	defer api.Close()
+	spa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "spa:"+r.URL.Path)
+	}))
+	defer spa.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: api.URL, Methods: []string{"GET"}, TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := router.SetDefaultRoute(&config.DefaultRouteConfig{Backend: spa.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/app/settings", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "spa:/app/settings" {
+		t.Errorf("unmatched path: %d %q, want 200 from the default backend", rec.Code, rec.Body.String())
+	}
+
+	// A matched route still enforces its methods rather than falling through.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/users", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("matched route with wrong method: got %d, want 405", rec.Code)
+	}
+
+	// Clearing the default route restores the 404.
+	if err := router.SetDefaultRoute(nil); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/app/settings", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without default_route: got %d, want 404", rec.Code)
+	}
+}
+
+func TestRouter_DefaultRouteStatic(t *testing.T) {
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: "http://localhost:9999", TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := router.SetDefaultRoute(&config.DefaultRouteConfig{
+		Status:      http.StatusNotFound,
+		Body:        "<h1>Not here</h1>",
+		ContentType: "text/html; charset=utf-8",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if rec.Body.String() != "<h1>Not here</h1>" {
+		t.Errorf("body = %q, want the custom page", rec.Body.String())
+	}
+}
+
+func TestRouter_PrefixStripping(t *testing.T) {
+	var receivedPath string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api/users", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000},
+	}
+	logger := slog.Default()
+	router, err := New(routes, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/users/123", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if receivedPath != "/123" {
+		t.Errorf("expected stripped path /123, got %q", receivedPath)
+	}
+}
+
+func TestRouter_PrefixStripping_RootPath(t *testing.T) {
+	var receivedPath string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api/users", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000},
+	}
+	logger := slog.Default()
+	router, err := New(routes, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if receivedPath != "/" {
+		t.Errorf("expected stripped path /, got %q", receivedPath)
+	}
+}
+
+func TestRouter_HeaderInjection(t *testing.T) {
+	var receivedHeaders http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{
+			PathPrefix: "/api",
+			Backend:    backend.URL,
+			TimeoutMs:  5000,
+			Headers:    map[string]string{"X-Source": "gateway", "X-Custom": "value"},
+		},
+	}
+	logger := slog.Default()
+	router, err := New(routes, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if receivedHeaders.Get("X-Source") != "gateway" {
+		t.Errorf("expected X-Source=gateway, got %q", receivedHeaders.Get("X-Source"))
+	}
+	if receivedHeaders.Get("X-Custom") != "value" {
+		t.Errorf("expected X-Custom=value, got %q", receivedHeaders.Get("X-Custom"))
+	}
+}
+
+// Note: X-Request-ID generation and preservation tests moved to
+// middleware/requestid_test.go (RequestID middleware now handles this).
+
+func TestRouter_XForwardedFor(t *testing.T) {
+	var receivedXFF string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedXFF = r.Header.Get("X-Forwarded-For")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	logger := slog.Default()
+	router, err := New(routes, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	req.RemoteAddr = "192.168.1.1:54321"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if receivedXFF != "192.168.1.1" {
+		t.Errorf("expected X-Forwarded-For=192.168.1.1, got %q", receivedXFF)
+	}
+}
+
+func TestRouter_GatewayLatencyHeader(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	logger := slog.Default()
+	router, err := New(routes, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	latency := rec.Header().Get("X-Gateway-Latency")
+	if latency == "" {
+		t.Error("expected X-Gateway-Latency header")
+	}
+}
+
+func TestRouter_ServerTimingHeader(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 1},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/test", nil))
+	if got := rec.Header().Get("Server-Timing"); got != "" {
+		t.Fatalf("Server-Timing = %q without server_timing, want none", got)
+	}
+
+	router.SetServerTiming(true)
+	calls.Store(0)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/test", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 after one retry", rec.Code)
+	}
+
+	// Parse "name;dur=ms, name;dur=ms, ...".
+	durs := make(map[string]float64)
+	for _, metric := range strings.Split(rec.Header().Get("Server-Timing"), ",") {
+		name, dur, ok := strings.Cut(strings.TrimSpace(metric), ";dur=")
+		if !ok {
+			t.Fatalf("malformed metric %q in %q", metric, rec.Header().Get("Server-Timing"))
+		}
+		v, err := strconv.ParseFloat(dur, 64)
+		if err != nil {
+			t.Fatalf("metric %q: %v", metric, err)
+		}
+		durs[name] = v
+	}
+	if len(durs) != 3 {
+		t.Fatalf("metrics = %v, want backend, gateway, retries", durs)
+	}
+	// Both attempts count as backend time; the failed attempt and its
+	// 100ms backoff are retry time; the backoff is gateway time.
+	if durs["backend"] < 50 {
+		t.Errorf("backend = %vms, want >= 50", durs["backend"])
+	}
+	if durs["retries"] < 120 {
+		t.Errorf("retries = %vms, want >= 120", durs["retries"])
+	}
+	if durs["gateway"] < 100 {
+		t.Errorf("gateway = %vms, want >= 100", durs["gateway"])
+	}
+}
+
+func TestRouter_MaxConcurrentRetries(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	retrying := make(chan struct{})
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Test-ID")
+		mu.Lock()
+		hits[id]++
+		n := hits[id]
+		mu.Unlock()
+		if id == "a" && n == 2 {
+			close(retrying)
+			<-release // hold A's retry, and its retry slot, in flight
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 1},
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New(routes, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.SetMaxConcurrentRetries(1)
+
+	serve := func(id string) int {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.Header.Set("X-Test-ID", id)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve("a")
+	}()
+	<-retrying
+
+	// The only slot is A's: B's failure is served without a retry.
+	if code := serve("b"); code != http.StatusServiceUnavailable {
+		t.Errorf("b: status = %d, want 503", code)
+	}
+	close(release)
+	<-done
+
+	// A finished and freed the slot, so C retries again.
+	serve("c")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["a"] != 2 || hits["b"] != 1 || hits["c"] != 2 {
+		t.Errorf("backend hits = %v, want a:2 b:1 c:2", hits)
+	}
+	if got := testutil.ToFloat64(m.RetriesSkipped.WithLabelValues("/api", backend.URL)); got != 1 {
+		t.Errorf("gateway_retries_skipped_total = %v, want 1", got)
+	}
+}
+
+func TestRouter_ClientCancellationIsNotABackendError(t *testing.T) {
+	arrived := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-r.Context().Done() // never answers; the client gives up first
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New(routes, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/slow", nil).WithContext(ctx))
+
+	if rec.Code != statusClientClosedRequest {
+		t.Errorf("status = %d, want %d", rec.Code, statusClientClosedRequest)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want none for a departed client", rec.Body.String())
+	}
+	if got := testutil.ToFloat64(m.ClientCancellations.WithLabelValues("/api")); got != 1 {
+		t.Errorf("gateway_client_cancellations_total = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.BackendErrors); got != 0 {
+		t.Errorf("gateway_backend_errors_total has %d series, want none", got)
+	}
+}
+
+func TestRouter_HonorsBackendRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var hitTimes []time.Time
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hitTimes = append(hitTimes, time.Now())
+		first := len(hitTimes) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "2")
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 1},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hitTimes) != 2 {
+		t.Fatalf("backend hits = %d, want 2", len(hitTimes))
+	}
+	if wait := hitTimes[1].Sub(hitTimes[0]); wait < 1900*time.Millisecond {
+		t.Errorf("retried after %v, want the backend's 2s Retry-After", wait)
+	}
+	// The retry failed without a hint of its own; the first one is kept.
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("response = %d Retry-After %q, want 503 with Retry-After 2", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRouter_RetryAfterAppliesOnlyToItsAttempt(t *testing.T) {
+	var mu sync.Mutex
+	var hitTimes []time.Time
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hitTimes = append(hitTimes, time.Now())
+		first := len(hitTimes) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 2},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hitTimes) != 3 {
+		t.Fatalf("backend hits = %d, want 3", len(hitTimes))
+	}
+	if wait := hitTimes[1].Sub(hitTimes[0]); wait < 900*time.Millisecond {
+		t.Errorf("first retry after %v, want the backend's 1s Retry-After", wait)
+	}
+	// The second failure gave no hint: the usual 200ms backoff applies.
+	if wait := hitTimes[2].Sub(hitTimes[1]); wait > 700*time.Millisecond {
+		t.Errorf("second retry after %v, want the 200ms backoff, not the first attempt's Retry-After", wait)
+	}
+	if ra := rec.Header().Get("Retry-After"); ra != "" {
+		t.Errorf("Retry-After = %q, want none: the hint was not for the last failure", ra)
+	}
+}
+
+func TestRouter_RetriesShareTheRouteTimeout(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			// Fail fast once, then hang until the gateway gives up.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer backend.Close()
+
+	const budget = 400 * time.Millisecond
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: int(budget.Milliseconds()), RetryAttempts: 2},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+	elapsed := time.Since(start)
+
+	// Attempt 1 fails at once; after the 100ms backoff attempt 2 gets the
+	// remaining ~300ms and times out, leaving nothing for attempt 3.
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("backend hits = %d, want 2", n)
+	}
+	if elapsed > budget+150*time.Millisecond {
+		t.Errorf("request took %v across retries, want it within the %v route timeout", elapsed, budget)
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfterDelay(tt.in, now); got != tt.want {
+			t.Errorf("retryAfterDelay(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRouter_ResponseHeaderTimeout(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Accept the request but sit on the headers: past the header
+			// timeout, well within the route timeout.
+			select {
+			case <-time.After(500 * time.Millisecond):
+			case <-r.Context().Done():
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{{
+		PathPrefix:              "/api",
+		Backend:                 backend.URL,
+		TimeoutMs:               5000,
+		ResponseHeaderTimeoutMs: 50,
+		RetryAttempts:           1,
+	}}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begin := time.Now()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+	elapsed := time.Since(begin)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 from the retry", rec.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("backend calls = %d, want 2 (header timeout, then retry)", calls.Load())
+	}
+	if elapsed >= 500*time.Millisecond {
+		t.Errorf("took %v; the first attempt should have failed at the 50ms header timeout", elapsed)
+	}
+}
+
+func TestRouter_InvalidBackendURL(t *testing.T) {
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: "://bad-url", TimeoutMs: 5000},
+	}
+	logger := slog.Default()
+	_, err := New(routes, nil, logger, nil)
+	if err == nil {
+		t.Error("expected error for invalid backend URL")
+	}
+}
+
+// Note: newUUID test moved to middleware/requestid_test.go.
+
+// Note: matchesPrefix tests moved to internal/routing/match_test.go
+// since the function was extracted into the shared routing package.
+
+func TestRouter_PathBoundaryEnforcement(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	logger := slog.Default()
+	router, err := New(routes, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// /api/test should match /api
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/api/test: expected 200, got %d", rec.Code)
+	}
+
+	// /api.evil.com should NOT match /api
+	req2 := httptest.NewRequest("GET", "/api.evil.com/steal", nil)
+	rec2 := httptest.NewRecorder()
+	router.ServeHTTP(rec2, req2)
+	if rec2.Code != http.StatusNotFound {
+		t.Errorf("/api.evil.com/steal: expected 404, got %d", rec2.Code)
+	}
+
+	// /apiary should NOT match /api
+	req3 := httptest.NewRequest("GET", "/apiary", nil)
+	rec3 := httptest.NewRecorder()
+	router.ServeHTTP(rec3, req3)
+	if rec3.Code != http.StatusNotFound {
+		t.Errorf("/apiary: expected 404, got %d", rec3.Code)
+	}
+}
+
+// DP-008: Two routes pointing to the same backend must share exactly one
+// *httputil.ReverseProxy so they share a Transport and its connection pool.
+func TestRouter_SharesProxyAcrossRoutesWithSameBackend(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api/users", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/api/orders", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(router.table.Load().proxies); got != 1 {
+		t.Fatalf("expected 1 shared proxy for identical backends, got %d", got)
+	}
+
+	// All three PathPrefixes must resolve to the same backend key.
+	keys := map[string]struct{}{}
+	for _, pp := range []string{"/api/users", "/api/orders", "/api"} {
+		keys[router.table.Load().routeBackendKey[pp]] = struct{}{}
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected all three routes to share one backend key, got %d distinct", len(keys))
+	}
+
+	// Sanity: requests still route correctly through the shared proxy.
+	for _, path := range []string{"/api", "/api/users/1", "/api/orders/9"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("path %s: expected 200, got %d", path, rec.Code)
+		}
+	}
+}
+
+// Routes on the same backend origin share one Transport even when their
+// backend paths (and so their proxies) differ; a route with its own pool
+// settings gets its own.
+func TestRouter_SharesTransportAcrossBackendPaths(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/v1", Backend: backend.URL + "/v1", TimeoutMs: 5000},
+		{PathPrefix: "/v2", Backend: backend.URL + "/v2", TimeoutMs: 5000},
+		{PathPrefix: "/bulk", Backend: backend.URL + "/v1", TimeoutMs: 5000,
+			ConnectionPool: &config.ConnectionPoolConfig{MaxIdleConns: 10, MaxIdlePerHost: 2, IdleTimeout: 30 * time.Second}},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v1 := router.table.Load().proxies[router.table.Load().routeBackendKey["/v1"]]
+	v2 := router.table.Load().proxies[router.table.Load().routeBackendKey["/v2"]]
+	bulk := router.table.Load().proxies[router.table.Load().routeBackendKey["/bulk"]]
+	if v1 == v2 {
+		t.Fatal("backends with different paths should keep separate proxies")
+	}
+	if v1.Transport != v2.Transport {
+		t.Error("routes on the same origin should share a Transport")
+	}
+	if bulk.Transport == v1.Transport {
+		t.Error("a route with its own connection_pool should get its own Transport")
+	}
+	if got := len(router.TransportStats()); got != 2 {
+		t.Errorf("expected 2 transports, got %d", got)
+	}
+
+	for _, path := range []string{"/v1/a", "/v2/b", "/bulk/c"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("path %s: expected 200, got %d", path, rec.Code)
+		}
+	}
+}
+
+// Different backends still get distinct proxies — the 1:1 case is unchanged.
+func TestRouter_DistinctProxiesForDistinctBackends(t *testing.T) {
+	a := httptest.NewServer(echoHandler())
 	defer a.Close()
 	b := httptest.NewServer(echoHandler())
 	defer b.Close()
@@ -345,10 +1402,10 @@ func TestRouter_DistinctProxiesForDistinctBackends(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if got := len(router.proxies); got != 2 {
+	if got := len(router.table.Load().proxies); got != 2 {
 		t.Fatalf("expected 2 proxies for 2 distinct backends, got %d", got)
 	}
-	if router.routeBackendKey["/a"] == router.routeBackendKey["/b"] {
+	if router.table.Load().routeBackendKey["/a"] == router.table.Load().routeBackendKey["/b"] {
 		t.Fatal("distinct backends must produce distinct keys")
 	}
 }
@@ -367,7 +1424,627 @@ func TestRouter_DistinctProxiesForSameHostDifferentPath(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got := len(router.proxies); got != 2 {
+	if got := len(router.table.Load().proxies); got != 2 {
 		t.Fatalf("different backend paths must not collapse: got %d proxies", got)
 	}
 }
+
+// gateway_retry_attempts observes each request's retry count once: 0 for
+// a first-try success, 1 for a request that needed one retry.
+func TestRouter_ObservesRetryAttempts(t *testing.T) {
+	var flakyHits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/flaky") && flakyHits.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/stable", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 2},
+		{PathPrefix: "/flaky", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 2},
+	}
+	reg := prometheus.NewRegistry()
+	router, err := New(routes, nil, slog.Default(), metrics.New(reg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/stable/1", "/flaky/1"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200", path, rec.Code)
+		}
+	}
+
+	for route, want := range map[string]float64{"/stable": 0, "/flaky": 1} {
+		count, sum := histogramSample(t, reg, "gateway_retry_attempts", map[string]string{"route": route})
+		if count != 1 || sum != want {
+			t.Errorf("%s: observed %d samples summing to %v, want 1 sample of %v", route, count, sum, want)
+		}
+	}
+}
+
+// histogramSample returns the sample count and sum of the histogram series
+// in reg matching name and the given label values.
+func histogramSample(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) (uint64, float64) {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+	next:
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if want, ok := labels[lp.GetName()]; ok && want != lp.GetValue() {
+					continue next
+				}
+			}
+			return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+		}
+	}
+	return 0, 0
+}
+
+func TestRouter_BodySizeMetricsByDirection(t *testing.T) {
+	upload := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upload.Close()
+	download := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(strings.Repeat("d", 4096)))
+	}))
+	defer download.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/upload", Backend: upload.URL, TimeoutMs: 5000},
+		{PathPrefix: "/download", Backend: download.URL, TimeoutMs: 5000},
+	}
+	reg := prometheus.NewRegistry()
+	router, err := New(routes, nil, slog.Default(), metrics.New(reg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/upload/file", strings.NewReader(strings.Repeat("u", 1000)))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest("GET", "/download/file", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	tests := []struct {
+		route, direction string
+		wantSum          float64
+	}{
+		{"/upload", "request", 1000},
+		{"/upload", "response", 0},
+		{"/download", "request", 0},
+		{"/download", "response", 4096},
+	}
+	for _, tt := range tests {
+		count, sum := histogramSample(t, reg, "gateway_body_size_bytes", map[string]string{"route": tt.route, "direction": tt.direction})
+		if count != 1 {
+			t.Errorf("%s/%s: expected 1 observation, got %d", tt.route, tt.direction, count)
+		}
+		if sum != tt.wantSum {
+			t.Errorf("%s/%s: expected %v bytes, got %v", tt.route, tt.direction, tt.wantSum, sum)
+		}
+	}
+}
+
+func TestRouter_BreakerScopeIsolatesRoutes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/flaky") {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	for _, tc := range []struct {
+		scope      string
+		wantSteady int
+	}{
+		{"route", http.StatusOK},
+		{"backend", http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.scope, func(t *testing.T) {
+			routes := []config.RouteConfig{
+				{PathPrefix: "/flaky", Backend: backend.URL, TimeoutMs: 5000, BreakerScope: tc.scope},
+				{PathPrefix: "/steady", Backend: backend.URL, TimeoutMs: 5000, BreakerScope: tc.scope},
+			}
+			breakers := make(map[string]*circuitbreaker.CompositeBreaker)
+			for _, route := range routes {
+				if _, ok := breakers[route.BreakerKey()]; !ok {
+					breakers[route.BreakerKey()] = circuitbreaker.NewComposite(route.BreakerKey(), circuitbreaker.Config{
+						WindowSize:       4,
+						FailureThreshold: 0.5,
+						ResetTimeout:     time.Minute,
+						HalfOpenMax:      1,
+					}, slog.Default(), nil)
+				}
+			}
+			router, err := New(routes, breakers, slog.Default(), nil)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			for i := 0; i < 4; i++ {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/flaky", nil))
+			}
+			if got := breakers[routes[0].BreakerKey()].InnerState(); got != circuitbreaker.StateOpen {
+				t.Fatalf("expected /flaky breaker open, got %v", got)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "/steady", nil))
+			if rec.Code != tc.wantSteady {
+				t.Errorf("expected /steady status %d, got %d", tc.wantSteady, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAttemptTimeout_Jitter(t *testing.T) {
+	route := config.RouteConfig{TimeoutMs: 1000}
+	if got := attemptTimeout(route); got != time.Second {
+		t.Errorf("expected exact timeout without jitter, got %v", got)
+	}
+
+	route.TimeoutJitter = 0.2
+	lower := 800 * time.Millisecond
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 200; i++ {
+		got := attemptTimeout(route)
+		if got < lower || got > time.Second {
+			t.Fatalf("timeout %v outside jitter bound [%v, %v]", got, lower, time.Second)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 50 {
+		t.Errorf("expected jittered timeouts to vary, got %d distinct values in 200 draws", len(seen))
+	}
+}
+
+func TestRouter_MetricsLabelAndOptOut(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/orders", Backend: backend.URL, TimeoutMs: 5000, MetricsLabel: "commerce"},
+		{PathPrefix: "/carts", Backend: backend.URL, TimeoutMs: 5000, MetricsLabel: "commerce"},
+		{PathPrefix: "/noisy", Backend: backend.URL, TimeoutMs: 5000, MetricsDisabled: true},
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New(routes, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/orders/1", "/carts/2", "/noisy/3", "/noisy/4"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.RequestsTotal.WithLabelValues("commerce", "GET", "200")); got != 2 {
+		t.Errorf("expected shared label to aggregate 2 requests, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.RequestsTotal); got != 1 {
+		t.Errorf("expected only the shared series, got %d RequestsTotal series", got)
+	}
+	if got := testutil.CollectAndCount(m.RequestDuration); got != 1 {
+		t.Errorf("expected only the shared series, got %d RequestDuration series", got)
+	}
+}
+
+// Request durations carry the request ID as an exemplar, so a latency
+// spike can be traced to example requests.
+func TestRouter_RequestDurationExemplar(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	router, err := New([]config.RouteConfig{{PathPrefix: "/orders", Backend: backend.URL, TimeoutMs: 5000}}, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/orders/1", nil)
+	req.Header.Set("X-Request-ID", "req-abc")
+	middleware.RequestID(router).ServeHTTP(httptest.NewRecorder(), req)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, mf := range families {
+		if mf.GetName() != "gateway_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			for _, b := range metric.GetHistogram().GetBucket() {
+				for _, lp := range b.GetExemplar().GetLabel() {
+					if lp.GetName() == "request_id" {
+						ids = append(ids, lp.GetValue())
+					}
+				}
+			}
+		}
+	}
+	if len(ids) != 1 || ids[0] != "req-abc" {
+		t.Errorf("request_id exemplars = %q, want [req-abc]", ids)
+	}
+}
+
+func TestRouter_PathTemplatingCollapsesIDs(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api/users", Backend: backend.URL, TimeoutMs: 5000, PathTemplating: true},
+		{PathPrefix: "/api/orders", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New(routes, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"/api/users/123",
+		"/api/users/456",
+		"/api/users/550e8400-e29b-41d4-a716-446655440000",
+		"/api/orders/789",
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.RequestsByTemplate.WithLabelValues("/api/users", "/api/users/{id}", "GET", "200")); got != 2 {
+		t.Errorf("expected two IDs to collapse into one series with 2 requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.RequestsByTemplate.WithLabelValues("/api/users", "/api/users/{uuid}", "GET", "200")); got != 1 {
+		t.Errorf("expected UUID request under {uuid}, got %v", got)
+	}
+	// The route without path_templating contributes no template series.
+	if got := testutil.CollectAndCount(m.RequestsByTemplate); got != 2 {
+		t.Errorf("expected 2 template series, got %d", got)
+	}
+}
+
+func TestRouter_SlowClientBodyGets408WithoutBreakerFailure(t *testing.T) {
+	var backendHits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHits.Add(1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	route := config.RouteConfig{PathPrefix: "/upload", Backend: backend.URL, TimeoutMs: 5000, ClientBodyTimeoutMs: 100}
+	breaker := circuitbreaker.NewComposite(route.BreakerKey(), circuitbreaker.Config{
+		WindowSize:       1, // a single recorded failure opens the circuit
+		FailureThreshold: 0.5,
+		ResetTimeout:     time.Minute,
+		HalfOpenMax:      1,
+	}, slog.Default(), nil)
+	router, err := New([]config.RouteConfig{route}, map[string]*circuitbreaker.CompositeBreaker{route.BreakerKey(): breaker}, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	gw := httptest.NewServer(router)
+	defer gw.Close()
+
+	// The client sends part of the body, then stalls past the timeout.
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("partial"))
+		time.Sleep(500 * time.Millisecond)
+		_ = pw.Close()
+	}()
+	resp, err := http.Post(gw.URL+"/upload", "text/plain", pr)
+	if err != nil {
+		t.Fatalf("slow upload: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("slow upload status = %d, want 408", resp.StatusCode)
+	}
+	if n := backendHits.Load(); n != 0 {
+		t.Errorf("backend saw %d requests for a body that never arrived", n)
+	}
+	if st := breaker.InnerState(); st != circuitbreaker.StateClosed {
+		t.Fatalf("breaker state = %v after a slow client, want closed", st)
+	}
+
+	// A prompt client goes straight through with the buffered body.
+	resp, err = http.Post(gw.URL+"/upload", "text/plain", strings.NewReader("complete"))
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("prompt upload status = %d, want 200", resp.StatusCode)
+	}
+}
+
+// UpdateRoutes swaps the route table as a whole: a bad route list leaves
+// the old one serving, and a good one keeps the connection pool of a
+// backend that is still in use.
+func TestRouter_UpdateRoutes(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	router, err := New([]config.RouteConfig{
+		{PathPrefix: "/a", Backend: backend.URL, TimeoutMs: 5000},
+	}, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := router.table.Load().proxies[router.table.Load().routeBackendKey["/a"]].Transport
+
+	err = router.UpdateRoutes([]config.RouteConfig{
+		{PathPrefix: "/b", Backend: "://bad", TimeoutMs: 5000},
+	}, nil)
+	if err == nil {
+		t.Fatal("expected an invalid backend URL to be rejected")
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/a/x", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("after a failed update: /a status = %d, want 200", rec.Code)
+	}
+
+	err = router.UpdateRoutes([]config.RouteConfig{
+		{PathPrefix: "/a", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/b", Backend: backend.URL + "/b", TimeoutMs: 5000},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/a/x", "/b/y"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("after update: %s status = %d, want 200", path, rec.Code)
+		}
+	}
+	if after := router.table.Load().proxies[router.table.Load().routeBackendKey["/a"]].Transport; after != before {
+		t.Error("the update replaced the Transport of an unchanged backend")
+	}
+}
+
+// Update swaps routes and the default route in one table: a default
+// route that fails to build leaves the new routes out too.
+func TestRouter_UpdateRoutesAndDefaultRoute(t *testing.T) {
+	backend := httptest.NewServer(echoHandler())
+	defer backend.Close()
+
+	router, err := New([]config.RouteConfig{
+		{PathPrefix: "/a", Backend: backend.URL, TimeoutMs: 5000},
+	}, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+	routes := []config.RouteConfig{
+		{PathPrefix: "/a", Backend: backend.URL, TimeoutMs: 5000},
+		{PathPrefix: "/b", Backend: backend.URL, TimeoutMs: 5000},
+	}
+
+	before := router.table.Load()
+	if err := router.Update(routes, &config.DefaultRouteConfig{Backend: "://bad"}, nil); err == nil {
+		t.Fatal("expected an invalid default_route backend URL to be rejected")
+	}
+	if router.table.Load() != before {
+		t.Error("a failed update swapped the table")
+	}
+	if code := get("/b/x"); code != http.StatusNotFound {
+		t.Errorf("after a failed update: /b status = %d, want 404", code)
+	}
+
+	if err := router.Update(routes, &config.DefaultRouteConfig{Status: http.StatusTeapot}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int{"/a/x": http.StatusOK, "/b/x": http.StatusOK, "/other": http.StatusTeapot} {
+		if code := get(path); code != want {
+			t.Errorf("after update: %s status = %d, want %d", path, code, want)
+		}
+	}
+}
+
+// With the breaker open, a fail-closed route rejects without reaching the
+// backend while a fail-open one proxies the request once, marked degraded.
+func TestRouter_FailOpenWhileBreakerOpen(t *testing.T) {
+	tests := []struct {
+		name         string
+		failOpen     bool
+		wantStatus   int
+		wantHits     int32
+		wantDegraded string
+	}{
+		{"fail-closed", false, http.StatusServiceUnavailable, 0, ""},
+		{"fail-open", true, http.StatusBadGateway, 1, "circuit-open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+			defer backend.Close()
+
+			route := config.RouteConfig{PathPrefix: "/enrich", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 2, FailOpen: tt.failOpen}
+			breaker := circuitbreaker.NewComposite(route.BreakerKey(), circuitbreaker.Config{
+				WindowSize:       1,
+				FailureThreshold: 0.5,
+				ResetTimeout:     time.Minute,
+				HalfOpenMax:      1,
+			}, slog.Default(), nil)
+			breaker.RecordFailure(time.Millisecond)
+			router, err := New([]config.RouteConfig{route}, map[string]*circuitbreaker.CompositeBreaker{route.BreakerKey(): breaker}, slog.Default(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "/enrich/1", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if n := hits.Load(); n != tt.wantHits {
+				t.Errorf("backend hits = %d, want %d (no retries while degraded)", n, tt.wantHits)
+			}
+			if got := rec.Header().Get("X-Gateway-Degraded"); got != tt.wantDegraded {
+				t.Errorf("X-Gateway-Degraded = %q, want %q", got, tt.wantDegraded)
+			}
+			if st := breaker.InnerState(); st != circuitbreaker.StateOpen {
+				t.Errorf("breaker state = %v, want it still open", st)
+			}
+		})
+	}
+}
+
+// While the breaker is open, a serve_stale_on_open route answers a GET
+// with the last good response for that URL, and otherwise falls back.
+// Responses a shared cache could not store are never served stale, nor
+// are responses varying on a header the request has a different value of.
+func TestRouter_ServeStaleOnOpen(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/catalog/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/catalog/any":
+			w.Header().Set("Vary", "*")
+		case "/catalog/lang":
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"path":"`+r.URL.Path+`"}`)
+	}))
+	defer backend.Close()
+
+	route := config.RouteConfig{
+		PathPrefix:       "/catalog",
+		Backend:          backend.URL,
+		TimeoutMs:        5000,
+		ServeStaleOnOpen: true,
+		FallbackStatus:   http.StatusOK,
+		FallbackBody:     `{"items":[]}`,
+	}
+	breaker := circuitbreaker.NewComposite(route.BreakerKey(), circuitbreaker.Config{
+		WindowSize:       1,
+		FailureThreshold: 0.5,
+		ResetTimeout:     time.Minute,
+		HalfOpenMax:      1,
+	}, slog.Default(), nil)
+	router, err := New([]config.RouteConfig{route}, map[string]*circuitbreaker.CompositeBreaker{route.BreakerKey(): breaker}, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/catalog/1", "/catalog/private", "/catalog/any", "/catalog/lang"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Language", "en")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Gateway-Stale") != "" {
+			t.Fatalf("%s while closed: status = %d, X-Gateway-Stale = %q", path, rec.Code, rec.Header().Get("X-Gateway-Stale"))
+		}
+	}
+	breaker.RecordFailure(time.Millisecond)
+	if st := breaker.InnerState(); st != circuitbreaker.StateOpen {
+		t.Fatalf("breaker state = %v, want open", st)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		auth      bool
+		lang      string
+		wantBody  string
+		wantStale string
+	}{
+		{"stale served", "/catalog/1", false, "", `{"path":"/catalog/1"}`, "true"},
+		{"no cached response", "/catalog/2", false, "", `{"items":[]}` + "\n", ""},
+		{"private response not kept", "/catalog/private", false, "", `{"items":[]}` + "\n", ""},
+		{"credentialed request", "/catalog/1", true, "", `{"items":[]}` + "\n", ""},
+		{"vary star not kept", "/catalog/any", false, "en", `{"items":[]}` + "\n", ""},
+		{"same variant", "/catalog/lang", false, "en", `{"path":"/catalog/lang"}`, "true"},
+		{"other variant", "/catalog/lang", false, "fr", `{"items":[]}` + "\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer t")
+			}
+			if tt.lang != "" {
+				req.Header.Set("Accept-Language", tt.lang)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if got := rec.Header().Get("X-Gateway-Stale"); got != tt.wantStale {
+				t.Errorf("X-Gateway-Stale = %q, want %q", got, tt.wantStale)
+			}
+		})
+	}
+	if n := hits.Load(); n != 4 {
+		t.Errorf("backend hits = %d, want 4 (none while open)", n)
+	}
+}
+
+// Requests rejected by an open breaker are counted as fallbacks on routes
+// with a fallback response and as circuit-open rejections otherwise.
+func TestRouter_CountsCircuitOpenResponses(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	routes := []config.RouteConfig{
+		{PathPrefix: "/recs", Backend: "http://127.0.0.1:1/recs", TimeoutMs: 5000, FallbackStatus: http.StatusOK, FallbackBody: `{"items":[]}`},
+		{PathPrefix: "/orders", Backend: "http://127.0.0.1:1/orders", TimeoutMs: 5000},
+	}
+	breakers := make(map[string]*circuitbreaker.CompositeBreaker)
+	for _, route := range routes {
+		cb := circuitbreaker.NewComposite(route.BreakerKey(), circuitbreaker.Config{
+			WindowSize: 1, FailureThreshold: 0.5, ResetTimeout: time.Minute, HalfOpenMax: 1,
+		}, slog.Default(), nil)
+		cb.RecordFailure(time.Millisecond)
+		breakers[route.BreakerKey()] = cb
+	}
+	router, err := New(routes, breakers, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/recs/1", "/recs/2", "/orders/1"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if got := testutil.ToFloat64(m.FallbackResponses.WithLabelValues("/recs")); got != 2 {
+		t.Errorf("fallback responses for /recs = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.CircuitOpenRejections.WithLabelValues("/orders")); got != 1 {
+		t.Errorf("circuit-open rejections for /orders = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.CircuitOpenRejections); got != 1 {
+		t.Errorf("circuit-open rejections recorded for %d routes, want only /orders", got)
+	}
+}
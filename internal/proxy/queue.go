@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errQueueTimeout is returned by routeQueue.acquire when no slot freed up
+// within the route's queue_timeout_ms.
+var errQueueTimeout = errors.New("proxy: route concurrency queue wait exceeded")
+
+// routeQueue enforces a route's max_concurrent limit. Where the backend
+// bulkhead rejects at capacity, routeQueue lets callers wait up to maxWait
+// for a slot, smoothing short bursts instead of shedding them.
+type routeQueue struct {
+	sem     chan struct{}
+	maxWait time.Duration
+}
+
+func newRouteQueue(maxConcurrent int, maxWait time.Duration) *routeQueue {
+	return &routeQueue{
+		sem:     make(chan struct{}, maxConcurrent),
+		maxWait: maxWait,
+	}
+}
+
+// acquire takes a slot, waiting up to maxWait when none is free. queued
+// reports whether the caller had to wait. On success the caller MUST call
+// release; on error (errQueueTimeout or ctx.Err()) no slot is held.
+func (q *routeQueue) acquire(ctx context.Context) (queued bool, err error) {
+	select {
+	case q.sem <- struct{}{}:
+		return false, nil
+	default:
+	}
+
+	timer := time.NewTimer(q.maxWait)
+	defer timer.Stop()
+	select {
+	case q.sem <- struct{}{}:
+		return true, nil
+	case <-timer.C:
+		return true, errQueueTimeout
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// release frees a slot taken by a successful acquire.
+func (q *routeQueue) release() {
+	<-q.sem
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// slowBackend holds each request for delay and tracks peak concurrency.
+func slowBackend(delay time.Duration, inFlight, peak *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		inFlight.Add(-1)
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func serveConcurrently(router http.Handler, path string, n int) []*httptest.ResponseRecorder {
+	recs := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer wg.Done()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		}(recs[i])
+	}
+	wg.Wait()
+	return recs
+}
+
+func TestRouter_MaxConcurrentQueuesThenProceeds(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	backend := slowBackend(50*time.Millisecond, &inFlight, &peak)
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, MaxConcurrent: 1, QueueTimeoutMs: 2000},
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New(routes, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, rec := range serveConcurrently(router, "/api/work", 3) {
+		if rec.Code != http.StatusOK {
+			t.Errorf("request %d: expected 200 after queueing, got %d", i, rec.Code)
+		}
+	}
+	if got := peak.Load(); got != 1 {
+		t.Errorf("expected at most 1 concurrent backend request, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.RouteQueued.WithLabelValues("/api")); got != 2 {
+		t.Errorf("expected 2 queued requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.RouteQueueRejections.WithLabelValues("/api")); got != 0 {
+		t.Errorf("expected no queue rejections, got %v", got)
+	}
+}
+
+func TestRouter_MaxConcurrentRejectsAfterQueueTimeout(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	backend := slowBackend(300*time.Millisecond, &inFlight, &peak)
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, MaxConcurrent: 1, QueueTimeoutMs: 50},
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New(routes, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ok, rejected int
+	for _, rec := range serveConcurrently(router, "/api/work", 2) {
+		switch rec.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusServiceUnavailable:
+			rejected++
+			if !strings.Contains(rec.Body.String(), "GATEWAY_QUEUE_TIMEOUT") {
+				t.Errorf("expected GATEWAY_QUEUE_TIMEOUT, got %s", rec.Body.String())
+			}
+		default:
+			t.Errorf("unexpected status %d", rec.Code)
+		}
+	}
+	if ok != 1 || rejected != 1 {
+		t.Errorf("expected 1 served and 1 rejected, got %d and %d", ok, rejected)
+	}
+	if got := testutil.ToFloat64(m.RouteQueueRejections.WithLabelValues("/api")); got != 1 {
+		t.Errorf("expected 1 queue rejection, got %v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// internalLocation resolves resp's Location header against the request URL
+// and returns it when it points at the backend host, or nil otherwise.
+func internalLocation(resp *http.Response, target *url.URL) *url.URL {
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return nil
+	}
+	u, err := resp.Request.URL.Parse(loc)
+	if err != nil || !strings.EqualFold(u.Host, target.Host) {
+		return nil
+	}
+	return u
+}
+
+// rewriteLocation replaces a Location pointing at the backend with the
+// equivalent URL on the gateway: the client-facing scheme and host, the
+// backend's base path removed, and the route prefix restored when the route
+// strips it.
+func rewriteLocation(resp *http.Response, target *url.URL, info *routeInfo) {
+	u := internalLocation(resp, target)
+	if u == nil {
+		return
+	}
+	path := u.Path
+	if base := strings.TrimRight(target.Path, "/"); base != "" && strings.HasPrefix(path, base) {
+		path = strings.TrimPrefix(path, base)
+		if path == "" {
+			path = "/"
+		}
+	}
+	if info.route.StripPrefix {
+		path = strings.TrimRight(info.route.PathPrefix, "/") + path
+	}
+	external := url.URL{
+		Scheme:   info.externalScheme,
+		Host:     info.externalHost,
+		Path:     path,
+		RawQuery: u.RawQuery,
+		Fragment: u.Fragment,
+	}
+	resp.Header.Set("Location", external.String())
+}
+
+// followRedirects follows redirects that stay on the backend host, up to the
+// route's max_redirects, replacing resp with the final response. Redirects
+// to other hosts are passed through untouched — the gateway never follows
+// them, which would turn it into an open proxy. Redirects that would need to
+// resend a request body (307/308 on a request that had one) are not
+// followed either, since the body has already been consumed. If the limit
+// is reached the last redirect is rewritten so the client can still follow
+// it through the gateway.
+func followRedirects(resp *http.Response, target *url.URL, transport http.RoundTripper, info *routeInfo, logger *slog.Logger) error {
+	for hops := 0; isRedirect(resp.StatusCode); hops++ {
+		next := internalLocation(resp, target)
+		if next == nil {
+			return nil
+		}
+		if hops >= info.route.MaxRedirects {
+			rewriteLocation(resp, target, info)
+			return nil
+		}
+
+		prev := resp.Request
+		method := prev.Method
+		switch resp.StatusCode {
+		case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			if prev.Body != nil && prev.Body != http.NoBody && prev.ContentLength != 0 {
+				return nil
+			}
+		default:
+			if method != http.MethodGet && method != http.MethodHead {
+				method = http.MethodGet
+			}
+		}
+
+		req, err := http.NewRequestWithContext(prev.Context(), method, next.String(), nil)
+		if err != nil {
+			return fmt.Errorf("building redirect request: %w", err)
+		}
+		req.Header = prev.Header.Clone()
+		req.Header.Del("Content-Length")
+		req.Header.Del("Content-Type")
+		req.Host = next.Host
+
+		nextResp, err := transport.RoundTrip(req)
+		if err != nil {
+			return fmt.Errorf("following redirect to %s: %w", next.Redacted(), err)
+		}
+		if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)); err != nil {
+			logger.Debug("proxy: failed to drain redirect response body", "backend", info.route.Backend, "error", err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			logger.Debug("proxy: failed to close redirect response body", "backend", info.route.Backend, "error", err)
+		}
+		*resp = *nextResp
+		// ReverseProxy cleaned only the first response.
+		removeHopByHopHeaders(resp.Header)
+	}
+	return nil
+}
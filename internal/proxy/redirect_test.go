@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+func TestRouter_RedirectRewrite(t *testing.T) {
+	var backend *httptest.Server
+	backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, backend.URL+"/login?next=%2Fme", http.StatusFound)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api/users", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000, RedirectPolicy: "rewrite"},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://gw.example.com/api/users/me", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", rec.Code)
+	}
+	want := "http://gw.example.com/api/users/login?next=%2Fme"
+	if got := rec.Header().Get("Location"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestRouter_RedirectRewriteLeavesExternalLocation(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://idp.example.com/authorize", http.StatusFound)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000, RedirectPolicy: "rewrite"},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+
+	if got := rec.Header().Get("Location"); got != "https://idp.example.com/authorize" {
+		t.Errorf("expected external Location untouched, got %q", got)
+	}
+}
+
+func TestRouter_RedirectFollow(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("X-Final", "yes")
+			_, _ = io.WriteString(w, "final body")
+		}
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/", Backend: backend.URL, TimeoutMs: 5000, RedirectPolicy: "follow", MaxRedirects: 3},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/old", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after following, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Final") != "yes" || rec.Body.String() != "final body" {
+		t.Errorf("expected final response, got headers %v body %q", rec.Header(), rec.Body.String())
+	}
+}
+
+func TestRouter_RedirectFollowIsBounded(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/svc", Backend: backend.URL, StripPrefix: true, TimeoutMs: 5000, RedirectPolicy: "follow", MaxRedirects: 2},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://gw.example.com/svc/start", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := hits.Load(); got != 3 {
+		t.Errorf("expected 1 request + 2 followed redirects, got %d backend hits", got)
+	}
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected final 302 once the limit is reached, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "http://gw.example.com/svc/loop" {
+		t.Errorf("expected last Location rewritten to the gateway, got %q", got)
+	}
+}
+
+func TestRouter_RedirectPassthroughByDefault(t *testing.T) {
+	var backend *httptest.Server
+	backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, backend.URL+"/elsewhere", http.StatusFound)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/x", nil))
+
+	if got := rec.Header().Get("Location"); !strings.HasPrefix(got, backend.URL) {
+		t.Errorf("expected backend Location passed through, got %q", got)
+	}
+}
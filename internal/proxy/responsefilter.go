@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxFilterBytes caps the response bodies strip_response_fields buffers;
+// larger bodies stream through unfiltered.
+const maxFilterBytes = 1 << 20
+
+// stripResponseFields removes the named keys, at any depth, from a JSON
+// response body. Only uncompressed application/json (or +json) bodies of
+// at most maxFilterBytes are rewritten; anything else streams through as
+// is. A rewritten body is re-serialized, so its object keys come out
+// sorted. A body with none of the keys is passed on byte for byte.
+func stripResponseFields(resp *http.Response, fields []string) error {
+	if resp.Request.Method == http.MethodHead || resp.ContentLength > maxFilterBytes ||
+		!isJSONContentType(resp.Header.Get("Content-Type")) ||
+		(resp.Header.Get("Content-Encoding") != "" && resp.Header.Get("Content-Encoding") != "identity") {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFilterBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxFilterBytes {
+		// Over the cap with no Content-Length: send what was read, then
+		// the rest, untouched.
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return nil
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep numbers exactly as the backend wrote them
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return nil // not a single JSON document: leave it alone
+	}
+	drop := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		drop[f] = true
+	}
+	if !removeFields(doc, drop) {
+		return nil
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(out.Bytes()))
+	resp.ContentLength = int64(out.Len())
+	resp.Header.Set("Content-Length", strconv.Itoa(out.Len()))
+	resp.Header.Del("ETag") // no longer describes the body
+	resp.TransferEncoding = nil
+	return nil
+}
+
+// removeFields deletes the keys in drop from every object in v and
+// reports whether any were found.
+func removeFields(v any, drop map[string]bool) bool {
+	removed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if drop[k] {
+				delete(v, k)
+				removed = true
+				continue
+			}
+			if removeFields(child, drop) {
+				removed = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if removeFields(child, drop) {
+				removed = true
+			}
+		}
+	}
+	return removed
+}
+
+// readCloser pairs a Reader with the Closer of the body it reads from.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
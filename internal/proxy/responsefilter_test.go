@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+func TestRouter_StripResponseFields(t *testing.T) {
+	large := `{"_debug": "` + strings.Repeat("x", maxFilterBytes) + `"}`
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/large":
+			_, _ = w.Write([]byte(large))
+		case "/clean":
+			_, _ = w.Write([]byte(`{"id": 1,  "name": "a"}`))
+		default:
+			_, _ = w.Write([]byte(`{"id": 12345678901234567890, "_debug": {"sql": "select"}, "items": [{"sku": "a", "internal_id": 7}], "note": "<b>"}`))
+		}
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/", Backend: backend.URL, StripResponseFields: []string{"_debug", "internal_id"}, TimeoutMs: 5000},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/orders", nil))
+	want := `{"id":12345678901234567890,"items":[{"sku":"a"}],"note":"<b>"}` + "\n"
+	if rec.Body.String() != want {
+		t.Errorf("filtered body = %q, want %q", rec.Body.String(), want)
+	}
+
+	// Nothing to strip: the backend's bytes are passed on unchanged.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/clean", nil))
+	if got := rec.Body.String(); got != `{"id": 1,  "name": "a"}` {
+		t.Errorf("clean body = %q, want it unchanged", got)
+	}
+
+	// Over the size cap: streamed through unfiltered.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/large", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != large {
+		t.Errorf("large body: status %d, %d bytes, want the %d-byte body unchanged", rec.Code, rec.Body.Len(), len(large))
+	}
+}
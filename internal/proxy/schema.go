@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/dskow/gateway-core/internal/jsonschema"
+)
+
+// maxBodyProblems caps the validation failures reported to the client.
+const maxBodyProblems = 10
+
+// hasJSONBody reports whether r carries a body declared as JSON.
+func hasJSONBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && isJSONContentType(r.Header.Get("Content-Type"))
+}
+
+// validateBody reads r's body in full, replaces it with an in-memory copy
+// for the backend, and checks it against schema. It returns the problems
+// found, at most maxBodyProblems; a body that is not valid JSON is one
+// problem. Read errors, including the *http.MaxBytesError of a body over
+// the server's limit, are returned as err.
+func validateBody(r *http.Request, schema *jsonschema.Schema) (problems []string, err error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.ContentLength = int64(len(data))
+	r.TransferEncoding = nil
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []string{"/: invalid JSON: " + err.Error()}, nil
+	}
+	problems = schema.Validate(doc)
+	if len(problems) > maxBodyProblems {
+		problems = problems[:maxBodyProblems]
+	}
+	return problems, nil
+}
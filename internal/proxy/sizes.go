@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/middleware"
+)
+
+// sizeWindow is the number of recent requests the rolling average mostly
+// reflects: it is a plain mean until a route has seen sizeWindow requests,
+// then an exponential average weighting each new size 1/sizeWindow.
+const sizeWindow = 20
+
+// sizeTracker keeps a rolling average body size per route and direction
+// and warns when a body exceeds it by logging.size_anomaly.multiplier.
+type sizeTracker struct {
+	cfg    config.SizeAnomalyConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	stats sync.Map // route ID + " " + direction → *sizeStats
+}
+
+type sizeStats struct {
+	mu         sync.Mutex
+	avg        float64
+	n          int
+	lastWarn   time.Time
+	suppressed int // anomalies not logged since lastWarn
+}
+
+func newSizeTracker(cfg config.SizeAnomalyConfig, logger *slog.Logger) *sizeTracker {
+	return &sizeTracker{cfg: cfg, logger: logger, now: time.Now}
+}
+
+// observe records a request's body sizes for route.
+func (s *sizeTracker) observe(r *http.Request, route config.RouteConfig, path string, reqBytes, respBytes int64) {
+	s.observeOne(r, route, path, "request", reqBytes)
+	s.observeOne(r, route, path, "response", respBytes)
+}
+
+// prune drops the statistics of routes no longer in routes, so routes
+// removed by reloads do not accumulate.
+func (s *sizeTracker) prune(routes []config.RouteConfig) {
+	keep := make(map[string]bool, 2*len(routes))
+	for _, route := range routes {
+		keep[route.ID()+" request"] = true
+		keep[route.ID()+" response"] = true
+	}
+	s.stats.Range(func(key, _ any) bool {
+		if !keep[key.(string)] {
+			s.stats.Delete(key)
+		}
+		return true
+	})
+}
+
+func (s *sizeTracker) observeOne(r *http.Request, route config.RouteConfig, path, direction string, size int64) {
+	key := route.ID() + " " + direction
+	v, ok := s.stats.Load(key)
+	if !ok {
+		v, _ = s.stats.LoadOrStore(key, &sizeStats{})
+	}
+	st := v.(*sizeStats)
+
+	st.mu.Lock()
+	avg := st.avg
+	anomalous := st.n >= s.cfg.MinSamples && size >= s.cfg.MinBytes && float64(size) > s.cfg.Multiplier*avg
+	// Anomalies count toward the average, so a lasting change in size
+	// becomes the new normal instead of warning forever.
+	st.n++
+	st.avg += (float64(size) - st.avg) / float64(min(st.n, sizeWindow))
+	logIt, suppressed := false, 0
+	if anomalous {
+		if now := s.now(); now.Sub(st.lastWarn) >= s.cfg.LogInterval {
+			logIt, suppressed = true, st.suppressed
+			st.lastWarn, st.suppressed = now, 0
+		} else {
+			st.suppressed++
+		}
+	}
+	st.mu.Unlock()
+
+	if logIt {
+		s.logger.Warn("body size anomaly",
+			"route", route.PathPrefix,
+			"direction", direction,
+			"bytes", size,
+			"average_bytes", int64(avg),
+			"method", r.Method,
+			"path", path,
+			"backend", route.Backend,
+			"request_id", middleware.GetRequestID(r.Context()),
+			"suppressed", suppressed,
+		)
+	}
+}
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// A response far larger than the route's recent ones logs one anomaly
+// warning; more within log_interval are counted, not logged.
+func TestRouter_SizeAnomalyWarnsOnSuddenLargeResponse(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		w.Write([]byte(strings.Repeat("x", n)))
+	}))
+	defer backend.Close()
+
+	var logs bytes.Buffer
+	router := sizeAnomalyRouter(t, "/reports", backend.URL, &logs)
+	now := time.Now()
+	router.sizes.now = func() time.Time { return now }
+
+	get := func(size int) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports/daily?size="+strconv.Itoa(size), nil))
+	}
+	for range 5 {
+		get(200)
+	}
+	if anomalies := sizeAnomalies(t, &logs); len(anomalies) != 0 {
+		t.Fatalf("warned on ordinary responses: %v", anomalies)
+	}
+
+	get(5000)
+	anomalies := sizeAnomalies(t, &logs)
+	if len(anomalies) != 1 {
+		t.Fatalf("got %d anomaly warnings, want 1", len(anomalies))
+	}
+	if a := anomalies[0]; a["direction"] != "response" || a["bytes"] != float64(5000) || a["average_bytes"] != float64(200) || a["path"] != "/reports/daily" {
+		t.Errorf("warning = %v, want the 5000-byte response against a 200-byte average", a)
+	}
+
+	get(20000)
+	if anomalies := sizeAnomalies(t, &logs); len(anomalies) != 0 {
+		t.Fatalf("second anomaly within log_interval was logged: %v", anomalies)
+	}
+	now = now.Add(time.Minute)
+	get(200000)
+	anomalies = sizeAnomalies(t, &logs)
+	if len(anomalies) != 1 || anomalies[0]["suppressed"] != float64(1) {
+		t.Errorf("after log_interval: warnings = %v, want one reporting 1 suppressed", anomalies)
+	}
+}
+
+// A request body far larger than the route's recent ones is flagged the
+// same way, as direction "request".
+func TestRouter_SizeAnomalyWarnsOnSuddenLargeRequest(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	var logs bytes.Buffer
+	router := sizeAnomalyRouter(t, "/upload", backend.URL, &logs)
+	post := func(size int) {
+		req := httptest.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat("x", size)))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for range 5 {
+		post(300)
+	}
+	post(8000)
+
+	anomalies := sizeAnomalies(t, &logs)
+	if len(anomalies) != 1 {
+		t.Fatalf("got %d anomaly warnings, want 1: %v", len(anomalies), anomalies)
+	}
+	if a := anomalies[0]; a["direction"] != "request" || a["bytes"] != float64(8000) || a["average_bytes"] != float64(300) {
+		t.Errorf("warning = %v, want the 8000-byte request against a 300-byte average", a)
+	}
+}
+
+// Bodies under min_bytes are never flagged, however much larger than the
+// average they are.
+func TestRouter_SizeAnomalyIgnoresBodiesUnderMinBytes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		w.Write([]byte(strings.Repeat("x", n)))
+	}))
+	defer backend.Close()
+
+	var logs bytes.Buffer
+	router := sizeAnomalyRouter(t, "/tiny", backend.URL, &logs)
+	get := func(size int) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tiny?size="+strconv.Itoa(size), nil))
+	}
+	for range 5 {
+		get(10)
+	}
+	get(1000) // 100× the average, still under min_bytes 1024
+
+	if anomalies := sizeAnomalies(t, &logs); len(anomalies) != 0 {
+		t.Errorf("warned on a body under min_bytes: %v", anomalies)
+	}
+}
+
+// A reload that removes a route drops its size statistics.
+func TestRouter_UpdateRoutesPrunesSizeStats(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	var logs bytes.Buffer
+	router := sizeAnomalyRouter(t, "/old", backend.URL, &logs)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/old", nil))
+	if _, ok := router.sizes.stats.Load("/old response"); !ok {
+		t.Fatal("no statistics recorded for /old")
+	}
+
+	if err := router.UpdateRoutes([]config.RouteConfig{{PathPrefix: "/new", Backend: backend.URL, TimeoutMs: 5000}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	router.sizes.stats.Range(func(key, _ any) bool {
+		t.Errorf("statistics kept for %v after its route was removed", key)
+		return true
+	})
+}
+
+// sizeAnomalyRouter returns a Router with one route, prefix → backend,
+// logging JSON to logs, with size anomaly detection after 5 samples.
+func sizeAnomalyRouter(t *testing.T, prefix, backend string, logs *bytes.Buffer) *Router {
+	t.Helper()
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+	router, err := New([]config.RouteConfig{{PathPrefix: prefix, Backend: backend, TimeoutMs: 5000}}, nil, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.SetSizeAnomaly(config.SizeAnomalyConfig{
+		Enabled:     true,
+		Multiplier:  10,
+		MinSamples:  5,
+		MinBytes:    1024,
+		LogInterval: time.Minute,
+	})
+	return router
+}
+
+// sizeAnomalies returns the body size anomaly warnings in logs, and
+// empties it.
+func sizeAnomalies(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if entry["msg"] == "body size anomaly" {
+			out = append(out, entry)
+		}
+	}
+	logs.Reset()
+	return out
+}
@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// staleHeader marks a last-good response served by a serve_stale_on_open
+// route while its circuit breaker was open.
+const staleHeader = "X-Gateway-Stale"
+
+const (
+	// staleMaxEntries and staleMaxBytes bound the last-good store across
+	// all routes, by count and by the size of the stored responses; the
+	// least recently used entries are evicted first.
+	staleMaxEntries = 1024
+	staleMaxBytes   = 64 << 20
+	// staleMaxBodyBytes is the largest response body kept. Larger
+	// responses stream through and are not stored.
+	staleMaxBodyBytes = 1 << 20
+)
+
+// staleStore is an LRU of the last 200 response per URL on
+// serve_stale_on_open routes; for a response with Vary, only the variant
+// fetched last is kept. Entries never expire: the point is to have
+// something to serve after the backend stopped answering, however old.
+type staleStore struct {
+	maxEntries int
+	maxBytes   int
+
+	mu    sync.Mutex
+	order *list.List // front: most recently used
+	items map[string]*list.Element
+	bytes int // sum of the entries' sizes
+}
+
+type staleEntry struct {
+	key    string
+	header http.Header
+	body   []byte
+	// vary holds the request's values of the headers the response's Vary
+	// names; the entry is served only to requests with the same values.
+	vary string
+}
+
+// size approximates the memory an entry holds.
+func (e *staleEntry) size() int {
+	n := len(e.key) + len(e.body) + len(e.vary)
+	for k, vs := range e.header {
+		n += len(k)
+		for _, v := range vs {
+			n += len(v)
+		}
+	}
+	return n
+}
+
+func newStaleStore(maxEntries, maxBytes int) *staleStore {
+	return &staleStore{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// staleKey returns the store key for r on route, or "" when r may not be
+// stored or served: only GETs without credentials are, since an entry is
+// served to every client.
+func staleKey(routeID string, r *http.Request) string {
+	if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return ""
+	}
+	return routeID + " " + r.Host + r.URL.RequestURI()
+}
+
+func (s *staleStore) get(key string) (*staleEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*staleEntry), true
+}
+
+func (s *staleStore) add(entry *staleEntry) {
+	size := entry.size()
+	if size > s.maxBytes {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[entry.key]; ok {
+		s.bytes -= el.Value.(*staleEntry).size()
+		el.Value = entry
+		s.order.MoveToFront(el)
+	} else {
+		s.items[entry.key] = s.order.PushFront(entry)
+	}
+	s.bytes += size
+	for s.order.Len() > s.maxEntries || s.bytes > s.maxBytes {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		e := oldest.Value.(*staleEntry)
+		delete(s.items, e.key)
+		s.bytes -= e.size()
+	}
+}
+
+// storable reports whether a shared cache could keep resp. Vary: * says
+// no request is sure to get the same response, so it rules storing out.
+func storable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || len(resp.Header.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	for _, name := range varyNames(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	for _, v := range resp.Header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "no-store" || d == "private" || strings.HasPrefix(d, "private=") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// varyNames returns the header names listed in h's Vary headers.
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// varyKey returns reqHeader's values of the headers respHeader varies on,
+// in a form two requests share only if they would get the same variant.
+func varyKey(respHeader, reqHeader http.Header) string {
+	var b strings.Builder
+	for _, name := range varyNames(respHeader) {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(reqHeader.Values(name), ","))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// captureLastGood arranges for resp to be stored once its body has been
+// read in full, when the request has a stale key and resp is storable.
+// It runs last in ModifyResponse, so the stored headers are those the
+// client got.
+func captureLastGood(resp *http.Response, info *routeInfo) {
+	if info.stale == nil || info.staleKey == "" || !storable(resp) {
+		return
+	}
+	if resp.ContentLength > staleMaxBodyBytes {
+		return
+	}
+	resp.Body = &lastGoodBody{
+		ReadCloser: resp.Body,
+		store:      info.stale,
+		key:        info.staleKey,
+		header:     resp.Header.Clone(),
+		vary:       varyKey(resp.Header, resp.Request.Header),
+	}
+}
+
+// lastGoodBody copies a response body as it is proxied and stores the
+// response when the body reaches EOF within staleMaxBodyBytes. A body cut
+// short by the backend or the client is not stored.
+type lastGoodBody struct {
+	io.ReadCloser
+	store    *staleStore
+	key      string
+	header   http.Header
+	vary     string
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (b *lastGoodBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.overflow {
+		if b.buf.Len()+n > staleMaxBodyBytes {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.overflow {
+		b.overflow = true // store once
+		b.store.add(&staleEntry{key: b.key, header: b.header, body: b.buf.Bytes(), vary: b.vary})
+	}
+	return n, err
+}
+
+// serveStale writes the stored response for key, if there is one and it
+// is the variant r would get, and reports whether it did.
+func (rt *Router) serveStale(w http.ResponseWriter, r *http.Request, key string) bool {
+	if key == "" {
+		return false
+	}
+	entry, ok := rt.stale.get(key)
+	if !ok || entry.vary != varyKey(entry.header, r.Header) {
+		return false
+	}
+	h := w.Header()
+	for k, v := range entry.header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set(staleHeader, "true")
+	h.Set("Content-Length", strconv.Itoa(len(entry.body)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(entry.body); err != nil {
+		rt.logger.Debug("proxy: failed to write stale response", "error", err)
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+)
+
+// The store evicts least recently used entries to stay within its byte
+// budget, and does not keep an entry larger than the whole budget.
+func TestStaleStore_EvictsByBytes(t *testing.T) {
+	s := newStaleStore(100, 3000)
+	entry := func(key string, size int) *staleEntry {
+		return &staleEntry{key: key, body: []byte(strings.Repeat("x", size-len(key)))}
+	}
+
+	s.add(entry("a", 1000))
+	s.add(entry("b", 1000))
+	s.add(entry("c", 1000))
+	if _, ok := s.get("a"); !ok { // a is now the most recently used
+		t.Fatal("a evicted while within budget")
+	}
+	s.add(entry("d", 1000))
+	if _, ok := s.get("b"); ok {
+		t.Error("b kept past the byte budget; want the least recently used evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := s.get(key); !ok {
+			t.Errorf("%s evicted, want only b", key)
+		}
+	}
+	if s.bytes != 3000 {
+		t.Errorf("bytes = %d, want 3000", s.bytes)
+	}
+
+	// Replacing an entry accounts for the size it had.
+	s.add(entry("d", 500))
+	if s.bytes != 2500 {
+		t.Errorf("after replacing d: bytes = %d, want 2500", s.bytes)
+	}
+
+	s.add(entry("huge", 4000))
+	if _, ok := s.get("huge"); ok || s.bytes != 2500 {
+		t.Errorf("entry over the budget stored: bytes = %d", s.bytes)
+	}
+}
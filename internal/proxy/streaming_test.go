@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// TestRouter_StreamsEventsOnRetryRoutes checks that a route with retries
+// does not buffer a Server-Sent Events response: the backend only sends
+// its second event after the client has received the first.
+func TestRouter_StreamsEventsOnRetryRoutes(t *testing.T) {
+	received := make(chan struct{})
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-received:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, "data: two\n\n")
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/events", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 2},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := httptest.NewServer(router)
+	defer gw.Close()
+
+	resp, err := http.Get(gw.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if line := sc.Text(); strings.HasPrefix(line, "data: ") {
+				lines <- line
+			}
+		}
+		close(lines)
+	}()
+
+	for i, want := range []string{"data: one", "data: two"} {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("event %d = %q, want %q", i, got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("event %d did not arrive; the response is being buffered", i)
+		}
+		if i == 0 {
+			close(received)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("backend hits = %d, want 1", n)
+	}
+}
+
+func TestRouter_StreamsResponsesLargerThanRetryBuffer(t *testing.T) {
+	body := strings.Repeat("x", 4096)
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, body)
+	}))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/big", Backend: backend.URL, TimeoutMs: 5000, RetryAttempts: 2, RetryMaxBufferBytes: 1024},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/big/file", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the backend's 503", rec.Code)
+	}
+	if rec.Body.String() != body {
+		t.Errorf("body is %d bytes, want the backend's %d", rec.Body.Len(), len(body))
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("backend hits = %d, want 1: a response past the buffer limit must not be retried", n)
+	}
+}
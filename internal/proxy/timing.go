@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverTiming accumulates the Server-Timing breakdown of one request
+// across its attempts. Durations are reported in milliseconds:
+//
+//	backend  time waiting on the backend, summed over every attempt (the
+//	         final one up to its response headers)
+//	gateway  the rest of the time in the proxy: routing, queueing, body
+//	         reads and retry backoff
+//	retries  time spent on failed attempts and their backoff, part of the
+//	         two above
+type serverTiming struct {
+	backend time.Duration
+	retries time.Duration
+}
+
+// header renders the Server-Timing value for a response sent total after
+// the request reached the proxy, with final the final attempt's backend
+// time so far.
+func (st *serverTiming) header(total, final time.Duration) string {
+	backend := st.backend + final
+	gateway := max(total-backend, 0)
+
+	var b strings.Builder
+	b.WriteString("backend;dur=")
+	b.WriteString(formatMillis(backend))
+	b.WriteString(", gateway;dur=")
+	b.WriteString(formatMillis(gateway))
+	b.WriteString(", retries;dur=")
+	b.WriteString(formatMillis(st.retries))
+	return b.String()
+}
+
+// formatMillis formats d in milliseconds with one decimal, the precision
+// Server-Timing consumers display.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)
+}
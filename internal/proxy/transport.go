@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/metrics"
+)
+
+// upstreamTransport is a backend Transport with its connection counters.
+type upstreamTransport struct {
+	http.RoundTripper
+	base  *http.Transport
+	stats *transportStats
+}
+
+// closeIdleConnections closes the transport's idle connections, once a
+// reload has left it unused; requests still in flight are unaffected.
+func (t *upstreamTransport) closeIdleConnections() { t.base.CloseIdleConnections() }
+
+// newUpstreamTransport builds a Transport with route's pool settings,
+// counted under backend.
+func newUpstreamTransport(route config.RouteConfig, backend string, m *metrics.Metrics) *upstreamTransport {
+	stats := newTransportStats(backend, poolKey(route), m)
+	base := buildTransport(route.ConnectionPool, route.ResponseHeaderTimeout())
+	return &upstreamTransport{
+		RoundTripper: stats.instrument(base),
+		base:         base,
+		stats:        stats,
+	}
+}
+
+// sharedTransport returns the Transport in transports for target's origin
+// and route's pool settings, on first use taking it from prev (the
+// previous route table's, across a reload) or creating it. Routes whose
+// backends differ only in path share one connection pool; a route with
+// its own connection_pool or response_header_timeout_ms gets its own.
+func sharedTransport(transports, prev map[string]*upstreamTransport, route config.RouteConfig, target *url.URL, m *metrics.Metrics) *upstreamTransport {
+	origin := originKey(target)
+	key := withPool(origin, route)
+	t, ok := transports[key]
+	if !ok {
+		if t, ok = prev[key]; !ok {
+			t = newUpstreamTransport(route, origin, m)
+		}
+		transports[key] = t
+	}
+	return t
+}
+
+// TransportStats are the connection counters of one backend's transport,
+// served by /admin/transport.
+type TransportStats struct {
+	Backend        string `json:"backend"`
+	Pool           string `json:"pool,omitempty"`  // non-default pool settings, if any
+	Dials          int64  `json:"dials"`           // connections dialed since start
+	OpenConns      int64  `json:"open_conns"`      // connections currently open
+	ActiveRequests int64  `json:"active_requests"` // requests awaiting or streaming a response
+	IdleConns      int64  `json:"idle_conns"`      // open connections not serving a request (HTTP/1)
+}
+
+// transportStats counts what http.Transport does not expose: dials and
+// open connections, via its DialContext, and requests in flight, via a
+// RoundTripper wrapper. m may be nil.
+type transportStats struct {
+	backend string
+	pool    string
+	m       *metrics.Metrics
+
+	dials  atomic.Int64
+	open   atomic.Int64
+	active atomic.Int64
+}
+
+func newTransportStats(backend, pool string, m *metrics.Metrics) *transportStats {
+	return &transportStats{backend: backend, pool: pool, m: m}
+}
+
+// instrument wraps t's DialContext to count connections and returns t as a
+// RoundTripper that counts requests.
+func (s *transportStats) instrument(t *http.Transport) http.RoundTripper {
+	dial := t.DialContext
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		s.dials.Add(1)
+		s.open.Add(1)
+		if s.m != nil {
+			s.m.UpstreamDials.WithLabelValues(s.backend).Inc()
+		}
+		return &countedConn{Conn: conn, stats: s}, nil
+	}
+	return &countingRoundTripper{next: t, stats: s}
+}
+
+func (s *transportStats) snapshot() TransportStats {
+	st := TransportStats{
+		Backend:        s.backend,
+		Pool:           s.pool,
+		Dials:          s.dials.Load(),
+		OpenConns:      s.open.Load(),
+		ActiveRequests: s.active.Load(),
+	}
+	st.IdleConns = max(st.OpenConns-st.ActiveRequests, 0)
+	return st
+}
+
+func (s *transportStats) addActive(delta int64) {
+	s.active.Add(delta)
+	if s.m != nil {
+		s.m.UpstreamActiveRequests.WithLabelValues(s.backend).Add(float64(delta))
+	}
+}
+
+// countedConn decrements the open count once when closed.
+type countedConn struct {
+	net.Conn
+	stats *transportStats
+	once  sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { c.stats.open.Add(-1) })
+	return c.Conn.Close()
+}
+
+// countingRoundTripper counts a request as active from RoundTrip until its
+// response body is closed. Upgraded (101) connections stop counting once
+// the handshake completes: their body is the tunnel itself and must reach
+// the ReverseProxy unwrapped.
+type countingRoundTripper struct {
+	next  http.RoundTripper
+	stats *transportStats
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.stats.addActive(1)
+	resp, err := c.next.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		c.stats.addActive(-1)
+		return resp, err
+	}
+	resp.Body = &activeBody{ReadCloser: resp.Body, stats: c.stats}
+	return resp, nil
+}
+
+// activeBody ends its request's active count when closed.
+type activeBody struct {
+	io.ReadCloser
+	stats *transportStats
+	once  sync.Once
+}
+
+func (b *activeBody) Close() error {
+	b.once.Do(func() { b.stats.addActive(-1) })
+	return b.ReadCloser.Close()
+}
+
+// TransportStats reports the connection counters of every backend
+// transport, sorted by backend.
+func (rt *Router) TransportStats() []TransportStats {
+	transports := rt.table.Load().transports
+	out := make([]TransportStats, 0, len(transports))
+	for _, t := range transports {
+		out = append(out, t.stats.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Backend != out[j].Backend {
+			return out[i].Backend < out[j].Backend
+		}
+		return out[i].Pool < out[j].Pool
+	})
+	return out
+}
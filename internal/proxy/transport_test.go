@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTransportStats_CountsDialsAndActiveRequests(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/slow" {
+			started <- struct{}{}
+			<-release
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	m := metrics.New(prometheus.NewRegistry())
+	router, err := New([]config.RouteConfig{
+		{PathPrefix: "/api", Backend: backend.URL, TimeoutMs: 5000},
+	}, nil, slog.Default(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := func() TransportStats {
+		all := router.TransportStats()
+		if len(all) != 1 {
+			t.Fatalf("TransportStats() = %+v, want one backend", all)
+		}
+		return all[0]
+	}
+	serve := func(path string) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", path, rec.Code)
+		}
+	}
+
+	serve("/api/a")
+	if s := stats(); s.Dials != 1 || s.OpenConns != 1 || s.ActiveRequests != 0 || s.IdleConns != 1 {
+		t.Fatalf("after first request: %+v, want 1 dial, 1 idle connection", s)
+	}
+
+	// A keep-alive connection is reused: no new dial.
+	serve("/api/b")
+	if s := stats(); s.Dials != 1 {
+		t.Fatalf("after reuse: dials = %d, want 1", s.Dials)
+	}
+
+	// With the idle connection busy, a second request dials a fresh one.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve("/api/slow")
+	}()
+	<-started
+	if s := stats(); s.ActiveRequests != 1 {
+		t.Errorf("during slow request: active = %d, want 1", s.ActiveRequests)
+	}
+	serve("/api/c")
+	close(release)
+	<-done
+
+	s := stats()
+	if s.Dials != 2 || s.OpenConns != 2 || s.ActiveRequests != 0 {
+		t.Errorf("after concurrent requests: %+v, want 2 dials, 2 open, 0 active", s)
+	}
+	if got := testutil.ToFloat64(m.UpstreamDials.WithLabelValues(backend.URL)); got != 2 {
+		t.Errorf("gateway_upstream_dials_total = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.UpstreamActiveRequests.WithLabelValues(backend.URL)); got != 0 {
+		t.Errorf("gateway_upstream_active_requests = %v, want 0", got)
+	}
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// isUpgradeRequest reports whether r asks to switch protocols (e.g. a
+// WebSocket handshake): an Upgrade header plus "upgrade" among the
+// Connection tokens.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// warnUpgradeRetries logs, once per route, that retry_attempts is ignored
+// for upgrade requests. A retry would need the handshake response buffered,
+// which cannot carry a hijacked connection, so upgrades always get exactly
+// one direct attempt.
+func (rt *Router) warnUpgradeRetries(route config.RouteConfig) {
+	if _, warned := rt.upgradeWarned.LoadOrStore(route.ID(), true); warned {
+		return
+	}
+	rt.logger.Warn("retries disabled for protocol upgrade requests on route",
+		"path_prefix", route.PathPrefix,
+		"retry_attempts", route.RetryAttempts,
+	)
+}
+
+// Hijack takes over the client connection for a protocol upgrade, recording
+// 101 as the status since httputil.ReverseProxy writes the handshake
+// response straight to the hijacked connection.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rr.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	rr.statusCode = http.StatusSwitchingProtocols
+	rr.written = true
+	if rr.onHijack != nil {
+		rr.onHijack()
+	}
+	return conn, brw, nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (lw *latencyWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/config"
+)
+
+// echoUpgradeBackend completes an "Upgrade: echo" handshake and then
+// echoes every line it reads back to the client.
+func echoUpgradeBackend(handshakes *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		handshakes.Add(1)
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+		for {
+			line, err := brw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fmt.Fprint(brw, line)
+			_ = brw.Flush()
+		}
+	})
+}
+
+func TestRouter_UpgradeBypassesRetries(t *testing.T) {
+	var handshakes atomic.Int32
+	backend := httptest.NewServer(echoUpgradeBackend(&handshakes))
+	defer backend.Close()
+
+	routes := []config.RouteConfig{
+		{PathPrefix: "/ws", Backend: backend.URL, TimeoutMs: 200, RetryAttempts: 2},
+	}
+	router, err := New(routes, nil, slog.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := httptest.NewServer(router)
+	defer gw.Close()
+
+	conn, err := net.Dial("tcp", gw.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprint(conn, "GET /ws/chat HTTP/1.1\r\nHost: gateway\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 101, got %d: %s", resp.StatusCode, body)
+	}
+
+	// Outlive the route timeout: it bounds the handshake, not the tunnel.
+	time.Sleep(300 * time.Millisecond)
+	fmt.Fprint(conn, "ping\n")
+	line, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read through tunnel: %v", err)
+	}
+	if line != "ping\n" {
+		t.Errorf("expected echoed ping, got %q", line)
+	}
+	if got := handshakes.Load(); got != 1 {
+		t.Errorf("expected exactly one backend handshake, got %d", got)
+	}
+}
+
+func TestIsUpgradeRequest(t *testing.T) {
+	tests := []struct {
+		upgrade, connection string
+		want                bool
+	}{
+		{"websocket", "Upgrade", true},
+		{"websocket", "keep-alive, upgrade", true},
+		{"websocket", "keep-alive", false},
+		{"", "Upgrade", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		if tt.upgrade != "" {
+			r.Header.Set("Upgrade", tt.upgrade)
+		}
+		r.Header.Set("Connection", tt.connection)
+		if got := isUpgradeRequest(r); got != tt.want {
+			t.Errorf("Upgrade=%q Connection=%q: got %v, want %v", tt.upgrade, tt.connection, got, tt.want)
+		}
+	}
+}
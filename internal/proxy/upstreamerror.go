@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dskow/gateway-core/internal/apierror"
+)
+
+// wrapUpstreamError replaces the body of a backend 5xx response with the
+// gateway's JSON error format, keeping the status and the other headers.
+// Responses that are already JSON pass through: the backend's own error
+// detail is more useful than ours.
+func wrapUpstreamError(resp *http.Response) {
+	if resp.StatusCode < 500 || isJSONContentType(resp.Header.Get("Content-Type")) {
+		return
+	}
+	// Drain a little so the connection can be reused; a large error page
+	// is not worth reading in full.
+	_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+	_ = resp.Body.Close()
+
+	body, _ := json.Marshal(apierror.ErrorResponse{
+		Error:     http.StatusText(resp.StatusCode),
+		ErrorCode: string(apierror.UpstreamError),
+		Message:   "upstream service returned an error",
+		RequestID: resp.Request.Header.Get("X-Request-ID"),
+	})
+	body = append(body, '\n')
+
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("ETag")
+	resp.ContentLength = int64(len(body))
+	resp.TransferEncoding = nil
+	resp.Uncompressed = false
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+}
+
+// isJSONContentType reports whether ct is application/json or a +json type.
+func isJSONContentType(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
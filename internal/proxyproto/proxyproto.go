@@ -0,0 +1,222 @@
+// Package proxyproto accepts the PROXY protocol (v1 text and v2 binary)
+// that L4 load balancers prepend to a connection to carry the original
+// client address, so the gateway sees the real client rather than the
+// balancer as each connection's RemoteAddr.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// v2Signature opens every PROXY protocol v2 header.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxV1Header is the longest v1 header the spec allows, CRLF included.
+const maxV1Header = 107
+
+// listener reads a PROXY protocol header from connections whose peer is in
+// trusted. Connections from anyone else are passed through untouched, so an
+// untrusted client cannot claim an address by sending a header itself.
+type listener struct {
+	net.Listener
+	trusted []*net.IPNet
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewListener wraps inner so connections from the trusted networks may
+// start with a PROXY protocol v1 or v2 header; the address it carries
+// becomes the connection's RemoteAddr. A trusted connection without a
+// header keeps its own address. The header is read lazily, on the
+// connection's first RemoteAddr, Read or deadline call, so a slow peer
+// never stalls Accept; timeout bounds the read.
+func NewListener(inner net.Listener, trusted []*net.IPNet, timeout time.Duration, logger *slog.Logger) net.Listener {
+	return &listener{Listener: inner, trusted: trusted, timeout: timeout, logger: logger}
+}
+
+// Accept returns the next connection, wrapped to read its PROXY header when
+// the peer is trusted.
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.isTrusted(c.RemoteAddr()) {
+		return c, nil
+	}
+	return &conn{Conn: c, r: bufio.NewReader(c), timeout: l.timeout, logger: l.logger}, nil
+}
+
+func (l *listener) isTrusted(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range l.trusted {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// conn is a connection from a trusted peer whose PROXY header, if any, is
+// consumed before the first byte of payload is read.
+type conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	logger  *slog.Logger
+
+	once   sync.Once
+	remote net.Addr // from the header; nil keeps the peer address
+	err    error
+}
+
+func (c *conn) init() {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+		}
+		c.remote, c.err = readHeader(c.r)
+		if c.err != nil {
+			c.logger.Debug("proxyproto: invalid PROXY header", "peer", c.Conn.RemoteAddr().String(), "error", c.err)
+			_ = c.Conn.Close()
+		}
+	})
+}
+
+func (c *conn) Read(p []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(p)
+}
+
+// RemoteAddr returns the client address from the PROXY header, or the
+// peer's own address when it sent none.
+func (c *conn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// The deadline setters read the header first, under its own timeout, so a
+// caller's deadline is not cleared when that read finishes.
+
+func (c *conn) SetDeadline(t time.Time) error {
+	c.init()
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.init()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// readHeader consumes a PROXY header from r and returns the source address
+// it carries. It returns a nil address, and consumes nothing, when r does
+// not start with a header, and also for headers that carry no address
+// (v1 UNKNOWN, v2 LOCAL or a non-TCP family).
+func readHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, nil // empty connection; let the caller's read see EOF
+	}
+	switch first[0] {
+	case 'P':
+		if b, _ := r.Peek(6); string(b) == "PROXY " {
+			return readV1(r)
+		}
+	case '\r':
+		if b, _ := r.Peek(len(v2Signature)); bytes.Equal(b, v2Signature) {
+			return readV2(r)
+		}
+	}
+	return nil, nil
+}
+
+// readV1 parses "PROXY TCP4|TCP6|UNKNOWN src dst sport dport\r\n".
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < maxV1Header {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading v1 header: %w", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("v1 header too long or not CRLF-terminated")
+	}
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed v1 header %q", strings.TrimSpace(string(line)))
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, fmt.Errorf("invalid v1 source address %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v1 source port %q", fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 parses the binary v2 header: the signature, version/command,
+// family/protocol, a big-endian length, then the addresses and any TLVs.
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("reading v2 header: %w", err)
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", hdr[12]>>4)
+	}
+	cmd, family := hdr[12]&0x0f, hdr[13]
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("reading v2 addresses: %w", err)
+	}
+	if cmd == 0 { // LOCAL: a health check from the balancer itself
+		return nil, nil
+	}
+	if cmd != 1 {
+		return nil, fmt.Errorf("unsupported v2 command %d", cmd)
+	}
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(body) < 12 {
+			return nil, errors.New("short v2 IPv4 address block")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(body) < 36 {
+			return nil, errors.New("short v2 IPv6 address block")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	}
+	return nil, nil
+}
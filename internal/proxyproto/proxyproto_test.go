@@ -0,0 +1,115 @@
+package proxyproto
+
+import (
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+// roundTrip sends raw on a new connection to a listener trusting trusted
+// and returns the accepted connection's RemoteAddr, what it read, and the
+// read error.
+func roundTrip(t *testing.T, trusted string, raw []byte) (string, string, error) {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, n, err := net.ParseCIDR(trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := NewListener(inner, []*net.IPNet{n}, time.Second, slog.Default())
+	defer ln.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write(raw)
+	}()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	defer conn.Close()
+	remote := conn.RemoteAddr().String()
+	payload, err := io.ReadAll(conn)
+	return remote, string(payload), err
+}
+
+func TestListener_V1(t *testing.T) {
+	remote, payload, err := roundTrip(t, "127.0.0.0/8",
+		[]byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET / HTTP/1.1\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if remote != "203.0.113.7:51234" {
+		t.Errorf("RemoteAddr = %s, want 203.0.113.7:51234", remote)
+	}
+	if payload != "GET / HTTP/1.1\r\n\r\n" {
+		t.Errorf("payload = %q, want the request without the header", payload)
+	}
+}
+
+func TestListener_V2(t *testing.T) {
+	hdr := append([]byte{}, v2Signature...)
+	hdr = append(hdr, 0x21, 0x21) // v2 PROXY, TCP over IPv6
+	hdr = binary.BigEndian.AppendUint16(hdr, 36+3)
+	hdr = append(hdr, net.ParseIP("2001:db8::1")...)
+	hdr = append(hdr, net.ParseIP("2001:db8::2")...)
+	hdr = binary.BigEndian.AppendUint16(hdr, 40000)
+	hdr = binary.BigEndian.AppendUint16(hdr, 443)
+	hdr = append(hdr, 0x04, 0x00, 0x00) // an empty TLV, skipped
+
+	remote, payload, err := roundTrip(t, "127.0.0.0/8", append(hdr, "hello"...))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if remote != "[2001:db8::1]:40000" {
+		t.Errorf("RemoteAddr = %s, want [2001:db8::1]:40000", remote)
+	}
+	if payload != "hello" {
+		t.Errorf("payload = %q, want %q", payload, "hello")
+	}
+}
+
+func TestListener_TrustedPeerWithoutHeader(t *testing.T) {
+	remote, payload, err := roundTrip(t, "127.0.0.0/8", []byte("PUT /x HTTP/1.1\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if host, _, _ := net.SplitHostPort(remote); host != "127.0.0.1" {
+		t.Errorf("RemoteAddr = %s, want the peer's own address", remote)
+	}
+	if payload != "PUT /x HTTP/1.1\r\n\r\n" {
+		t.Errorf("payload = %q, want it untouched", payload)
+	}
+}
+
+func TestListener_IgnoresHeaderFromUntrustedPeer(t *testing.T) {
+	raw := "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET / HTTP/1.1\r\n\r\n"
+	remote, payload, err := roundTrip(t, "10.0.0.0/8", []byte(raw))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if host, _, _ := net.SplitHostPort(remote); host != "127.0.0.1" {
+		t.Errorf("RemoteAddr = %s, want the peer's own address", remote)
+	}
+	if payload != raw {
+		t.Errorf("payload = %q, want the header passed through", payload)
+	}
+}
+
+func TestListener_RejectsMalformedHeader(t *testing.T) {
+	_, payload, err := roundTrip(t, "127.0.0.0/8", []byte("PROXY TCP4 not-an-ip 10.0.0.1 1 2\r\nGET / HTTP/1.1\r\n\r\n"))
+	if err == nil || payload != "" {
+		t.Errorf("read %q, %v; want an error and the connection dropped", payload, err)
+	}
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/dskow/gateway-core/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+// acceptLimitListener caps the rate at which the server accepts new
+// connections with a single global token bucket. It runs before any
+// middleware (and before TLS handshakes), so it is a cheap first line of
+// defense against connection floods, independent of the per-client Limiter.
+type acceptLimitListener struct {
+	net.Listener
+	limiter *rate.Limiter
+	reject  bool
+	logger  *slog.Logger
+	metrics *metrics.Metrics
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewAcceptListener wraps inner so connections are accepted at no more than
+// rps per second with the given burst. In delay mode (reject false) Accept
+// waits for a token before accepting, leaving excess connections queued in
+// the kernel backlog. In reject mode excess connections are accepted and
+// closed immediately. m may be nil.
+func NewAcceptListener(inner net.Listener, rps float64, burst int, reject bool, logger *slog.Logger, m *metrics.Metrics) net.Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &acceptLimitListener{
+		Listener: inner,
+		limiter:  rate.NewLimiter(rate.Limit(rps), burst),
+		reject:   reject,
+		logger:   logger,
+		metrics:  m,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Accept returns the next connection permitted by the global accept rate.
+func (l *acceptLimitListener) Accept() (net.Conn, error) {
+	for {
+		if !l.reject {
+			if err := l.limiter.Wait(l.ctx); err != nil {
+				return nil, net.ErrClosed
+			}
+		}
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if !l.reject || l.limiter.Allow() {
+			return conn, nil
+		}
+		if l.metrics != nil {
+			l.metrics.ListenerRejections.Inc()
+		}
+		l.logger.Debug("ratelimit: connection rejected by global accept rate", "remote_addr", conn.RemoteAddr().String())
+		if cerr := conn.Close(); cerr != nil {
+			l.logger.Debug("ratelimit: failed to close rejected connection", "error", cerr)
+		}
+	}
+}
+
+// Close unblocks a delayed Accept and closes the underlying listener.
+func (l *acceptLimitListener) Close() error {
+	l.cancel()
+	return l.Listener.Close()
+}
+
+// connLimitListener holds at most cap(sem) connections open at once and
+// counts them in the active connections gauge.
+type connLimitListener struct {
+	net.Listener
+	sem     chan struct{} // nil: unlimited
+	metrics *metrics.Metrics
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewConnLimitListener wraps inner so no more than max connections are
+// open at once. At the limit Accept waits for one to close, leaving new
+// connections queued in the kernel backlog. max 0 only counts connections.
+// m may be nil.
+func NewConnLimitListener(inner net.Listener, max int, m *metrics.Metrics) net.Listener {
+	l := &connLimitListener{Listener: inner, metrics: m, done: make(chan struct{})}
+	if max > 0 {
+		l.sem = make(chan struct{}, max)
+	}
+	return l
+}
+
+// Accept waits for a free slot, then returns the next connection. The slot
+// is released when the connection is closed.
+func (l *connLimitListener) Accept() (net.Conn, error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-l.done:
+			return nil, net.ErrClosed
+		}
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	if l.metrics != nil {
+		l.metrics.ActiveTCPConnections.Inc()
+	}
+	return &limitedConn{Conn: conn, release: l.closed}, nil
+}
+
+// closed accounts for a connection that was accepted and is now closed.
+func (l *connLimitListener) closed() {
+	if l.metrics != nil {
+		l.metrics.ActiveTCPConnections.Dec()
+	}
+	l.release()
+}
+
+func (l *connLimitListener) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// Close unblocks a waiting Accept and closes the underlying listener.
+func (l *connLimitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// limitedConn gives back its listener slot the first time it is closed.
+type limitedConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func acceptAll(ln net.Listener) <-chan net.Conn {
+	accepted := make(chan net.Conn, 64)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	return accepted
+}
+
+func TestAcceptListener_DelayModeBoundsRate(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ln := NewAcceptListener(inner, 20, 1, false, slog.Default(), nil)
+	defer ln.Close()
+	accepted := acceptAll(ln)
+
+	const dials = 11
+	start := time.Now()
+	for i := 0; i < dials; i++ {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+	}
+	for i := 0; i < dials; i++ {
+		select {
+		case conn := <-accepted:
+			_ = conn.Close()
+		case <-time.After(3 * time.Second):
+			t.Fatalf("only %d of %d connections accepted", i, dials)
+		}
+	}
+	// One token up front, then one every 50ms.
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("accepted %d connections in %v, expected rate limit of 20/s", dials, elapsed)
+	}
+}
+
+func TestAcceptListener_RejectModeClosesExcess(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	ln := NewAcceptListener(inner, 0.001, 2, true, slog.Default(), m)
+	defer ln.Close()
+	accepted := acceptAll(ln)
+
+	const dials = 5
+	for i := 0; i < dials; i++ {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		if i >= 2 {
+			// Rejected connections are closed by the server.
+			_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+				t.Errorf("dial %d: expected EOF from rejected connection, got %v", i, err)
+			}
+		}
+	}
+
+	if got := len(accepted); got != 2 {
+		t.Errorf("expected 2 connections handed to the server, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.ListenerRejections); got != dials-2 {
+		t.Errorf("expected %d listener rejections, got %v", dials-2, got)
+	}
+}
+
+func TestAcceptListener_CloseUnblocksDelayedAccept(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ln := NewAcceptListener(inner, 0.001, 1, false, slog.Default(), nil)
+	// Spend the only token so the next Accept waits on the limiter.
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if c, err := ln.Accept(); err == nil {
+		_ = c.Close()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		errCh <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	_ = ln.Close()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected error from Accept after Close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Accept did not return after Close")
+	}
+}
+
+func TestConnLimitListener_QueuesBeyondMax(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	ln := NewConnLimitListener(inner, 2, m)
+	defer ln.Close()
+	accepted := acceptAll(ln)
+
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+	}
+
+	var open []net.Conn
+	for i := 0; i < 2; i++ {
+		select {
+		case conn := <-accepted:
+			open = append(open, conn)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of 2 connections accepted", i)
+		}
+	}
+	select {
+	case <-accepted:
+		t.Fatal("third connection accepted while 2 were open")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if got := testutil.ToFloat64(m.ActiveTCPConnections); got != 2 {
+		t.Errorf("gateway_active_tcp_connections = %v, want 2", got)
+	}
+
+	// Closing one (twice, to check the slot is released once) admits the third.
+	_ = open[0].Close()
+	_ = open[0].Close()
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued connection not accepted after a slot freed")
+	}
+	if got := testutil.ToFloat64(m.ActiveTCPConnections); got != 2 {
+		t.Errorf("gateway_active_tcp_connections = %v, want 2", got)
+	}
+	_ = open[1].Close()
+}
@@ -1,26 +1,37 @@
-// Package ratelimit provides per-client-IP token bucket rate limiting
-// middleware for the API gateway.
+// Package ratelimit provides per-client-IP rate limiting middleware for the
+// API gateway, using a token bucket or, optionally, a sliding window.
 package ratelimit
 
 import (
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dskow/gateway-core/internal/apierror"
 	"github.com/dskow/gateway-core/internal/config"
 	"github.com/dskow/gateway-core/internal/metrics"
-	"github.com/dskow/gateway-core/internal/routing"
+	"github.com/dskow/gateway-core/internal/middleware"
 	"golang.org/x/time/rate"
 )
 
+// allower is the per-client admission decision. *rate.Limiter (token
+// bucket) and *slidingWindow both implement it.
+type allower interface {
+	Allow() bool
+	// TokensAt reports the approximate number of requests that would be
+	// admitted at t, for throttling metrics and admin snapshots.
+	TokensAt(t time.Time) float64
+}
+
 type client struct {
-	limiter  *rate.Limiter
+	limiter  allower
 	lastSeen time.Time
+	inflight atomic.Int32 // requests currently being served; see maxConcurrent
 }
 
 // clientKey avoids fmt.Sprintf allocation in the hot path. The composite
@@ -39,8 +50,12 @@ type Limiter struct {
 	clients         map[clientKey]*client
 	rate            rate.Limit
 	burst           int
+	slidingWindow   time.Duration // > 0 selects the sliding-window algorithm
+	maxConcurrent   int32         // per-client in-flight cap; 0 = unlimited
+	retryAfterDate  bool          // rate_limit.retry_after_format "http-date"
 	routes          []config.RouteConfig
 	trustedCIDRs    []*net.IPNet
+	bypassCIDRs     []*net.IPNet
 	idleTTL         time.Duration
 	cleanupInterval time.Duration
 	logger          *slog.Logger
@@ -53,14 +68,13 @@ type Limiter struct {
 // to keep the hot path unblocked during large evictions (DP-005).
 const evictBatchSize = 256
 
-
 // New creates a new Limiter with the given global rate limit settings and
 // route-level overrides. It starts a background janitor that evicts idle
 // client entries at cfg.CleanupInterval; stop it with Close(). trustedProxies
 // is a list of CIDR strings (e.g. "10.0.0.0/8") whose X-Forwarded-For headers
 // are trusted.
 func New(cfg config.RateLimitConfig, routes []config.RouteConfig, trustedProxies []string, logger *slog.Logger, m *metrics.Metrics) *Limiter {
-	cidrs := parseCIDRs(trustedProxies, logger)
+	cidrs := middleware.ParseTrustedProxies(trustedProxies)
 	// Defensive defaults: configs routed through config.Load already have
 	// these applied, but direct callers (tests, embedding) may pass zeros.
 	idleTTL := cfg.IdleTTL
@@ -75,8 +89,12 @@ func New(cfg config.RateLimitConfig, routes []config.RouteConfig, trustedProxies
 		clients:         make(map[clientKey]*client),
 		rate:            rate.Limit(cfg.RequestsPerSecond),
 		burst:           cfg.BurstSize,
-		routes:          routes,
+		slidingWindow:   windowFor(cfg),
+		maxConcurrent:   int32(cfg.MaxConcurrentPerClient),
+		retryAfterDate:  cfg.RetryAfterFormat == "http-date",
+		routes:          compilePaths(routes),
 		trustedCIDRs:    cidrs,
+		bypassCIDRs:     middleware.ParseTrustedProxies(cfg.BypassCIDRs),
 		idleTTL:         idleTTL,
 		cleanupInterval: cleanupInterval,
 		logger:          logger,
@@ -88,17 +106,16 @@ func New(cfg config.RateLimitConfig, routes []config.RouteConfig, trustedProxies
 	return l
 }
 
-func parseCIDRs(cidrs []string, logger *slog.Logger) []*net.IPNet {
-	var nets []*net.IPNet
-	for _, cidr := range cidrs {
-		_, ipNet, err := net.ParseCIDR(cidr)
-		if err != nil {
-			logger.Warn("invalid trusted proxy CIDR, skipping", "cidr", cidr, "error", err)
-			continue
-		}
-		nets = append(nets, ipNet)
+// windowFor returns the sliding window length when cfg selects the
+// sliding_window algorithm, and 0 for the token bucket.
+func windowFor(cfg config.RateLimitConfig) time.Duration {
+	if cfg.Algorithm != "sliding_window" {
+		return 0
 	}
-	return nets
+	if cfg.Window <= 0 {
+		return time.Minute
+	}
+	return cfg.Window
 }
 
 // Stop terminates the background cleanup goroutine. Alias for Close.
@@ -117,17 +134,69 @@ func (l *Limiter) Close() {
 }
 
 // UpdateConfig hot-reloads the global rate limit settings and route overrides.
-// Existing per-client limiters are cleared so new limits take effect immediately.
+// New limits take effect immediately, in fresh buckets; clients keep their
+// buckets, tokens included, under limits that did not change. A change of
+// algorithm or window clears every bucket.
 func (l *Limiter) UpdateConfig(cfg config.RateLimitConfig, routes []config.RouteConfig) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	window := windowFor(cfg)
 	l.rate = rate.Limit(cfg.RequestsPerSecond)
 	l.burst = cfg.BurstSize
-	l.routes = routes
+	l.maxConcurrent = int32(cfg.MaxConcurrentPerClient)
+	l.retryAfterDate = cfg.RetryAfterFormat == "http-date"
+	l.routes = compilePaths(routes)
+	l.bypassCIDRs = middleware.ParseTrustedProxies(cfg.BypassCIDRs)
+
+	if window != l.slidingWindow {
+		// A new algorithm or window: no existing bucket is of the right
+		// kind.
+		l.slidingWindow = window
+		l.clients = make(map[clientKey]*client)
+		if l.metrics != nil {
+			l.metrics.RateLimitClientsTracked.Set(0)
+			l.metrics.RateLimitClientsThrottled.Set(0)
+		}
+		return
+	}
 
-	// Clear existing limiters so new rates apply on next request.
-	l.clients = make(map[clientKey]*client)
+	// Buckets are keyed by rate and burst, so one whose limits are still
+	// in effect keeps its tokens: a reload must not hand every client a
+	// fresh burst. Buckets for limits no longer used are dropped, and the
+	// throttled gauge is recounted over the buckets that remain.
+	inUse := map[limits]bool{{l.rate, l.burst}: true}
+	for _, route := range routes {
+		if o := route.RateOverride; o != nil {
+			inUse[limits{rate.Limit(o.RequestsPerSecond), o.BurstSize}] = true
+		}
+	}
+	now := time.Now()
+	throttled := 0
+	for key, c := range l.clients {
+		if !inUse[limits{key.rate, key.burst}] {
+			delete(l.clients, key)
+		} else if c.limiter.TokensAt(now) < 1 {
+			throttled++
+		}
+	}
+	if l.metrics != nil {
+		l.metrics.RateLimitClientsTracked.Set(float64(len(l.clients)))
+		l.metrics.RateLimitClientsThrottled.Set(float64(throttled))
+	}
+}
+
+// compilePaths compiles the regex patterns of routes once, not per
+// request. Load has already rejected patterns that do not compile.
+func compilePaths(routes []config.RouteConfig) []config.RouteConfig {
+	compiled, _ := config.CompilePaths(routes)
+	return compiled
+}
+
+// limits is a rate and burst pair, as applied to a client's bucket.
+type limits struct {
+	rate  rate.Limit
+	burst int
 }
 
 // Middleware returns an HTTP middleware that enforces rate limits.
@@ -138,16 +207,35 @@ func (l *Limiter) Middleware() func(http.Handler) http.Handler {
 
 			// Single route scan returns rate, burst, and prefix — avoids
 			// the old double-iteration of limitsForPath + routeForPath.
-			rateLimit, burst, routePrefix := l.limitsForPath(r.URL.Path)
+			rateLimit, burst, routePrefix, overridePrefix, skip := l.limitsForRequest(r)
+			if skip || l.bypassed(ip) {
+				// Exempt traffic skips only the limiter; auth and the
+				// proxy further down the chain still apply.
+				middleware.AddLogAttrs(r.Context(), "rate_limit_bypass", true)
+				next.ServeHTTP(w, r)
+				return
+			}
+			middleware.AddLogAttrs(r.Context(),
+				"rate_limit_rps", float64(rateLimit),
+				"rate_limit_burst", burst,
+				"rate_limit_override", overridePrefix,
+			)
+
+			c := l.getClient(ip, rateLimit, burst)
+			if !l.acquire(c) {
+				l.logger.Warn("concurrent request limit exceeded", "client_ip", ip, "path", r.URL.Path)
+				l.setRetryAfter(w, time.Second)
+				apierror.WriteJSON(w, r, http.StatusTooManyRequests, apierror.RateLimitExceeded, "too many concurrent requests, retry later")
+				return
+			}
+			defer l.release(c)
 
-			limiter := l.getLimiter(ip, rateLimit, burst)
-			if !limiter.Allow() {
+			if !c.limiter.Allow() {
 				l.logger.Warn("rate limit exceeded", "client_ip", ip, "path", r.URL.Path)
 				if l.metrics != nil {
 					l.metrics.RateLimitHits.WithLabelValues(routePrefix).Inc()
 				}
-				retryAfter := strconv.FormatFloat(1.0/float64(rateLimit), 'f', 0, 64)
-				w.Header().Set("Retry-After", retryAfter)
+				l.setRetryAfter(w, retryDelay(c.limiter, time.Now()))
 				apierror.WriteJSON(w, r, http.StatusTooManyRequests, apierror.RateLimitExceeded, "rate limit exceeded, retry later")
 				return
 			}
@@ -157,77 +245,114 @@ func (l *Limiter) Middleware() func(http.Handler) http.Handler {
 	}
 }
 
-// clientIP extracts the real client IP. X-Forwarded-For is only trusted when
-// the direct peer (RemoteAddr) is in the trusted proxies list.
+// clientIP returns the real client IP, reusing the value resolved by
+// middleware.ClientIPResolver when it ran earlier in the chain.
+// X-Forwarded-For is only trusted when the direct peer (RemoteAddr) is in
+// the trusted proxies list.
 func (l *Limiter) clientIP(r *http.Request) string {
-	peerIP := extractIP(r.RemoteAddr)
-
-	if len(l.trustedCIDRs) > 0 && l.isTrusted(peerIP) {
-		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-			// Walk right-to-left, return first non-trusted IP
-			parts := strings.Split(xff, ",")
-			for i := len(parts) - 1; i >= 0; i-- {
-				ip := strings.TrimSpace(parts[i])
-				if ip != "" && !l.isTrusted(ip) {
-					return ip
-				}
-			}
-		}
+	if ip := middleware.GetClientIP(r.Context()); ip != "" {
+		return ip
 	}
-
-	return peerIP
+	return middleware.ResolveClientIP(r, l.trustedCIDRs)
 }
 
-func (l *Limiter) isTrusted(ipStr string) bool {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
+// bypassed reports whether ip is in rate_limit.bypass_cidrs.
+func (l *Limiter) bypassed(ip string) bool {
+	l.mu.RLock()
+	nets := l.bypassCIDRs
+	l.mu.RUnlock()
+	if len(nets) == 0 {
 		return false
 	}
-	for _, cidr := range l.trustedCIDRs {
-		if cidr.Contains(ip) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(parsed) {
 			return true
 		}
 	}
 	return false
 }
 
-func extractIP(remoteAddr string) string {
-	host, _, err := net.SplitHostPort(remoteAddr)
-	if err != nil {
-		return remoteAddr
-	}
-	return host
-}
-
-// limitsForPath returns the rate limit, burst, and matching route prefix
-// for the given path. This combines the old limitsForPath + routeForPath
-// into a single route scan to avoid iterating routes twice on rate-limit hits.
-func (l *Limiter) limitsForPath(path string) (rate.Limit, int, string) {
+// limitsForRequest returns the rate limit, burst, and matching route prefix
+// for r, plus the prefix of the route whose rate_override
+// supplied the limits ("" when the global limits apply) and whether the
+// matching route sets skip_rate_limit. This combines the old limitsForPath
+// + routeForPath into a single route scan to avoid iterating routes twice
+// on rate-limit hits.
+func (l *Limiter) limitsForRequest(r *http.Request) (rate.Limit, int, string, string, bool) {
 	var bestOverride *config.RateLimitConfig
-	bestLen := 0
+	bestHost, bestPriority := 0, 0
 	bestPrefix := "unknown"
+	overridePrefix := ""
+	skip := false
 
 	for _, route := range l.routes {
-		if routing.MatchesPrefix(path, route.PathPrefix) && len(route.PathPrefix) > bestLen {
-			bestLen = len(route.PathPrefix)
+		hr := route.HostRank(r.Host)
+		if hr == 0 || !route.MatchesPath(r.URL.Path) || !route.MatchesConditions(r) {
+			continue
+		}
+		if p := route.MatchPriority(); hr > bestHost || (hr == bestHost && p > bestPriority) {
+			bestHost, bestPriority = hr, p
 			bestPrefix = route.PathPrefix
+			skip = route.SkipRateLimit
 			if route.RateOverride != nil {
 				bestOverride = route.RateOverride
+				overridePrefix = route.PathPrefix
 			}
 		}
 	}
 
 	if bestOverride != nil {
-		return rate.Limit(bestOverride.RequestsPerSecond), bestOverride.BurstSize, bestPrefix
+		return rate.Limit(bestOverride.RequestsPerSecond), bestOverride.BurstSize, bestPrefix, overridePrefix, skip
+	}
+	return l.rate, l.burst, bestPrefix, "", skip
+}
+
+// retryDelay returns how long from now until a would admit a request: the
+// delay a reservation would get, computed without taking a token.
+func retryDelay(a allower, now time.Time) time.Duration {
+	switch a := a.(type) {
+	case *rate.Limiter:
+		missing := 1 - a.TokensAt(now)
+		if missing <= 0 || a.Limit() == rate.Inf || a.Limit() <= 0 {
+			return 0
+		}
+		return time.Duration(missing / float64(a.Limit()) * float64(time.Second))
+	case *slidingWindow:
+		return a.delayAt(now)
+	}
+	return 0
+}
+
+// maxRetryAfter caps Retry-After. A very low rate or a long sliding
+// window would otherwise tell a client to go away for hours over what may
+// be a brief burst.
+const maxRetryAfter = time.Minute
+
+// setRetryAfter sets Retry-After on a 429 for a client that may retry
+// after d: in whole seconds, rounded up and between 1 and maxRetryAfter,
+// or as that moment in HTTP-date form with rate_limit.retry_after_format
+// "http-date".
+func (l *Limiter) setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	secs := min(max(1, int64(math.Ceil(d.Seconds()))), int64(maxRetryAfter/time.Second))
+	l.mu.RLock()
+	date := l.retryAfterDate
+	l.mu.RUnlock()
+	if date {
+		w.Header().Set("Retry-After", time.Now().Add(time.Duration(secs)*time.Second).UTC().Format(http.TimeFormat))
+		return
 	}
-	return l.rate, l.burst, bestPrefix
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
 }
 
-// getLimiter returns or creates a rate limiter for the given client key.
+// getClient returns or creates the client entry for the given client key.
 // Uses RWMutex: read-lock for existing clients (common path), write-lock
-// only for new insertions. rate.Limiter is internally goroutine-safe so
-// Allow() does not need to be called under our lock.
-func (l *Limiter) getLimiter(ip string, r rate.Limit, burst int) *rate.Limiter {
+// only for new insertions. The entry's limiter and inflight counter are
+// goroutine-safe, so they are used without our lock.
+func (l *Limiter) getClient(ip string, r rate.Limit, burst int) *client {
 	key := clientKey{ip: ip, rate: r, burst: burst}
 
 	// Fast path: read-lock for existing clients (the common case).
@@ -245,7 +370,7 @@ func (l *Limiter) getLimiter(ip string, r rate.Limit, burst int) *rate.Limiter {
 		} else {
 			l.mu.RUnlock()
 		}
-		return c.limiter
+		return c
 	}
 	l.mu.RUnlock()
 
@@ -256,12 +381,45 @@ func (l *Limiter) getLimiter(ip string, r rate.Limit, burst int) *rate.Limiter {
 	// Double-check after acquiring write lock.
 	if c, exists := l.clients[key]; exists {
 		c.lastSeen = time.Now()
-		return c.limiter
+		return c
 	}
 
-	limiter := rate.NewLimiter(r, burst)
-	l.clients[key] = &client{limiter: limiter, lastSeen: time.Now()}
-	return limiter
+	c := &client{limiter: l.newAllower(r, burst), lastSeen: time.Now()}
+	l.clients[key] = c
+	if l.metrics != nil {
+		l.metrics.RateLimitClientsTracked.Set(float64(len(l.clients)))
+	}
+	return c
+}
+
+// acquire takes one of c's concurrent-request slots, reporting false when
+// all max_concurrent_per_client are in use. Without a limit it always
+// succeeds but still counts, so the janitor can tell the entry is busy.
+func (l *Limiter) acquire(c *client) bool {
+	n := c.inflight.Add(1)
+	l.mu.RLock()
+	limit := l.maxConcurrent
+	l.mu.RUnlock()
+	if limit > 0 && n > limit {
+		c.inflight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release returns a slot taken by acquire.
+func (l *Limiter) release(c *client) {
+	c.inflight.Add(-1)
+}
+
+// newAllower builds a client's limiter for the configured algorithm. A
+// sliding window admits r × window requests per window; burst applies to
+// the token bucket only. Callers hold l.mu.
+func (l *Limiter) newAllower(r rate.Limit, burst int) allower {
+	if l.slidingWindow > 0 {
+		return newSlidingWindow(int(math.Round(float64(r)*l.slidingWindow.Seconds())), l.slidingWindow)
+	}
+	return rate.NewLimiter(r, burst)
 }
 
 // cleanup runs the janitor loop until stopCh closes. Each tick: scan the
@@ -285,16 +443,29 @@ func (l *Limiter) cleanup() {
 // use with the request path.
 func (l *Limiter) evictOnce(now time.Time) {
 	// Phase 1: read-lock scan — collect expired keys without blocking readers.
+	// The same pass counts clients that are currently out of tokens. Clients
+	// with requests in flight are never expired, so their concurrency count
+	// is not lost mid-request.
 	l.mu.RLock()
 	expired := make([]clientKey, 0, len(l.clients)/4)
+	throttled := 0
 	for key, c := range l.clients {
-		if now.Sub(c.lastSeen) > l.idleTTL {
+		if now.Sub(c.lastSeen) > l.idleTTL && c.inflight.Load() == 0 {
 			expired = append(expired, key)
+		} else if c.limiter.TokensAt(now) < 1 {
+			throttled++
 		}
 	}
 	l.mu.RUnlock()
 
+	if l.metrics != nil {
+		l.metrics.RateLimitClientsThrottled.Set(float64(throttled))
+	}
+
 	if len(expired) == 0 {
+		if l.metrics != nil {
+			l.metrics.RateLimitEvictionsPerCleanup.Observe(0)
+		}
 		l.updateTrackedGauge()
 		return
 	}
@@ -309,7 +480,7 @@ func (l *Limiter) evictOnce(now time.Time) {
 		}
 		l.mu.Lock()
 		for _, key := range expired[start:end] {
-			if c, ok := l.clients[key]; ok && now.Sub(c.lastSeen) > l.idleTTL {
+			if c, ok := l.clients[key]; ok && now.Sub(c.lastSeen) > l.idleTTL && c.inflight.Load() == 0 {
 				delete(l.clients, key)
 				evicted++
 			}
@@ -317,8 +488,9 @@ func (l *Limiter) evictOnce(now time.Time) {
 		l.mu.Unlock()
 	}
 
-	if evicted > 0 && l.metrics != nil {
+	if l.metrics != nil {
 		l.metrics.RateLimitClientsEvicted.Add(float64(evicted))
+		l.metrics.RateLimitEvictionsPerCleanup.Observe(float64(evicted))
 	}
 	l.updateTrackedGauge()
 }
@@ -333,12 +505,34 @@ func (l *Limiter) updateTrackedGauge() {
 	l.metrics.RateLimitClientsTracked.Set(float64(n))
 }
 
+// Reset removes every bucket held by ip (one per distinct rate/burst it has
+// hit), so its next request starts with a full burst. It returns the number
+// of buckets removed. An empty ip removes all clients.
+func (l *Limiter) Reset(ip string) int {
+	l.mu.Lock()
+	removed := 0
+	for key := range l.clients {
+		if ip == "" || key.ip == ip {
+			delete(l.clients, key)
+			removed++
+		}
+	}
+	l.mu.Unlock()
+
+	l.updateTrackedGauge()
+	return removed
+}
+
 // LimiterEntry is a snapshot of a single rate limiter client for admin inspection.
+// Tokens is approximate: it is the bucket level at snapshot time, and
+// LastSeen is only refreshed every half idle TTL on the hot path.
 type LimiterEntry struct {
-	IP       string    `json:"ip"`
-	Rate     float64   `json:"rate"`
-	Burst    int       `json:"burst"`
-	LastSeen time.Time `json:"last_seen"`
+	IP              string    `json:"ip"`
+	Rate            float64   `json:"rate"`
+	Burst           int       `json:"burst"`
+	Tokens          float64   `json:"tokens"`
+	LastSeen        time.Time `json:"last_seen"`
+	LastSeenSeconds float64   `json:"last_seen_seconds"`
 }
 
 // maxSnapshotEntries caps the number of entries returned by Snapshot to
@@ -357,12 +551,15 @@ func (l *Limiter) Snapshot() []LimiterEntry {
 		capacity = maxSnapshotEntries
 	}
 	entries := make([]LimiterEntry, 0, capacity)
+	now := time.Now()
 	for key, c := range l.clients {
 		entries = append(entries, LimiterEntry{
-			IP:       key.ip,
-			Rate:     float64(key.rate),
-			Burst:    key.burst,
-			LastSeen: c.lastSeen,
+			IP:              key.ip,
+			Rate:            float64(key.rate),
+			Burst:           key.burst,
+			Tokens:          c.limiter.TokensAt(now),
+			LastSeen:        c.lastSeen,
+			LastSeenSeconds: now.Sub(c.lastSeen).Seconds(),
 		})
 		if len(entries) >= maxSnapshotEntries {
 			break
@@ -1,14 +1,23 @@
 package ratelimit
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dskow/gateway-core/internal/config"
+	"github.com/dskow/gateway-core/internal/metrics"
+	"github.com/dskow/gateway-core/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func okHandler() http.Handler {
@@ -75,6 +84,64 @@ func TestLimiter_BlocksAfterBurst(t *testing.T) {
 	}
 }
 
+// Retry-After comes from when the client's bucket next admits a request,
+// rounded up to whole seconds, not from 1/rate, which rounds to 0 above
+// 1 rps, and is capped at maxRetryAfter.
+func TestLimiter_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RateLimitConfig
+		minWait time.Duration
+		maxWait time.Duration
+	}{
+		{"token bucket seconds", config.RateLimitConfig{RequestsPerSecond: 10, BurstSize: 1}, time.Second, time.Second},
+		{"token bucket http-date", config.RateLimitConfig{RequestsPerSecond: 10, BurstSize: 1, RetryAfterFormat: "http-date"}, 0, 2 * time.Second},
+		{"sliding window", config.RateLimitConfig{RequestsPerSecond: 1, Algorithm: "sliding_window", Window: 30 * time.Second}, 29 * time.Second, 30 * time.Second},
+		{"100 rps rounds up to 1s", config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 1}, time.Second, time.Second},
+		{"capped", config.RateLimitConfig{RequestsPerSecond: 0.001, BurstSize: 1}, maxRetryAfter, maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := New(tt.cfg, nil, nil, slog.Default(), nil)
+			defer limiter.Stop()
+			handler := limiter.Middleware()(okHandler())
+
+			var rec *httptest.ResponseRecorder
+			for range 40 {
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.RemoteAddr = "10.0.0.7:12345"
+				rec = httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code == http.StatusTooManyRequests {
+					break
+				}
+			}
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d, want 429", rec.Code)
+			}
+
+			header := rec.Header().Get("Retry-After")
+			var wait time.Duration
+			if tt.cfg.RetryAfterFormat == "http-date" {
+				at, err := http.ParseTime(header)
+				if err != nil {
+					t.Fatalf("Retry-After = %q, want an HTTP-date: %v", header, err)
+				}
+				wait = time.Until(at)
+			} else {
+				secs, err := strconv.Atoi(header)
+				if err != nil {
+					t.Fatalf("Retry-After = %q, want delay-seconds: %v", header, err)
+				}
+				wait = time.Duration(secs) * time.Second
+			}
+			if wait < tt.minWait || wait > tt.maxWait {
+				t.Errorf("Retry-After = %q (%v), want between %v and %v", header, wait, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
 func TestLimiter_PerClientIsolation(t *testing.T) {
 	cfg := config.RateLimitConfig{
 		RequestsPerSecond: 1,
@@ -112,6 +179,181 @@ func TestLimiter_PerClientIsolation(t *testing.T) {
 	}
 }
 
+func TestLimiter_ResetClearsOnlyThatClient(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		RequestsPerSecond: 1,
+		BurstSize:         1,
+	}
+	limiter := New(cfg, nil, nil, slog.Default(), nil)
+	defer limiter.Stop()
+
+	handler := limiter.Middleware()(okHandler())
+	send := func(ip string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = ip + ":12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	send("10.0.0.1")
+	send("10.0.0.2")
+	if n := limiter.Reset("10.0.0.1"); n != 1 {
+		t.Fatalf("Reset removed %d buckets, want 1", n)
+	}
+	if code := send("10.0.0.1"); code != http.StatusOK {
+		t.Errorf("reset client: got %d, want 200", code)
+	}
+	if code := send("10.0.0.2"); code != http.StatusTooManyRequests {
+		t.Errorf("other client: got %d, want 429", code)
+	}
+
+	if n := limiter.Reset(""); n != 2 {
+		t.Errorf("Reset(\"\") removed %d buckets, want 2", n)
+	}
+}
+
+// A token bucket admits a full burst at once; a sliding window with the
+// same rate never admits more than rate × window, whatever the burst.
+func TestLimiter_SlidingWindowHasNoBurst(t *testing.T) {
+	admitted := func(cfg config.RateLimitConfig) int {
+		limiter := New(cfg, nil, nil, slog.Default(), nil)
+		defer limiter.Stop()
+		handler := limiter.Middleware()(okHandler())
+
+		n := 0
+		for i := 0; i < 20; i++ {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "10.0.0.1:12345"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code == http.StatusOK {
+				n++
+			} else if rec.Header().Get("Retry-After") == "" {
+				t.Errorf("%s: 429 without Retry-After", cfg.Algorithm)
+			}
+		}
+		return n
+	}
+
+	base := config.RateLimitConfig{RequestsPerSecond: 2, BurstSize: 10, Window: 2 * time.Second}
+
+	bucket := base
+	bucket.Algorithm = "token_bucket"
+	if got := admitted(bucket); got != 10 {
+		t.Errorf("token_bucket admitted %d of 20, want the burst of 10", got)
+	}
+
+	window := base
+	window.Algorithm = "sliding_window"
+	if got := admitted(window); got != 4 {
+		t.Errorf("sliding_window admitted %d of 20, want 2 rps × 2s = 4", got)
+	}
+}
+
+func TestLimiter_MaxConcurrentPerClient(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		RequestsPerSecond:      1000,
+		BurstSize:              1000,
+		MaxConcurrentPerClient: 5,
+	}
+	limiter := New(cfg, nil, nil, slog.Default(), nil)
+	defer limiter.Stop()
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.RemoteAddr, "10.0.0.1:") {
+			entered <- struct{}{}
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = ip + ":12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := send("10.0.0.1"); rec.Code != http.StatusOK {
+				t.Errorf("in-limit request: got %d, want 200", rec.Code)
+			}
+		}()
+		<-entered
+	}
+
+	rec := send("10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("6th concurrent request: got %d, want 429", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "concurrent") {
+		t.Errorf("body = %q, want the concurrent-request message", rec.Body.String())
+	}
+
+	// Another client is unaffected.
+	if code := send("10.0.0.2").Code; code != http.StatusOK {
+		t.Errorf("other client: got %d, want 200", code)
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	// Slots are released once the requests finish.
+	done := make(chan int)
+	go func() { done <- send("10.0.0.1").Code }()
+	<-entered
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("after release: got %d, want 200", code)
+	}
+}
+
+func TestLimiter_BypassCIDRsAndSkipRoute(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		RequestsPerSecond: 1,
+		BurstSize:         1,
+		BypassCIDRs:       []string{"10.9.0.0/16"},
+	}
+	routes := []config.RouteConfig{
+		{PathPrefix: "/status", SkipRateLimit: true},
+		{PathPrefix: "/api"},
+	}
+	limiter := New(cfg, routes, nil, slog.Default(), nil)
+	defer limiter.Stop()
+
+	handler := limiter.Middleware()(okHandler())
+	send := func(ip, path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 10; i++ {
+		if code := send("10.9.1.1", "/api/x"); code != http.StatusOK {
+			t.Fatalf("bypass client request %d: got %d, want 200", i, code)
+		}
+		if code := send("10.0.0.1", "/status"); code != http.StatusOK {
+			t.Fatalf("skip_rate_limit route request %d: got %d, want 200", i, code)
+		}
+	}
+
+	send("10.0.0.1", "/api/x")
+	if code := send("10.0.0.1", "/api/x"); code != http.StatusTooManyRequests {
+		t.Errorf("non-bypass client past burst: got %d, want 429", code)
+	}
+	if len(limiter.Snapshot()) != 1 {
+		t.Errorf("bypassed requests should not create buckets, got %d", len(limiter.Snapshot()))
+	}
+}
+
 func TestLimiter_XForwardedFor_NoTrustedProxies(t *testing.T) {
 	cfg := config.RateLimitConfig{
 		RequestsPerSecond: 1,
@@ -256,6 +498,122 @@ func TestLimiter_PerRouteOverride(t *testing.T) {
 	}
 }
 
+// UpdateConfig keeps the buckets of limits it leaves unchanged, so a
+// reload does not refill depleted clients; buckets of changed limits start
+// over.
+func TestLimiter_UpdateConfigKeepsUnchangedBuckets(t *testing.T) {
+	cfg := config.RateLimitConfig{RequestsPerSecond: 0.01, BurstSize: 1}
+	override := func(burst int) []config.RouteConfig {
+		return []config.RouteConfig{{
+			PathPrefix:   "/limited",
+			RateOverride: &config.RateLimitConfig{RequestsPerSecond: 0.02, BurstSize: burst},
+		}}
+	}
+	m := metrics.New(prometheus.NewRegistry())
+	limiter := New(cfg, override(1), nil, slog.Default(), m)
+	defer limiter.Stop()
+	handler := limiter.Middleware()(okHandler())
+	get := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.9:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Deplete the global bucket and the /limited one.
+	for _, path := range []string{"/open", "/limited/x"} {
+		if code := get(path); code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200", path, code)
+		}
+		if code := get(path); code != http.StatusTooManyRequests {
+			t.Fatalf("GET %s again: status = %d, want 429", path, code)
+		}
+	}
+
+	// The global limits stay; /limited gets a larger burst.
+	routes := append(override(2), config.RouteConfig{PathPrefix: "/new"})
+	limiter.UpdateConfig(cfg, routes)
+
+	// Only the depleted global bucket is left.
+	if got := testutil.ToFloat64(m.RateLimitClientsTracked); got != 1 {
+		t.Errorf("clients tracked = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.RateLimitClientsThrottled); got != 1 {
+		t.Errorf("clients throttled = %v, want 1", got)
+	}
+	if code := get("/open"); code != http.StatusTooManyRequests {
+		t.Errorf("unchanged global limits: status = %d, want 429 (bucket kept)", code)
+	}
+	if code := get("/limited/x"); code != http.StatusOK {
+		t.Errorf("changed override: status = %d, want 200 (new bucket)", code)
+	}
+
+	// Switching algorithm clears every bucket.
+	cfg.Algorithm = "sliding_window"
+	cfg.RequestsPerSecond = 1
+	limiter.UpdateConfig(cfg, routes)
+	if code := get("/open"); code != http.StatusOK {
+		t.Errorf("after switching algorithm: status = %d, want 200", code)
+	}
+}
+
+func TestLimiter_DebugLogIncludesAppliedLimits(t *testing.T) {
+	cfg := config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 100}
+	routes := []config.RouteConfig{
+		{PathPrefix: "/tuned", RateOverride: &config.RateLimitConfig{RequestsPerSecond: 5, BurstSize: 2}},
+		{PathPrefix: "/plain"},
+	}
+	limiter := New(cfg, routes, nil, slog.Default(), nil)
+	defer limiter.Stop()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	levels := func(r *http.Request) slog.Level {
+		if r.URL.Path == "/quiet" {
+			return slog.LevelInfo
+		}
+		return slog.LevelDebug
+	}
+	handler := middleware.Logging(logger, levels, nil)(limiter.Middleware()(okHandler()))
+
+	tests := []struct {
+		path      string
+		wantRPS   float64
+		wantBurst float64
+		wantRoute string
+	}{
+		{"/tuned/x", 5, 2, "/tuned"},
+		{"/plain/x", 100, 100, ""},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = "10.0.0.9:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: decoding log entry %q: %v", tt.path, buf.String(), err)
+		}
+		if entry["rate_limit_rps"] != tt.wantRPS || entry["rate_limit_burst"] != tt.wantBurst ||
+			entry["rate_limit_override"] != tt.wantRoute {
+			t.Errorf("%s: got rps=%v burst=%v override=%v, want %v/%v/%q", tt.path,
+				entry["rate_limit_rps"], entry["rate_limit_burst"], entry["rate_limit_override"],
+				tt.wantRPS, tt.wantBurst, tt.wantRoute)
+		}
+	}
+
+	// Routes logged above debug don't carry the tuning attrs.
+	buf.Reset()
+	req := httptest.NewRequest("GET", "/quiet", nil)
+	req.RemoteAddr = "10.0.0.9:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if strings.Contains(buf.String(), "rate_limit_rps") {
+		t.Errorf("info-level log includes rate limit attrs: %s", buf.String())
+	}
+}
+
 func TestLimiter_ResponseBody(t *testing.T) {
 	cfg := config.RateLimitConfig{
 		RequestsPerSecond: 1,
@@ -369,6 +727,87 @@ func TestLimiter_JanitorSparesActiveClients(t *testing.T) {
 	}
 }
 
+func TestLimiter_UsesResolvedClientIPFromContext(t *testing.T) {
+	cfg := config.RateLimitConfig{RequestsPerSecond: 0.001, BurstSize: 1}
+	limiter := New(cfg, nil, nil, slog.Default(), nil)
+	defer limiter.Stop()
+
+	// The limiter itself trusts no proxies, but the resolver earlier in
+	// the chain does; its answer is reused rather than re-derived.
+	handler := middleware.ClientIPResolver([]string{"10.0.0.0/8"})(limiter.Middleware()(okHandler()))
+	for i, xff := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "10.0.0.5:443"
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("request %d from %s: expected 200, got %d", i, xff, rec.Code)
+		}
+	}
+}
+
+func TestLimiter_ExportsClientMetrics(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		RequestsPerSecond: 0.001,
+		BurstSize:         2,
+		IdleTTL:           time.Minute,
+		CleanupInterval:   time.Minute, // we drive evictOnce directly
+	}
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	limiter := New(cfg, nil, nil, slog.Default(), m)
+	defer limiter.Close()
+
+	handler := limiter.Middleware()(okHandler())
+	serve := func(addr string, n int) {
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = addr
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+	serve("10.0.0.1:1234", 1)
+	serve("10.0.0.2:1234", 1)
+	serve("10.0.0.3:1234", 3) // exhausts its burst of 2
+
+	// Tracked count is live, without waiting for a janitor pass.
+	if got := testutil.ToFloat64(m.RateLimitClientsTracked); got != 3 {
+		t.Errorf("expected 3 tracked clients, got %v", got)
+	}
+
+	limiter.evictOnce(time.Now())
+	if got := testutil.ToFloat64(m.RateLimitClientsThrottled); got != 1 {
+		t.Errorf("expected 1 throttled client, got %v", got)
+	}
+
+	limiter.evictOnce(time.Now().Add(2 * time.Minute))
+	if got := testutil.ToFloat64(m.RateLimitClientsTracked); got != 0 {
+		t.Errorf("expected 0 tracked clients after eviction, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.RateLimitClientsEvicted); got != 3 {
+		t.Errorf("expected 3 evictions, got %v", got)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	found := false
+	for _, mf := range mfs {
+		if mf.GetName() != "gateway_ratelimit_evictions_per_cleanup" {
+			continue
+		}
+		found = true
+		h := mf.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 2 || h.GetSampleSum() != 3 {
+			t.Errorf("expected 2 cleanup passes evicting 3 in total, got count=%d sum=%v", h.GetSampleCount(), h.GetSampleSum())
+		}
+	}
+	if !found {
+		t.Error("gateway_ratelimit_evictions_per_cleanup not exported")
+	}
+}
+
 // DP-005: Close must be idempotent and block until the janitor exits.
 func TestLimiter_CloseIsIdempotent(t *testing.T) {
 	cfg := config.RateLimitConfig{
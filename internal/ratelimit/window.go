@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// slidingWindow admits at most limit requests in any window-long interval.
+// It keeps the admission time of each of the last limit requests in a ring
+// buffer: a request is allowed when the ring has room or its oldest entry
+// has left the window. Unlike a token bucket there is no burst credit, so a
+// client can never exceed limit within a window, however it spaces them.
+type slidingWindow struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	ring   []time.Time // grows up to limit, then reused oldest-first
+	next   int         // index of the oldest entry once the ring is full
+	now    func() time.Time
+}
+
+func newSlidingWindow(limit int, window time.Duration) *slidingWindow {
+	if limit < 1 {
+		limit = 1
+	}
+	return &slidingWindow{limit: limit, window: window, now: time.Now}
+}
+
+// Allow reports whether a request may proceed now, recording it if so.
+func (s *slidingWindow) Allow() bool {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.ring) < s.limit {
+		s.ring = append(s.ring, now)
+		return true
+	}
+	if now.Sub(s.ring[s.next]) < s.window {
+		return false
+	}
+	s.ring[s.next] = now
+	s.next = (s.next + 1) % s.limit
+	return true
+}
+
+// delayAt returns how long after t the next request would be admitted: 0
+// while the ring has room, else until its oldest entry leaves the window.
+func (s *slidingWindow) delayAt(t time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ring) < s.limit {
+		return 0
+	}
+	return max(0, s.window-t.Sub(s.ring[s.next]))
+}
+
+// TokensAt returns how many more requests would be admitted at t: limit
+// minus the requests recorded in the window ending at t.
+func (s *slidingWindow) TokensAt(t time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used := 0
+	for _, ts := range s.ring {
+		if t.Sub(ts) < s.window {
+			used++
+		}
+	}
+	return float64(s.limit - used)
+}
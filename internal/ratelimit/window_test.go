@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindow_AdmitsLimitPerWindow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	w := newSlidingWindow(3, time.Minute)
+	w.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !w.Allow() {
+			t.Fatalf("request %d denied, want allowed", i)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	// t=30s: three requests in the last minute.
+	if w.Allow() {
+		t.Fatal("4th request within the window allowed")
+	}
+	if got := w.TokensAt(now); got != 0 {
+		t.Errorf("TokensAt = %v, want 0", got)
+	}
+
+	// t=60s: the first request (t=0) has left the window; only one slot frees.
+	now = now.Add(30 * time.Second)
+	if !w.Allow() {
+		t.Fatal("request after the oldest expired denied")
+	}
+	if w.Allow() {
+		t.Fatal("second request at t=60s allowed; t=10s and t=20s are still in the window")
+	}
+
+	// Two windows later everything has expired.
+	now = now.Add(2 * time.Minute)
+	if got := w.TokensAt(now); got != 3 {
+		t.Errorf("TokensAt after idle = %v, want 3", got)
+	}
+}
@@ -0,0 +1,27 @@
+package routing
+
+import (
+	"net"
+	"strings"
+)
+
+// NormalizeHost lower-cases a request Host value and strips any port, so
+// "API.Example.com:8443" compares equal to "api.example.com".
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// MatchesHost reports whether the normalized host matches pattern: an
+// exact name, or a wildcard "*.example.com" matching any subdomain of
+// example.com (at any depth) but not example.com itself. Patterns are
+// compared case-insensitively.
+func MatchesHost(host, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
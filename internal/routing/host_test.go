@@ -0,0 +1,40 @@
+package routing
+
+import "testing"
+
+func TestNormalizeHost(t *testing.T) {
+	tests := map[string]string{
+		"api.example.com":      "api.example.com",
+		"API.Example.com:8443": "api.example.com",
+		"api.example.com.":     "api.example.com",
+		"[::1]:8080":           "::1",
+		"127.0.0.1":            "127.0.0.1",
+	}
+	for in, want := range tests {
+		if got := NormalizeHost(in); got != want {
+			t.Errorf("NormalizeHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchesHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		pattern string
+		want    bool
+	}{
+		{"api.foo.com", "api.foo.com", true},
+		{"api.foo.com", "API.foo.com", true},
+		{"api.bar.com", "api.foo.com", false},
+		{"a.foo.com", "*.foo.com", true},
+		{"a.b.foo.com", "*.foo.com", true},
+		{"foo.com", "*.foo.com", false},
+		{"evilfoo.com", "*.foo.com", false},
+		{".foo.com", "*.foo.com", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesHost(tt.host, tt.pattern); got != tt.want {
+			t.Errorf("MatchesHost(%q, %q) = %v, want %v", tt.host, tt.pattern, got, tt.want)
+		}
+	}
+}
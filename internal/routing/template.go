@@ -0,0 +1,65 @@
+package routing
+
+import "strings"
+
+// Placeholders substituted by TemplatePath.
+const (
+	IDPlaceholder   = "{id}"
+	UUIDPlaceholder = "{uuid}"
+)
+
+// TemplatePath collapses identifier segments of path into placeholders so
+// per-path metrics stay low-cardinality: all-digit segments become "{id}"
+// and canonical UUIDs (8-4-4-4-12 hex) become "{uuid}". Other segments
+// are kept verbatim, so "/api/users/123/orders" becomes
+// "/api/users/{id}/orders".
+func TemplatePath(path string) string {
+	segments := strings.Split(path, "/")
+	changed := false
+	for i, seg := range segments {
+		switch {
+		case isNumeric(seg):
+			segments[i] = IDPlaceholder
+			changed = true
+		case isUUID(seg):
+			segments[i] = UUIDPlaceholder
+			changed = true
+		}
+	}
+	if !changed {
+		return path
+	}
+	return strings.Join(segments, "/")
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package routing
+
+import "testing"
+
+func TestTemplatePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/users/123", "/api/users/{id}"},
+		{"/api/users/123/orders/456", "/api/users/{id}/orders/{id}"},
+		{"/api/users/550e8400-e29b-41d4-a716-446655440000", "/api/users/{uuid}"},
+		{"/api/users/550E8400-E29B-41D4-A716-446655440000/", "/api/users/{uuid}/"},
+		{"/api/users/me", "/api/users/me"},
+		{"/api/v2/items", "/api/v2/items"},
+		{"/api/users/12a", "/api/users/12a"},
+		{"/api/users/550e8400e29b41d4a716446655440000", "/api/users/550e8400e29b41d4a716446655440000"},
+		{"/", "/"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TemplatePath(tt.path); got != tt.want {
+			t.Errorf("TemplatePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
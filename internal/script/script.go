@@ -0,0 +1,396 @@
+// Package script runs per-route request scripts written in Lua 5.1, on the
+// gopher-lua runtime. A script can read the request's method, path, query
+// and headers, set or remove request headers, rewrite the path sent to the
+// backend, or answer the request itself.
+//
+// Each run gets a fresh interpreter with only the base, string, table and
+// math libraries; the functions that reach the file system, load code or
+// write to stdout (dofile, load, loadfile, loadstring, require, module,
+// print, and the os, io, package and debug libraries) are not available.
+// Sources are capped at maxSourceLen bytes and the call stack at
+// callStackSize frames. A run stops when its context is done; the context
+// is checked between instructions, so the string functions that can build
+// a large string in one call (string.rep, string.gsub and string.format)
+// are capped at maxStringLen bytes.
+//
+// The request is exposed through these globals:
+//
+//	method, path                      the request's method and path
+//	header(name)                      request header value, or nil
+//	query(name)                       query parameter value, or nil
+//	set_header(name, value)           set a request header
+//	del_header(name)                  remove a request header
+//	set_path(path)                    path to send to the backend
+//	respond(status[, body[, type]])   answer with status and stop; the
+//	                                  body's Content-Type defaults to
+//	                                  text/plain; charset=utf-8
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	"github.com/yuin/gopher-lua/pm"
+)
+
+// chunkName prefixes the positions in script errors.
+const chunkName = "request_script"
+
+// maxSourceLen caps a script's source. Compiling deeply nested blocks
+// takes time quadratic in the depth.
+const maxSourceLen = 16 << 10
+
+// maxStringLen caps the strings string.rep and string.gsub build, so a
+// script cannot exhaust memory with one call the context never gets to
+// interrupt.
+const maxStringLen = 1 << 20
+
+const (
+	callStackSize   = 64
+	registrySize    = 1024
+	registryMaxSize = 64 * 1024
+)
+
+// unsafeGlobals are base library functions removed from every state: they
+// load code from files or strings, write to stdout, or reach past the
+// sandbox.
+var unsafeGlobals = []string{
+	"collectgarbage", "dofile", "getfenv", "load", "loadfile", "loadstring",
+	"module", "newproxy", "print", "_printregs", "require", "setfenv",
+}
+
+// Script is a compiled script. It is safe for concurrent use.
+type Script struct {
+	proto *lua.FunctionProto
+}
+
+// Compile parses and compiles src, reporting syntax errors.
+func Compile(src string) (*Script, error) {
+	if len(src) > maxSourceLen {
+		return nil, fmt.Errorf("%s: longer than %d bytes", chunkName, maxSourceLen)
+	}
+	chunk, err := parse.Parse(strings.NewReader(src), chunkName)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, chunkName)
+	if err != nil {
+		return nil, err
+	}
+	return &Script{proto: proto}, nil
+}
+
+// Result is what a run decided.
+type Result struct {
+	// Header is the request's header with the script's changes applied.
+	Header http.Header
+	// Path is the path set by set_path, or "" if the script did not set one.
+	Path string
+	// Status is the status passed to respond, or 0 if the request is to be
+	// proxied. Body and ContentType go with it.
+	Status      int
+	Body        string
+	ContentType string
+}
+
+// Run runs s against r, which it does not modify. Once ctx is done the run
+// stops with an error wrapping ctx.Err().
+func (s *Script) Run(ctx context.Context, r *http.Request) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	x := &run{req: r, res: Result{Header: header}}
+
+	L := newState()
+	defer L.Close()
+	x.install(L)
+	L.SetContext(ctx)
+	L.Push(L.NewFunctionFromProto(s.proto))
+	err := L.PCall(0, 0, nil)
+	switch {
+	case x.res.Status != 0:
+		// respond ends the run by raising an error.
+		return x.res, nil
+	case err != nil && ctx.Err() != nil:
+		return Result{}, fmt.Errorf("%s: %w", chunkName, ctx.Err())
+	case err != nil:
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			// The message without the Lua stack trace.
+			return Result{}, errors.New(apiErr.Object.String())
+		}
+		return Result{}, err
+	}
+	return x.res, nil
+}
+
+// newState returns an interpreter with the sandboxed libraries open.
+func newState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   callStackSize,
+		RegistrySize:    registrySize,
+		RegistryMaxSize: registryMaxSize,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	strlib := L.GetGlobal(lua.StringLibName)
+	L.SetField(strlib, "rep", L.NewFunction(strRep))
+	L.SetField(strlib, "gsub", L.NewFunction(strGsub))
+	format := L.GetField(strlib, "format")
+	L.SetField(strlib, "format", L.NewFunction(func(L *lua.LState) int {
+		checkFormat(L, L.CheckString(1))
+		L.Insert(format, 1)
+		L.Call(L.GetTop()-1, 1)
+		return 1
+	}))
+	return L
+}
+
+// strRep is string.rep with the result capped at maxStringLen bytes.
+func strRep(L *lua.LState) int {
+	s, n := L.CheckString(1), L.CheckInt(2)
+	if n > 0 && len(s) > 0 && n > maxStringLen/len(s) {
+		L.ArgError(2, fmt.Sprintf("result longer than %d bytes", maxStringLen))
+	}
+	L.Push(lua.LString(strings.Repeat(s, max(n, 0))))
+	return 1
+}
+
+// strGsub is string.gsub building its result in one pass, capped at
+// maxStringLen bytes; gopher-lua's copies the whole string for every
+// replacement.
+func strGsub(L *lua.LState) int {
+	s, pat := L.CheckString(1), L.CheckString(2)
+	L.CheckTypes(3, lua.LTString, lua.LTTable, lua.LTFunction)
+	repl := L.Get(3)
+	matches, err := pm.Find(pat, []byte(s), 0, L.OptInt(4, -1))
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m.Capture(0), m.Capture(1)
+		b.WriteString(s[last:start])
+		b.WriteString(replacement(L, s, m, repl))
+		last = end
+		if b.Len() > maxStringLen {
+			break
+		}
+	}
+	b.WriteString(s[last:])
+	if b.Len() > maxStringLen {
+		L.RaiseError("gsub: result longer than %d bytes", maxStringLen)
+	}
+	L.Push(lua.LString(b.String()))
+	L.Push(lua.LNumber(len(matches)))
+	return 2
+}
+
+// replacement returns what repl makes of the match m in s, following Lua
+// 5.1: a nil or false value from a table or function keeps the match.
+func replacement(L *lua.LState, s string, m *pm.MatchData, repl lua.LValue) string {
+	var v lua.LValue
+	switch repl := repl.(type) {
+	case lua.LString:
+		var b strings.Builder
+		for i := 0; i < len(repl); i++ {
+			c := repl[i]
+			if c != '%' || i+1 == len(repl) {
+				b.WriteByte(c)
+				continue
+			}
+			i++
+			if c = repl[i]; c < '0' || c > '9' {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(lua.LVAsString(capture(L, s, m, int(c-'0'))))
+		}
+		return b.String()
+	case *lua.LTable:
+		v = L.GetTable(repl, capture(L, s, m, 1))
+	case *lua.LFunction:
+		n := max(m.CaptureLength()/2-1, 1)
+		L.Push(repl)
+		for i := 1; i <= n; i++ {
+			L.Push(capture(L, s, m, i))
+		}
+		L.Call(n, 1)
+		v = L.Get(-1)
+		L.Pop(1)
+	}
+	switch v.Type() {
+	case lua.LTNil, lua.LTBool:
+		if lua.LVAsBool(v) {
+			L.RaiseError("invalid replacement value (a boolean)")
+		}
+		return s[m.Capture(0):m.Capture(1)]
+	case lua.LTString, lua.LTNumber:
+		return lua.LVAsString(v)
+	}
+	L.RaiseError("invalid replacement value (a %s)", v.Type())
+	return ""
+}
+
+// capture returns capture i of the match m in s; capture 0 is the whole
+// match, as is capture 1 when the pattern has no captures.
+func capture(L *lua.LState, s string, m *pm.MatchData, i int) lua.LValue {
+	if i == 1 && m.CaptureLength() == 2 {
+		i = 0
+	}
+	if 2*i >= m.CaptureLength() {
+		L.RaiseError("invalid capture index")
+	}
+	if m.IsPosCapture(2 * i) {
+		return lua.LNumber(m.Capture(2 * i))
+	}
+	return lua.LString(s[m.Capture(2*i):m.Capture(2*i+1)])
+}
+
+// checkFormat rejects the widths and precisions Lua 5.1 rejects, longer
+// than two digits, which bounds what one string.format call can build.
+func checkFormat(L *lua.LState, format string) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0 {
+			i++
+		}
+		for _, part := range []string{"width", "precision"} {
+			if part == "precision" {
+				if i == len(format) || format[i] != '.' {
+					break
+				}
+				i++
+			}
+			n := 0
+			for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+				if n++; n > 2 {
+					L.RaiseError("invalid format (%s too long)", part)
+				}
+			}
+		}
+	}
+}
+
+// run is the request side of one run: what the globals read and change.
+type run struct {
+	req   *http.Request
+	query url.Values // parsed on first use
+	res   Result
+}
+
+func (x *run) install(L *lua.LState) {
+	L.SetGlobal("method", lua.LString(x.req.Method))
+	L.SetGlobal("path", lua.LString(x.req.URL.Path))
+	for name, fn := range map[string]lua.LGFunction{
+		"header":     x.header,
+		"query":      x.queryValue,
+		"set_header": x.setHeader,
+		"del_header": x.delHeader,
+		"set_path":   x.setPath,
+		"respond":    x.respond,
+	} {
+		L.SetGlobal(name, L.NewFunction(fn))
+	}
+}
+
+func (x *run) header(L *lua.LState) int {
+	return pushFirst(L, x.res.Header.Values(L.CheckString(1)))
+}
+
+func (x *run) queryValue(L *lua.LState) int {
+	if x.query == nil {
+		x.query = x.req.URL.Query()
+	}
+	return pushFirst(L, x.query[L.CheckString(1)])
+}
+
+func pushFirst(L *lua.LState, values []string) int {
+	if len(values) == 0 {
+		L.Push(lua.LNil)
+	} else {
+		L.Push(lua.LString(values[0]))
+	}
+	return 1
+}
+
+func (x *run) setHeader(L *lua.LState) int {
+	name, value := checkHeaderName(L, 1), L.CheckString(2)
+	if strings.ContainsAny(value, "\r\n\x00") {
+		L.ArgError(2, "header value contains a control character")
+	}
+	x.res.Header.Set(name, value)
+	return 0
+}
+
+func (x *run) delHeader(L *lua.LState) int {
+	x.res.Header.Del(checkHeaderName(L, 1))
+	return 0
+}
+
+func (x *run) setPath(L *lua.LState) int {
+	p := L.CheckString(1)
+	if !strings.HasPrefix(p, "/") || strings.ContainsAny(p, "?#\r\n\x00") {
+		L.ArgError(1, "path must start with / and have no query or fragment")
+	}
+	x.res.Path = p
+	return 0
+}
+
+func (x *run) respond(L *lua.LState) int {
+	status := L.CheckInt(1)
+	if status < 200 || status > 599 {
+		L.ArgError(1, "status must be from 200 to 599")
+	}
+	body := L.OptString(2, "")
+	contentType := L.OptString(3, "text/plain; charset=utf-8")
+	if strings.ContainsAny(contentType, "\r\n\x00") {
+		L.ArgError(3, "content type contains a control character")
+	}
+	x.res.Status, x.res.Body, x.res.ContentType = status, body, contentType
+	L.RaiseError("respond")
+	return 0
+}
+
+func checkHeaderName(L *lua.LState, n int) string {
+	name := L.CheckString(n)
+	if name == "" || strings.IndexFunc(name, func(c rune) bool { return !isTokenChar(c) }) >= 0 {
+		L.ArgError(n, fmt.Sprintf("invalid header name %q", name))
+	}
+	return name
+}
+
+// isTokenChar reports whether c may appear in a header name (RFC 9110
+// token).
+func isTokenChar(c rune) bool {
+	return c < 0x7f && c > 0x20 && !strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c)
+}
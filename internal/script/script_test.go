@@ -0,0 +1,277 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runScript(t *testing.T, src string, r *http.Request) (Result, error) {
+	t.Helper()
+	s, err := Compile(src)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return s.Run(context.Background(), r)
+}
+
+func TestCompile_SyntaxErrors(t *testing.T) {
+	for _, src := range []string{
+		`set_header("X-A", "1"`,
+		"if method == \"GET\" then\n  respond(403)\n",
+		`local s = "abc`,
+		`x = = 1`,
+	} {
+		_, err := Compile(src)
+		if err == nil || !strings.Contains(err.Error(), chunkName) {
+			t.Errorf("Compile(%q) error = %v, want a syntax error naming %s", src, err, chunkName)
+		}
+	}
+}
+
+func TestCompile_Limits(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"source too long", "-- " + strings.Repeat("x", maxSourceLen), "longer than 16384 bytes"},
+		{"too many locals", strings.Repeat("local a, b, c, d, e, f, g, h, i, j\n", 30), "too many local variables"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Compile error = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Nesting that fits in maxSourceLen compiles, and quickly.
+	n := maxSourceLen / len("do end ")
+	start := time.Now()
+	if _, err := Compile(strings.Repeat("do ", n) + strings.Repeat("end ", n)); err != nil {
+		t.Fatalf("Compile of %d nested blocks: %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Compile of %d nested blocks took %v", n, elapsed)
+	}
+}
+
+func TestRun_SetsHeadersAndPath(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/users/42?tenant=acme", nil)
+	r.Header.Set("X-Remove", "1")
+	r.Header.Set("X-Version", "2")
+	res, err := runScript(t, `
+		-- Route by the version header.
+		local v = tonumber(header("X-Version")) or 1
+		if v >= 2 then
+			set_path("/v" .. v .. path)
+		end
+		set_header("X-Tenant", query("tenant"):upper())
+		set_header("X-Client", method:lower() .. " " .. path)
+		del_header("X-Remove")
+	`, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != 0 {
+		t.Errorf("Status = %d, want 0", res.Status)
+	}
+	if res.Path != "/v2/api/users/42" {
+		t.Errorf("Path = %q, want /v2/api/users/42", res.Path)
+	}
+	if got := res.Header.Get("X-Tenant"); got != "ACME" {
+		t.Errorf("X-Tenant = %q, want ACME", got)
+	}
+	if got := res.Header.Get("X-Client"); got != "get /api/users/42" {
+		t.Errorf("X-Client = %q, want %q", got, "get /api/users/42")
+	}
+	if res.Header.Get("X-Remove") != "" {
+		t.Error("X-Remove was not removed")
+	}
+	if r.Header.Get("X-Remove") == "" || r.Header.Get("X-Tenant") != "" {
+		t.Error("Run modified the request's header")
+	}
+}
+
+func TestRun_RespondStops(t *testing.T) {
+	src := `
+		if header("X-Tenant") == nil then
+			respond(400, '{"error":"missing tenant"}', "application/json")
+		end
+		set_header("X-Seen", "1")
+	`
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	res, err := runScript(t, src, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != 400 || res.Body != `{"error":"missing tenant"}` || res.ContentType != "application/json" {
+		t.Errorf("Result = %d %q %q, want the 400 response", res.Status, res.Body, res.ContentType)
+	}
+	if res.Header.Get("X-Seen") != "" {
+		t.Error("the script ran past respond")
+	}
+
+	r.Header.Set("X-Tenant", "acme")
+	if res, err = runScript(t, src, r); err != nil || res.Status != 0 || res.Header.Get("X-Seen") != "1" {
+		t.Errorf("with X-Tenant: Result = %+v, %v; want it proxied with X-Seen", res, err)
+	}
+}
+
+func TestRun_StandardLua(t *testing.T) {
+	res, err := runScript(t, `
+		local parts = {}
+		for seg in string.gmatch(path, "[^/]+") do
+			table.insert(parts, seg)
+		end
+		set_header("X-Segments", table.concat(parts, ","))
+		set_header("X-Max", tostring(math.max(3, 7)))
+	`, httptest.NewRequest(http.MethodGet, "/a/b/c", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Header.Get("X-Segments"); got != "a,b,c" {
+		t.Errorf("X-Segments = %q, want a,b,c", got)
+	}
+	if got := res.Header.Get("X-Max"); got != "7" {
+		t.Errorf("X-Max = %q, want 7", got)
+	}
+}
+
+func TestRun_StringGsub(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`string.gsub("hello world", "o", "0")`, "hell0 w0rld"},
+		{`string.gsub("abc", "", "-")`, "-a-b-c-"},
+		{`string.gsub("hello world", "(%w+)", "<%1>")`, "<hello> <world>"},
+		{`string.gsub("hello world", "%w+", "%0 %0", 1)`, "hello hello world"},
+		{`string.gsub("100%", "%%", "%% off")`, "100% off"},
+		{`string.gsub("$name is $age", "%$(%w+)", {name = "bob", age = 3})`, "bob is 3"},
+		{`string.gsub("abc", "%w", {a = false})`, "abc"},
+		{`string.gsub("a=1, b=2", "(%w+)=(%w+)", function(k, v) return v .. "=" .. k end)`, "1=a, 2=b"},
+		{`string.gsub("abc", "()b", "%1")`, "a2c"},
+		{`select(2, string.gsub("abc", "%w", "x"))`, "3"},
+	}
+	for _, tt := range tests {
+		res, err := runScript(t, `set_header("X-Out", tostring((`+tt.src+`)))`, httptest.NewRequest(http.MethodGet, "/", nil))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got := res.Header.Get("X-Out"); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRun_Sandbox(t *testing.T) {
+	for _, name := range []string{
+		"os", "io", "package", "debug", "require", "dofile", "loadfile",
+		"load", "loadstring", "module", "print", "collectgarbage", "getfenv", "setfenv",
+	} {
+		res, err := runScript(t, `set_header("X-Type", type(`+name+`))`, httptest.NewRequest(http.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := res.Header.Get("X-Type"); got != "nil" {
+			t.Errorf("type(%s) = %q, want nil", name, got)
+		}
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`error("boom")`, `request_script:1: boom`},
+		{`local n = 1 + {}`, `request_script:1:`},
+		{`set_header("Bad Name", "1")`, `invalid header name "Bad Name"`},
+		{`set_header("X-A", "1\r\nX-Injected: 1")`, `header value contains a control character`},
+		{`set_path("relative")`, `path must start with / and have no query or fragment`},
+		{`respond(99)`, `status must be from 200 to 599`},
+		{`local s = string.rep("x", 2 * 1024 * 1024)`, `result longer than 1048576 bytes`},
+		{`local s = ("ab"):rep(600 * 1024)`, `result longer than 1048576 bytes`},
+		{`local s = string.rep("x", 1024 * 1024):gsub("x", "yy")`, `gsub: result longer than 1048576 bytes`},
+		{`local s = ("x"):rep(1024):gsub("x", ("y"):rep(1024 * 1024))`, `gsub: result longer than 1048576 bytes`},
+		{`string.gsub("abc", "(%w)", "%2")`, `invalid capture index`},
+		{`string.gsub("abc", "%w", {a = {}})`, `invalid replacement value (a table)`},
+		{`local s = string.format("%100d", 1)`, `invalid format (width too long)`},
+		{`local s = string.format("%1.100f", 1)`, `invalid format (precision too long)`},
+		{`local function f() return f() + 1 end f()`, `stack overflow`},
+	}
+	for _, tt := range tests {
+		_, err := runScript(t, tt.src, httptest.NewRequest(http.MethodGet, "/", nil))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Run(%q) error = %v, want it to contain %q", tt.src, err, tt.want)
+		}
+		if err != nil && strings.Contains(err.Error(), "stack traceback") {
+			t.Errorf("Run(%q) error includes the stack trace: %q", tt.src, err)
+		}
+	}
+}
+
+func TestRun_StringsUnderCap(t *testing.T) {
+	res, err := runScript(t, `
+		set_header("X-Rep", tostring(#string.rep("ab", 512 * 1024)))
+		set_header("X-Gsub", tostring(#string.rep("x", 512 * 1024):gsub("x", "yy")))
+		set_header("X-Format", string.format("%5.2f|%-3d|%%|%s", 3.14159, 7, "s"))
+	`, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"X-Rep":    "1048576",
+		"X-Gsub":   "1048576",
+		"X-Format": " 3.14|7  |%|s",
+	} {
+		if got := res.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRun_StopsAtTimeout(t *testing.T) {
+	s, err := Compile(`
+		local n = 0
+		while true do
+			n = n + 1
+		end
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = s.Run(ctx, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run error = %v, want one wrapping context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run took %v after a 20ms timeout", elapsed)
+	}
+}
+
+func TestRun_RunsAreIsolated(t *testing.T) {
+	s, err := Compile(`
+		if seen then respond(409) end
+		seen = true
+		string.upper = nil
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		res, err := s.Run(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if err != nil || res.Status != 0 {
+			t.Fatalf("run %d: Result = %+v, %v; want globals from the previous run gone", i, res, err)
+		}
+	}
+}
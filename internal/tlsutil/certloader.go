@@ -4,8 +4,11 @@ package tlsutil
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -16,13 +19,39 @@ import (
 // files for changes, automatically reloading on rotation. The GetCertificate
 // callback is designed for use with tls.Config.GetCertificate.
 type CertLoader struct {
-	mu       sync.RWMutex
-	cert     *tls.Certificate
-	certFile string
-	keyFile  string
-	logger   *slog.Logger
-	watcher  *fsnotify.Watcher
-	stopCh   chan struct{}
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool // nil until LoadClientCAs succeeds
+	certFile  string
+	keyFile   string
+	caFile    string
+	logger    *slog.Logger
+	watcher   *fsnotify.Watcher
+	stopCh    chan struct{}
+
+	// OCSP stapling state; ocspKick is nil until EnableOCSPStapling.
+	ocspClient     *http.Client
+	ocspRetry      time.Duration
+	ocspKick       chan struct{}
+	ocspNextUpdate time.Time // NextUpdate of cert's OCSPStaple; zero if it has none
+}
+
+// ClientAuthType maps a config client_auth_mode string to the tls package
+// constant. Unknown or empty modes map to tls.NoClientCert; config
+// validation rejects unknown values before they reach here.
+func ClientAuthType(mode string) tls.ClientAuthType {
+	switch mode {
+	case "request":
+		return tls.RequestClientCert
+	case "require":
+		return tls.RequireAnyClientCert
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven
+	case "require_and_verify":
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
 }
 
 // New loads the initial certificate and starts watching both files for changes.
@@ -75,7 +104,49 @@ func (cl *CertLoader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate,
 	return cl.cert, nil
 }
 
-// Reload reloads the cert/key from disk. Exported for manual reload and testing.
+// LoadClientCAs loads the PEM bundle at caFile as the pool used to verify
+// client certificates and adds it to the watched files, so a rotated CA
+// bundle is picked up the same way as a rotated server certificate.
+func (cl *CertLoader) LoadClientCAs(caFile string) error {
+	cl.mu.Lock()
+	cl.caFile = caFile
+	cl.mu.Unlock()
+
+	if err := cl.loadClientCAs(); err != nil {
+		return fmt.Errorf("initial client CA load: %w", err)
+	}
+	if cl.watcher != nil {
+		if err := cl.watcher.Add(caFile); err != nil {
+			return fmt.Errorf("watching client CA file: %w", err)
+		}
+	}
+	cl.logger.Info("TLS client CA bundle loaded, watching for changes", "client_ca_file", caFile)
+	return nil
+}
+
+// ClientCAs returns the current client CA pool, or nil when no CA bundle
+// has been loaded.
+func (cl *CertLoader) ClientCAs() *x509.CertPool {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.clientCAs
+}
+
+// GetConfigForClient returns a tls.Config.GetConfigForClient callback that
+// hands each handshake a copy of base carrying the current client CA pool.
+// tls.Config.ClientCAs is a plain field, so without this a rotated CA bundle
+// would never reach the listener.
+func (cl *CertLoader) GetConfigForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = cl.ClientCAs()
+		return cfg, nil
+	}
+}
+
+// Reload reloads the cert/key (and client CA bundle, when configured) from
+// disk. Exported for manual reload and testing.
 func (cl *CertLoader) Reload() error {
 	if err := cl.loadCert(); err != nil {
 		cl.logger.Error("TLS certificate reload failed, keeping current",
@@ -83,6 +154,20 @@ func (cl *CertLoader) Reload() error {
 		return err
 	}
 	cl.logger.Info("TLS certificate reloaded", "cert_file", cl.certFile, "key_file", cl.keyFile)
+	cl.kickOCSP()
+
+	cl.mu.RLock()
+	caFile := cl.caFile
+	cl.mu.RUnlock()
+	if caFile == "" {
+		return nil
+	}
+	if err := cl.loadClientCAs(); err != nil {
+		cl.logger.Error("TLS client CA reload failed, keeping current",
+			"error", err, "client_ca_file", caFile)
+		return err
+	}
+	cl.logger.Info("TLS client CA bundle reloaded", "client_ca_file", caFile)
 	return nil
 }
 
@@ -107,6 +192,25 @@ func (cl *CertLoader) loadCert() error {
 	return nil
 }
 
+func (cl *CertLoader) loadClientCAs() error {
+	cl.mu.RLock()
+	caFile := cl.caFile
+	cl.mu.RUnlock()
+
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificates found in %s", caFile)
+	}
+	cl.mu.Lock()
+	cl.clientCAs = pool
+	cl.mu.Unlock()
+	return nil
+}
+
 func (cl *CertLoader) watchLoop() {
 	var debounce *time.Timer
 
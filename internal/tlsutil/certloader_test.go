@@ -124,3 +124,114 @@ func TestCertLoader_Reload(t *testing.T) {
 		t.Fatal("expected non-nil certificate after reload")
 	}
 }
+
+// writeTestCA writes a self-signed CA certificate to dir and returns its path.
+func writeTestCA(t *testing.T, dir, cn string) (caFile string, ca *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA cert: %v", err)
+	}
+	ca, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA cert: %v", err)
+	}
+	caFile = filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatalf("write CA: %v", err)
+	}
+	return caFile, ca
+}
+
+func TestCertLoader_ClientCAsLoadAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := generateTestCert(t, dir)
+	caFile, first := writeTestCA(t, dir, "ca-1")
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cl, err := New(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cl.Stop()
+
+	if cl.ClientCAs() != nil {
+		t.Fatal("expected no client CA pool before LoadClientCAs")
+	}
+	if err := cl.LoadClientCAs(caFile); err != nil {
+		t.Fatalf("LoadClientCAs: %v", err)
+	}
+
+	base := &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}
+	getConfig := cl.GetConfigForClient(base)
+	cfg, err := getConfig(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient: %v", err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("expected base ClientAuth to carry over, got %v", cfg.ClientAuth)
+	}
+	if _, err := first.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs}); err != nil {
+		t.Errorf("expected first CA to be trusted: %v", err)
+	}
+
+	// Rotate the CA bundle and reload.
+	_, second := writeTestCA(t, dir, "ca-2")
+	if err := cl.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	cfg, _ = getConfig(&tls.ClientHelloInfo{})
+	if _, err := second.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs}); err != nil {
+		t.Errorf("expected rotated CA to be trusted: %v", err)
+	}
+}
+
+func TestCertLoader_ClientCAsInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := generateTestCert(t, dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cl, err := New(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cl.Stop()
+
+	if err := cl.LoadClientCAs(caFile); err == nil {
+		t.Fatal("expected error for invalid CA bundle")
+	}
+}
+
+func TestClientAuthType(t *testing.T) {
+	tests := map[string]tls.ClientAuthType{
+		"":                   tls.NoClientCert,
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify_if_given":    tls.VerifyClientCertIfGiven,
+		"require_and_verify": tls.RequireAndVerifyClientCert,
+	}
+	for mode, want := range tests {
+		if got := ClientAuthType(mode); got != want {
+			t.Errorf("ClientAuthType(%q) = %v, want %v", mode, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// handshakeLimitListener is a TLS listener that performs handshakes itself,
+// at most cap(sem) at a time, and only hands fully handshaken connections to
+// Accept. While every slot is busy the accept loop stops pulling from the
+// underlying listener, so a connection storm queues in the kernel backlog
+// instead of burning CPU on thousands of parallel handshakes.
+type handshakeLimitListener struct {
+	inner   net.Listener
+	config  *tls.Config
+	timeout time.Duration
+	logger  *slog.Logger
+
+	sem   chan struct{}
+	conns chan net.Conn
+	errs  chan error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewHandshakeLimitListener wraps inner so that no more than max TLS
+// handshakes run concurrently. Each handshake is bounded by timeout; clients
+// that stall are dropped. The returned listener yields *tls.Conn values whose
+// handshake has already completed, so it can be passed to http.Server.Serve
+// directly (config.NextProtos must list "h2" for HTTP/2 to be negotiated).
+func NewHandshakeLimitListener(inner net.Listener, config *tls.Config, max int, timeout time.Duration, logger *slog.Logger) net.Listener {
+	l := &handshakeLimitListener{
+		inner:   inner,
+		config:  config,
+		timeout: timeout,
+		logger:  logger,
+		sem:     make(chan struct{}, max),
+		conns:   make(chan net.Conn),
+		errs:    make(chan error),
+		done:    make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *handshakeLimitListener) acceptLoop() {
+	for {
+		select {
+		case l.sem <- struct{}{}:
+		case <-l.done:
+			return
+		}
+
+		conn, err := l.inner.Accept()
+		if err != nil {
+			<-l.sem
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go l.handshake(conn)
+	}
+}
+
+func (l *handshakeLimitListener) handshake(conn net.Conn) {
+	tlsConn := tls.Server(conn, l.config)
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	err := tlsConn.HandshakeContext(ctx)
+	cancel()
+	<-l.sem
+
+	if err != nil {
+		l.logger.Debug("tlsutil: TLS handshake failed", "remote_addr", conn.RemoteAddr().String(), "error", err)
+		if cerr := conn.Close(); cerr != nil {
+			l.logger.Debug("tlsutil: failed to close connection", "error", cerr)
+		}
+		return
+	}
+
+	select {
+	case l.conns <- tlsConn:
+	case <-l.done:
+		if cerr := tlsConn.Close(); cerr != nil {
+			l.logger.Debug("tlsutil: failed to close connection", "error", cerr)
+		}
+	}
+}
+
+// Accept returns the next connection whose TLS handshake has completed.
+func (l *handshakeLimitListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting and closes the underlying listener. Handshakes
+// already in progress finish or time out on their own.
+func (l *handshakeLimitListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.inner.Close()
+}
+
+// Addr returns the underlying listener's address.
+func (l *handshakeLimitListener) Addr() net.Addr {
+	return l.inner.Addr()
+}
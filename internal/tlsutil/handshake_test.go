@@ -0,0 +1,127 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandshakeLimitListener_BoundsConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := generateTestCert(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("load key pair: %v", err)
+	}
+
+	const limit, clients = 2, 8
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+
+	// GetCertificate runs mid-handshake, so blocking in it holds a slot.
+	config := &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			inFlight.Add(-1)
+			return &cert, nil
+		},
+	}
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	ln := NewHandshakeLimitListener(inner, config, limit, 5*time.Second, logger)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, clients)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			_ = conn.Close()
+		}()
+	}
+
+	// Give every client time to connect; only `limit` may be handshaking.
+	deadline := time.Now().Add(2 * time.Second)
+	for inFlight.Load() < limit && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := inFlight.Load(); got != limit {
+		t.Errorf("expected %d handshakes in flight, got %d", limit, got)
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < clients; i++ {
+		select {
+		case conn := <-accepted:
+			if _, ok := conn.(*tls.Conn); !ok {
+				t.Errorf("expected *tls.Conn, got %T", conn)
+			}
+			_ = conn.Close()
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of %d connections accepted", i, clients)
+		}
+	}
+	if got := peak.Load(); got > limit {
+		t.Errorf("peak concurrent handshakes %d exceeds limit %d", got, limit)
+	}
+}
+
+func TestHandshakeLimitListener_CloseUnblocksAccept(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	ln := NewHandshakeLimitListener(inner, &tls.Config{}, 1, time.Second, logger)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		errCh <- err
+	}()
+	_ = ln.Close()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected error from Accept after Close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Accept did not return after Close")
+	}
+}
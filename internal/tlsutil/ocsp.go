@@ -0,0 +1,229 @@
+package tlsutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// ocspFetchTimeout bounds a single request to the OCSP responder.
+	ocspFetchTimeout = 10 * time.Second
+	// ocspRetryInterval is how long to wait after a failed fetch before
+	// asking the responder again.
+	ocspRetryInterval = 5 * time.Minute
+	// ocspDefaultRefresh is used when a response carries no NextUpdate.
+	ocspDefaultRefresh = time.Hour
+	// maxOCSPResponseBytes caps the responder body we are willing to read.
+	maxOCSPResponseBytes = 1 << 20
+)
+
+// EnableOCSPStapling fetches an OCSP response for the loaded certificate,
+// attaches it as the certificate's OCSPStaple, and starts a background loop
+// that refreshes it halfway through its validity window and again whenever
+// the certificate is reloaded. Responder failures are logged and retried;
+// the last staple is served until its NextUpdate passes and the certificate
+// without one after that, so enabling stapling never breaks handshakes. A
+// revoked answer removes the staple at once.
+func (cl *CertLoader) EnableOCSPStapling() {
+	cl.mu.Lock()
+	if cl.ocspKick != nil {
+		cl.mu.Unlock()
+		return
+	}
+	cl.ocspKick = make(chan struct{}, 1)
+	if cl.ocspClient == nil {
+		cl.ocspClient = &http.Client{Timeout: ocspFetchTimeout}
+	}
+	if cl.ocspRetry == 0 {
+		cl.ocspRetry = ocspRetryInterval
+	}
+	cl.mu.Unlock()
+
+	next := cl.refreshOCSP()
+	go cl.ocspLoop(next)
+}
+
+// kickOCSP asks the refresh loop to fetch a new staple immediately. No-op
+// when stapling is disabled or a refresh is already pending.
+func (cl *CertLoader) kickOCSP() {
+	cl.mu.RLock()
+	kick := cl.ocspKick
+	cl.mu.RUnlock()
+	if kick == nil {
+		return
+	}
+	select {
+	case kick <- struct{}{}:
+	default:
+	}
+}
+
+func (cl *CertLoader) ocspLoop(next time.Duration) {
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(cl.refreshOCSP())
+		case <-cl.ocspKick:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(cl.refreshOCSP())
+		case <-cl.stopCh:
+			return
+		}
+	}
+}
+
+// refreshOCSP fetches and attaches a staple for the current certificate and
+// returns how long to wait before the next refresh.
+func (cl *CertLoader) refreshOCSP() time.Duration {
+	cl.mu.RLock()
+	cert := cl.cert
+	cl.mu.RUnlock()
+
+	raw, resp, err := cl.fetchOCSP(cert)
+	switch {
+	case err == nil && resp.Status == ocsp.Revoked:
+		cl.logger.Error("OCSP responder reports the certificate revoked, removing staple",
+			"cert_file", cl.certFile, "revoked_at", resp.RevokedAt, "retry_in", cl.ocspRetry)
+		cl.setStaple(cert, nil, time.Time{})
+		return cl.ocspRetry
+	case err == nil && resp.Status != ocsp.Good:
+		err = fmt.Errorf("OCSP status is not good (status %d)", resp.Status)
+	}
+	if err != nil {
+		return cl.ocspFetchFailed(cert, err)
+	}
+
+	cl.setStaple(cert, raw, resp.NextUpdate)
+
+	refresh := ocspDefaultRefresh
+	if !resp.NextUpdate.IsZero() {
+		refresh = resp.ThisUpdate.Add(resp.NextUpdate.Sub(resp.ThisUpdate) / 2).Sub(time.Now())
+		if refresh < time.Minute {
+			refresh = time.Minute
+		}
+	}
+	cl.logger.Info("OCSP staple refreshed", "cert_file", cl.certFile,
+		"this_update", resp.ThisUpdate, "next_update", resp.NextUpdate)
+	return refresh
+}
+
+// ocspFetchFailed handles a failed refresh for cert: its staple is kept
+// until the staple's NextUpdate (indefinitely if the response had none)
+// and removed after that. It returns how long to wait before trying
+// again, no later than that NextUpdate.
+func (cl *CertLoader) ocspFetchFailed(cert *tls.Certificate, err error) time.Duration {
+	cl.mu.RLock()
+	nextUpdate := cl.ocspNextUpdate
+	cl.mu.RUnlock()
+
+	if len(cert.OCSPStaple) == 0 {
+		cl.logger.Warn("OCSP staple fetch failed, serving certificate without staple",
+			"error", err, "cert_file", cl.certFile, "retry_in", cl.ocspRetry)
+		return cl.ocspRetry
+	}
+	retry := cl.ocspRetry
+	if !nextUpdate.IsZero() {
+		untilExpiry := time.Until(nextUpdate)
+		if untilExpiry <= 0 {
+			cl.logger.Warn("OCSP staple fetch failed and the staple has expired, serving certificate without staple",
+				"error", err, "cert_file", cl.certFile, "next_update", nextUpdate, "retry_in", retry)
+			cl.setStaple(cert, nil, time.Time{})
+			return retry
+		}
+		retry = min(retry, untilExpiry)
+	}
+	cl.logger.Warn("OCSP staple fetch failed, keeping the current staple until it expires",
+		"error", err, "cert_file", cl.certFile, "next_update", nextUpdate, "retry_in", retry)
+	return retry
+}
+
+// setStaple replaces the current certificate with a copy of cert carrying
+// staple, valid until nextUpdate. Nothing changes if the certificate was
+// rotated while the staple was fetched; a reload kicks a fresh fetch for
+// the new certificate.
+func (cl *CertLoader) setStaple(cert *tls.Certificate, staple []byte, nextUpdate time.Time) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.cert != cert {
+		return
+	}
+	stapled := *cert
+	stapled.OCSPStaple = staple
+	cl.cert = &stapled
+	cl.ocspNextUpdate = nextUpdate
+}
+
+// fetchOCSP queries the certificate's OCSP responder and returns the raw
+// DER response along with its parsed form, whatever status it reports.
+func (cl *CertLoader) fetchOCSP(cert *tls.Certificate) ([]byte, *ocsp.Response, error) {
+	if len(cert.Certificate) < 2 {
+		return nil, nil, errors.New("certificate chain has no issuer certificate")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, nil, fmt.Errorf("parsing leaf certificate: %w", err)
+		}
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return nil, nil, errors.New("certificate has no OCSP responder URL")
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing issuer certificate: %w", err)
+	}
+
+	reqDER, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating OCSP request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ocspFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(reqDER))
+	if err != nil {
+		return nil, nil, fmt.Errorf("building OCSP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	httpResp, err := cl.ocspClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying OCSP responder: %w", err)
+	}
+	defer func() {
+		if cerr := httpResp.Body.Close(); cerr != nil {
+			cl.logger.Debug("tlsutil: failed to close OCSP response body", "error", cerr)
+		}
+	}()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("OCSP responder returned status %d", httpResp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(httpResp.Body, maxOCSPResponseBytes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading OCSP response: %w", err)
+	}
+
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing OCSP response: %w", err)
+	}
+	return raw, resp, nil
+}
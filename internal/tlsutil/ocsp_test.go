@@ -0,0 +1,278 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspFixture is a CA-issued leaf certificate whose OCSP responder URL
+// points at a mock responder signed by the same CA. The responder answers
+// with status (-1 for an HTTP 500), valid for validFor past now.
+type ocspFixture struct {
+	certFile, keyFile string
+	responder         *httptest.Server
+	requests          int
+	status            atomic.Int64
+	validFor          atomic.Int64 // time.Duration
+}
+
+func newOCSPFixture(t *testing.T, status int) *ocspFixture {
+	t.Helper()
+	dir := t.TempDir()
+	fx := &ocspFixture{}
+	fx.status.Store(int64(status))
+	fx.validFor.Store(int64(time.Hour))
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA cert: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("parse CA cert: %v", err)
+	}
+
+	var leafCert *x509.Certificate
+	fx.responder = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fx.requests++
+		body, _ := io.ReadAll(r.Body)
+		if _, err := ocsp.ParseRequest(body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		status := int(fx.status.Load())
+		if status < 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp, err := ocsp.CreateResponse(caCert, caCert, ocsp.Response{
+			Status:       status,
+			SerialNumber: leafCert.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Duration(fx.validFor.Load())),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		if err != nil {
+			t.Errorf("create OCSP response: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(fx.responder.Close)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate leaf key: %v", err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "gateway.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		OCSPServer:   []string{fx.responder.URL},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, caCert, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create leaf cert: %v", err)
+	}
+	leafCert, err = x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("parse leaf cert: %v", err)
+	}
+
+	fx.certFile = filepath.Join(dir, "cert.pem")
+	fx.keyFile = filepath.Join(dir, "key.pem")
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+	if err := os.WriteFile(fx.certFile, chain, 0o644); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(leafKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	if err := os.WriteFile(fx.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o644); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return fx
+}
+
+func TestCertLoader_OCSPStapleAttached(t *testing.T) {
+	fx := newOCSPFixture(t, ocsp.Good)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cl, err := New(fx.certFile, fx.keyFile, logger)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cl.Stop()
+	cl.EnableOCSPStapling()
+
+	cert, err := cl.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	if len(cert.OCSPStaple) == 0 {
+		t.Fatal("expected OCSP staple on certificate")
+	}
+	resp, err := ocsp.ParseResponse(cert.OCSPStaple, nil)
+	if err != nil {
+		t.Fatalf("parse staple: %v", err)
+	}
+	if resp.Status != ocsp.Good {
+		t.Errorf("expected good status, got %d", resp.Status)
+	}
+}
+
+func TestCertLoader_OCSPResponderFailureServesWithoutStaple(t *testing.T) {
+	for name, status := range map[string]int{"responder error": -1, "revoked": ocsp.Revoked} {
+		t.Run(name, func(t *testing.T) {
+			fx := newOCSPFixture(t, status)
+			logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+			cl, err := New(fx.certFile, fx.keyFile, logger)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer cl.Stop()
+			cl.EnableOCSPStapling()
+
+			cert, err := cl.GetCertificate(&tls.ClientHelloInfo{})
+			if err != nil {
+				t.Fatalf("GetCertificate: %v", err)
+			}
+			if cert == nil {
+				t.Fatal("expected certificate to still be served")
+			}
+			if len(cert.OCSPStaple) != 0 {
+				t.Error("expected no staple when the responder fails")
+			}
+			if fx.requests == 0 {
+				t.Error("expected the responder to be queried")
+			}
+		})
+	}
+}
+
+func TestCertLoader_OCSPWithoutResponderURL(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := generateTestCert(t, dir) // self-signed, no issuer or OCSP URL
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cl, err := New(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cl.Stop()
+	cl.EnableOCSPStapling()
+
+	cert, _ := cl.GetCertificate(&tls.ClientHelloInfo{})
+	if cert == nil || len(cert.OCSPStaple) != 0 {
+		t.Error("expected certificate served without staple")
+	}
+}
+
+// staple returns the OCSP staple cl is serving.
+func staple(t *testing.T, cl *CertLoader) []byte {
+	t.Helper()
+	cert, err := cl.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	return cert.OCSPStaple
+}
+
+func TestCertLoader_OCSPFailedRefreshKeepsStapleUntilNextUpdate(t *testing.T) {
+	for name, tt := range map[string]struct {
+		validFor   time.Duration
+		wantStaple bool
+	}{
+		"before NextUpdate": {time.Hour, true},
+		"after NextUpdate":  {-time.Second, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fx := newOCSPFixture(t, ocsp.Good)
+			fx.validFor.Store(int64(tt.validFor))
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			cl, err := New(fx.certFile, fx.keyFile, logger)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer cl.Stop()
+			cl.EnableOCSPStapling()
+			if len(staple(t, cl)) == 0 {
+				t.Fatal("expected OCSP staple on certificate")
+			}
+
+			fx.status.Store(-1)
+			if retry := cl.refreshOCSP(); retry > cl.ocspRetry {
+				t.Errorf("retry in %v, want at most %v", retry, cl.ocspRetry)
+			}
+			if got := len(staple(t, cl)) > 0; got != tt.wantStaple {
+				t.Errorf("stapled after the failed refresh = %v, want %v", got, tt.wantStaple)
+			}
+		})
+	}
+}
+
+func TestCertLoader_OCSPRevokedRemovesStaple(t *testing.T) {
+	fx := newOCSPFixture(t, ocsp.Good)
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	cl, err := New(fx.certFile, fx.keyFile, logger)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cl.Stop()
+	cl.EnableOCSPStapling()
+	if len(staple(t, cl)) == 0 {
+		t.Fatal("expected OCSP staple on certificate")
+	}
+
+	// The good staple is still within its NextUpdate, but a revoked
+	// answer removes it at once.
+	fx.status.Store(ocsp.Revoked)
+	cl.refreshOCSP()
+	if len(staple(t, cl)) != 0 {
+		t.Error("expected the staple to be removed after a revoked answer")
+	}
+
+	// An unknown answer is a failed refresh and does not bring it back.
+	fx.status.Store(ocsp.Unknown)
+	cl.refreshOCSP()
+	if len(staple(t, cl)) != 0 {
+		t.Error("expected no staple after an unknown answer")
+	}
+}